@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"server/models"
+	"server/store"
+)
+
+func TestAbsenceDetection(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Pradeep")
+	api.assignEmployer(studentID, models.Employer{Name: "Hilton", Latitude: 6.9271, Longitude: 79.8612})
+	week := models.WeeklySchedule{Days: []models.ScheduleDay{{Weekday: time.Monday, Start: "08:00", End: "16:00"}}}
+	api.mustDo("PUT", "/schedule", api.adminToken, studentHeader(studentID), week, http.StatusOK, nil)
+
+	// 2030-01-07 is a Monday; absences are due two hours after the start
+	colombo, _ := time.LoadLocation("Asia/Colombo")
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, colombo)
+	if n, err := api.handler.DetectAbsences(monday.Add(9 * time.Hour)); err != nil || n != 0 {
+		t.Fatalf("before the grace window: %d absences, %v", n, err)
+	}
+	if n, err := api.handler.DetectAbsences(monday.Add(10*time.Hour + time.Minute)); err != nil || n != 1 {
+		t.Fatalf("after the grace window: %d absences, %v; want 1", n, err)
+	}
+	// Running again, as another replica would, changes nothing
+	if n, err := api.handler.DetectAbsences(monday.Add(10*time.Hour + 5*time.Minute)); err != nil || n != 0 {
+		t.Fatalf("second run: %d absences, %v; want 0", n, err)
+	}
+
+	var days []models.AttendanceDay
+	api.mustDo("GET", "/attendance-days?from=2030-01-07&to=2030-01-07", api.adminToken, studentHeader(studentID), nil, http.StatusOK, &days)
+	if len(days) != 1 || !days[0].Absent || days[0].HasOpenSession || days[0].Sessions[0].Punctuality != models.PunctualityAbsent {
+		t.Fatalf("days = %+v, want one absent day", days)
+	}
+	absence := days[0].Sessions[0]
+
+	// Turning up late replaces the absence
+	token := api.pairDevice(studentID)
+	api.attend(token, true, monday.Add(11*time.Hour), http.StatusOK, nil)
+	api.mustDo("GET", "/attendance-days?from=2030-01-07&to=2030-01-07", api.adminToken, studentHeader(studentID), nil, http.StatusOK, &days)
+	if len(days) != 1 || days[0].Absent || len(days[0].Sessions) != 1 || days[0].Sessions[0].Punctuality != models.PunctualityLate {
+		t.Fatalf("days = %+v, want one late session", days)
+	}
+	// The superseded absence is kept out of reads and not recorded again
+	if _, err := api.store.Attendance.Get(int(absence.ID)); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("getting the superseded absence: %v, want not found", err)
+	}
+	if n, err := api.handler.DetectAbsences(monday.Add(12 * time.Hour)); err != nil || n != 0 {
+		t.Errorf("run after the late check-in: %d absences, %v; want 0", n, err)
+	}
+}
+
+func TestAbsenceKeepsThePlacementOfItsShift(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Tharindu")
+	headers := studentHeader(studentID)
+	var first, second models.Employer
+	api.mustDo("POST", "/create-employer", api.adminToken, nil, models.Employer{Name: "Kingsbury", Latitude: 6.9271, Longitude: 79.8612}, http.StatusOK, &first)
+	api.mustDo("POST", "/create-employer", api.adminToken, nil, models.Employer{Name: "Cinnamon Grand", Latitude: 6.9271, Longitude: 79.8612}, http.StatusOK, &second)
+
+	colombo, _ := time.LoadLocation("Asia/Colombo")
+	today := time.Now().In(colombo)
+	yesterday := time.Date(today.Year(), today.Month(), today.Day()-1, 0, 0, 0, 0, colombo)
+	var started models.Placement
+	api.mustDo("POST", "/start-placement", api.adminToken, headers, models.PlacementRequest{EmployerID: int(first.ID), StartDate: yesterday.AddDate(0, 0, -7).Format("2006-01-02")}, http.StatusCreated, &started)
+	api.mustDo("POST", "/start-placement", api.adminToken, headers, models.PlacementRequest{EmployerID: int(second.ID)}, http.StatusCreated, nil)
+
+	// Yesterday's 08:30 shift was missed while still with the first employer
+	if n, err := api.handler.DetectAbsences(yesterday.Add(10*time.Hour + 31*time.Minute)); err != nil || n != 1 {
+		t.Fatalf("recorded %d absences, %v; want 1", n, err)
+	}
+	date := yesterday.Format("2006-01-02")
+	var days []models.AttendanceDay
+	api.mustDo("GET", "/attendance-days?from="+date+"&to="+date, api.adminToken, headers, nil, http.StatusOK, &days)
+	if len(days) != 1 || !days[0].Absent {
+		t.Fatalf("days = %+v, want one absent day", days)
+	}
+	if p := days[0].Sessions[0].PlacementID; p == nil || *p != started.ID {
+		t.Errorf("absence placement = %v, want %d", p, started.ID)
+	}
+}
+
+func TestAutoCloseStaleSessions(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Hiruni")
+	api.assignEmployer(studentID, models.Employer{Name: "Shangri-La", Latitude: 6.9271, Longitude: 79.8612})
+	week := models.WeeklySchedule{Days: []models.ScheduleDay{{Weekday: time.Monday, Start: "08:00", End: "16:00"}}}
+	api.mustDo("PUT", "/schedule", api.adminToken, studentHeader(studentID), week, http.StatusOK, nil)
+	token := api.pairDevice(studentID)
+
+	colombo, _ := time.LoadLocation("Asia/Colombo")
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, colombo)
+	api.attend(token, true, monday.Add(8*time.Hour), http.StatusOK, nil)
+
+	// Sessions are closed an hour after the scheduled end, at the scheduled end
+	if n, err := api.handler.CloseStaleSessions(monday.Add(16*time.Hour + 30*time.Minute)); err != nil || n != 0 {
+		t.Fatalf("within the window: closed %d, %v", n, err)
+	}
+	if n, err := api.handler.CloseStaleSessions(monday.Add(17*time.Hour + time.Minute)); err != nil || n != 1 {
+		t.Fatalf("after the window: closed %d, %v; want 1", n, err)
+	}
+	if n, err := api.handler.CloseStaleSessions(monday.Add(18 * time.Hour)); err != nil || n != 0 {
+		t.Fatalf("second run: closed %d, %v; want 0", n, err)
+	}
+
+	var days []models.AttendanceDay
+	api.mustDo("GET", "/attendance-days?from=2030-01-07&to=2030-01-07", api.adminToken, studentHeader(studentID), nil, http.StatusOK, &days)
+	if len(days) != 1 || days[0].HasOpenSession || days[0].WorkedMinutes != 480 || !days[0].Sessions[0].AutoClosed {
+		t.Fatalf("days = %+v, want one auto-closed eight hour session", days)
+	}
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"server/controllers"
+	"server/models"
+)
+
+func attendanceRequest(checkIn bool, at time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"check_in":      checkIn,
+		"check_in_lat":  6.9271,
+		"check_in_long": 79.8612,
+		"timestamp":     at.Format(time.RFC3339),
+	}
+}
+
+// attend posts a check-in or check-out the server receives at at
+func (api *testAPI) attend(token string, checkIn bool, at time.Time, wantStatus int, out interface{}) {
+	api.t.Helper()
+	api.setClock(at)
+	defer api.setClock(time.Time{})
+	api.mustDo("POST", "/attendance", token, nil, attendanceRequest(checkIn, at), wantStatus, out)
+}
+
+func TestAttendanceCheckInAndOut(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Amali")
+	token := api.pairDevice(studentID)
+	checkIn := time.Now().UTC().Truncate(time.Second)
+
+	var in models.Attendance
+	api.attend(token, true, checkIn, http.StatusOK, &in)
+	if in.ID == 0 || in.StudentID != studentID || !in.CheckInDateTime.Equal(checkIn) {
+		t.Fatalf("check-in = %+v, want record for student %d at %v", in, studentID, checkIn)
+	}
+	if in.CheckOutDateTime.Valid {
+		t.Errorf("new check-in already has a check-out")
+	}
+
+	checkOut := checkIn.Add(time.Minute)
+	var out models.Attendance
+	api.attend(token, false, checkOut, http.StatusOK, &out)
+	if out.ID != in.ID {
+		t.Errorf("check-out updated record %d, want %d", out.ID, in.ID)
+	}
+	if !out.CheckOutDateTime.Valid || !out.CheckOutDateTime.Time.Equal(checkOut) {
+		t.Errorf("check-out time = %+v, want %v", out.CheckOutDateTime, checkOut)
+	}
+
+	var cards []models.StudentCard
+	api.mustDo("GET", "/dashboard", api.adminToken, nil, nil, http.StatusOK, &cards)
+	if len(cards) != 1 || !cards[0].CheckInDateTime.Equal(checkIn) || !cards[0].CheckOutDateTime.Equal(checkOut) {
+		t.Errorf("dashboard = %+v, want latest attendance for student %d", cards, studentID)
+	}
+}
+
+func TestAttendanceCheckOutWithoutCheckIn(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Dilan")
+	token := api.pairDevice(studentID)
+
+	var out models.Attendance
+	api.mustDo("POST", "/attendance", token, nil, attendanceRequest(false, time.Now().UTC()), http.StatusOK, &out)
+	if !out.OrphanCheckOut || !out.CheckOutDateTime.Valid || !out.CheckInDateTime.IsZero() {
+		t.Errorf("orphan check-out = %+v, want flagged check-out without check-in", out)
+	}
+}
+
+func TestAttendanceRejectsOrphanCheckOut(t *testing.T) {
+	t.Setenv("ATTENDANCE_ORPHAN_CHECK_OUT", "reject")
+	api := newTestAPI(t)
+	token := api.pairDevice(api.createStudent("Kasun"))
+
+	api.mustDo("POST", "/attendance", token, nil, attendanceRequest(false, time.Now().UTC()), http.StatusConflict, nil)
+}
+
+func TestAttendanceSplitShift(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Madhavi")
+	token := api.pairDevice(studentID)
+	// Both sessions fall on today's date whatever time the test runs
+	start := time.Now().UTC().Truncate(24 * time.Hour).Add(time.Hour)
+
+	var first models.Attendance
+	api.attend(token, true, start, http.StatusOK, &first)
+	// A second check-in while the session is open is refused
+	api.attend(token, true, start.Add(time.Hour), http.StatusConflict, nil)
+	api.attend(token, false, start.Add(2*time.Hour), http.StatusOK, nil)
+
+	// Back from a break: a new session, the first one is kept
+	var second models.Attendance
+	api.attend(token, true, start.Add(3*time.Hour), http.StatusOK, &second)
+	if second.ID == first.ID {
+		t.Fatalf("re-check-in reused session %d", first.ID)
+	}
+	api.attend(token, false, start.Add(4*time.Hour+30*time.Minute), http.StatusOK, nil)
+
+	date := start.Format("2006-01-02")
+	var days []models.AttendanceDay
+	api.mustDo("GET", "/attendance-days?from="+date+"&to="+date, api.adminToken, studentHeader(studentID), nil, http.StatusOK, &days)
+	if len(days) != 1 {
+		t.Fatalf("got %d days, want 1", len(days))
+	}
+	if len(days[0].Sessions) != 2 || days[0].WorkedMinutes != 210 || days[0].HasOpenSession {
+		t.Errorf("day = %+v, want two closed sessions totalling 210 minutes", days[0])
+	}
+}
+
+func TestAttendanceDaysErrors(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Pasan")
+
+	api.mustDo("GET", "/attendance-days", api.adminToken, nil, nil, http.StatusBadRequest, nil)
+	api.mustDo("GET", "/attendance-days", api.adminToken, studentHeader(999), nil, http.StatusNotFound, nil)
+	api.mustDo("GET", "/attendance-days?from=yesterday", api.adminToken, studentHeader(studentID), nil, http.StatusBadRequest, nil)
+	api.mustDo("GET", "/attendance-days?from=2024-02-01&to=2024-01-01", api.adminToken, studentHeader(studentID), nil, http.StatusBadRequest, nil)
+}
+
+func TestAttendanceDaysDefaultToServerWeek(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Ishara")
+	token := api.pairDevice(studentID)
+
+	colombo, _ := time.LoadLocation("Asia/Colombo")
+	monday := time.Date(2030, 1, 7, 9, 0, 0, 0, colombo)
+	api.attend(token, true, monday, http.StatusOK, nil)
+
+	// Without from and to, the last seven days end on the server's today
+	api.setClock(monday.AddDate(0, 0, 6))
+	defer api.setClock(time.Time{})
+	var days []models.AttendanceDay
+	api.mustDo("GET", "/attendance-days", api.adminToken, studentHeader(studentID), nil, http.StatusOK, &days)
+	if len(days) != 1 || days[0].Date != "2030-01-07" {
+		t.Errorf("days = %+v, want 2030-01-07", days)
+	}
+	api.setClock(monday.AddDate(0, 0, 7))
+	api.mustDo("GET", "/attendance-days", api.adminToken, studentHeader(studentID), nil, http.StatusOK, &days)
+	if len(days) != 0 {
+		t.Errorf("days a week later = %+v, want none", days)
+	}
+}
+
+func TestAttendanceErrors(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Ishara")
+	token := api.pairDevice(studentID)
+
+	// Dashboard sessions cannot record attendance for a trainee
+	api.mustDo("POST", "/attendance", api.adminToken, studentHeader(studentID), attendanceRequest(true, time.Now()), http.StatusForbidden, nil)
+	api.mustDo("POST", "/attendance", "", nil, attendanceRequest(true, time.Now()), http.StatusUnauthorized, nil)
+
+	api.mustDo("POST", "/attendance", token, nil, map[string]interface{}{"check_in": true, "timestamp": "yesterday"}, http.StatusBadRequest, nil)
+	api.mustDo("POST", "/attendance", token, nil, "not an object", http.StatusBadRequest, nil)
+}
+
+func TestPostMood(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Tharindu")
+	token := api.pairDevice(studentID)
+	recordedAt := time.Now().UTC().Truncate(time.Second)
+
+	var mood models.Mood
+	api.mustDo("POST", "/post-mood", token, nil, map[string]interface{}{"emotion": "happy", "is_daily": true, "timestamp": recordedAt.Format(time.RFC3339)}, http.StatusOK, &mood)
+	if mood.ID == 0 || mood.StudentID != studentID || mood.Emotion != "happy" || !mood.IsDaily {
+		t.Fatalf("mood = %+v, want daily happy mood for student %d", mood, studentID)
+	}
+
+	var moods []models.Mood
+	api.mustDo("GET", "/get-mood", api.adminToken, nil, nil, http.StatusOK, &moods)
+	if len(moods) != 1 || moods[0].ID != mood.ID {
+		t.Errorf("moods = %+v, want the posted mood", moods)
+	}
+
+	api.mustDo("POST", "/post-mood", token, nil, map[string]interface{}{"emotion": "sad", "timestamp": "now"}, http.StatusBadRequest, nil)
+	api.mustDo("GET", "/get-mood", token, nil, nil, http.StatusForbidden, nil)
+}
+
+// assignEmployer creates an employer and makes it the student's workplace
+func (api *testAPI) assignEmployer(studentID int, employer models.Employer) models.Employer {
+	api.t.Helper()
+	var created models.Employer
+	api.mustDo("POST", "/create-employer", api.adminToken, nil, employer, http.StatusOK, &created)
+	api.mustDo("PUT", "/update-employee", api.adminToken, studentHeader(studentID), models.Student{FirstName: "Assigned", EmployerID: &created.ID}, http.StatusOK, nil)
+	return created
+}
+
+func locationRequest(checkIn bool, lat, long float64) map[string]interface{} {
+	return map[string]interface{}{
+		"check_in":      checkIn,
+		"check_in_lat":  lat,
+		"check_in_long": long,
+		"timestamp":     time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+func TestAttendanceGeofenceRadius(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Sachini")
+	api.assignEmployer(studentID, models.Employer{Name: "Galle Face Hotel", Latitude: 6.9271, Longitude: 79.8612, GeofenceRadius: 100})
+	token := api.pairDevice(studentID)
+
+	// About 1.1 km north of the workplace
+	status, body := api.do("POST", "/attendance", token, nil, locationRequest(true, 6.9371, 79.8612))
+	if status != http.StatusForbidden {
+		t.Fatalf("far check-in: status %d (%s), want 403", status, body)
+	}
+
+	var in models.Attendance
+	api.mustDo("POST", "/attendance", token, nil, locationRequest(true, 6.9272, 79.8612), http.StatusOK, &in)
+	if in.CheckInInsideGeofence == nil || !*in.CheckInInsideGeofence || in.CheckInDistanceM == nil || *in.CheckInDistanceM > 20 {
+		t.Errorf("check-in geofence = %v/%v, want inside within 20 m", in.CheckInInsideGeofence, in.CheckInDistanceM)
+	}
+
+	// Leaving from outside the geofence is allowed but flagged
+	var out models.Attendance
+	api.mustDo("POST", "/attendance", token, nil, locationRequest(false, 6.9371, 79.8612), http.StatusOK, &out)
+	if out.CheckOutInsideGeofence == nil || *out.CheckOutInsideGeofence || out.CheckOutDistanceM == nil || *out.CheckOutDistanceM < 1000 {
+		t.Errorf("check-out geofence = %v/%v, want outside over 1000 m", out.CheckOutInsideGeofence, out.CheckOutDistanceM)
+	}
+
+	var cards []models.StudentCard
+	api.mustDo("GET", "/dashboard", api.adminToken, nil, nil, http.StatusOK, &cards)
+	if len(cards) != 1 || cards[0].CheckInInsideGeofence == nil || cards[0].CheckOutInsideGeofence == nil || *cards[0].CheckOutInsideGeofence {
+		t.Errorf("dashboard = %+v, want geofence results", cards)
+	}
+}
+
+func TestAttendanceGeofencePolygon(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Harsha")
+	api.assignEmployer(studentID, models.Employer{
+		Name:     "Port City Site",
+		Latitude: 6.9300, Longitude: 79.8400,
+		GeofencePolygon: []models.GeoPoint{
+			{Lat: 6.9250, Long: 79.8350},
+			{Lat: 6.9350, Long: 79.8350},
+			{Lat: 6.9350, Long: 79.8450},
+			{Lat: 6.9250, Long: 79.8450},
+		},
+	})
+	token := api.pairDevice(studentID)
+
+	var location controllers.LocationResponse
+	api.mustDo("POST", "/validate-location", token, nil, models.GeoPoint{Lat: 6.9340, Long: 79.8440}, http.StatusOK, &location)
+	if !location.InRange {
+		t.Errorf("corner of the polygon reported out of range: %+v", location)
+	}
+	// The offline provider scales the straight-line distance by the detour factor
+	if location.DrivingDistance < location.Displacement {
+		t.Errorf("driving distance %d is shorter than displacement %d", location.DrivingDistance, location.Displacement)
+	}
+	api.mustDo("POST", "/validate-location", token, nil, models.GeoPoint{Lat: 6.9400, Long: 79.8400}, http.StatusOK, &location)
+	if location.InRange {
+		t.Errorf("point north of the polygon reported in range: %+v", location)
+	}
+
+	api.mustDo("POST", "/attendance", token, nil, locationRequest(true, 6.9400, 79.8400), http.StatusForbidden, nil)
+	api.mustDo("POST", "/attendance", token, nil, locationRequest(true, 6.9260, 79.8360), http.StatusOK, nil)
+}
+
+func TestEmployerGeofenceValidation(t *testing.T) {
+	api := newTestAPI(t)
+
+	var created models.Employer
+	api.mustDo("POST", "/create-employer", api.adminToken, nil, models.Employer{Name: "Default Radius"}, http.StatusOK, &created)
+	if created.GeofenceRadius != 200 {
+		t.Errorf("default radius = %v, want 200", created.GeofenceRadius)
+	}
+	api.mustDo("POST", "/create-employer", api.adminToken, nil, models.Employer{Name: "Line", GeofencePolygon: []models.GeoPoint{{Lat: 1}, {Lat: 2}}}, http.StatusBadRequest, nil)
+}
+
+func TestAttendanceDaysUseEmployerTimezone(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Ishara")
+	employer := api.assignEmployer(studentID, models.Employer{Name: "Cinnamon Grand", Latitude: 6.9271, Longitude: 79.8612})
+	if employer.Timezone != "Asia/Colombo" {
+		t.Errorf("default timezone = %q, want Asia/Colombo", employer.Timezone)
+	}
+	token := api.pairDevice(studentID)
+
+	// 23:00 UTC is 04:30 the next morning in Colombo
+	colombo, _ := time.LoadLocation("Asia/Colombo")
+	start := time.Now().UTC().Truncate(24 * time.Hour).Add(-time.Hour)
+	api.attend(token, true, start, http.StatusOK, nil)
+
+	utcDate := start.Format("2006-01-02")
+	localDate := start.In(colombo).Format("2006-01-02")
+	var days []models.AttendanceDay
+	api.mustDo("GET", "/attendance-days?from="+utcDate+"&to="+localDate, api.adminToken, studentHeader(studentID), nil, http.StatusOK, &days)
+	if len(days) != 1 || days[0].Date != localDate {
+		t.Fatalf("days = %+v, want one day on %s", days, localDate)
+	}
+}
+
+func TestEmployerTimezoneValidation(t *testing.T) {
+	api := newTestAPI(t)
+	api.mustDo("POST", "/create-employer", api.adminToken, nil, models.Employer{Name: "Nowhere", Timezone: "Mars/Olympus_Mons"}, http.StatusBadRequest, nil)
+
+	var created models.Employer
+	api.mustDo("POST", "/create-employer", api.adminToken, nil, models.Employer{Name: "Dubai", Timezone: "Asia/Dubai"}, http.StatusOK, &created)
+	if created.Timezone != "Asia/Dubai" {
+		t.Errorf("timezone = %q, want Asia/Dubai", created.Timezone)
+	}
+}
+
+// morningIn returns the most recent 00:00 local time in loc that leaves a
+// full day shift in the past
+func morningIn(loc *time.Location) time.Time {
+	now := time.Now().In(loc)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if now.Sub(day) < 17*time.Hour {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day
+}
+
+func TestAttendancePunctuality(t *testing.T) {
+	// The shift below can have started up to 41 hours ago
+	t.Setenv("ATTENDANCE_MAX_SESSION_LENGTH", "48h")
+	api := newTestAPI(t)
+	colombo, _ := time.LoadLocation("Asia/Colombo")
+	day := morningIn(colombo)
+	lateGrace, generousGrace := 15, 30
+	shift := models.ScheduleOverride{Date: day.Format("2006-01-02"), Start: "08:30", End: "16:30"}
+
+	late := api.createStudent("Kasun")
+	api.assignEmployer(late, models.Employer{Name: "Strict", Latitude: 6.9271, Longitude: 79.8612, LateGraceMinutes: &lateGrace})
+	api.mustDo("PUT", "/schedule-override", api.adminToken, studentHeader(late), shift, http.StatusOK, nil)
+	token := api.pairDevice(late)
+
+	var session models.Attendance
+	api.attend(token, true, day.Add(8*time.Hour+50*time.Minute), http.StatusOK, &session)
+	if session.Punctuality != models.PunctualityLate || session.MinutesLate == nil || *session.MinutesLate != 20 {
+		t.Errorf("check-in = %q, %v minutes late; want late by 20", session.Punctuality, session.MinutesLate)
+	}
+	api.attend(token, false, day.Add(16*time.Hour), http.StatusOK, &session)
+	if session.Punctuality != models.PunctualityLate || session.MinutesEarly == nil || *session.MinutesEarly != 30 {
+		t.Errorf("check-out = %q, %v minutes early; want still late and 30 early", session.Punctuality, session.MinutesEarly)
+	}
+
+	early := api.createStudent("Dilini")
+	api.assignEmployer(early, models.Employer{Name: "Relaxed", Latitude: 6.9271, Longitude: 79.8612, LateGraceMinutes: &generousGrace})
+	api.mustDo("PUT", "/schedule-override", api.adminToken, studentHeader(early), shift, http.StatusOK, nil)
+	token = api.pairDevice(early)
+
+	api.attend(token, true, day.Add(8*time.Hour+50*time.Minute), http.StatusOK, &session)
+	if session.Punctuality != models.PunctualityOnTime {
+		t.Errorf("check-in within grace = %q, want on_time", session.Punctuality)
+	}
+	api.attend(token, false, day.Add(16*time.Hour), http.StatusOK, &session)
+	if session.Punctuality != models.PunctualityLeftEarly {
+		t.Errorf("check-out 30 minutes early = %q, want left_early", session.Punctuality)
+	}
+
+	// Coming back after a break is not a late check-in
+	api.attend(token, true, day.Add(16*time.Hour+5*time.Minute), http.StatusOK, &session)
+	if session.Punctuality != "" || session.MinutesLate != nil {
+		t.Errorf("second session = %q, %v; want unevaluated", session.Punctuality, session.MinutesLate)
+	}
+	api.attend(token, false, day.Add(16*time.Hour+30*time.Minute), http.StatusOK, &session)
+	if session.Punctuality != models.PunctualityOnTime {
+		t.Errorf("second check-out = %q, want on_time", session.Punctuality)
+	}
+}
+
+func TestAttendanceIgnoresDeviceClock(t *testing.T) {
+	api := newTestAPI(t)
+	colombo, _ := time.LoadLocation("Asia/Colombo")
+	day := morningIn(colombo)
+	studentID := api.createStudent("Tharindu")
+	api.assignEmployer(studentID, models.Employer{Name: "Kingsbury", Latitude: 6.9271, Longitude: 79.8612})
+	shift := models.ScheduleOverride{Date: day.Format("2006-01-02"), Start: "08:30", End: "16:30"}
+	api.mustDo("PUT", "/schedule-override", api.adminToken, studentHeader(studentID), shift, http.StatusOK, nil)
+	token := api.pairDevice(studentID)
+
+	// A check-in arriving an hour late but dated on time is late
+	received := day.Add(9*time.Hour + 30*time.Minute)
+	api.setClock(received)
+	defer api.setClock(time.Time{})
+	var session models.Attendance
+	api.mustDo("POST", "/attendance", token, nil, attendanceRequest(true, day.Add(8*time.Hour+30*time.Minute)), http.StatusOK, &session)
+	if !session.CheckInDateTime.Equal(received) || session.Punctuality != models.PunctualityLate || *session.MinutesLate != 60 {
+		t.Errorf("check-in = %v, %q, %v minutes late; want late by 60 at %v", session.CheckInDateTime, session.Punctuality, session.MinutesLate, received)
+	}
+}
+
+func TestAttendanceBreakIsNotEarlyLeave(t *testing.T) {
+	t.Setenv("ATTENDANCE_MAX_SESSION_LENGTH", "48h")
+	api := newTestAPI(t)
+	colombo, _ := time.LoadLocation("Asia/Colombo")
+	day := morningIn(colombo)
+	date := day.Format("2006-01-02")
+	studentID := api.createStudent("Sanduni")
+	api.assignEmployer(studentID, models.Employer{Name: "Kingsbury", Latitude: 6.9271, Longitude: 79.8612})
+	shift := models.ScheduleOverride{Date: date, Start: "08:30", End: "16:30"}
+	api.mustDo("PUT", "/schedule-override", api.adminToken, studentHeader(studentID), shift, http.StatusOK, nil)
+	token := api.pairDevice(studentID)
+
+	// Until the trainee is back, leaving at noon looks like leaving early
+	var session models.Attendance
+	api.attend(token, true, day.Add(8*time.Hour+30*time.Minute), http.StatusOK, nil)
+	api.attend(token, false, day.Add(12*time.Hour), http.StatusOK, &session)
+	if session.Punctuality != models.PunctualityLeftEarly {
+		t.Errorf("check-out at noon = %q, want left_early", session.Punctuality)
+	}
+	api.attend(token, true, day.Add(12*time.Hour+30*time.Minute), http.StatusOK, nil)
+	api.attend(token, false, day.Add(16*time.Hour+30*time.Minute), http.StatusOK, nil)
+
+	var days []models.AttendanceDay
+	api.mustDo("GET", "/attendance-days?from="+date+"&to="+date, api.adminToken, studentHeader(studentID), nil, http.StatusOK, &days)
+	if len(days) != 1 || len(days[0].Sessions) != 2 {
+		t.Fatalf("days = %+v, want one day with two sessions", days)
+	}
+	morning, afternoon := days[0].Sessions[0], days[0].Sessions[1]
+	if morning.Punctuality != models.PunctualityOnTime || morning.MinutesEarly != nil {
+		t.Errorf("before the break = %q, %v minutes early; want on_time and unevaluated", morning.Punctuality, morning.MinutesEarly)
+	}
+	if afternoon.Punctuality != models.PunctualityOnTime || afternoon.MinutesEarly == nil || *afternoon.MinutesEarly != 0 {
+		t.Errorf("after the break = %q, %v minutes early; want on_time", afternoon.Punctuality, afternoon.MinutesEarly)
+	}
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// Role identifies what kind of caller a session belongs to
+type Role string
+
+const (
+	RoleAdmin      Role = "admin"
+	RoleSupervisor Role = "supervisor"
+	RoleTrainee    Role = "trainee"
+	// RoleEmployer accounts belong to a placement employer and only reach
+	// the trainees placed with them
+	RoleEmployer Role = "employer"
+)
+
+// Valid reports whether r is one of the known roles
+func (r Role) Valid() bool {
+	switch r {
+	case RoleAdmin, RoleSupervisor, RoleTrainee, RoleEmployer:
+		return true
+	}
+	return false
+}
+
+// Principal is the authenticated caller attached to a request.
+// Dashboard users carry a UserID (and SupervisorID for supervisors,
+// EmployerID for employers), trainee sessions carry the StudentID and the DeviceID they were issued to.
+type Principal struct {
+	Role         Role      `json:"role"`
+	UserID       int       `json:"user_id,omitempty"`
+	SupervisorID int       `json:"supervisor_id,omitempty"`
+	EmployerID   int       `json:"employer_id,omitempty"`
+	StudentID    int       `json:"student_id,omitempty"`
+	DeviceID     int       `json:"device_id,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// HasRole reports whether the principal holds any of the given roles
+func (p *Principal) HasRole(roles ...Role) bool {
+	if p == nil {
+		return false
+	}
+	for _, r := range roles {
+		if p.Role == r {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal stored in ctx, or nil if the request is anonymous
+func PrincipalFrom(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token has expired")
+)
+
+var (
+	secretMu sync.RWMutex
+	secret   []byte
+)
+
+// SetSecret sets the key used to sign and verify session tokens
+func SetSecret(key []byte) {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	secret = append([]byte(nil), key...)
+}
+
+// LoadSecretFromEnv reads AUTH_TOKEN_SECRET. When it is not set a random key
+// is generated, which means sessions will not survive a restart.
+func LoadSecretFromEnv() {
+	if key := os.Getenv("AUTH_TOKEN_SECRET"); key != "" {
+		SetSecret([]byte(key))
+		return
+	}
+	log.Println("⚠️ AUTH_TOKEN_SECRET not set, generating a temporary signing key")
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		log.Fatalf("❌ Failed to generate token signing key: %v", err)
+	}
+	SetSecret(key)
+}
+
+func signingKey() []byte {
+	secretMu.RLock()
+	defer secretMu.RUnlock()
+	return secret
+}
+
+// IssueToken signs p and returns an opaque bearer token valid until p.ExpiresAt
+func IssueToken(p Principal) (string, error) {
+	key := signingKey()
+	if len(key) == 0 {
+		return "", errors.New("token signing key not configured")
+	}
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + sign(key, body), nil
+}
+
+// ParseToken verifies a token produced by IssueToken and returns its principal
+func ParseToken(token string) (*Principal, error) {
+	key := signingKey()
+	if len(key) == 0 {
+		return nil, errors.New("token signing key not configured")
+	}
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(sign(key, body))) {
+		return nil, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var p Principal
+	if err := json.Unmarshal(payload, &p); err != nil || !p.Role.Valid() {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().After(p.ExpiresAt) {
+		return nil, ErrExpiredToken
+	}
+	return &p, nil
+}
+
+func sign(key []byte, body string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"server/models"
+)
+
+func TestLoginRejectsWrongPassword(t *testing.T) {
+	api := newTestAPI(t)
+	api.mustDo("POST", "/login", "", nil, models.LoginRequest{Username: testAdminUsername, Password: "wrong password"}, http.StatusUnauthorized, nil)
+}
+
+func TestRoutesRequireSession(t *testing.T) {
+	api := newTestAPI(t)
+	api.mustDo("GET", "/get-students", "", nil, nil, http.StatusUnauthorized, nil)
+	api.mustDo("GET", "/get-students", "not-a-token", nil, nil, http.StatusUnauthorized, nil)
+}
+
+func TestOTPPairingCycle(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Nimal")
+
+	var first, second models.OTPResponse
+	api.mustDo("POST", "/generate-otp", api.adminToken, studentHeader(studentID), nil, http.StatusOK, &first)
+	api.mustDo("POST", "/generate-otp", api.adminToken, studentHeader(studentID), nil, http.StatusOK, &second)
+	if first.OTPCode != second.OTPCode {
+		t.Errorf("active OTP was replaced: %s then %s", first.OTPCode, second.OTPCode)
+	}
+
+	var validation models.OTPValidationResponse
+	api.mustDo("POST", "/validate-otp", "", map[string]string{"otp-code": first.OTPCode}, nil, http.StatusOK, &validation)
+	if !validation.Success || validation.StudentID != studentID || validation.SecretCode == "" {
+		t.Fatalf("validation = %+v, want success for student %d with a secret", validation, studentID)
+	}
+
+	// A code can only be redeemed once
+	var reused models.OTPValidationResponse
+	api.mustDo("POST", "/validate-otp", "", map[string]string{"otp-code": first.OTPCode}, nil, http.StatusOK, &reused)
+	if reused.Success {
+		t.Errorf("OTP %s was accepted twice", first.OTPCode)
+	}
+
+	var verified struct {
+		Authorized bool `json:"authorized"`
+	}
+	api.mustDo("POST", "/verify-device-auth", "", nil, models.AuthRequest{StudentID: studentID, SecretCode: validation.SecretCode}, http.StatusOK, &verified)
+	if !verified.Authorized {
+		t.Errorf("device secret was not accepted: %+v", verified)
+	}
+
+	var session models.SessionResponse
+	api.mustDo("POST", "/device-session", "", nil, models.AuthRequest{StudentID: studentID, SecretCode: validation.SecretCode}, http.StatusOK, &session)
+	if session.Role != "trainee" || session.StudentID != studentID {
+		t.Errorf("session = %+v, want trainee session for student %d", session, studentID)
+	}
+}
+
+func TestOTPErrors(t *testing.T) {
+	api := newTestAPI(t)
+
+	api.mustDo("POST", "/generate-otp", api.adminToken, nil, nil, http.StatusBadRequest, nil)
+	api.mustDo("POST", "/generate-otp", api.adminToken, map[string]string{"student-id": "abc"}, nil, http.StatusBadRequest, nil)
+	api.mustDo("POST", "/generate-otp", api.adminToken, studentHeader(999), nil, http.StatusNotFound, nil)
+	api.mustDo("POST", "/validate-otp", "", nil, nil, http.StatusBadRequest, nil)
+
+	var validation models.OTPValidationResponse
+	api.mustDo("POST", "/validate-otp", "", map[string]string{"otp-code": "0000"}, nil, http.StatusOK, &validation)
+	if validation.Success {
+		t.Errorf("unknown OTP was accepted")
+	}
+
+	api.mustDo("POST", "/device-session", "", nil, models.AuthRequest{StudentID: 1, SecretCode: "bogus"}, http.StatusUnauthorized, nil)
+}
+
+func TestZeroOTPDurationsAreClamped(t *testing.T) {
+	t.Setenv("OTP_TTL", "0s")
+	t.Setenv("OTP_LOCKOUT_DURATION", "-1m")
+	t.Setenv("OTP_MAX_FAILED_ATTEMPTS", "1")
+	api := newTestAPI(t)
+	// Fresh codes still pair, and a failure still locks the IP out
+	if token := api.pairDevice(api.createStudent("Isuru")); token == "" {
+		t.Error("pairing returned no token")
+	}
+	api.mustDo("POST", "/validate-otp", "", map[string]string{"otp-code": "0000"}, nil, http.StatusOK, nil)
+	api.mustDo("POST", "/validate-otp", "", map[string]string{"otp-code": "0000"}, nil, http.StatusTooManyRequests, nil)
+}
+
+func TestZeroOTPRateLimitsAreTreatedAsOne(t *testing.T) {
+	t.Setenv("OTP_RATE_LIMIT_PER_IP", "0")
+	t.Setenv("OTP_RATE_LIMIT_PER_CODE", "-1")
+	t.Setenv("OTP_MAX_FAILED_ATTEMPTS", "0")
+	api := newTestAPI(t)
+	// The first attempt goes through; the next one from the same IP waits
+	if token := api.pairDevice(api.createStudent("Isuru")); token == "" {
+		t.Error("pairing returned no token")
+	}
+	api.mustDo("POST", "/validate-otp", "", map[string]string{"otp-code": "0000"}, nil, http.StatusTooManyRequests, nil)
+}
+
+func TestRevokedDeviceIsRejected(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Kamal")
+	token := api.pairDevice(studentID)
+
+	var devices []models.AuthorizedDevice
+	api.mustDo("GET", "/get-devices", api.adminToken, studentHeader(studentID), nil, http.StatusOK, &devices)
+	if len(devices) != 1 {
+		t.Fatalf("got %d devices, want 1", len(devices))
+	}
+	deviceID := map[string]string{"device-id": strconv.Itoa(int(devices[0].ID))}
+	api.mustDo("DELETE", "/revoke-device", api.adminToken, deviceID, nil, http.StatusOK, nil)
+	api.mustDo("DELETE", "/revoke-device", api.adminToken, deviceID, nil, http.StatusNotFound, nil)
+
+	api.mustDo("POST", "/post-mood", token, nil, map[string]interface{}{"emotion": "happy", "timestamp": "2024-01-01T09:00:00Z"}, http.StatusUnauthorized, nil)
+}
+
+func TestTraineeCannotActAsAnotherStudent(t *testing.T) {
+	api := newTestAPI(t)
+	own := api.createStudent("Sunil")
+	other := api.createStudent("Ruwan")
+	token := api.pairDevice(own)
+
+	api.mustDo("GET", "/get-student", token, studentHeader(other), nil, http.StatusForbidden, nil)
+	api.mustDo("GET", "/get-students", token, nil, nil, http.StatusForbidden, nil)
+
+	var student models.Student
+	api.mustDo("GET", "/get-student", token, nil, nil, http.StatusOK, &student)
+	if int(student.ID) != own {
+		t.Errorf("got student %d, want %d", student.ID, own)
+	}
+}
+
+func TestSupervisorOnlyReachesOwnTrainees(t *testing.T) {
+	api := newTestAPI(t)
+	own := api.supervisedStudent("Chamari")
+	other := api.createStudent("Ruwan")
+	s, err := api.store.Students.Get(own)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := api.supervisorToken("ruwani", int(*s.SupervisorID))
+	api.mustDo("POST", "/update-emergency-contact", api.adminToken, nil, map[string]string{"phone_number": "+94112345678"}, http.StatusOK, nil)
+
+	for _, route := range []struct{ method, path string }{
+		{"POST", "/generate-otp"},
+		{"GET", "/get-student"},
+		{"GET", "/get-emergency-contact"},
+		{"GET", "/trainee-profile"},
+		{"GET", "/trainee-summary"},
+		{"GET", "/attendance-days"},
+		{"GET", "/schedule"},
+		{"GET", "/schedule-overrides"},
+		{"GET", "/expected-shift"},
+	} {
+		api.mustDo(route.method, route.path, token, studentHeader(own), nil, http.StatusOK, nil)
+		api.mustDo(route.method, route.path, token, studentHeader(other), nil, http.StatusForbidden, nil)
+	}
+	location := models.GeoPoint{Lat: 6.9271, Long: 79.8612}
+	api.mustDo("POST", "/validate-location", token, studentHeader(own), location, http.StatusOK, nil)
+	api.mustDo("POST", "/validate-location", token, studentHeader(other), location, http.StatusForbidden, nil)
+}
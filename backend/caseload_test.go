@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"server/controllers"
+	"server/models"
+)
+
+// middayZone names a fixed-offset zone where it is currently around noon, so
+// a shift from 08:00 to 17:00 today is always under way
+func middayZone() string {
+	offset := 12 - time.Now().UTC().Hour()
+	switch {
+	case offset > 0:
+		return fmt.Sprintf("Etc/GMT-%d", offset)
+	case offset < 0:
+		return fmt.Sprintf("Etc/GMT+%d", -offset)
+	}
+	return "Etc/GMT"
+}
+
+func TestSupervisorCaseload(t *testing.T) {
+	api := newTestAPI(t)
+	supervisorID := api.createSupervisor("Ruwani")
+	var employer models.Employer
+	api.mustDo("POST", "/create-employer", api.adminToken, nil, models.Employer{Name: "Kingsbury", Latitude: 6.9271, Longitude: 79.8612, GeofenceRadius: 100, Timezone: middayZone()}, http.StatusOK, &employer)
+	loc, err := time.LoadLocation(employer.Timezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := models.ScheduleOverride{Date: time.Now().In(loc).Format("2006-01-02"), Start: "08:00", End: "17:00"}
+
+	trainee := func(name string) int {
+		id := api.createStudent(name)
+		sup := uint(supervisorID)
+		api.mustDo("PUT", "/update-employee", api.adminToken, studentHeader(id), models.Student{FirstName: name, SupervisorID: &sup, EmployerID: &employer.ID}, http.StatusOK, nil)
+		return id
+	}
+	quiet := trainee("Kasun")
+	late := trainee("Chamari")
+	missing := trainee("Nimal")
+	away := trainee("Dilini")
+	elsewhere := api.supervisedStudent("Sachini")
+
+	api.mustDo("PUT", "/schedule-override", api.adminToken, studentHeader(late), today, http.StatusOK, nil)
+	api.mustDo("POST", "/attendance", api.pairDevice(late), nil, attendanceRequest(true, time.Now()), http.StatusOK, nil)
+	api.mustDo("PUT", "/schedule-override", api.adminToken, studentHeader(missing), today, http.StatusOK, nil)
+	awayToken := api.pairDevice(away)
+	// Check-outs outside the geofence are recorded rather than refused
+	api.mustDo("POST", "/attendance", awayToken, nil, locationRequest(true, 6.9271, 79.8612), http.StatusOK, nil)
+	api.mustDo("POST", "/attendance", awayToken, nil, locationRequest(false, 6.9371, 79.8612), http.StatusOK, nil)
+	postMood(api, awayToken, "sad", true, time.Now())
+
+	token := api.supervisorToken("ruwani", supervisorID)
+	var caseload models.CaseloadDashboard
+	api.mustDo("GET", "/caseload-dashboard", token, nil, nil, http.StatusOK, &caseload)
+	want := models.CaseloadCounts{Trainees: 4, Flagged: 3, LateToday: 1, NotCheckedIn: 1, NegativeMood: 1, OutsideGeofence: 1}
+	if caseload.SupervisorID != supervisorID || caseload.Counts != want {
+		t.Errorf("counts = %+v, want %+v", caseload.Counts, want)
+	}
+	flags := map[int64]models.CaseloadExceptions{}
+	for _, c := range caseload.Trainees {
+		flags[c.StudentID] = c.Exceptions
+		if c.StudentID == int64(elsewhere) {
+			t.Errorf("caseload includes another supervisor's trainee")
+		}
+	}
+	for id, e := range map[int]models.CaseloadExceptions{
+		quiet:   {},
+		late:    {LateToday: true},
+		missing: {NotCheckedIn: true},
+		away:    {NegativeMood: true, OutsideGeofence: true},
+	} {
+		if got, ok := flags[int64(id)]; !ok || got != e {
+			t.Errorf("student %d exceptions = %+v, want %+v", id, got, e)
+		}
+	}
+	if n := len(caseload.Trainees); n != 4 || caseload.Trainees[n-1].StudentID != int64(quiet) {
+		t.Errorf("trainees = %+v, want the unflagged trainee last", caseload.Trainees)
+	}
+
+	api.mustDo("GET", "/caseload-dashboard?flagged=true", token, nil, nil, http.StatusOK, &caseload)
+	if len(caseload.Trainees) != 3 || caseload.Counts.Trainees != 4 {
+		t.Errorf("flagged only = %d trainees, counts %+v", len(caseload.Trainees), caseload.Counts)
+	}
+
+	var rows []controllers.StudentEmployerSupervisor
+	api.mustDo("GET", "/caseload-management", token, nil, nil, http.StatusOK, &rows)
+	if len(rows) != 4 {
+		t.Errorf("caseload management has %d rows, want 4", len(rows))
+	}
+	var employees []controllers.EmployeeResponse
+	api.mustDo("GET", "/caseload-employees", token, nil, nil, http.StatusOK, &employees)
+	if len(employees) != 4 || employees[1].StudentID != late || employees[1].LatestOTPCode != nil || employees[1].ExpiresAt != nil {
+		t.Errorf("caseload employees = %+v, want no OTPs for a supervisor", employees)
+	}
+
+	other := map[string]string{"supervisor-id": strconv.Itoa(supervisorID + 1)}
+	api.mustDo("GET", "/caseload-dashboard", token, other, nil, http.StatusForbidden, nil)
+	api.mustDo("GET", "/caseload-dashboard", api.adminToken, nil, nil, http.StatusBadRequest, nil)
+	api.mustDo("GET", "/caseload-dashboard", api.adminToken, map[string]string{"supervisor-id": "999"}, nil, http.StatusNotFound, nil)
+	api.mustDo("GET", "/caseload-management", api.adminToken, map[string]string{"supervisor-id": strconv.Itoa(supervisorID)}, nil, http.StatusOK, &rows)
+	if len(rows) != 4 {
+		t.Errorf("admin view of the caseload has %d rows, want 4", len(rows))
+	}
+	api.mustDo("GET", "/caseload-employees", api.adminToken, map[string]string{"supervisor-id": strconv.Itoa(supervisorID)}, nil, http.StatusOK, &employees)
+	if len(employees) != 4 || employees[1].LatestOTPCode == nil {
+		t.Errorf("admin caseload employees = %+v, want the OTP", employees)
+	}
+	api.mustDo("GET", "/caseload-dashboard", api.pairDevice(quiet), nil, nil, http.StatusForbidden, nil)
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// String returns the environment variable key, or def when it is unset
+func String(key, def string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return def
+}
+
+// Int returns the environment variable key parsed as an int, or def when it
+// is unset or invalid
+func Int(key string, def int) int {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("⚠️ Invalid %s=%q, using default %d", key, v, def)
+		return def
+	}
+	return n
+}
+
+// Float returns the environment variable key parsed as a float64, or def when
+// it is unset or invalid
+func Float(key string, def float64) float64 {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Printf("⚠️ Invalid %s=%q, using default %v", key, v, def)
+		return def
+	}
+	return f
+}
+
+// Bool returns the environment variable key parsed as a bool, or def when it
+// is unset or invalid
+func Bool(key string, def bool) bool {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Printf("⚠️ Invalid %s=%q, using default %v", key, v, def)
+		return def
+	}
+	return b
+}
+
+// Duration returns the environment variable key parsed with time.ParseDuration
+// (e.g. "30m"), or def when it is unset or invalid
+func Duration(key string, def time.Duration) time.Duration {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("⚠️ Invalid %s=%q, using default %s", key, v, def)
+		return def
+	}
+	return d
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"server/auth"
+	"server/models"
+	"server/store"
+)
+
+// supervises reports whether the caller oversees the student: admins
+// oversee everyone, supervisors only their own trainees
+func supervises(p *auth.Principal, s *models.Student) bool {
+	if p == nil {
+		return false
+	}
+	if p.Role == auth.RoleAdmin {
+		return true
+	}
+	return p.Role == auth.RoleSupervisor && s.SupervisorID != nil && int(*s.SupervisorID) == p.SupervisorID
+}
+
+// employs reports whether the caller is an employer account of the
+// employer the student is placed with
+func employs(p *auth.Principal, s *models.Student) bool {
+	return p != nil && p.Role == auth.RoleEmployer && s.EmployerID != nil && int(*s.EmployerID) == p.EmployerID
+}
+
+// actingUser returns the dashboard user behind the request, nil when unknown
+func actingUser(r *http.Request) *int {
+	if p := auth.PrincipalFrom(r.Context()); p != nil && p.UserID != 0 {
+		id := p.UserID
+		return &id
+	}
+	return nil
+}
+
+// viewableStudent loads the student in the student-id header if the caller
+// may see their records: trainees only themselves (the route middleware pins
+// the header), supervisors their assigned trainees, employers the trainees
+// placed with them and admins everyone. It writes the error response itself.
+func (h *Handler) viewableStudent(w http.ResponseWriter, r *http.Request) (*models.Student, bool) {
+	studentID, err := getStudentIDFromHeader(r)
+	if err != nil {
+		http.Error(w, "Invalid or missing student-id header", http.StatusBadRequest)
+		return nil, false
+	}
+	student, err := h.store.Students.Get(studentID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Student not found", http.StatusNotFound)
+		return nil, false
+	} else if err != nil {
+		log.Printf("Error loading student %d: %v", studentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if !mayView(auth.PrincipalFrom(r.Context()), student) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+	return student, true
+}
+
+// mayView reports whether the caller may see the student's records, by the
+// rules viewableStudent applies
+func mayView(p *auth.Principal, s *models.Student) bool {
+	if p == nil {
+		return false
+	}
+	if p.Role == auth.RoleTrainee {
+		return p.StudentID == int(s.ID)
+	}
+	return supervises(p, s) || employs(p, s)
+}
@@ -3,37 +3,28 @@ package controllers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
-	"server/database"
+	"server/auth"
 	"server/models"
+	"server/notify"
+	"server/store"
 	"strconv"
 	"time"
 )
 
-// getStartAndEndOfDay returns the UTC start and end time for the current day.
-func getStartAndEndOfDay() (time.Time, time.Time) {
-	now := time.Now().UTC()
-	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
-	endOfDay := startOfDay.Add(24 * time.Hour)
+// getStartAndEndOfDay returns the start and end of the day containing t in loc.
+// Days are not always 24 hours long, so the end is the next local midnight.
+func getStartAndEndOfDay(t time.Time, loc *time.Location) (time.Time, time.Time) {
+	local := t.In(loc)
+	startOfDay := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	endOfDay := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, loc)
 	return startOfDay, endOfDay
 }
 
-// findTodayAttendance tries to find today's attendance record for a student.
-func findTodayAttendance(studentID int, startOfDay, endOfDay time.Time) (models.Attendance, error) {
-	var attendance models.Attendance
-	query := `SELECT id, student_id, check_in_lat, check_in_long, check_in_date_time, check_out_lat, check_out_long, check_out_date_time 
-                  FROM attendance 
-                  WHERE student_id = $1 AND check_in_date_time >= $2 AND check_in_date_time < $3 
-                  ORDER BY check_in_date_time DESC LIMIT 1`
-	log.Printf("Select Query: %s", query)
-	log.Printf("Select Params: student_id=%d, start=%v, end=%v", studentID, startOfDay, endOfDay)
-	row := database.DB.QueryRow(query, studentID, startOfDay, endOfDay)
-	err := row.Scan(&attendance.ID, &attendance.StudentID, &attendance.CheckInLat, &attendance.CheckInLong, &attendance.CheckInDateTime, &attendance.CheckOutLat, &attendance.CheckOutLong, &attendance.CheckOutDateTime)
-	return attendance, err
-}
-
-func PostAttendance(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) PostAttendance(w http.ResponseWriter, r *http.Request) {
 	log.Println("Received attendance request")
 
 	StudentIDHeader := r.Header.Get("student-id")
@@ -65,94 +56,163 @@ func PostAttendance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	checkInTime, err := time.Parse(time.RFC3339, requestData.Timestamp)
+	deviceTime, err := time.Parse(time.RFC3339, requestData.Timestamp)
 	if err != nil {
 		http.Error(w, "Invalid timestamp format", http.StatusBadRequest)
 		return
 	}
+	// Sessions are timed, and punctuality judged, by when the server receives
+	// them, so a device cannot backdate a check-in; its clock is only logged
+	checkInTime := h.now()
+	if drift := deviceTime.Sub(checkInTime); drift > time.Minute || drift < -time.Minute {
+		log.Printf("Device clock of student %d is %v off", studentID, drift.Round(time.Second))
+	}
 
 	log.Printf("Request data: check_in=%v, lat=%f, long=%f",
 		requestData.CheckIn, requestData.Latitude, requestData.Longitude)
 
-	var attendance models.Attendance
-	startOfDay, endOfDay := getStartAndEndOfDay()
+	// Evaluate the reported location against the employer's geofence
+	employer, err := h.employerFor(studentID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Student not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Failed to load employer for student %d: %v", studentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var inside *bool
+	var distance *int
+	if employer != nil {
+		result := evaluateGeofence(employer, requestData.Latitude, requestData.Longitude)
+		inside, distance = &result.Inside, &result.DistanceM
+		log.Printf("Geofence for employer %d: inside=%v, distance=%dm", employer.ID, result.Inside, result.DistanceM)
 
-	if requestData.CheckIn {
-		// Delete any existing records for today first
-		deleteQuery := `DELETE FROM attendance WHERE student_id = $1 AND check_in_date_time >= $2 AND check_in_date_time < $3`
-		log.Printf("Delete Query: %s", deleteQuery)
-		log.Printf("Delete Params: student_id=%d, start=%v, end=%v", studentID, startOfDay, endOfDay)
-		_, err := database.DB.Exec(deleteQuery, studentID, startOfDay, endOfDay)
-		if err != nil {
-			log.Printf("Failed to delete existing records: %v", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if requestData.CheckIn && !result.Inside {
+			if student, err := h.store.Students.Get(studentID); err == nil {
+				h.notify(notify.Event{
+					Kind:      notify.OutsideGeofence,
+					Employer:  employer.Name,
+					At:        checkInTime.In(h.locationOf(employer)).Format(notificationTimeLayout),
+					DistanceM: result.DistanceM,
+				}, student, false)
+			}
+		}
+
+		// Check-outs outside the geofence are only flagged so nobody gets stuck checked in
+		if requestData.CheckIn && !result.Inside && h.enforceGeofence {
+			http.Error(w, fmt.Sprintf("Check-in location is outside the workplace geofence (%d m away)", result.DistanceM), http.StatusForbidden)
 			return
 		}
+	}
 
+	student, err := h.store.Students.Get(studentID)
+	if err != nil {
+		log.Printf("Failed to load student %d: %v", studentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	lateGrace, earlyLeaveGrace := h.gracePeriods(employer)
+
+	var attendance models.Attendance
+	// Sessions checked in longer ago than this are stale and no longer open
+	openSince := checkInTime.Add(-h.maxSessionLength)
+
+	if requestData.CheckIn {
 		attendance.StudentID = studentID
+		attendance.PlacementID = h.currentPlacementID(studentID)
 		attendance.CheckInLat = requestData.Latitude
 		attendance.CheckInLong = requestData.Longitude
 		attendance.CheckInDateTime = checkInTime
+		attendance.CheckInInsideGeofence = inside
+		attendance.CheckInDistanceM = distance
 
-		log.Println("Creating new check-in record")
-		query := `INSERT INTO attendance (student_id, check_in_lat, check_in_long, check_in_date_time) VALUES ($1, $2, $3, $4) RETURNING id, student_id, check_in_lat, check_in_long, check_in_date_time`
-		log.Printf("Insert Query: %s", query)
-		log.Printf("Insert Params: student_id=%d, lat=%f, long=%f, datetime=%v", attendance.StudentID, attendance.CheckInLat, attendance.CheckInLong, attendance.CheckInDateTime)
-		row := database.DB.QueryRow(query, attendance.StudentID, attendance.CheckInLat, attendance.CheckInLong, attendance.CheckInDateTime)
-		err = row.Scan(&attendance.ID, &attendance.StudentID, &attendance.CheckInLat, &attendance.CheckInLong, &attendance.CheckInDateTime)
-		if err != nil {
+		// Punctuality is informational, so a failed lookup does not block the check-in
+		afterBreak := false
+		if shift, first, err := h.shiftForSession(student, employer, checkInTime); err != nil {
+			log.Printf("Skipping punctuality for student %d: %v", studentID, err)
+		} else {
+			evaluateCheckIn(&attendance, shift, first, lateGrace)
+			afterBreak = !first
+		}
+
+		log.Println("Opening new attendance session")
+		err := h.store.Attendance.CheckIn(&attendance, openSince)
+		if errors.Is(err, store.ErrConflict) {
+			http.Error(w, "Already checked in; check out before checking in again", http.StatusConflict)
+			return
+		} else if err != nil {
 			log.Printf("Database error on insert: %v", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		log.Printf("Check-in record created: %+v", attendance)
+		log.Printf("Attendance session opened: %+v", attendance)
+
+		// A late arrival replaces an absence the background job already recorded
+		dayStart, dayEnd := getStartAndEndOfDay(checkInTime, h.locationOf(employer))
+		if err := h.store.Attendance.ClearAbsence(studentID, dayStart, dayEnd); err != nil {
+			log.Printf("Failed to clear absence for student %d: %v", studentID, err)
+		}
+		if afterBreak {
+			h.resumeShift(studentID, dayStart, checkInTime)
+		}
 	} else {
-		// Try to find existing record for today
-		attendance, err = findTodayAttendance(studentID, startOfDay, endOfDay)
-		if err == sql.ErrNoRows {
-			// No check-in record exists, create a new record with zero check-in values and actual checkout data
-			log.Println("No check-in record found, creating checkout record with zero check-in values")
+		// Close the open session, if there is one
+		open, err := h.store.Attendance.OpenSession(studentID, openSince)
+		if errors.Is(err, store.ErrNotFound) {
+			if h.rejectOrphanCheckOuts {
+				http.Error(w, "Not checked in; there is no open session to check out of", http.StatusConflict)
+				return
+			}
+
+			// Keep the check-out but flag it rather than inventing a check-in
+			log.Println("No open session found, recording orphan check-out")
 			attendance.StudentID = studentID
-			attendance.CheckInLat = 0
-			attendance.CheckInLong = 0
-			attendance.CheckInDateTime = time.Time{} // zero timestamp
+			attendance.PlacementID = h.currentPlacementID(studentID)
+			attendance.OrphanCheckOut = true
 			attendance.CheckOutLat = sql.NullFloat64{Float64: requestData.Latitude, Valid: true}
 			attendance.CheckOutLong = sql.NullFloat64{Float64: requestData.Longitude, Valid: true}
 			attendance.CheckOutDateTime = sql.NullTime{Time: checkInTime, Valid: true}
+			attendance.CheckOutInsideGeofence = inside
+			attendance.CheckOutDistanceM = distance
+			h.evaluateSessionCheckOut(student, employer, &attendance, earlyLeaveGrace)
 
-			insertQuery := `INSERT INTO attendance (student_id, check_in_lat, check_in_long, check_in_date_time, check_out_lat, check_out_long, check_out_date_time) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
-			log.Printf("Insert Query: %s", insertQuery)
-			row := database.DB.QueryRow(insertQuery, attendance.StudentID, attendance.CheckInLat, attendance.CheckInLong, attendance.CheckInDateTime, attendance.CheckOutLat, attendance.CheckOutLong, attendance.CheckOutDateTime)
-			err := row.Scan(&attendance.ID)
-			if err != nil {
+			if err := h.store.Attendance.Create(&attendance); err != nil {
 				log.Printf("Database error on checkout insert: %v", err)
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
-			log.Printf("Checkout record with zero check-in values created: %+v", attendance)
+			log.Printf("Orphan check-out recorded: %+v", attendance)
 		} else if err != nil {
 			log.Printf("Database error on select: %v", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		} else {
-			// Update existing record with checkout data (preserve existing check-in data)
-			log.Printf("Found existing check-in record, updating with check-out data: %+v", attendance)
+			attendance = *open
+			if checkInTime.Before(attendance.CheckInDateTime) {
+				http.Error(w, "Check-out time is before the check-in time", http.StatusBadRequest)
+				return
+			}
+			log.Printf("Found open session, closing it: %+v", attendance)
 
 			attendance.CheckOutLat = sql.NullFloat64{Float64: requestData.Latitude, Valid: true}
 			attendance.CheckOutLong = sql.NullFloat64{Float64: requestData.Longitude, Valid: true}
 			attendance.CheckOutDateTime = sql.NullTime{Time: checkInTime, Valid: true}
+			attendance.CheckOutInsideGeofence = inside
+			attendance.CheckOutDistanceM = distance
+			h.evaluateSessionCheckOut(student, employer, &attendance, earlyLeaveGrace)
 
-			log.Println("Updating existing record with check-out data")
-			updateQuery := `UPDATE attendance SET check_out_lat = $1, check_out_long = $2, check_out_date_time = $3 WHERE id = $4`
-			log.Printf("Update Query: %s", updateQuery)
-			log.Printf("Update Params: lat=%v, long=%v, datetime=%v, id=%d", attendance.CheckOutLat, attendance.CheckOutLong, attendance.CheckOutDateTime, attendance.ID)
-			_, err = database.DB.Exec(updateQuery, attendance.CheckOutLat, attendance.CheckOutLong, attendance.CheckOutDateTime, attendance.ID)
-			if err != nil {
+			err := h.store.Attendance.UpdateCheckOut(&attendance)
+			if errors.Is(err, store.ErrNotFound) {
+				// Another request closed the session first
+				http.Error(w, "Session was already checked out of", http.StatusConflict)
+				return
+			} else if err != nil {
 				log.Printf("Failed to save record: %v", err)
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
-			log.Printf("Check-out updated for record ID: %d", attendance.ID)
+			log.Printf("Check-out recorded for session ID: %d", attendance.ID)
 		}
 	}
 
@@ -160,3 +220,130 @@ func PostAttendance(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(attendance)
 }
+
+// dateLayout is the format of the from and to query parameters
+const dateLayout = "2006-01-02"
+
+// parseDateRange reads the inclusive from and to query dates (YYYY-MM-DD) in
+// loc. Without them the range ends today and spans defaultDays days.
+func (h *Handler) parseDateRange(r *http.Request, loc *time.Location, defaultDays int) (from, to time.Time, err error) {
+	today, _ := getStartAndEndOfDay(h.now(), loc)
+	from, to = today.AddDate(0, 0, 1-defaultDays), today
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.ParseInLocation(dateLayout, v, loc); err != nil {
+			return from, to, errors.New("Invalid from date, expected YYYY-MM-DD")
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.ParseInLocation(dateLayout, v, loc); err != nil {
+			return from, to, errors.New("Invalid to date, expected YYYY-MM-DD")
+		}
+	}
+	if to.Before(from) {
+		return from, to, errors.New("to must not be before from")
+	}
+	if to.Sub(from) > 366*24*time.Hour {
+		return from, to, errors.New("Date range must not exceed one year")
+	}
+	return from, to, nil
+}
+
+// GetAttendanceDays returns the student's sessions grouped by day with the
+// minutes worked in closed sessions. from and to are inclusive YYYY-MM-DD
+// dates and default to the last seven days.
+func (h *Handler) GetAttendanceDays(w http.ResponseWriter, r *http.Request) {
+	studentID, err := getStudentIDFromHeader(r)
+	if err != nil {
+		http.Error(w, "Invalid or missing student-id header", http.StatusBadRequest)
+		return
+	}
+
+	student, loc, err := h.studentWithLocation(studentID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Student not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Employers see the sessions of their own placements, past ones included
+	principal := auth.PrincipalFrom(r.Context())
+	allowed := principal != nil && mayView(principal, student)
+	if !allowed && principal != nil && principal.Role == auth.RoleEmployer {
+		history, err := h.loadPlacementHistory(studentID)
+		if err != nil {
+			log.Printf("Error fetching placements of student %d: %v", studentID, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		allowed = history.employedBy(principal.EmployerID)
+	}
+	if !allowed {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	from, to, err := h.parseDateRange(r, loc, 7)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.loadShiftPlan(student, loc, from, to)
+	if err != nil {
+		log.Printf("Error fetching schedule for student %d: %v", studentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sessions, err := h.store.Attendance.Between(studentID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		log.Printf("Error fetching attendance for student %d: %v", studentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if principal != nil && principal.Role == auth.RoleEmployer {
+		var theirs []models.Attendance
+		for _, a := range sessions {
+			if plan.history.sessionAt(a, principal.EmployerID) {
+				theirs = append(theirs, a)
+			}
+		}
+		sessions = theirs
+	}
+
+	days := groupAttendanceDays(sessions, plan.history.zoneOf(loc))
+	for i := range days {
+		shift := plan.shiftOnDate(days[i].Date)
+		days[i].ExpectedShift = &shift
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(days)
+}
+
+// groupAttendanceDays buckets sessions, oldest first, by the local day they
+// started in the zone zoneOf gives for each
+func groupAttendanceDays(sessions []models.Attendance, zoneOf func(models.Attendance) *time.Location) []models.AttendanceDay {
+	days := []models.AttendanceDay{}
+	for _, a := range sessions {
+		date := a.Start().In(zoneOf(a)).Format(dateLayout)
+		if len(days) == 0 || days[len(days)-1].Date != date {
+			days = append(days, models.AttendanceDay{Date: date})
+		}
+		day := &days[len(days)-1]
+		day.Sessions = append(day.Sessions, a)
+		day.WorkedMinutes += a.WorkedMinutes()
+		if a.Open() {
+			day.HasOpenSession = true
+		}
+		if a.OrphanCheckOut {
+			day.OrphanCheckOuts++
+		}
+		if a.Absent {
+			day.Absent = true
+		}
+	}
+	return days
+}
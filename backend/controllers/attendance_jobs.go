@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"server/models"
+	"server/notify"
+	"server/store"
+	"time"
+)
+
+// RunAttendanceJobs records absences and closes stale sessions every
+// interval until ctx is done. Both jobs only change rows that are still in
+// the state they expect, so several replicas can run them at once.
+func (h *Handler) RunAttendanceJobs(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		now := h.now()
+		if n, err := h.DetectAbsences(now); err != nil {
+			log.Printf("⚠️ Absence detection: %v", err)
+		} else if n > 0 {
+			log.Printf("Recorded %d absence(s)", n)
+		}
+		if n, err := h.CloseStaleSessions(now); err != nil {
+			log.Printf("⚠️ Closing stale sessions: %v", err)
+		} else if n > 0 {
+			log.Printf("Auto-closed %d session(s)", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DetectAbsences records an absence for every working shift that started
+// more than absentAfter before now without any session that day, under the
+// placement the shift fell in. It returns how many absences were recorded.
+func (h *Handler) DetectAbsences(now time.Time) (int, error) {
+	dir, err := h.loadDirectory()
+	if err != nil {
+		return 0, err
+	}
+
+	marked := 0
+	var firstErr error
+	for _, s := range dir.students {
+		employer := dir.employerOf(s)
+		loc := h.locationOf(employer)
+		yesterday := now.In(loc).AddDate(0, 0, -1)
+		plan, err := h.loadShiftPlan(&s, loc, yesterday, now)
+		if err != nil {
+			log.Printf("Error loading schedule for student %d: %v", s.ID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		// Yesterday's shift may still be due, e.g. a night shift
+		for _, day := range []time.Time{yesterday, now} {
+			shift := plan.shiftOn(day)
+			if !shift.Working || shift.StartsAt == nil {
+				continue
+			}
+			due := shift.StartsAt.Add(h.absentAfter)
+			if now.Before(due) || now.Sub(due) > h.absenceLookback {
+				continue
+			}
+
+			// The shift may fall in an earlier placement than the current one
+			shiftLoc, shiftEmployer := loc, employer
+			var placementID *int
+			if p := plan.history.on(shift.Date); p != nil {
+				placementID = &p.ID
+				shiftLoc, shiftEmployer = p.loc, dir.employerByID(p.EmployerID)
+			}
+
+			dayStart, dayEnd := getStartAndEndOfDay(*shift.StartsAt, shiftLoc)
+			absence := models.Attendance{
+				StudentID:     int(s.ID),
+				PlacementID:   placementID,
+				Absent:        true,
+				ExpectedStart: shift.StartsAt,
+				Punctuality:   models.PunctualityAbsent,
+			}
+			err := h.store.Attendance.MarkAbsent(&absence, dayStart, dayEnd)
+			if errors.Is(err, store.ErrConflict) {
+				// Checked in, or already recorded absent
+				continue
+			} else if err != nil {
+				log.Printf("Error recording absence for student %d: %v", s.ID, err)
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			marked++
+			h.notify(notify.Event{
+				Kind:     notify.MissedCheckIn,
+				Employer: employerName(shiftEmployer),
+				At:       shift.StartsAt.In(shiftLoc).Format(notificationTimeLayout),
+			}, &s, true)
+		}
+	}
+	return marked, firstErr
+}
+
+// CloseStaleSessions checks out of sessions still open autoCloseAfter past
+// the end of their shift, at the scheduled end. Sessions without a shift
+// end are closed after maxSessionLength with no time worked, since nobody
+// confirmed when the trainee left. It returns how many sessions were closed.
+func (h *Handler) CloseStaleSessions(now time.Time) (int, error) {
+	sessions, err := h.store.Attendance.OpenSessions()
+	if err != nil {
+		return 0, err
+	}
+	if len(sessions) == 0 {
+		return 0, nil
+	}
+	dir, err := h.loadDirectory()
+	if err != nil {
+		return 0, err
+	}
+	students := make(map[int]models.Student, len(dir.students))
+	for _, s := range dir.students {
+		students[int(s.ID)] = s
+	}
+
+	closed := 0
+	var firstErr error
+	for _, a := range sessions {
+		s, ok := students[a.StudentID]
+		if !ok {
+			continue
+		}
+		loc := h.locationOf(dir.employerOf(s))
+		plan, err := h.loadShiftPlan(&s, loc, a.CheckInDateTime, a.CheckInDateTime)
+		if err != nil {
+			log.Printf("Error loading schedule for student %d: %v", s.ID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		checkOut, closeAt := a.CheckInDateTime, a.CheckInDateTime.Add(h.maxSessionLength)
+		if shift := plan.shiftOn(a.CheckInDateTime); shift.Working && shift.EndsAt != nil && shift.EndsAt.After(a.CheckInDateTime) {
+			checkOut, closeAt = *shift.EndsAt, shift.EndsAt.Add(h.autoCloseAfter)
+		}
+		if now.Before(closeAt) {
+			continue
+		}
+
+		a.CheckOutDateTime = sql.NullTime{Time: checkOut, Valid: true}
+		a.AutoClosed = true
+		err = h.store.Attendance.UpdateCheckOut(&a)
+		if errors.Is(err, store.ErrNotFound) {
+			// Checked out, or closed by another replica, in the meantime
+			continue
+		} else if err != nil {
+			log.Printf("Error auto-closing session %d: %v", a.ID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		closed++
+	}
+	return closed, firstErr
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"server/auth"
+	"server/models"
+	"server/notify"
+	"server/store"
+	"strings"
+)
+
+// ReviewAttendance lets an employer confirm or dispute a session recorded
+// by one of the trainees placed with them at the time. A session can be reviewed again
+// to change the outcome; disputes notify the trainee's supervisor.
+func (h *Handler) ReviewAttendance(w http.ResponseWriter, r *http.Request) {
+	var req models.ReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	switch req.Status {
+	case models.ReviewConfirmed:
+	case models.ReviewDisputed:
+		if req.Note == "" {
+			http.Error(w, "Give a note saying why the session is disputed", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "status must be confirmed or disputed", http.StatusBadRequest)
+		return
+	}
+
+	a, err := h.store.Attendance.Get(req.AttendanceID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Attendance session not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error loading attendance %d: %v", req.AttendanceID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	student, err := h.store.Students.Get(a.StudentID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.Printf("Error loading student %d: %v", a.StudentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	employer, err := h.sessionEmployer(a)
+	if err != nil {
+		log.Printf("Error fetching employer of attendance %d: %v", a.ID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if student == nil || employer == nil || int(employer.ID) != auth.PrincipalFrom(r.Context()).EmployerID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	now := h.now().UTC()
+	a.ReviewStatus = req.Status
+	a.ReviewNote = req.Note
+	a.ReviewedBy = actingUser(r)
+	a.ReviewedAt = &now
+	if err := h.store.Attendance.Review(a); err != nil {
+		log.Printf("Error reviewing attendance %d: %v", a.ID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if a.ReviewStatus == models.ReviewDisputed {
+		h.notify(notify.Event{
+			Kind:     notify.AttendanceDisputed,
+			Employer: employerName(employer),
+			At:       a.Start().In(h.locationOf(employer)).Format(notificationTimeLayout),
+			Note:     a.ReviewNote,
+		}, student, false)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a)
+}
+
+// sessionEmployer returns the employer of the placement a session was
+// recorded under, nil for sessions recorded while the student was unplaced
+func (h *Handler) sessionEmployer(a *models.Attendance) (*models.Employer, error) {
+	history, err := h.loadPlacementHistory(a.StudentID)
+	if err != nil {
+		return nil, err
+	}
+	p := history.byID(a.PlacementID)
+	if p == nil || p.EmployerID == nil {
+		return nil, nil
+	}
+	employer, err := h.store.Employers.Get(*p.EmployerID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	return employer, err
+}
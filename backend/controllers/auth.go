@@ -2,32 +2,73 @@ package controllers
 
 import (
 	"crypto/rand"
-	"database/sql"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"math/big"
+	"net"
 	"net/http"
-	"server/database"
+	"server/auth"
+	"server/config"
+	"server/middleware"
 	"server/models"
+	"server/notify"
+	"server/ratelimit"
+	"server/store"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 )
 
+// ErrStudentNotFound is returned by GenerateOTP for an unknown student ID
+var ErrStudentNotFound = errors.New("student not found")
+
 // AuthService handles authentication-related operations
 type AuthService struct {
-	db *sql.DB
+	students store.StudentStore
+	otps     store.OTPStore
+	devices  store.DeviceStore
+	users    store.UserStore
+
+	supervisors store.SupervisorStore
+	employers   store.EmployerStore
+	notifier    *notify.Service
+
+	otpLength int
+	otpTTL    time.Duration
+
+	// Brute-force protection for /validate-otp
+	otpAttemptsByIP   *ratelimit.Limiter
+	otpAttemptsByCode *ratelimit.Limiter
+	otpFailuresByIP   *ratelimit.Limiter
 }
 
 // NewAuthService creates a new auth service
-func NewAuthService() *AuthService {
+func NewAuthService(stores *store.Store, notifier *notify.Service) *AuthService {
+	// A lockout or TTL of zero would disable the lockout or every code, and a
+	// huge one would make codes effectively permanent
+	lockout := min(max(config.Duration("OTP_LOCKOUT_DURATION", 15*time.Minute), time.Minute), 24*time.Hour)
 	return &AuthService{
-		db: database.DB, // Use the sql.DB instance
+		students: stores.Students,
+		otps:     stores.OTPs,
+		devices:  stores.Devices,
+		users:    stores.Users,
+
+		supervisors: stores.Supervisors,
+		employers:   stores.Employers,
+		notifier:    notifier,
+
+		otpLength: max(config.Int("OTP_LENGTH", 4), 4),
+		otpTTL:    min(max(config.Duration("OTP_TTL", 30*time.Minute), time.Minute), 24*time.Hour),
+
+		otpAttemptsByIP:   ratelimit.New(config.Int("OTP_RATE_LIMIT_PER_IP", 10), time.Minute, time.Minute),
+		otpAttemptsByCode: ratelimit.New(config.Int("OTP_RATE_LIMIT_PER_CODE", 5), lockout, lockout),
+		otpFailuresByIP:   ratelimit.New(config.Int("OTP_MAX_FAILED_ATTEMPTS", 5), lockout, lockout),
 	}
 }
 
@@ -47,8 +88,26 @@ func (s *AuthService) HandleGenerateOTP(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Supervisors only pair devices for their own trainees
+	student, err := s.students.Get(studentID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Student not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error loading student %d: %v", studentID, err)
+		http.Error(w, "Failed to generate OTP: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !supervises(auth.PrincipalFrom(r.Context()), student) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
 	resp, err := s.GenerateOTP(studentID)
-	if err != nil {
+	if errors.Is(err, ErrStudentNotFound) {
+		http.Error(w, "Student not found", http.StatusNotFound)
+		return
+	} else if err != nil {
 		log.Printf("Error generating OTP: %v", err)
 		http.Error(w, "Failed to generate OTP: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -63,14 +122,47 @@ func (s *AuthService) HandleGenerateOTP(w http.ResponseWriter, r *http.Request)
 
 // HandleValidateOTP
 func (s *AuthService) HandleValidateOTP(w http.ResponseWriter, r *http.Request) {
-	OTPCodeHeader := r.Header.Get("otp-code")
+	OTPCodeHeader := strings.TrimSpace(r.Header.Get("otp-code"))
 	if OTPCodeHeader == "" {
 		log.Println("Missing otp-code header")
 		http.Error(w, "Missing otp-code header", http.StatusBadRequest)
 		return
 	}
 
-	resp, err := s.ValidateOTP(OTPCodeHeader)
+	req := models.OTPValidationRequest{
+		OTPCode:    OTPCodeHeader,
+		DeviceName: strings.TrimSpace(r.Header.Get("device-name")),
+		ClientIP:   clientIP(r),
+	}
+	if idStr := r.Header.Get("student-id"); idStr != "" {
+		studentID, err := strconv.Atoi(idStr)
+		if err != nil {
+			http.Error(w, "Invalid student-id header", http.StatusBadRequest)
+			return
+		}
+		req.StudentID = studentID
+	}
+
+	// Refuse callers that are locked out before touching the database
+	retryAfter := max(
+		s.otpFailuresByIP.Check(req.ClientIP),
+		s.otpAttemptsByIP.Check(req.ClientIP),
+		s.otpAttemptsByCode.Check(req.OTPCode),
+	)
+	if retryAfter > 0 {
+		log.Printf("Rate limited OTP validation from %s", req.ClientIP)
+		s.recordFailedOTP(req, nil, "rate limited")
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		http.Error(w, "Too many attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+	s.otpAttemptsByIP.Record(req.ClientIP)
+	s.otpAttemptsByCode.Record(req.OTPCode)
+
+	resp, err := s.ValidateOTP(req)
+	if err == nil && !resp.Success {
+		s.otpFailuresByIP.Record(req.ClientIP)
+	}
 	if err != nil {
 		log.Printf("Error validating OTP: %v", err)
 		http.Error(w, "Failed to validate OTP", http.StatusInternalServerError)
@@ -113,135 +205,124 @@ func (s *AuthService) HandleVerifyDeviceAuth(w http.ResponseWriter, r *http.Requ
 // GenerateOTP creates a new OTP for a student
 func (s *AuthService) GenerateOTP(studentID int) (*models.OTPResponse, error) {
 	// Check if student exists
-	var count int64
-	err := s.db.QueryRow("SELECT COUNT(*) FROM student WHERE id = $1", studentID).Scan(&count)
-	if err != nil {
+	student, err := s.students.Get(studentID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrStudentNotFound
+	} else if err != nil {
 		log.Printf("Database error while checking student existence: %v", err)
 		return nil, fmt.Errorf("database error: %w", err)
 	}
-	if count == 0 {
-		return nil, errors.New("student not found")
-	}
 
 	// Delete expired OTPs
-	_, _ = s.db.Exec("DELETE FROM otps WHERE expires_at <= $1", time.Now())
+	_ = s.otps.DeleteExpired(time.Now())
 
 	// Check for existing active OTP
-	var existingOTP string
-	var expiresAt time.Time
-	err = s.db.QueryRow(
-		"SELECT otp_code, expires_at FROM otps WHERE student_id = $1 AND is_used = false AND expires_at > $2",
-		studentID, time.Now(),
-	).Scan(&existingOTP, &expiresAt)
+	existing, err := s.otps.ActiveForStudent(studentID, time.Now())
 	if err == nil {
 		// Active OTP exists, return it
 		return &models.OTPResponse{
 			StudentID: studentID,
-			OTPCode:   existingOTP,
-			ExpiresAt: expiresAt,
+			OTPCode:   existing.OTPCode,
+			ExpiresAt: existing.ExpiresAt,
 		}, nil
-	} else if err != sql.ErrNoRows {
+	} else if !errors.Is(err, store.ErrNotFound) {
 		// Unexpected DB error
 		log.Printf("Database error while checking active OTP: %v", err)
 		return nil, fmt.Errorf("database error: %w", err)
 	}
 
 	// Invalidate any existing unused OTPs for this student (mark expired ones as used)
-	_, err = s.db.Exec("UPDATE otps SET is_used = true WHERE student_id = $1 AND is_used = false", studentID)
-	if err != nil {
+	if err := s.otps.InvalidateForStudent(studentID); err != nil {
 		log.Printf("Error invalidating existing OTPs for student ID %d: %v", studentID, err)
 		return nil, fmt.Errorf("failed to invalidate existing OTPs: %w", err)
 	}
 
-	// Generate a random 4-digit OTP
-	otp, err := s.generateRandomOTP(4)
+	// Generate a random OTP that no other student currently holds
+	code, err := s.generateUniqueOTP()
 	if err != nil {
 		log.Printf("Error generating random OTP: %v", err)
 		return nil, fmt.Errorf("failed to generate OTP: %w", err)
 	}
 
-	// Set expiration time (30 minutes from now)
-	expiresAt = time.Now().Add(30 * time.Minute)
-
 	// Insert new OTP
-	_, err = s.db.Exec("INSERT INTO otps (student_id, otp_code, expires_at, is_used) VALUES ($1, $2, $3, $4)", studentID, otp, expiresAt, false)
-	if err != nil {
+	otp := models.OTP{
+		StudentID: studentID,
+		OTPCode:   code,
+		ExpiresAt: time.Now().Add(s.otpTTL),
+	}
+	if err := s.otps.Create(&otp); err != nil {
 		log.Printf("Error storing new OTP: %v", err)
 		return nil, fmt.Errorf("failed to store OTP: %w", err)
 	}
+	notifyAbout(s.notifier, s.supervisors, notify.Event{Kind: notify.OTPIssued}, student, false)
 
 	return &models.OTPResponse{
 		StudentID: studentID,
-		OTPCode:   otp,
-		ExpiresAt: expiresAt,
+		OTPCode:   otp.OTPCode,
+		ExpiresAt: otp.ExpiresAt,
 	}, nil
 }
 
-// ValidateOTP checks if an OTP is valid and returns student_id and a new secret code
-func (s *AuthService) ValidateOTP(otpCode string) (*models.OTPValidationResponse, error) {
-	var otp models.OTP
-	log.Printf("Validating OTP: %s", otpCode) // Add debug log
-	err := s.db.QueryRow("SELECT student_id, is_used, expires_at FROM otps WHERE otp_code = $1", otpCode).Scan(&otp.StudentID, &otp.IsUsed, &otp.ExpiresAt)
-	if errors.Is(err, sql.ErrNoRows) {
-		log.Printf("OTP not found for code: %s", otpCode)
+// ValidateOTP checks if an OTP is valid, pairs the calling device and returns
+// student_id and a new secret code. Only active codes are considered, and every
+// failure is written to the audit log.
+func (s *AuthService) ValidateOTP(req models.OTPValidationRequest) (*models.OTPValidationResponse, error) {
+	otp, err := s.otps.FindActive(req.OTPCode, req.StudentID, time.Now())
+	if errors.Is(err, store.ErrNotFound) {
+		// Unknown, used and expired codes are indistinguishable to the caller
+		log.Printf("No active OTP matched validation from %s", req.ClientIP)
+		s.recordFailedOTP(req, nil, "no active code")
 		return &models.OTPValidationResponse{
 			Success: false,
-			Message: "Invalid OTP",
+			Message: "Invalid or expired OTP",
 		}, nil
 	} else if err != nil {
-		log.Printf("Database error while fetching OTP for code %s: %v", otpCode, err)
+		log.Printf("Database error while fetching OTP: %v", err)
 		return nil, fmt.Errorf("database error: %w", err)
 	}
 
 	// Optionally, delete all expired OTPs (for all students)
-	_, _ = s.db.Exec("DELETE FROM otps WHERE expires_at <= $1", time.Now())
+	_ = s.otps.DeleteExpired(time.Now())
 
-	// Check if OTP is already used
-	if otp.IsUsed {
-		log.Printf("OTP already used for code: %s", otpCode) // Improved logging
-		return &models.OTPValidationResponse{
-			Success: false,
-			Message: "OTP has already been used",
-		}, nil
+	secretCode, err := s.generateSecretCode()
+	if err != nil {
+		log.Printf("Error generating secret code: %v", err)
+		return nil, fmt.Errorf("failed to generate secret code: %w", err)
 	}
 
-	// Check if OTP is expired
-	if time.Now().After(otp.ExpiresAt) {
-		log.Printf("OTP expired for code: %s", otpCode) // Improved logging
-		otp.IsUsed = true
-		_, err := s.db.Exec("UPDATE otps SET is_used = true WHERE otp_code = ?", otpCode)
-		if err != nil {
-			log.Printf("Error marking expired OTP as used for code %s: %v", otpCode, err) // Improved logging
-		}
+	// Only a hash of the secret is stored; the plain value is returned once
+	device := models.AuthorizedDevice{
+		StudentID:  otp.StudentID,
+		SecretCode: hashSecretCode(secretCode),
+		DeviceName: req.DeviceName,
+	}
+	err = s.otps.Redeem(req.OTPCode, otp.StudentID, &device)
+	if errors.Is(err, store.ErrConflict) {
+		s.recordFailedOTP(req, &otp.StudentID, "already used")
 		return &models.OTPValidationResponse{
 			Success: false,
-			Message: "OTP has expired",
+			Message: "Invalid or expired OTP",
 		}, nil
+	} else if err != nil {
+		log.Printf("Error storing device credentials for student ID %d: %v", otp.StudentID, err)
+		return nil, fmt.Errorf("failed to store device credentials: %w", err)
 	}
-
-	// Mark OTP as used
-	otp.IsUsed = true
-	_, err = s.db.Exec("UPDATE otps SET is_used = true WHERE otp_code = ?", otpCode)
-	if err != nil {
-		log.Printf("Error marking OTP as used for code %s: %v", otpCode, err) // Improved logging
-	}
-
-	// Removed secret code generation and storage
+	log.Printf("Paired device %d for student ID %d", device.ID, otp.StudentID)
 
 	return &models.OTPValidationResponse{
-		Success:   true,
-		StudentID: otp.StudentID,
-		Message:   "Authentication successful",
+		Success:    true,
+		StudentID:  otp.StudentID,
+		DeviceID:   int(device.ID),
+		SecretCode: secretCode,
+		Message:    "Authentication successful",
 	}, nil
 }
 
 // VerifyDeviceAuth verifies if a device is authorized using student_id and secret_code
 func (s *AuthService) VerifyDeviceAuth(studentID int, secretCode string) (bool, error) {
-	var authDevice models.AuthorizedDevice
-
 	// Check if the device exists
-	err := s.db.QueryRow("SELECT * FROM authorized_devices WHERE student_id = ? AND secret_code = ?", studentID, secretCode).Scan(&authDevice.ID, &authDevice.StudentID, &authDevice.SecretCode)
-	if errors.Is(err, sql.ErrNoRows) {
+	_, err := s.findDevice(studentID, secretCode)
+	if errors.Is(err, store.ErrNotFound) {
 		return false, nil
 	} else if err != nil {
 		log.Printf("Database error while verifying device authorization: %v", err)
@@ -265,6 +346,8 @@ func (s *AuthService) RegisterRoutes(router *mux.Router) {
 			"testkey",
 			"student-id",
 			"otp-code",
+			"device-name",
+			"device-id",
 		}),
 		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "HEAD", "OPTIONS"}),
 		handlers.AllowedOrigins([]string{"*"}),
@@ -274,13 +357,60 @@ func (s *AuthService) RegisterRoutes(router *mux.Router) {
 	)
 
 	// Apply CORS middleware to AuthService routes
-	router.HandleFunc("/generate-otp", s.HandleGenerateOTP).Methods("POST")
+	dashboard := middleware.RequireRoles(auth.RoleAdmin, auth.RoleSupervisor)
+	adminOnly := middleware.RequireRoles(auth.RoleAdmin)
+	anyRole := middleware.RequireRoles(auth.RoleAdmin, auth.RoleSupervisor, auth.RoleTrainee, auth.RoleEmployer)
+
+	router.Handle("/generate-otp", dashboard(http.HandlerFunc(s.HandleGenerateOTP))).Methods("POST")
 	router.HandleFunc("/validate-otp", s.HandleValidateOTP).Methods("POST")
 	router.HandleFunc("/verify-device-auth", s.HandleVerifyDeviceAuth).Methods("POST")
+
+	// Session routes
+	router.HandleFunc("/login", s.HandleLogin).Methods("POST")
+	router.HandleFunc("/device-session", s.HandleDeviceSession).Methods("POST")
+	router.Handle("/me", anyRole(http.HandlerFunc(s.HandleMe))).Methods("GET")
+	router.Handle("/create-user", adminOnly(http.HandlerFunc(s.HandleCreateUser))).Methods("POST")
+
+	// Device management routes
+	router.Handle("/get-devices", adminOnly(http.HandlerFunc(s.HandleGetDevices))).Methods("GET")
+	router.Handle("/revoke-device", adminOnly(http.HandlerFunc(s.HandleRevokeDevice))).Methods("DELETE")
 	router.Use(corsMiddleware)
 }
 
-// Helper function to generate a random 4-digit OTP
+// generateUniqueOTP draws OTPs until it finds one that is not active for any student
+func (s *AuthService) generateUniqueOTP() (string, error) {
+	for range 10 {
+		otp, err := s.generateRandomOTP(s.otpLength)
+		if err != nil {
+			return "", err
+		}
+		inUse, err := s.otps.CodeInUse(otp, time.Now())
+		if err != nil {
+			return "", fmt.Errorf("database error: %w", err)
+		}
+		if !inUse {
+			return otp, nil
+		}
+	}
+	return "", errors.New("could not find an unused OTP, consider increasing OTP_LENGTH")
+}
+
+// recordFailedOTP writes an audit row for a failed validation. Errors are only
+// logged so auditing never masks the response to the caller.
+func (s *AuthService) recordFailedOTP(req models.OTPValidationRequest, studentID *int, reason string) {
+	err := s.otps.RecordFailure(&models.OTPFailedAttempt{
+		AttemptedCode: req.OTPCode,
+		StudentID:     studentID,
+		ClientIP:      req.ClientIP,
+		DeviceName:    req.DeviceName,
+		Reason:        reason,
+	})
+	if err != nil {
+		log.Printf("Error recording failed OTP attempt from %s: %v", req.ClientIP, err)
+	}
+}
+
+// Helper function to generate a random numeric OTP
 func (s *AuthService) generateRandomOTP(digits int) (string, error) {
 	maxNum := big.NewInt(0).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
 	n, err := rand.Int(rand.Reader, maxNum)
@@ -301,3 +431,19 @@ func (s *AuthService) generateSecretCode() (string, error) {
 	}
 	return hex.EncodeToString(bytes), nil
 }
+
+// clientIP returns the caller's address, preferring the first X-Forwarded-For
+// hop when TRUST_PROXY_HEADERS is enabled (the Choreo gateway sets it)
+func clientIP(r *http.Request) string {
+	if config.Bool("TRUST_PROXY_HEADERS", false) {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			first, _, _ := strings.Cut(fwd, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
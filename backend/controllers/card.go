@@ -3,90 +3,101 @@ package controllers
 import (
 	"encoding/json"
 	"net/http"
-	"server/database"
+	"server/auth"
 	"server/models"
 	"time"
 )
 
-func GetStudentDetails(w http.ResponseWriter, r *http.Request) {
-	query := `
-    SELECT
-        s.id AS student_id,
-        s.first_name,
-        s.last_name,
-        e.name AS employer_name,
-        s.check_in_time,
-        s.check_out_time,
-		a.check_in_date_time,
-		a.check_out_date_time,
-        m.emotion
-    FROM student s
-    LEFT JOIN employer e ON s.employer_id = e.id
-    LEFT JOIN (
-        SELECT a1.*
-        FROM attendance a1
-        INNER JOIN (
-            SELECT student_id, MAX(check_in_date_time) AS latest_check_in
-            FROM attendance
-            GROUP BY student_id
-        ) a2 ON a1.student_id = a2.student_id AND a1.check_in_date_time = a2.latest_check_in
-    ) a ON s.id = a.student_id
-    LEFT JOIN (
-        SELECT m1.*
-        FROM mood m1
-        INNER JOIN (
-            SELECT student_id, MAX(recorded_at) AS latest_update
-            FROM mood
-            GROUP BY student_id
-        ) m2 ON m1.student_id = m2.student_id AND m1.recorded_at = m2.latest_update
-    ) m ON s.id = m.student_id;
-    `
+func (h *Handler) GetStudentDetails(w http.ResponseWriter, r *http.Request) {
+	dir, err := h.loadDirectory()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(models.ErrorResponse{Error: "Failed to execute query"})
+		return
+	}
+	// Employers only see the trainees placed with them
+	dir = dir.visibleTo(auth.PrincipalFrom(r.Context()))
 
-	var students []models.StudentCard
-	rows, err := database.DB.Query(query)
+	students, err := h.studentCards(dir, h.now())
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(models.ErrorResponse{Error: "Failed to execute query"})
 		return
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var student models.StudentCard
-		var checkInDateTime, checkOutDateTime *time.Time
-		var emotion *string
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(students)
+}
+
+// studentCards builds a dashboard card for every student in dir, in the
+// same order, as of now
+func (h *Handler) studentCards(dir *directory, now time.Time) ([]models.StudentCard, error) {
+	latestAttendance, err := h.store.Attendance.LatestByStudent()
+	if err != nil {
+		return nil, err
+	}
+	latestMood, err := h.store.Moods.LatestByStudent()
+	if err != nil {
+		return nil, err
+	}
+	plans, err := h.loadShiftPlans(dir, now, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var students []models.StudentCard
+	for _, s := range dir.students {
+		student := models.StudentCard{
+			StudentID: int64(s.ID),
+			FirstName: s.FirstName,
+			LastName:  s.LastName,
+		}
+		employer := dir.employerOf(s)
+		if employer != nil {
+			student.EmployerName = &employer.Name
+		}
+		loc := h.locationOf(employer)
+		student.Timezone = loc.String()
+
+		plan := plans[int(s.ID)]
+		shift := plan.shiftOn(now)
+		student.ScheduledToday = shift.Working
+		student.CheckInTime, student.CheckOutTime = shift.Start, shift.End
 
-		err := rows.Scan(
-			&student.StudentID,
-			&student.FirstName,
-			&student.LastName,
-			&student.EmployerName,
-			&student.CheckInTime,
-			&student.CheckOutTime,
-			&checkInDateTime,
-			&checkOutDateTime,
-			&emotion,
-		)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(models.ErrorResponse{Error: "Failed to scan row"})
-			return
+		// Students without attendance or moods keep zero values. Employers
+		// do not see sessions or moods from the student's other placements.
+		a, ok := latestAttendance[int(s.ID)]
+		if ok && dir.employerID != 0 {
+			ok = plan.history.sessionAt(a, dir.employerID)
 		}
+		if ok {
+			student.CheckInDateTime = a.CheckInDateTime
+			if a.CheckOutDateTime.Valid {
+				student.CheckOutDateTime = a.CheckOutDateTime.Time
+			}
+			student.CheckInInsideGeofence = a.CheckInInsideGeofence
+			student.CheckInDistanceM = a.CheckInDistanceM
+			student.CheckOutInsideGeofence = a.CheckOutInsideGeofence
+			student.CheckOutDistanceM = a.CheckOutDistanceM
+			student.Punctuality = a.Punctuality
 
-		// Handle NULL values
-		if checkInDateTime != nil {
-			student.CheckInDateTime = *checkInDateTime
+			today, tomorrow := getStartAndEndOfDay(now, loc)
+			start := a.Start()
+			student.AttendedToday = !a.Absent && !start.Before(today) && start.Before(tomorrow)
 		}
-		if checkOutDateTime != nil {
-			student.CheckOutDateTime = *checkOutDateTime
+		m, ok := latestMood[int(s.ID)]
+		if ok && dir.employerID != 0 {
+			date := m.LocalDate
+			if date == "" {
+				date = m.RecordedAt.In(loc).Format(dateLayout)
+			}
+			ok = plan.history.withEmployerOn(date, dir.employerID)
 		}
-		if emotion != nil {
-			student.Emotion = *emotion
+		if ok {
+			student.Emotion = m.Emotion
 		}
 
 		students = append(students, student)
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(students)
+	return students, nil
 }
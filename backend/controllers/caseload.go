@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"server/auth"
+	"server/models"
+	"server/store"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// caseloadSupervisor returns whose caseload to show: a supervisor's own, or
+// for admins the supervisor in the supervisor-id header. It writes the error
+// response itself.
+func (h *Handler) caseloadSupervisor(w http.ResponseWriter, r *http.Request) (int, bool) {
+	principal := auth.PrincipalFrom(r.Context())
+	header := strings.TrimSpace(r.Header.Get("supervisor-id"))
+	if principal.Role == auth.RoleSupervisor {
+		if header != "" && header != strconv.Itoa(principal.SupervisorID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return 0, false
+		}
+		return principal.SupervisorID, true
+	}
+
+	supervisorID, err := strconv.Atoi(header)
+	if err != nil {
+		http.Error(w, "Invalid or missing supervisor-id header", http.StatusBadRequest)
+		return 0, false
+	}
+	if _, err := h.store.Supervisors.Get(supervisorID); errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Supervisor not found", http.StatusNotFound)
+		return 0, false
+	} else if err != nil {
+		log.Printf("Error loading supervisor %d: %v", supervisorID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return 0, false
+	}
+	return supervisorID, true
+}
+
+// loadCaseload returns the directory narrowed to the caseload the request
+// asks for, writing the error response itself
+func (h *Handler) loadCaseload(w http.ResponseWriter, r *http.Request) (int, *directory, bool) {
+	supervisorID, ok := h.caseloadSupervisor(w, r)
+	if !ok {
+		return 0, nil, false
+	}
+	dir, err := h.loadDirectory()
+	if err != nil {
+		log.Printf("Error loading caseload of supervisor %d: %v", supervisorID, err)
+		http.Error(w, "Failed to fetch data", http.StatusInternalServerError)
+		return 0, nil, false
+	}
+	return supervisorID, dir.caseloadOf(supervisorID), true
+}
+
+// caseloadExceptions works out what about the student's day so far needs
+// attention. Days are counted in the employer's timezone.
+func (h *Handler) caseloadExceptions(s *models.Student, employer *models.Employer, now time.Time) (models.CaseloadExceptions, error) {
+	var e models.CaseloadExceptions
+	loc := h.locationOf(employer)
+	today, tomorrow := getStartAndEndOfDay(now, loc)
+
+	sessions, err := h.store.Attendance.Between(int(s.ID), today, tomorrow)
+	if err != nil {
+		return e, err
+	}
+	checkedIn := false
+	for _, a := range sessions {
+		if a.Punctuality == models.PunctualityLate {
+			e.LateToday = true
+		}
+		if (a.CheckInInsideGeofence != nil && !*a.CheckInInsideGeofence) || (a.CheckOutInsideGeofence != nil && !*a.CheckOutInsideGeofence) {
+			e.OutsideGeofence = true
+		}
+		if !a.Absent && !a.OrphanCheckOut {
+			checkedIn = true
+		}
+	}
+
+	plan, err := h.loadShiftPlan(s, loc, now, now)
+	if err != nil {
+		return e, err
+	}
+	shift := plan.shiftOn(now)
+	grace, _ := h.gracePeriods(employer)
+	if shift.Working && shift.StartsAt != nil && !now.Before(shift.StartsAt.Add(grace)) && !checkedIn {
+		e.NotCheckedIn = true
+	}
+
+	recent, err := h.recentDailyMoods(int(s.ID))
+	if err != nil {
+		return e, err
+	}
+	if len(recent) > 0 && recent[0].LocalDate == today.Format(dateLayout) && isNegativeMood(recent[0]) {
+		e.NegativeMood = true
+	}
+	if streak, _ := negativeStreak(recent); h.negativeMoodStreak > 0 && streak >= h.negativeMoodStreak {
+		e.NegativeMood = true
+	}
+	return e, nil
+}
+
+// GetCaseloadDashboard returns the dashboard cards of one supervisor's
+// trainees with what needs their attention today, flagged trainees first.
+// Supervisors get their own caseload; admins name one in the supervisor-id
+// header. flagged=true leaves out trainees without exceptions; the counts
+// always cover the whole caseload.
+func (h *Handler) GetCaseloadDashboard(w http.ResponseWriter, r *http.Request) {
+	supervisorID, dir, ok := h.loadCaseload(w, r)
+	if !ok {
+		return
+	}
+	flaggedOnly := r.URL.Query().Get("flagged") == "true"
+
+	now := h.now()
+	cards, err := h.studentCards(dir, now)
+	if err != nil {
+		log.Printf("Error building caseload of supervisor %d: %v", supervisorID, err)
+		http.Error(w, "Failed to fetch data", http.StatusInternalServerError)
+		return
+	}
+
+	res := models.CaseloadDashboard{SupervisorID: supervisorID, Trainees: []models.CaseloadCard{}}
+	for i, s := range dir.students {
+		e, err := h.caseloadExceptions(&s, dir.employerOf(s), now)
+		if err != nil {
+			log.Printf("Error checking exceptions of student %d: %v", s.ID, err)
+			http.Error(w, "Failed to fetch data", http.StatusInternalServerError)
+			return
+		}
+		card := models.CaseloadCard{StudentCard: cards[i], Exceptions: e, Flagged: e.Any()}
+
+		res.Counts.Trainees++
+		for _, flag := range []struct {
+			set   bool
+			count *int
+		}{
+			{card.Flagged, &res.Counts.Flagged},
+			{e.LateToday, &res.Counts.LateToday},
+			{e.NotCheckedIn, &res.Counts.NotCheckedIn},
+			{e.NegativeMood, &res.Counts.NegativeMood},
+			{e.OutsideGeofence, &res.Counts.OutsideGeofence},
+		} {
+			if flag.set {
+				*flag.count++
+			}
+		}
+		if flaggedOnly && !card.Flagged {
+			continue
+		}
+		res.Trainees = append(res.Trainees, card)
+	}
+	sort.SliceStable(res.Trainees, func(i, j int) bool {
+		return res.Trainees[i].Flagged && !res.Trainees[j].Flagged
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// GetCaseloadManagement is /management for one supervisor's trainees
+func (h *Handler) GetCaseloadManagement(w http.ResponseWriter, r *http.Request) {
+	_, dir, ok := h.loadCaseload(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(managementRows(dir))
+}
+
+// GetCaseloadEmployees is /employees for one supervisor's trainees. Pairing
+// OTPs are left out unless an admin asks, as they are on /employees.
+func (h *Handler) GetCaseloadEmployees(w http.ResponseWriter, r *http.Request) {
+	supervisorID, dir, ok := h.loadCaseload(w, r)
+	if !ok {
+		return
+	}
+	principal := auth.PrincipalFrom(r.Context())
+	results, err := h.employeeRows(dir, principal != nil && principal.Role == auth.RoleAdmin)
+	if err != nil {
+		log.Printf("Error listing caseload of supervisor %d: %v", supervisorID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"server/auth"
+	"server/store"
+	"strconv"
+	"time"
+)
+
+// hashSecretCode returns the stored form of a device secret. Secrets carry
+// 256 bits of entropy, so a single unsalted SHA-256 is sufficient.
+func hashSecretCode(secretCode string) string {
+	sum := sha256.Sum256([]byte(secretCode))
+	return hex.EncodeToString(sum[:])
+}
+
+// findDevice returns the id of the active device matching a student's secret code
+// and records that it was used
+func (s *AuthService) findDevice(studentID int, secretCode string) (int, error) {
+	device, err := s.devices.FindActive(studentID, hashSecretCode(secretCode))
+	if err != nil {
+		return 0, err
+	}
+	if err := s.devices.Touch(int(device.ID), time.Now()); err != nil {
+		log.Printf("Error updating last_used_at for device %d: %v", device.ID, err)
+	}
+	return int(device.ID), nil
+}
+
+// RequireDevice only lets trainee sessions issued to a still-authorized device
+// through, so revoking a device takes effect immediately
+func (s *AuthService) RequireDevice(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := auth.PrincipalFrom(r.Context())
+		if principal == nil || principal.Role != auth.RoleTrainee || principal.DeviceID == 0 {
+			http.Error(w, "A paired device session is required", http.StatusForbidden)
+			return
+		}
+
+		active, err := s.devices.IsActive(principal.DeviceID, principal.StudentID)
+		if err != nil {
+			log.Printf("Error checking device %d: %v", principal.DeviceID, err)
+			http.Error(w, "Failed to verify device authorization", http.StatusInternalServerError)
+			return
+		}
+		if !active {
+			log.Printf("Rejected request from revoked device %d", principal.DeviceID)
+			http.Error(w, "Device is no longer authorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HandleGetDevices lists paired devices, optionally filtered by the student-id header
+func (s *AuthService) HandleGetDevices(w http.ResponseWriter, r *http.Request) {
+	var studentID int
+	if idStr := r.Header.Get("student-id"); idStr != "" {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			http.Error(w, "Invalid student-id header", http.StatusBadRequest)
+			return
+		}
+		studentID = id
+	}
+
+	devices, err := s.devices.List(studentID)
+	if err != nil {
+		log.Printf("Error fetching devices: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(devices)
+}
+
+// HandleRevokeDevice revokes the device given in the device-id header
+func (s *AuthService) HandleRevokeDevice(w http.ResponseWriter, r *http.Request) {
+	deviceID, err := strconv.Atoi(r.Header.Get("device-id"))
+	if err != nil {
+		http.Error(w, "Invalid device-id header", http.StatusBadRequest)
+		return
+	}
+
+	device, err := s.devices.Revoke(deviceID, time.Now())
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Device not found or already revoked", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error revoking device %d: %v", deviceID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Revoked device %d for student ID %d", device.ID, device.StudentID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(device)
+}
@@ -1,12 +1,16 @@
 package controllers
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
-	"server/database"
+	"server/auth"
 	"server/models"
+	"server/store"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // ErrorResponse for consistent error messages
@@ -14,89 +18,213 @@ type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
-// Get the current emergency contact
-func GetEmergencyContact(w http.ResponseWriter, r *http.Request) {
-	var contact models.EmergencyContact
-	err := database.DB.QueryRow("SELECT id, phone_number, updated_at FROM emergency_contact ORDER BY id DESC LIMIT 1").Scan(&contact.ID, &contact.PhoneNumber, &contact.UpdatedAt)
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: message})
+}
+
+// emergencyContactFor resolves the most specific contact in force for the
+// student: their employer's, then their supervisor's, then everyone's
+func (h *Handler) emergencyContactFor(s *models.Student) (*models.EmergencyContact, error) {
+	var scopes [][2]*int
+	if s != nil && s.EmployerID != nil {
+		id := int(*s.EmployerID)
+		scopes = append(scopes, [2]*int{&id, nil})
+	}
+	if s != nil && s.SupervisorID != nil {
+		id := int(*s.SupervisorID)
+		scopes = append(scopes, [2]*int{nil, &id})
+	}
+	scopes = append(scopes, [2]*int{nil, nil})
+
+	for _, scope := range scopes {
+		contact, err := h.store.EmergencyContacts.Current(scope[0], scope[1])
+		if !errors.Is(err, store.ErrNotFound) {
+			return contact, err
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+// contactScope reads the optional employer-id or supervisor-id header that
+// narrows a contact; neither means the contact for everyone
+func contactScope(r *http.Request) (employerID, supervisorID *int, err error) {
+	for header, target := range map[string]**int{"employer-id": &employerID, "supervisor-id": &supervisorID} {
+		if v := r.Header.Get(header); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, nil, errors.New("Invalid " + header + " header")
+			}
+			*target = &id
+		}
+	}
+	if employerID != nil && supervisorID != nil {
+		return nil, nil, errors.New("Give either employer-id or supervisor-id, not both")
+	}
+	return employerID, supervisorID, nil
+}
+
+// Get the emergency contact for the student in the student-id header, or
+// the contact for everyone when there is no header. Supervisors only look up
+// their own trainees.
+func (h *Handler) GetEmergencyContact(w http.ResponseWriter, r *http.Request) {
+	var student *models.Student
+	if r.Header.Get("student-id") != "" {
+		studentID, err := getStudentIDFromHeader(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid student-id header")
+			return
+		}
+		student, err = h.store.Students.Get(studentID)
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "Student not found")
+			return
+		} else if err != nil {
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if !mayView(auth.PrincipalFrom(r.Context()), student) {
+			writeError(w, http.StatusForbidden, "Forbidden")
+			return
+		}
+	}
+
+	contact, err := h.emergencyContactFor(student)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(ErrorResponse{Error: "No emergency contact found"})
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "No emergency contact found")
 		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(ErrorResponse{Error: "Database error"})
+			writeError(w, http.StatusInternalServerError, "Database error")
 		}
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"phone_number": contact.PhoneNumber,
-	})
+	json.NewEncoder(w).Encode(contact)
 }
 
-// Update emergency contact (replaces the existing one)
-func UpdateEmergencyContact(w http.ResponseWriter, r *http.Request) {
+// Update an emergency contact. The previous version is kept in the history.
+func (h *Handler) UpdateEmergencyContact(w http.ResponseWriter, r *http.Request) {
 	var request struct {
-		PhoneNumber string `json:"phone_number"`
+		PhoneNumber  string `json:"phone_number"`
+		EmployerID   *int   `json:"employer_id"`
+		SupervisorID *int   `json:"supervisor_id"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// Validate phone number
 	request.PhoneNumber = strings.TrimSpace(request.PhoneNumber)
 	if request.PhoneNumber == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Phone number cannot be empty"})
+		writeError(w, http.StatusBadRequest, "Phone number cannot be empty")
 		return
 	}
 
 	if len(request.PhoneNumber) < 10 || len(request.PhoneNumber) > 20 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Phone number must be between 10-20 characters"})
+		writeError(w, http.StatusBadRequest, "Phone number must be between 10-20 characters")
 		return
 	}
 
-	tx, err := database.DB.Begin()
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Database error"})
+	if request.EmployerID != nil && request.SupervisorID != nil {
+		writeError(w, http.StatusBadRequest, "Give either employer_id or supervisor_id, not both")
+		return
+	}
+	if request.EmployerID != nil {
+		if _, err := h.store.Employers.Get(*request.EmployerID); errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "Employer not found")
+			return
+		} else if err != nil {
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+	}
+	if request.SupervisorID != nil {
+		if _, err := h.store.Supervisors.Get(*request.SupervisorID); errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "Supervisor not found")
+			return
+		} else if err != nil {
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+	}
+
+	contact := models.EmergencyContact{
+		PhoneNumber:  request.PhoneNumber,
+		EmployerID:   request.EmployerID,
+		SupervisorID: request.SupervisorID,
+		ChangedBy:    actingUser(r),
+	}
+	if err := h.store.EmergencyContacts.Put(&contact); errors.Is(err, store.ErrConflict) {
+		writeError(w, http.StatusConflict, "The contact was changed concurrently, try again")
+		return
+	} else if err != nil {
+		log.Printf("Error saving emergency contact: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to save contact")
 		return
 	}
-	defer tx.Rollback()
 
-	// Clear existing contacts
-	_, err = tx.Exec("DELETE FROM emergency_contact")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":      "Emergency contact updated successfully",
+		"phone_number": contact.PhoneNumber,
+		"contact":      contact,
+	})
+}
+
+// Remove the override for the employer or supervisor in the headers, so
+// their trainees fall back to the next most specific contact
+func (h *Handler) DeleteEmergencyContact(w http.ResponseWriter, r *http.Request) {
+	employerID, supervisorID, err := contactScope(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to clear existing contact"})
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if employerID == nil && supervisorID == nil {
+		writeError(w, http.StatusBadRequest, "The contact for everyone can be changed but not removed")
 		return
 	}
 
-	// Insert new contact
-	_, err = tx.Exec(
-		"INSERT INTO emergency_contact (phone_number) VALUES ($1)",
-		request.PhoneNumber,
-	)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to insert new contact"})
+	contact, err := h.store.EmergencyContacts.Retire(employerID, supervisorID, actingUser(r), time.Now())
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "No emergency contact override found")
+		return
+	} else if err != nil {
+		log.Printf("Error removing emergency contact: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to remove contact")
 		return
 	}
 
-	if err = tx.Commit(); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to commit transaction"})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contact)
+}
+
+// List every version of every emergency contact, newest first. The
+// employer-id or supervisor-id header narrows it to one override.
+func (h *Handler) GetEmergencyContactHistory(w http.ResponseWriter, r *http.Request) {
+	employerID, supervisorID, err := contactScope(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	history, err := h.store.EmergencyContacts.History()
+	if err != nil {
+		log.Printf("Error loading emergency contact history: %v", err)
+		writeError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	filtered := []models.EmergencyContact{}
+	for _, c := range history {
+		if (employerID == nil || (c.EmployerID != nil && *c.EmployerID == *employerID)) &&
+			(supervisorID == nil || (c.SupervisorID != nil && *c.SupervisorID == *supervisorID)) {
+			filtered = append(filtered, c)
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"message":      "Emergency contact replaced successfully",
-		"phone_number": request.PhoneNumber,
-	})
+	json.NewEncoder(w).Encode(filtered)
 }
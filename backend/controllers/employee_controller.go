@@ -3,7 +3,8 @@ package controllers
 import (
 	"encoding/json"
 	"net/http"
-	"server/database"
+	"server/models"
+	"strings"
 	"time"
 )
 
@@ -23,63 +24,69 @@ type EmployeeResponse struct {
 }
 
 // GetEmployeeData handles the HTTP request to fetch employee data
-func GetEmployeeData(w http.ResponseWriter, r *http.Request) {
-	var results []EmployeeResponse
-
-	query := `
-		SELECT 
-			s.id AS student_id,
-			s.first_name || ' ' || s.last_name AS student_name,
-			s.contact_number AS student_contact,
-			e.id AS employer_id,
-			e.name AS employer_name,
-			e.contact_number AS employer_contact,
-			CONCAT_WS(', ', e.address_line1, e.address_line2, e.address_line3) AS employer_address,
-			sup.supervisor_id AS supervisor_id,
-			sup.first_name || ' ' || sup.last_name AS supervisor_name,
-			o.otp_code AS latest_otp_code,
-			o.expires_at
-		FROM 
-			student s
-		LEFT JOIN employer e ON s.employer_id = e.id
-		LEFT JOIN supervisor sup ON s.supervisor_id = sup.supervisor_id
-		LEFT JOIN LATERAL (
-			SELECT otp_code, expires_at
-			FROM otps
-			WHERE otps.student_id = s.id
-			ORDER BY created_at DESC
-			LIMIT 1
-		) o ON true;
-	`
-
-	rows, err := database.DB.Query(query)
+func (h *Handler) GetEmployeeData(w http.ResponseWriter, r *http.Request) {
+	dir, err := h.loadDirectory()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	results, err := h.employeeRows(dir, true)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var res EmployeeResponse
-		if err := rows.Scan(
-			&res.StudentID,
-			&res.StudentName,
-			&res.StudentContact,
-			&res.EmployerID,
-			&res.EmployerName,
-			&res.EmployerContact,
-			&res.EmployerAddress,
-			&res.SupervisorID,
-			&res.SupervisorName,
-			&res.LatestOTPCode,
-			&res.ExpiresAt,
-		); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// employeeRows lists every student in dir with their employer, supervisor
+// and, with withOTP, latest pairing OTP. Only admins may see the codes.
+func (h *Handler) employeeRows(dir *directory, withOTP bool) ([]EmployeeResponse, error) {
+	latestOTP := map[int]models.OTP{}
+	if withOTP {
+		var err error
+		if latestOTP, err = h.store.OTPs.LatestByStudent(); err != nil {
+			return nil, err
+		}
+	}
+
+	var results []EmployeeResponse
+	for _, s := range dir.students {
+		res := EmployeeResponse{
+			StudentID:      int(s.ID),
+			StudentName:    s.FirstName + " " + s.LastName,
+			StudentContact: s.ContactNumber,
+		}
+		if e := dir.employerOf(s); e != nil {
+			id := int(e.ID)
+			address := joinAddress(e.AddressLine1, e.AddressLine2, e.AddressLine3)
+			res.EmployerID = &id
+			res.EmployerName = &e.Name
+			res.EmployerContact = &e.ContactNumber
+			res.EmployerAddress = &address
+		}
+		if sup := dir.supervisorOf(s); sup != nil {
+			name := sup.FirstName + " " + sup.LastName
+			res.SupervisorID = &sup.SupervisorID
+			res.SupervisorName = &name
+		}
+		if o, ok := latestOTP[int(s.ID)]; ok {
+			res.LatestOTPCode = &o.OTPCode
+			res.ExpiresAt = &o.ExpiresAt
 		}
 		results = append(results, res)
 	}
+	return results, nil
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(results)
+// joinAddress joins the address lines with ", " like CONCAT_WS, skipping empty lines
+func joinAddress(lines ...string) string {
+	var parts []string
+	for _, line := range lines {
+		if line != "" {
+			parts = append(parts, line)
+		}
+	}
+	return strings.Join(parts, ", ")
 }
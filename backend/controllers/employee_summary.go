@@ -1,13 +1,13 @@
 package controllers
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
-	"server/database"
+	"server/store"
 )
 
 type Attendance struct {
@@ -26,7 +26,7 @@ type EmployeeSummary struct {
 	Moods       []Mood       `json:"moods"`
 }
 
-func GetEmployeeSummary(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetEmployeeSummary(w http.ResponseWriter, r *http.Request) {
 	idStr := r.Header.Get("student-id")
 	if idStr == "" {
 		http.Error(w, `{"error":"Missing student-id header"}`, http.StatusBadRequest)
@@ -40,65 +40,50 @@ func GetEmployeeSummary(w http.ResponseWriter, r *http.Request) {
 
 	summary := EmployeeSummary{}
 
-	// 1. Last 5 attendance records (before today)
-	rows, err := database.DB.Query(
-		`SELECT check_in_date_time, check_out_date_time FROM attendance WHERE student_id = $1 AND DATE(check_in_date_time) < CURRENT_DATE ORDER BY check_in_date_time DESC LIMIT 5`,
-		studentID,
-	)
+	// 1. Last 5 attendance records (before today in the employer's timezone)
+	loc, err := h.studentLocation(studentID)
+	if errors.Is(err, store.ErrNotFound) {
+		loc = h.defaultLocation
+	} else if err != nil {
+		http.Error(w, `{"error":"Failed to fetch attendance"}`, http.StatusInternalServerError)
+		return
+	}
+	startOfDay, _ := getStartAndEndOfDay(time.Now(), loc)
+	attendance, err := h.store.Attendance.Recent(studentID, startOfDay, 5)
 	if err != nil {
 		http.Error(w, `{"error":"Failed to fetch attendance"}`, http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-	for rows.Next() {
-		var att Attendance
-		var checkOut sql.NullTime
-		if err := rows.Scan(&att.CheckIn, &checkOut); err != nil {
-			http.Error(w, `{"error":"Failed to scan attendance"}`, http.StatusInternalServerError)
-			return
+	for _, a := range attendance {
+		if a.Absent {
+			continue
 		}
-		if checkOut.Valid {
-			att.CheckOut = &checkOut.Time
+		att := Attendance{CheckIn: a.CheckInDateTime.In(loc)}
+		if a.CheckOutDateTime.Valid {
+			checkOut := a.CheckOutDateTime.Time.In(loc)
+			att.CheckOut = &checkOut
 		}
 		summary.Attendances = append(summary.Attendances, att)
 	}
-	if err := rows.Err(); err != nil {
-		http.Error(w, `{"error":"Failed to fetch attendance"}`, http.StatusInternalServerError)
-		return
-	}
 
 	// 2. Remarks
-	var remarks sql.NullString
-	err = database.DB.QueryRow(`SELECT remarks FROM student WHERE id = $1`, studentID).Scan(&remarks)
-	if err != nil && err != sql.ErrNoRows {
+	student, err := h.store.Students.Get(studentID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
 		http.Error(w, `{"error":"Failed to fetch remarks"}`, http.StatusInternalServerError)
 		return
 	}
-	if remarks.Valid {
-		summary.Remarks = remarks.String
+	if student != nil {
+		summary.Remarks = student.Remarks
 	}
 
-	// 3. Last 5 daily mood entries
-	rows, err = database.DB.Query(
-		`SELECT emotion, recorded_at FROM mood WHERE student_id = $1 AND is_daily = true ORDER BY recorded_at ASC LIMIT 5`,
-		studentID,
-	)
+	// 3. Last 5 daily mood entries, oldest first
+	moods, err := h.store.Moods.Recent(studentID, true, 5)
 	if err != nil {
 		http.Error(w, `{"error":"Failed to fetch moods"}`, http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-	for rows.Next() {
-		var m Mood
-		if err := rows.Scan(&m.Emotion, &m.RecordedAt); err != nil {
-			http.Error(w, `{"error":"Failed to scan moods"}`, http.StatusInternalServerError)
-			return
-		}
-		summary.Moods = append(summary.Moods, m)
-	}
-	if err := rows.Err(); err != nil {
-		http.Error(w, `{"error":"Failed to fetch moods"}`, http.StatusInternalServerError)
-		return
+	for i := len(moods) - 1; i >= 0; i-- {
+		summary.Moods = append(summary.Moods, Mood{Emotion: moods[i].Emotion, RecordedAt: moods[i].RecordedAt})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1,36 +1,111 @@
 package controllers
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
-	"server/database"
 	"server/models"
+	"server/store"
 )
 
-func CreateEmployer(w http.ResponseWriter, r *http.Request) {
-	var employerInput struct {
-		Name          string  `json:"name"`
-		ContactNumber string  `json:"contact_number"`
-		AddressLine1  string  `json:"address_line1"`
-		AddressLine2  string  `json:"address_line2"`
-		AddressLine3  string  `json:"address_line3"`
-		Longitude     float64 `json:"addr_long"`
-		Latitude      float64 `json:"addr_lat"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&employerInput); err != nil {
+// employerInput is the writable subset of an employer. The geofence,
+// timezone and grace settings are optional: left out of an update they keep
+// their stored values, and left out of a create they take the defaults.
+type employerInput struct {
+	Name          string  `json:"name"`
+	ContactNumber string  `json:"contact_number"`
+	AddressLine1  string  `json:"address_line1"`
+	AddressLine2  string  `json:"address_line2"`
+	AddressLine3  string  `json:"address_line3"`
+	Longitude     float64 `json:"addr_long"`
+	Latitude      float64 `json:"addr_lat"`
+
+	GeofenceRadius  *float64           `json:"geofence_radius_m"`
+	GeofencePolygon *[]models.GeoPoint `json:"geofence_polygon"`
+
+	Timezone *string `json:"timezone"`
+
+	LateGraceMinutes       *int `json:"late_grace_minutes"`
+	EarlyLeaveGraceMinutes *int `json:"early_leave_grace_minutes"`
+}
+
+// validate rejects polygons that cannot enclose an area
+func (in employerInput) validate() error {
+	if in.GeofenceRadius != nil && *in.GeofenceRadius < 0 {
+		return errors.New("geofence_radius_m must not be negative")
+	}
+	if in.GeofencePolygon != nil && len(*in.GeofencePolygon) > 0 && len(*in.GeofencePolygon) < 3 {
+		return errors.New("geofence_polygon needs at least three points")
+	}
+	if in.LateGraceMinutes != nil && *in.LateGraceMinutes < 0 {
+		return errors.New("late_grace_minutes must not be negative")
+	}
+	if in.EarlyLeaveGraceMinutes != nil && *in.EarlyLeaveGraceMinutes < 0 {
+		return errors.New("early_leave_grace_minutes must not be negative")
+	}
+	if in.Timezone != nil && *in.Timezone != "" {
+		if _, err := time.LoadLocation(*in.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", *in.Timezone)
+		}
+	}
+	return nil
+}
+
+// employerFromInput applies in to stored, the employer being updated, or
+// to a new employer when stored is nil
+func (h *Handler) employerFromInput(in employerInput, stored *models.Employer) models.Employer {
+	var e models.Employer
+	if stored != nil {
+		e = *stored
+	}
+	e.Name = in.Name
+	e.ContactNumber = in.ContactNumber
+	e.AddressLine1 = in.AddressLine1
+	e.AddressLine2 = in.AddressLine2
+	e.AddressLine3 = in.AddressLine3
+	e.Longitude = in.Longitude
+	e.Latitude = in.Latitude
+
+	if in.GeofenceRadius != nil {
+		e.GeofenceRadius = *in.GeofenceRadius
+	}
+	if e.GeofenceRadius == 0 {
+		e.GeofenceRadius = h.defaultGeofenceRadius
+	}
+	if in.GeofencePolygon != nil {
+		e.GeofencePolygon = *in.GeofencePolygon
+	}
+	if in.Timezone != nil {
+		e.Timezone = *in.Timezone
+	}
+	if e.Timezone == "" {
+		e.Timezone = h.defaultLocation.String()
+	}
+	if in.LateGraceMinutes != nil {
+		e.LateGraceMinutes = in.LateGraceMinutes
+	}
+	if in.EarlyLeaveGraceMinutes != nil {
+		e.EarlyLeaveGraceMinutes = in.EarlyLeaveGraceMinutes
+	}
+	return e
+}
+
+func (h *Handler) CreateEmployer(w http.ResponseWriter, r *http.Request) {
+	var input employerInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	var employer models.Employer
-	err := database.DB.QueryRow(
-		`INSERT INTO employer (name, contact_number, address_line1, address_line2, address_line3, addr_long, addr_lat)
-		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, name, contact_number, address_line1, address_line2, address_line3, addr_long, addr_lat`,
-		employerInput.Name, employerInput.ContactNumber, employerInput.AddressLine1, employerInput.AddressLine2, employerInput.AddressLine3, employerInput.Longitude, employerInput.Latitude,
-	).Scan(&employer.ID, &employer.Name, &employer.ContactNumber, &employer.AddressLine1, &employer.AddressLine2, &employer.AddressLine3, &employer.Longitude, &employer.Latitude)
-	if err != nil {
+	if err := input.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	employer := h.employerFromInput(input, nil)
+	if err := h.store.Employers.Create(&employer); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -38,19 +113,15 @@ func CreateEmployer(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(employer)
 }
 
-func GetEmployer(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetEmployer(w http.ResponseWriter, r *http.Request) {
 	idStr := r.Header.Get("employer-id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		http.Error(w, "Invalid employer-id header", http.StatusBadRequest)
 		return
 	}
-	var employer models.Employer
-	err = database.DB.QueryRow(
-		`SELECT id, name, contact_number, address_line1, address_line2, address_line3, addr_long, addr_lat FROM employer WHERE id = $1`,
-		id,
-	).Scan(&employer.ID, &employer.Name, &employer.ContactNumber, &employer.AddressLine1, &employer.AddressLine2, &employer.AddressLine3, &employer.Longitude, &employer.Latitude)
-	if err == sql.ErrNoRows {
+	employer, err := h.store.Employers.Get(id)
+	if errors.Is(err, store.ErrNotFound) {
 		http.Error(w, "Employer not found", http.StatusNotFound)
 		return
 	} else if err != nil {
@@ -61,96 +132,84 @@ func GetEmployer(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(employer)
 }
 
-func UpdateEmployer(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdateEmployer(w http.ResponseWriter, r *http.Request) {
 	idStr := r.Header.Get("employer-id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		http.Error(w, "Invalid employer-id header", http.StatusBadRequest)
 		return
 	}
-	var employerInput struct {
-		Name          string  `json:"name"`
-		ContactNumber string  `json:"contact_number"`
-		AddressLine1  string  `json:"address_line1"` // changed from address_line1 (already correct)
-		AddressLine2  string  `json:"address_line2"` // changed from address_line2 (already correct)
-		AddressLine3  string  `json:"address_line3"` // changed from address_line3 (already correct)
-		Longitude     float64 `json:"addr_long"`
-		Latitude      float64 `json:"addr_lat"`
+	var input employerInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	if err := json.NewDecoder(r.Body).Decode(&employerInput); err != nil {
+	if err := input.validate(); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	res, err := database.DB.Exec(
-		`UPDATE employer SET name = $1, contact_number = $2, address_line1 = $3, address_line2 = $4, address_line3 = $5, addr_long = $6, addr_lat = $7 WHERE id = $8`,
-		employerInput.Name, employerInput.ContactNumber, employerInput.AddressLine1, employerInput.AddressLine2, employerInput.AddressLine3, employerInput.Longitude, employerInput.Latitude, id,
-	)
-	if err != nil {
+	stored, err := h.store.Employers.Get(id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Employer not found", http.StatusNotFound)
+		return
+	} else if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	n, _ := res.RowsAffected()
-	if n == 0 {
+	employer := h.employerFromInput(input, stored)
+	err = h.store.Employers.Update(id, &employer)
+	if errors.Is(err, store.ErrNotFound) {
 		http.Error(w, "Employer not found", http.StatusNotFound)
 		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 	// Return the updated employer
-	var employer models.Employer
-	err = database.DB.QueryRow(
-		`SELECT id, name, contact_number, address_line1, address_line2, address_line3, addr_long, addr_lat FROM employer WHERE id = $1`,
-		id,
-	).Scan(&employer.ID, &employer.Name, &employer.ContactNumber, &employer.AddressLine1, &employer.AddressLine2, &employer.AddressLine3, &employer.Longitude, &employer.Latitude)
+	updated, err := h.store.Employers.Get(id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(employer)
+	json.NewEncoder(w).Encode(updated)
 }
 
-func DeleteEmployer(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) DeleteEmployer(w http.ResponseWriter, r *http.Request) {
 	idStr := r.Header.Get("employer-id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		http.Error(w, "Invalid employer-id header", http.StatusBadRequest)
 		return
 	}
-	res, err := database.DB.Exec(`DELETE FROM employer WHERE id = $1`, id)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	n, _ := res.RowsAffected()
-	if n == 0 {
+	err = h.store.Employers.Delete(id)
+	if errors.Is(err, store.ErrNotFound) {
 		http.Error(w, "Employer not found", http.StatusNotFound)
 		return
+	} else if errors.Is(err, store.ErrConflict) {
+		// Placements keep the history of where trainees worked
+		http.Error(w, "Employer has placements and cannot be deleted", http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func GetAllEmployerIDsAndNames(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetAllEmployerIDsAndNames(w http.ResponseWriter, r *http.Request) {
 	type EmployerIDName struct {
 		ID   uint64 `json:"id"`
 		Name string `json:"name"`
 	}
-	rows, err := database.DB.Query(`SELECT id, name FROM employer`)
+	all, err := h.store.Employers.List()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 	var employers []EmployerIDName
-	for rows.Next() {
-		var e EmployerIDName
-		if err := rows.Scan(&e.ID, &e.Name); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		employers = append(employers, e)
-	}
-	if err := rows.Err(); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	for _, e := range all {
+		employers = append(employers, EmployerIDName{ID: uint64(e.ID), Name: e.Name})
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(employers)
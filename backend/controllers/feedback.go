@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"server/auth"
+	"server/models"
+	"server/store"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultFeedbackCompetencies are the competencies managers rate trainees on
+// unless FEEDBACK_COMPETENCIES lists others
+const defaultFeedbackCompetencies = "punctuality,independence,quality_of_work,communication,teamwork"
+
+// parseCompetencies reads a comma-separated list of competency names
+func parseCompetencies(spec string) []string {
+	var competencies []string
+	seen := map[string]bool{}
+	for _, c := range strings.Split(spec, ",") {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c != "" && !seen[c] {
+			seen[c] = true
+			competencies = append(competencies, c)
+		}
+	}
+	return competencies
+}
+
+// validateFeedback checks the period, ratings and comments of a submission
+func (h *Handler) validateFeedback(req *models.FeedbackRequest) error {
+	start, err := time.Parse(dateLayout, req.PeriodStart)
+	if err != nil {
+		return errors.New("Invalid period_start, expected YYYY-MM-DD")
+	}
+	end, err := time.Parse(dateLayout, req.PeriodEnd)
+	if err != nil {
+		return errors.New("Invalid period_end, expected YYYY-MM-DD")
+	}
+	if end.Before(start) {
+		return errors.New("period_end must not be before period_start")
+	}
+
+	known := map[string]bool{}
+	for _, c := range h.feedbackCompetencies {
+		known[c] = true
+	}
+	ratings := make(map[string]int, len(req.Ratings))
+	for competency, rating := range req.Ratings {
+		competency = strings.ToLower(strings.TrimSpace(competency))
+		if !known[competency] {
+			return errors.New("Unknown competency " + competency + "; expected one of " + strings.Join(h.feedbackCompetencies, ", "))
+		}
+		if rating < models.FeedbackRatingMin || rating > models.FeedbackRatingMax {
+			return errors.New("Ratings must be between " + strconv.Itoa(models.FeedbackRatingMin) + " and " + strconv.Itoa(models.FeedbackRatingMax))
+		}
+		ratings[competency] = rating
+	}
+	req.Ratings = ratings
+	req.Comments = strings.TrimSpace(req.Comments)
+	if len(req.Ratings) == 0 && req.Comments == "" {
+		return errors.New("Give at least one rating or a comment")
+	}
+	return nil
+}
+
+// GetFeedbackCompetencies lists the competencies feedback is rated on
+func (h *Handler) GetFeedbackCompetencies(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"competencies": h.feedbackCompetencies,
+		"rating_min":   models.FeedbackRatingMin,
+		"rating_max":   models.FeedbackRatingMax,
+	})
+}
+
+// SubmitFeedback stores a manager's feedback on the student in the
+// student-id header, against the student's current employer
+func (h *Handler) SubmitFeedback(w http.ResponseWriter, r *http.Request) {
+	student, ok := h.viewableStudent(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.FeedbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validateFeedback(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f := models.ManagerFeedback{
+		StudentID:   int(student.ID),
+		AuthorID:    actingUser(r),
+		AuthorName:  strings.TrimSpace(req.AuthorName),
+		PeriodStart: req.PeriodStart,
+		PeriodEnd:   req.PeriodEnd,
+		Ratings:     req.Ratings,
+		Comments:    req.Comments,
+		Source:      models.FeedbackSourceAPI,
+	}
+	if student.EmployerID != nil {
+		id := int(*student.EmployerID)
+		f.EmployerID = &id
+	}
+	if err := h.store.Feedback.Create(&f); err != nil {
+		log.Printf("Error storing feedback for student %d: %v", student.ID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(f)
+}
+
+// feedbackPeriod reads the optional from and to dates feedback periods must overlap
+func feedbackPeriod(r *http.Request) (from, to string, err error) {
+	from, to = r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	for _, v := range []string{from, to} {
+		if v == "" {
+			continue
+		}
+		if _, err := time.Parse(dateLayout, v); err != nil {
+			return "", "", errors.New("Invalid date " + v + ", expected YYYY-MM-DD")
+		}
+	}
+	return from, to, nil
+}
+
+// GetStudentFeedback lists feedback on the student in the student-id
+// header, latest period first. Employers only see feedback given at their
+// own workplace.
+func (h *Handler) GetStudentFeedback(w http.ResponseWriter, r *http.Request) {
+	student, ok := h.viewableStudent(w, r)
+	if !ok {
+		return
+	}
+	from, to, err := feedbackPeriod(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	studentID := int(student.ID)
+	q := store.FeedbackQuery{StudentID: &studentID, From: from, To: to}
+	if p := auth.PrincipalFrom(r.Context()); p.Role == auth.RoleEmployer {
+		q.EmployerID = &p.EmployerID
+	}
+	list, err := h.store.Feedback.List(q)
+	if err != nil {
+		log.Printf("Error listing feedback for student %d: %v", studentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// GetEmployerFeedback lists feedback given at the employer in the
+// employer-id header. Supervisors only see feedback on their own trainees
+// and employers only their own feedback, with the header defaulting to it.
+func (h *Handler) GetEmployerFeedback(w http.ResponseWriter, r *http.Request) {
+	principal := auth.PrincipalFrom(r.Context())
+	if principal.Role == auth.RoleEmployer && r.Header.Get("employer-id") == "" {
+		r.Header.Set("employer-id", strconv.Itoa(principal.EmployerID))
+	}
+	employerID, err := strconv.Atoi(r.Header.Get("employer-id"))
+	if err != nil {
+		http.Error(w, "Invalid or missing employer-id header", http.StatusBadRequest)
+		return
+	}
+	if principal.Role == auth.RoleEmployer && employerID != principal.EmployerID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if _, err := h.store.Employers.Get(employerID); errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Employer not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	from, to, err := feedbackPeriod(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.store.Feedback.List(store.FeedbackQuery{EmployerID: &employerID, From: from, To: to})
+	if err != nil {
+		log.Printf("Error listing feedback for employer %d: %v", employerID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if principal.Role == auth.RoleSupervisor {
+		students := map[int]*models.Student{}
+		visible := []models.ManagerFeedback{}
+		for _, f := range list {
+			s, seen := students[f.StudentID]
+			if !seen {
+				s, err = h.store.Students.Get(f.StudentID)
+				if err != nil && !errors.Is(err, store.ErrNotFound) {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				students[f.StudentID] = s
+			}
+			if s != nil && supervises(principal, s) {
+				visible = append(visible, f)
+			}
+		}
+		list = visible
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
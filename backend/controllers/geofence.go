@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"server/distance"
+	"server/models"
+)
+
+// geofenceResult is where a coordinate lies relative to an employer's geofence
+type geofenceResult struct {
+	Inside bool
+	// DistanceM is the straight-line distance to the employer's address coordinates
+	DistanceM int
+}
+
+// evaluateGeofence checks a coordinate against the employer's polygon when it
+// has one, otherwise against the circle of GeofenceRadius around the address
+func evaluateGeofence(e *models.Employer, lat, long float64) geofenceResult {
+	metres := distance.Haversine(e.Latitude, e.Longitude, lat, long)
+	if len(e.GeofencePolygon) >= 3 {
+		return geofenceResult{Inside: insidePolygon(e.GeofencePolygon, lat, long), DistanceM: metres}
+	}
+	return geofenceResult{Inside: float64(metres) <= e.GeofenceRadius, DistanceM: metres}
+}
+
+// insidePolygon is a ray-casting test treating degrees as planar coordinates,
+// which is accurate enough at the scale of a workplace
+func insidePolygon(polygon []models.GeoPoint, lat, long float64) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Lat > lat) != (b.Lat > lat) &&
+			long < (b.Long-a.Long)*(lat-a.Lat)/(b.Lat-a.Lat)+a.Long {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// employerFor returns the student's employer, or nil when none is assigned
+func (h *Handler) employerFor(studentID int) (*models.Employer, error) {
+	student, err := h.store.Students.Get(studentID)
+	if err != nil {
+		return nil, err
+	}
+	if student.EmployerID == nil {
+		return nil, nil
+	}
+	return h.store.Employers.Get(int(*student.EmployerID))
+}
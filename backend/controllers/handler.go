@@ -0,0 +1,240 @@
+package controllers
+
+import (
+	"log"
+	"server/auth"
+	"server/config"
+	"server/distance"
+	"server/feedback"
+	"server/models"
+	"server/notify"
+	"server/store"
+	"server/summary"
+	"time"
+)
+
+// Handler serves the dashboard and trainee endpoints from the given stores
+type Handler struct {
+	store    *store.Store
+	distance distance.Provider
+	notifier *notify.Service
+	// summaries writes trainee summaries
+	summaries *summary.Service
+
+	// now is the server clock attendance is timed by
+	now func() time.Time
+
+	// defaultGeofenceRadius applies to employers saved without a radius
+	defaultGeofenceRadius float64
+	// enforceGeofence rejects check-ins outside the employer's geofence
+	// instead of only recording them
+	enforceGeofence bool
+
+	// maxSessionLength is how long after check-in a session still counts as open
+	maxSessionLength time.Duration
+	// rejectOrphanCheckOuts refuses check-outs without an open session
+	// instead of recording them flagged
+	rejectOrphanCheckOuts bool
+
+	// defaultLocation is used for students without an employer and for
+	// employers saved without a timezone
+	defaultLocation *time.Location
+
+	// Grace periods for employers that do not set their own
+	lateGrace       time.Duration
+	earlyLeaveGrace time.Duration
+
+	// absentAfter is how long after the scheduled start a student without a
+	// session is recorded absent; absences due longer than absenceLookback
+	// ago are not back-filled
+	absentAfter     time.Duration
+	absenceLookback time.Duration
+	// autoCloseAfter is how long after the scheduled end open sessions are closed
+	autoCloseAfter time.Duration
+
+	// moodVocabulary is every emotion trainees may report; negativeMoodStreak
+	// negative daily moods in a row notify the supervisor
+	moodVocabulary     []models.MoodOption
+	negativeMoodStreak int
+	// dailyMoodEditWindow is how long after it was first recorded a daily
+	// mood may still be replaced
+	dailyMoodEditWindow time.Duration
+
+	// feedbackCompetencies are what managers rate trainees on
+	feedbackCompetencies []string
+	// feedbackSync mirrors the manager feedback Google Sheet, nil when none
+	// is configured
+	feedbackSync *feedback.Syncer
+}
+
+// NewHandler creates a handler backed by stores that reports events to notifier
+func NewHandler(stores *store.Store, notifier *notify.Service) *Handler {
+	vocabulary, err := parseMoodVocabulary(config.String("MOOD_VOCABULARY", defaultMoodVocabulary))
+	if err != nil {
+		log.Printf("⚠️ Ignoring MOOD_VOCABULARY: %v", err)
+		vocabulary, _ = parseMoodVocabulary(defaultMoodVocabulary)
+	}
+	h := &Handler{
+		store:                 stores,
+		now:                   time.Now,
+		distance:              distance.FromEnv(),
+		notifier:              notifier,
+		summaries:             summary.FromEnv(),
+		defaultGeofenceRadius: config.Float("GEOFENCE_DEFAULT_RADIUS_M", 200),
+		enforceGeofence:       config.Bool("GEOFENCE_ENFORCE_CHECK_IN", true),
+		maxSessionLength:      config.Duration("ATTENDANCE_MAX_SESSION_LENGTH", 16*time.Hour),
+		rejectOrphanCheckOuts: config.String("ATTENDANCE_ORPHAN_CHECK_OUT", "flag") == "reject",
+		defaultLocation:       loadLocation(config.String("DEFAULT_TIMEZONE", "Asia/Colombo"), time.UTC),
+		lateGrace:             time.Duration(config.Int("ATTENDANCE_LATE_GRACE_MINUTES", 10)) * time.Minute,
+		earlyLeaveGrace:       time.Duration(config.Int("ATTENDANCE_EARLY_LEAVE_GRACE_MINUTES", 10)) * time.Minute,
+		absentAfter:           config.Duration("ATTENDANCE_ABSENT_AFTER", 2*time.Hour),
+		absenceLookback:       config.Duration("ATTENDANCE_ABSENCE_LOOKBACK", 12*time.Hour),
+		autoCloseAfter:        config.Duration("ATTENDANCE_AUTO_CLOSE_AFTER", time.Hour),
+		moodVocabulary:        vocabulary,
+		negativeMoodStreak:    config.Int("NOTIFY_NEGATIVE_MOOD_STREAK", 3),
+		dailyMoodEditWindow:   config.Duration("MOOD_DAILY_EDIT_WINDOW", time.Hour),
+		feedbackCompetencies:  parseCompetencies(config.String("FEEDBACK_COMPETENCIES", defaultFeedbackCompetencies)),
+	}
+	if h.feedbackSync, err = feedback.SyncFromEnv(stores, h.defaultLocation); err != nil {
+		log.Printf("⚠️ Manager feedback sheet sync disabled: %v", err)
+	}
+	return h
+}
+
+// UseClock replaces the clock attendance is timed by
+func (h *Handler) UseClock(now func() time.Time) {
+	h.now = now
+}
+
+// loadLocation returns the named zone, or fallback when it is unknown
+func loadLocation(name string, fallback *time.Location) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("⚠️ Unknown timezone %q, using %s: %v", name, fallback, err)
+		return fallback
+	}
+	return loc
+}
+
+// locationOf returns the zone attendance days are counted in for an
+// employer, which may be nil for students without one
+func (h *Handler) locationOf(e *models.Employer) *time.Location {
+	if e == nil || e.Timezone == "" {
+		return h.defaultLocation
+	}
+	return loadLocation(e.Timezone, h.defaultLocation)
+}
+
+// studentLocation returns the zone of the student's employer
+func (h *Handler) studentLocation(studentID int) (*time.Location, error) {
+	employer, err := h.employerFor(studentID)
+	if err != nil {
+		return nil, err
+	}
+	return h.locationOf(employer), nil
+}
+
+// directory holds every student alongside lookups of their employers and
+// supervisors, for views that join the three tables
+type directory struct {
+	students    []models.Student
+	employers   map[uint]models.Employer
+	supervisors map[uint]models.Supervisor
+	// employerID is set once narrowed to an employer's view
+	employerID int
+}
+
+func (h *Handler) loadDirectory() (*directory, error) {
+	students, err := h.store.Students.List()
+	if err != nil {
+		return nil, err
+	}
+	employers, err := h.store.Employers.List()
+	if err != nil {
+		return nil, err
+	}
+	supervisors, err := h.store.Supervisors.List()
+	if err != nil {
+		return nil, err
+	}
+
+	d := &directory{
+		students:    students,
+		employers:   make(map[uint]models.Employer, len(employers)),
+		supervisors: make(map[uint]models.Supervisor, len(supervisors)),
+	}
+	for _, e := range employers {
+		d.employers[e.ID] = e
+	}
+	for _, s := range supervisors {
+		d.supervisors[uint(s.SupervisorID)] = s
+	}
+	return d, nil
+}
+
+// visibleTo narrows the directory to the students the caller may list:
+// employers only see the trainees placed with them
+func (d *directory) visibleTo(p *auth.Principal) *directory {
+	if p == nil || p.Role != auth.RoleEmployer {
+		return d
+	}
+	var students []models.Student
+	for _, s := range d.students {
+		if employs(p, &s) {
+			students = append(students, s)
+		}
+	}
+	narrowed := *d
+	narrowed.students = students
+	narrowed.employerID = p.EmployerID
+	return &narrowed
+}
+
+// caseloadOf narrows the directory to the trainees assigned to the supervisor
+func (d *directory) caseloadOf(supervisorID int) *directory {
+	var students []models.Student
+	for _, s := range d.students {
+		if s.SupervisorID != nil && int(*s.SupervisorID) == supervisorID {
+			students = append(students, s)
+		}
+	}
+	narrowed := *d
+	narrowed.students = students
+	return &narrowed
+}
+
+// employerOf returns the student's employer, or nil if none is assigned
+func (d *directory) employerOf(s models.Student) *models.Employer {
+	if s.EmployerID == nil {
+		return nil
+	}
+	e, ok := d.employers[*s.EmployerID]
+	if !ok {
+		return nil
+	}
+	return &e
+}
+
+// employerByID returns the employer with id, or nil if id is nil or unknown
+func (d *directory) employerByID(id *int) *models.Employer {
+	if id == nil {
+		return nil
+	}
+	e, ok := d.employers[uint(*id)]
+	if !ok {
+		return nil
+	}
+	return &e
+}
+
+// supervisorOf returns the student's supervisor, or nil if none is assigned
+func (d *directory) supervisorOf(s models.Student) *models.Supervisor {
+	if s.SupervisorID == nil {
+		return nil
+	}
+	sup, ok := d.supervisors[*s.SupervisorID]
+	if !ok {
+		return nil
+	}
+	return &sup
+}
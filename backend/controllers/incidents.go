@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"server/auth"
+	"server/models"
+	"server/notify"
+	"server/store"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RaiseSOS records an emergency with the trainee's location and alerts the
+// supervisor and the emergency contact. Pressing SOS again while an incident
+// is unresolved only updates its location.
+func (h *Handler) RaiseSOS(w http.ResponseWriter, r *http.Request) {
+	studentID, err := getStudentIDFromHeader(r)
+	if err != nil {
+		http.Error(w, "Invalid or missing student-id header", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Latitude  *float64 `json:"latitude"`
+		Longitude *float64 `json:"longitude"`
+		AccuracyM *float64 `json:"accuracy_m"`
+		Message   string   `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if request.Latitude == nil || request.Longitude == nil ||
+		*request.Latitude < -90 || *request.Latitude > 90 || *request.Longitude < -180 || *request.Longitude > 180 {
+		http.Error(w, "latitude and longitude are required", http.StatusBadRequest)
+		return
+	}
+
+	student, err := h.store.Students.Get(studentID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Student not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error loading student %d: %v", studentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	incident := models.Incident{
+		StudentID: studentID,
+		Latitude:  *request.Latitude,
+		Longitude: *request.Longitude,
+		AccuracyM: request.AccuracyM,
+		Message:   strings.TrimSpace(request.Message),
+	}
+	created, err := h.store.Incidents.Raise(&incident)
+	if err != nil {
+		log.Printf("Error recording SOS for student %d: %v", studentID, err)
+		http.Error(w, "Failed to record SOS", http.StatusInternalServerError)
+		return
+	}
+
+	if created {
+		log.Printf("🚨 SOS incident %d raised by student %d", incident.ID, studentID)
+		h.alertIncident(&incident, student)
+	} else {
+		log.Printf("SOS incident %d of student %d relocated", incident.ID, studentID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(incident)
+}
+
+// alertIncident notifies the supervisor by email and SMS and the emergency
+// contact by SMS, and has them delivered straight away
+func (h *Handler) alertIncident(i *models.Incident, s *models.Student) {
+	var employer *models.Employer
+	if s.EmployerID != nil {
+		if e, err := h.store.Employers.Get(int(*s.EmployerID)); err == nil {
+			employer = e
+		}
+	}
+	e := notify.Event{
+		Kind:       notify.SOSRaised,
+		StudentID:  int(s.ID),
+		Student:    strings.TrimSpace(s.FirstName + " " + s.LastName),
+		Employer:   employerName(employer),
+		At:         i.RaisedAt.In(h.locationOf(employer)).Format(notificationTimeLayout),
+		IncidentID: i.ID,
+		Location:   fmt.Sprintf("https://maps.google.com/?q=%f,%f", i.Latitude, i.Longitude),
+		Note:       i.Message,
+	}
+
+	var recipients []notify.Recipient
+	if s.SupervisorID != nil {
+		if sup, err := h.store.Supervisors.Get(int(*s.SupervisorID)); err == nil {
+			recipients = append(recipients,
+				notify.Recipient{Channel: notify.ChannelEmail, Address: sup.EmailAddress},
+				notify.Recipient{Channel: notify.ChannelSMS, Address: sup.ContactNumber})
+		} else {
+			log.Printf("Error loading supervisor of student %d: %v", s.ID, err)
+		}
+	}
+	if contact, err := h.emergencyContactFor(s); err == nil {
+		recipients = append(recipients, notify.Recipient{Channel: notify.ChannelSMS, Address: contact.PhoneNumber})
+	} else {
+		log.Printf("No emergency contact to alert for incident %d: %v", i.ID, err)
+	}
+
+	if err := h.notifier.Notify(e, recipients...); err != nil {
+		log.Printf("Error queueing SOS notification for incident %d: %v", i.ID, err)
+		return
+	}
+	h.notifier.Wake()
+}
+
+// GetCurrentIncident returns the trainee's unresolved incident, so the app
+// can show whether help has acknowledged it
+func (h *Handler) GetCurrentIncident(w http.ResponseWriter, r *http.Request) {
+	studentID, err := getStudentIDFromHeader(r)
+	if err != nil {
+		http.Error(w, "Invalid or missing student-id header", http.StatusBadRequest)
+		return
+	}
+	incident, err := h.store.Incidents.Unresolved(studentID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "No unresolved incident", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error loading incident of student %d: %v", studentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(incident)
+}
+
+// GetIncidents lists incidents newest first. status is open, acknowledged,
+// resolved or all, and defaults to every unresolved incident. Supervisors
+// only see their own trainees'.
+func (h *Handler) GetIncidents(w http.ResponseWriter, r *http.Request) {
+	var statuses []string
+	switch status := r.URL.Query().Get("status"); status {
+	case "":
+		statuses = []string{models.IncidentOpen, models.IncidentAcknowledged}
+	case "all":
+	case models.IncidentOpen, models.IncidentAcknowledged, models.IncidentResolved:
+		statuses = []string{status}
+	default:
+		http.Error(w, "status must be open, acknowledged, resolved or all", http.StatusBadRequest)
+		return
+	}
+
+	incidents, err := h.store.Incidents.List(statuses...)
+	if err != nil {
+		log.Printf("Error listing incidents: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	principal := auth.PrincipalFrom(r.Context())
+	if principal.Role != auth.RoleAdmin {
+		students, err := h.store.Students.List()
+		if err != nil {
+			log.Printf("Error listing students: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		mine := map[int]bool{}
+		for _, s := range students {
+			mine[int(s.ID)] = supervises(principal, &s)
+		}
+		visible := incidents[:0]
+		for _, i := range incidents {
+			if mine[i.StudentID] {
+				visible = append(visible, i)
+			}
+		}
+		incidents = visible
+	}
+
+	if incidents == nil {
+		incidents = []models.Incident{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(incidents)
+}
+
+// incidentFor reads the incident-id header and loads the incident, checking
+// the caller supervises its student. It writes the error response itself.
+func (h *Handler) incidentFor(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.Header.Get("incident-id"))
+	if err != nil {
+		http.Error(w, "Invalid incident-id header", http.StatusBadRequest)
+		return 0, false
+	}
+	incident, err := h.store.Incidents.Get(id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Incident not found", http.StatusNotFound)
+		return 0, false
+	} else if err != nil {
+		log.Printf("Error loading incident %d: %v", id, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return 0, false
+	}
+	student, err := h.store.Students.Get(incident.StudentID)
+	if err != nil {
+		log.Printf("Error loading student %d: %v", incident.StudentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return 0, false
+	}
+	if !supervises(auth.PrincipalFrom(r.Context()), student) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return 0, false
+	}
+	return id, true
+}
+
+// writeIncidentTransition reports the outcome of acknowledging or resolving
+func writeIncidentTransition(w http.ResponseWriter, incident *models.Incident, err error, conflict string) {
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Incident not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, store.ErrConflict) {
+		http.Error(w, conflict, http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("Error updating incident: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(incident)
+}
+
+// AcknowledgeIncident records that someone is responding to an open incident
+func (h *Handler) AcknowledgeIncident(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.incidentFor(w, r)
+	if !ok {
+		return
+	}
+	incident, err := h.store.Incidents.Acknowledge(id, actingUser(r), time.Now())
+	if err == nil {
+		log.Printf("Incident %d acknowledged", id)
+	}
+	writeIncidentTransition(w, incident, err, "Incident is already acknowledged or resolved")
+}
+
+// ResolveIncident closes an incident with a note on how it was handled
+func (h *Handler) ResolveIncident(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.incidentFor(w, r)
+	if !ok {
+		return
+	}
+	var request struct {
+		Resolution string `json:"resolution"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	request.Resolution = strings.TrimSpace(request.Resolution)
+	if request.Resolution == "" {
+		http.Error(w, "resolution is required", http.StatusBadRequest)
+		return
+	}
+	incident, err := h.store.Incidents.Resolve(id, actingUser(r), request.Resolution, time.Now())
+	if err == nil {
+		log.Printf("Incident %d resolved", id)
+	}
+	writeIncidentTransition(w, incident, err, "Incident is already resolved")
+}
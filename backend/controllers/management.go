@@ -3,7 +3,7 @@ package controllers
 import (
 	"encoding/json"
 	"net/http"
-	"server/database"
+	"server/auth"
 )
 
 // Response struct for the joined data
@@ -19,50 +19,38 @@ type StudentEmployerSupervisor struct {
 }
 
 // Handler to get the joined data
-func GetManagementTable(w http.ResponseWriter, r *http.Request) {
-	var results []StudentEmployerSupervisor
-
-	// Raw SQL for the LEFT JOINs
-	query := `
-		SELECT
-			s.id AS student_id,
-			s.first_name AS student_first_name,
-			s.last_name AS student_last_name,
-			e.name AS employer_name,
-			e.contact_number AS employer_contact_number,
-			sup.first_name AS supervisor_first_name,
-			sup.last_name AS supervisor_last_name,
-			sup.contact_number AS supervisor_contact_number
-		FROM student AS s
-		LEFT JOIN employer AS e ON s.employer_id = e.id
-		LEFT JOIN supervisor AS sup ON s.supervisor_id = sup.supervisor_id
-	`
-
-	rows, err := database.DB.Query(query)
+func (h *Handler) GetManagementTable(w http.ResponseWriter, r *http.Request) {
+	dir, err := h.loadDirectory()
 	if err != nil {
 		http.Error(w, "Failed to fetch data", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
+	// Employers only see the trainees placed with them
+	dir = dir.visibleTo(auth.PrincipalFrom(r.Context()))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(managementRows(dir))
+}
 
-	for rows.Next() {
-		var res StudentEmployerSupervisor
-		if err := rows.Scan(
-			&res.StudentID,
-			&res.StudentFirstName,
-			&res.StudentLastName,
-			&res.EmployerName,
-			&res.EmployerContactNumber,
-			&res.SupervisorFirstName,
-			&res.SupervisorLastName,
-			&res.SupervisorContactNumber,
-		); err != nil {
-			http.Error(w, "Failed to scan data", http.StatusInternalServerError)
-			return
+// managementRows joins every student in dir with their employer and supervisor
+func managementRows(dir *directory) []StudentEmployerSupervisor {
+	var results []StudentEmployerSupervisor
+	for _, s := range dir.students {
+		res := StudentEmployerSupervisor{
+			StudentID:        uint(s.ID),
+			StudentFirstName: s.FirstName,
+			StudentLastName:  &s.LastName,
+		}
+		if e := dir.employerOf(s); e != nil {
+			res.EmployerName = &e.Name
+			res.EmployerContactNumber = &e.ContactNumber
+		}
+		if sup := dir.supervisorOf(s); sup != nil {
+			res.SupervisorFirstName = &sup.FirstName
+			res.SupervisorLastName = &sup.LastName
+			res.SupervisorContactNumber = &sup.ContactNumber
 		}
 		results = append(results, res)
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(results)
+	return results
 }
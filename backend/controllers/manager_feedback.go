@@ -1,78 +1,99 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"log"
 	"net/http"
-	"os"
+	"server/auth"
+	"server/feedback"
+	"server/models"
+	"time"
 )
 
-const (
-	sheetID = "1LmvPIp-Ixdvur80OKFQ7Dm31QB1KpjOZDAstUWLkK-o"
-	rangeA1 = "Sheet1!A1:Z100"
-)
-
-type FeedbackResponse map[string]string
-
-type googleSheetResponse struct {
-	Values [][]string `json:"values"`
-}
-
-func getEnv(key string) string {
-	return os.Getenv(key)
+// UseFeedbackSync replaces the sync of the manager feedback Google Sheet
+func (h *Handler) UseFeedbackSync(s *feedback.Syncer) {
+	h.feedbackSync = s
 }
 
-func FetchManagerFeedback(w http.ResponseWriter, r *http.Request) {
-	apiKey := getEnv("GOOGLE_SHEET_API_KEY")
-	if apiKey == "" {
-		http.Error(w, "Google Sheets API key not configured", http.StatusInternalServerError)
+// RunFeedbackSync pulls the feedback sheet every interval until ctx is
+// cancelled. It returns at once when no sheet is configured.
+func (h *Handler) RunFeedbackSync(ctx context.Context, interval time.Duration) {
+	if h.feedbackSync == nil {
 		return
 	}
+	h.feedbackSync.Run(ctx, interval)
+}
 
-	url := fmt.Sprintf(
-		"https://sheets.googleapis.com/v4/spreadsheets/%s/values/%s?key=%s",
-		sheetID, rangeA1, apiKey,
-	)
-
-	resp, err := http.Get(url)
+// visibleSheetRows returns the pulled rows still in the sheet that the
+// caller may see: every row for admins, their trainees' rows for supervisors
+func (h *Handler) visibleSheetRows(r *http.Request) ([]models.FeedbackSheetRow, error) {
+	rows, err := h.store.FeedbackSheet.List()
 	if err != nil {
-		http.Error(w, "Failed to fetch Google Sheet data", http.StatusInternalServerError)
-		return
+		return nil, err
 	}
-	defer resp.Body.Close()
+	principal := auth.PrincipalFrom(r.Context())
+	visible := []models.FeedbackSheetRow{}
+	for _, row := range rows {
+		if row.RemovedAt != nil {
+			continue
+		}
+		if principal == nil || principal.Role != auth.RoleAdmin {
+			if row.StudentID == nil {
+				continue
+			}
+			student, err := h.store.Students.Get(*row.StudentID)
+			if err != nil || !supervises(principal, student) {
+				continue
+			}
+		}
+		visible = append(visible, row)
+	}
+	return visible, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		http.Error(w, fmt.Sprintf("Google Sheets API error: %s", resp.Status), resp.StatusCode)
+// FetchManagerFeedback returns the rows of the manager feedback sheet as
+// header to value maps, from the copy kept by the last sync
+func (h *Handler) FetchManagerFeedback(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.visibleSheetRows(r)
+	if err != nil {
+		log.Printf("Error listing feedback sheet rows: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	responses := make([]map[string]string, len(rows))
+	for i, row := range rows {
+		responses[i] = row.Cells
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses)
+}
 
-	var sheet googleSheetResponse
-	if err := json.NewDecoder(resp.Body).Decode(&sheet); err != nil {
-		http.Error(w, "Failed to decode Google Sheet response", http.StatusInternalServerError)
+// GetFeedbackSheetRows lists the pulled sheet rows with the student and
+// feedback each was stored as, or why it could not be
+func (h *Handler) GetFeedbackSheetRows(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.visibleSheetRows(r)
+	if err != nil {
+		log.Printf("Error listing feedback sheet rows: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rows)
+}
 
-	if len(sheet.Values) < 2 {
-		json.NewEncoder(w).Encode([]FeedbackResponse{})
+// SyncManagerFeedback pulls the feedback sheet now
+func (h *Handler) SyncManagerFeedback(w http.ResponseWriter, r *http.Request) {
+	if h.feedbackSync == nil {
+		http.Error(w, "No feedback sheet configured", http.StatusNotFound)
 		return
 	}
-
-	headers := sheet.Values[0]
-	rows := sheet.Values[1:]
-
-	var responses []FeedbackResponse
-	for _, row := range rows {
-		respMap := FeedbackResponse{}
-		for idx, header := range headers {
-			if idx < len(row) {
-				respMap[header] = row[idx]
-			} else {
-				respMap[header] = ""
-			}
-		}
-		responses = append(responses, respMap)
+	report, err := h.feedbackSync.Sync(r.Context(), time.Now())
+	if err != nil {
+		log.Printf("Error syncing feedback sheet: %v", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
 	}
-
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(responses)
+	json.NewEncoder(w).Encode(report)
 }
@@ -1,63 +1,196 @@
 package controllers
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
-	"server/database"
+	"server/auth"
 	"server/models"
+	"server/store"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 )
 
-func GetMoods(w http.ResponseWriter, r *http.Request) {
-	var moods []models.Mood
-	rows, err := database.DB.Query("SELECT id, student_id, recorded_at, emotion, is_daily FROM mood")
+// GetMoods lists every mood for the dashboard, oldest first. Supervisors
+// only get their own trainees' moods.
+func (h *Handler) GetMoods(w http.ResponseWriter, r *http.Request) {
+	moods, err := h.store.Moods.List()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-	for rows.Next() {
-		var m models.Mood
-		if err := rows.Scan(&m.ID, &m.StudentID, &m.RecordedAt, &m.Emotion, &m.IsDaily); err != nil {
+	if principal := auth.PrincipalFrom(r.Context()); principal.Role != auth.RoleAdmin {
+		students, err := h.store.Students.List()
+		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		moods = append(moods, m)
+		mine := map[int]bool{}
+		for _, s := range students {
+			mine[int(s.ID)] = supervises(principal, &s)
+		}
+		visible := []models.Mood{}
+		for _, m := range moods {
+			if mine[m.StudentID] {
+				visible = append(visible, m)
+			}
+		}
+		moods = visible
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(moods)
 }
 
-func GetMood(w http.ResponseWriter, r *http.Request) {
-	StudentIDHeader := r.Header.Get("student-id")
-	if StudentIDHeader == "" {
-		log.Println("Missing student-id header")
-		http.Error(w, "Missing student-id header", http.StatusBadRequest)
+// GetMood returns one of the student's moods by id
+func (h *Handler) GetMood(w http.ResponseWriter, r *http.Request) {
+	student, ok := h.viewableStudent(w, r)
+	if !ok {
 		return
 	}
-	studentID, err := strconv.Atoi(StudentIDHeader)
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		log.Printf("Invalid student-id header: %v", err)
-		http.Error(w, "Invalid student-id header", http.StatusBadRequest)
+		http.Error(w, "Invalid mood id", http.StatusBadRequest)
 		return
 	}
-	vars := mux.Vars(r)
-	id := vars["id"]
-	var mood models.Mood
-	err = database.DB.QueryRow("SELECT id, student_id, recorded_at, emotion, is_daily FROM mood WHERE id = $1 AND student_id = $2", id, studentID).Scan(&mood.ID, &mood.StudentID, &mood.RecordedAt, &mood.Emotion, &mood.IsDaily)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+	mood, err := h.store.Moods.Get(id, int(student.ID))
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Mood not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(mood)
 }
 
-func CreateMood(w http.ResponseWriter, r *http.Request) {
+// encodeMoodCursor and decodeMoodCursor turn the position after a mood into
+// an opaque token for the next page
+func encodeMoodCursor(m models.Mood) string {
+	raw := m.RecordedAt.UTC().Format(time.RFC3339Nano) + "|" + strconv.Itoa(m.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeMoodCursor(cursor string) (time.Time, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	at, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, 0, errors.New("malformed cursor")
+	}
+	recordedAt, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	moodID, err := strconv.Atoi(id)
+	if err != nil || moodID <= 0 {
+		return time.Time{}, 0, errors.New("malformed cursor")
+	}
+	return recordedAt, moodID, nil
+}
+
+// GetMoodHistory pages through a student's moods. from and to are inclusive
+// YYYY-MM-DD dates in the employer's timezone, is_daily filters daily
+// check-ins in or out, sort is desc (default) or asc, and limit defaults to
+// 50. Pass next_cursor from a response as cursor to get the following page
+// with the same filters.
+func (h *Handler) GetMoodHistory(w http.ResponseWriter, r *http.Request) {
+	student, ok := h.viewableStudent(w, r)
+	if !ok {
+		return
+	}
+	loc, err := h.studentLocation(int(student.ID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	params := r.URL.Query()
+	q := store.MoodQuery{StudentID: int(student.ID), Limit: 50}
+	if v := params.Get("from"); v != "" {
+		if q.From, err = time.ParseInLocation(dateLayout, v, loc); err != nil {
+			http.Error(w, "Invalid from date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := params.Get("to"); v != "" {
+		to, err := time.ParseInLocation(dateLayout, v, loc)
+		if err != nil {
+			http.Error(w, "Invalid to date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		q.To = to.AddDate(0, 0, 1)
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.To.After(q.From) {
+		http.Error(w, "to must not be before from", http.StatusBadRequest)
+		return
+	}
+	if v := params.Get("is_daily"); v != "" {
+		daily, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "is_daily must be true or false", http.StatusBadRequest)
+			return
+		}
+		q.Daily = &daily
+	}
+	switch params.Get("sort") {
+	case "", "desc":
+	case "asc":
+		q.Ascending = true
+	default:
+		http.Error(w, "sort must be asc or desc", http.StatusBadRequest)
+		return
+	}
+	if v := params.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			http.Error(w, "limit must be between 1 and 200", http.StatusBadRequest)
+			return
+		}
+		q.Limit = n
+	}
+	if v := params.Get("cursor"); v != "" {
+		if q.AfterTime, q.AfterID, err = decodeMoodCursor(v); err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// One extra mood tells whether there is another page
+	limit := q.Limit
+	q.Limit++
+	moods, err := h.store.Moods.Page(q)
+	if err != nil {
+		log.Printf("Error fetching moods of student %d: %v", student.ID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	page := models.MoodPage{Moods: moods}
+	if len(moods) > limit {
+		page.Moods = moods[:limit]
+		page.NextCursor = encodeMoodCursor(page.Moods[limit-1])
+	}
+	if page.Moods == nil {
+		page.Moods = []models.Mood{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// CreateMood records a mood. A daily mood counts for the local day it was
+// recorded on: the first one that day is stored, a later one replaces it
+// within dailyMoodEditWindow and is refused with 409 afterwards. Ad-hoc
+// moods are always added.
+func (h *Handler) CreateMood(w http.ResponseWriter, r *http.Request) {
 	StudentIDHeader := r.Header.Get("student-id")
 	if StudentIDHeader == "" {
 		log.Println("Missing student-id header")
@@ -80,25 +213,73 @@ func CreateMood(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	recordedAt, err := time.Parse(time.RFC3339, payload.Timestamp)
+	deviceTime, err := time.Parse(time.RFC3339, payload.Timestamp)
 	if err != nil {
 		log.Printf("Invalid timestamp format: %v", err)
 		http.Error(w, "Invalid timestamp format", http.StatusBadRequest)
 		return
 	}
+	// Moods are dated by when the server receives them, so a device cannot
+	// file a daily mood under another day; its clock is only logged
+	recordedAt := h.now()
+	if drift := deviceTime.Sub(recordedAt); drift > time.Minute || drift < -time.Minute {
+		log.Printf("Device clock of student %d is %v off", studentID, drift.Round(time.Second))
+	}
+	emotion := strings.ToLower(strings.TrimSpace(payload.Emotion))
+	valence, ok := h.moodValence(emotion)
+	if !ok {
+		http.Error(w, "Unknown emotion, see /mood-vocabulary", http.StatusBadRequest)
+		return
+	}
 	mood := models.Mood{
 		StudentID:  studentID,
-		Emotion:    payload.Emotion,
+		Emotion:    emotion,
 		IsDaily:    payload.IsDaily,
 		RecordedAt: recordedAt,
+		Valence:    &valence,
 	}
-	query := "INSERT INTO mood (student_id, emotion, is_daily, recorded_at) VALUES ($1, $2, $3, $4) RETURNING id"
-	err = database.DB.QueryRow(query, mood.StudentID, mood.Emotion, mood.IsDaily, mood.RecordedAt).Scan(&mood.ID)
-	if err != nil {
+
+	student, err := h.store.Students.Get(studentID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Student not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// A repeated negative daily mood must not report the same streak twice
+	wasNegative := false
+	if mood.IsDaily {
+		loc, err := h.studentLocation(studentID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		mood.LocalDate = recordedAt.In(loc).Format(dateLayout)
+		if existing, err := h.store.Moods.DailyOn(studentID, mood.LocalDate); err == nil {
+			wasNegative = isNegativeMood(*existing)
+		}
+
+		_, err = h.store.Moods.PutDaily(&mood, recordedAt.Add(-h.dailyMoodEditWindow))
+		if errors.Is(err, store.ErrConflict) {
+			http.Error(w, "The daily mood for "+mood.LocalDate+" was already recorded and can no longer be changed", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			log.Printf("Error saving daily mood: %v", err)
+			return
+		}
+	} else if err := h.store.Moods.Create(&mood); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		log.Printf("Error creating mood: %v", err)
 		return
 	}
+
+	if !wasNegative {
+		h.checkNegativeMoodStreak(student, mood)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(mood)
 }
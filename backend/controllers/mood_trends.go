@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"server/auth"
+	"server/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMoodVocabulary matches the faces offered by the mobile app
+const defaultMoodVocabulary = "happy:1,neutral:0,sad:-1"
+
+// parseMoodVocabulary reads comma-separated emotion:valence pairs, keeping
+// their order for the app
+func parseMoodVocabulary(spec string) ([]models.MoodOption, error) {
+	var options []models.MoodOption
+	seen := map[string]bool{}
+	for _, entry := range strings.Split(spec, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		emotion, score, ok := strings.Cut(entry, ":")
+		emotion = strings.ToLower(strings.TrimSpace(emotion))
+		valence, err := strconv.Atoi(strings.TrimSpace(score))
+		if !ok || emotion == "" || err != nil {
+			return nil, fmt.Errorf("invalid mood %q, expected emotion:valence", entry)
+		}
+		if seen[emotion] {
+			return nil, fmt.Errorf("mood %q is listed twice", emotion)
+		}
+		seen[emotion] = true
+		options = append(options, models.MoodOption{Emotion: emotion, Valence: valence})
+	}
+	if len(options) == 0 {
+		return nil, errors.New("no moods configured")
+	}
+	return options, nil
+}
+
+// moodValence returns the valence of an emotion in the vocabulary
+func (h *Handler) moodValence(emotion string) (int, bool) {
+	for _, o := range h.moodVocabulary {
+		if o.Emotion == emotion {
+			return o.Valence, true
+		}
+	}
+	return 0, false
+}
+
+// isNegativeMood reports whether a mood counts towards a negative streak.
+// Moods recorded outside the vocabulary never do.
+func isNegativeMood(m models.Mood) bool {
+	return m.Valence != nil && *m.Valence < 0
+}
+
+// negativeStreak counts the negative moods at the start of moods, which are
+// newest first, and returns when the run began
+func negativeStreak(moods []models.Mood) (int, time.Time) {
+	var since time.Time
+	n := 0
+	for _, m := range moods {
+		if !isNegativeMood(m) {
+			break
+		}
+		n++
+		since = m.RecordedAt
+	}
+	return n, since
+}
+
+// recentDailyMoods returns the student's latest daily moods, newest first,
+// enough of them that long negative streaks report their real length
+func (h *Handler) recentDailyMoods(studentID int) ([]models.Mood, error) {
+	return h.store.Moods.Recent(studentID, true, max(4*h.negativeMoodStreak, 10))
+}
+
+// bucketStart returns the local date a mood falls in: its day, or the
+// Monday of its week
+func bucketStart(t time.Time, loc *time.Location, weekly bool) time.Time {
+	day, _ := getStartAndEndOfDay(t, loc)
+	if weekly {
+		offset := (int(day.Weekday()) + 6) % 7
+		day = day.AddDate(0, 0, -offset)
+	}
+	return day
+}
+
+// aggregateMoods buckets moods, oldest first, by local day or week. Only
+// buckets with at least one mood are returned.
+func aggregateMoods(moods []models.Mood, loc *time.Location, weekly bool) []models.MoodTrendBucket {
+	buckets := []models.MoodTrendBucket{}
+	var sums []int
+	var scored []int
+	for _, m := range moods {
+		start := bucketStart(m.RecordedAt, loc, weekly).Format(dateLayout)
+		if len(buckets) == 0 || buckets[len(buckets)-1].Start != start {
+			buckets = append(buckets, models.MoodTrendBucket{Start: start, Emotions: map[string]int{}})
+			sums, scored = append(sums, 0), append(scored, 0)
+		}
+		n := len(buckets) - 1
+		b := &buckets[n]
+		b.Count++
+		b.Emotions[m.Emotion]++
+		if m.IsDaily {
+			b.DailyCount++
+		}
+		if m.Valence != nil {
+			sums[n] += *m.Valence
+			scored[n]++
+		}
+		if isNegativeMood(m) {
+			b.NegativeCount++
+		}
+	}
+	for n := range buckets {
+		if scored[n] > 0 {
+			avg := math.Round(float64(sums[n])/float64(scored[n])*100) / 100
+			buckets[n].AverageValence = &avg
+		}
+	}
+	return buckets
+}
+
+// GetMoodVocabulary lists the emotions trainees can report, in display order
+func (h *Handler) GetMoodVocabulary(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.moodVocabulary)
+}
+
+// GetMoodTrends aggregates a student's moods by day or, with period=week, by
+// week starting Monday in the employer's timezone. from and to are inclusive
+// YYYY-MM-DD dates and default to the last 30 days, or 12 weeks.
+func (h *Handler) GetMoodTrends(w http.ResponseWriter, r *http.Request) {
+	student, ok := h.viewableStudent(w, r)
+	if !ok {
+		return
+	}
+	studentID := int(student.ID)
+
+	period := r.URL.Query().Get("period")
+	defaultDays := 30
+	switch period {
+	case "", "day":
+		period = "day"
+	case "week":
+		defaultDays = 12 * 7
+	default:
+		http.Error(w, "period must be day or week", http.StatusBadRequest)
+		return
+	}
+
+	loc, err := h.studentLocation(studentID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	from, to, err := h.parseDateRange(r, loc, defaultDays)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	moods, err := h.store.Moods.Between(studentID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		log.Printf("Error fetching moods of student %d: %v", studentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// The streak is about the latest daily moods, whatever the range
+	recent, err := h.recentDailyMoods(studentID)
+	if err != nil {
+		log.Printf("Error fetching moods of student %d: %v", studentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	streak, _ := negativeStreak(recent)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.MoodTrends{
+		StudentID:      studentID,
+		Period:         period,
+		From:           from.Format(dateLayout),
+		To:             to.Format(dateLayout),
+		Buckets:        aggregateMoods(moods, loc, period == "week"),
+		NegativeStreak: streak,
+		StreakAlert:    h.negativeMoodStreak > 0 && streak >= h.negativeMoodStreak,
+	})
+}
+
+// GetMoodStreaks lists students whose latest NOTIFY_NEGATIVE_MOOD_STREAK
+// daily moods were all negative, longest streak first. Supervisors only see
+// their own trainees.
+func (h *Handler) GetMoodStreaks(w http.ResponseWriter, r *http.Request) {
+	students, err := h.store.Students.List()
+	if err != nil {
+		log.Printf("Error listing students: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	principal := auth.PrincipalFrom(r.Context())
+	streaks := []models.MoodStreak{}
+	for _, s := range students {
+		if !supervises(principal, &s) {
+			continue
+		}
+		recent, err := h.recentDailyMoods(int(s.ID))
+		if err != nil {
+			log.Printf("Error fetching moods of student %d: %v", s.ID, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		n, since := negativeStreak(recent)
+		if n == 0 || n < h.negativeMoodStreak {
+			continue
+		}
+		streaks = append(streaks, models.MoodStreak{
+			StudentID:  int(s.ID),
+			FirstName:  s.FirstName,
+			LastName:   s.LastName,
+			Streak:     n,
+			Since:      since,
+			LastMood:   recent[0].Emotion,
+			LastMoodAt: recent[0].RecordedAt,
+		})
+	}
+	sort.SliceStable(streaks, func(i, j int) bool { return streaks[i].Streak > streaks[j].Streak })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(streaks)
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"server/models"
+	"server/notify"
+	"server/store"
+	"strconv"
+	"strings"
+)
+
+// notificationTimeLayout is how times appear in notification messages
+const notificationTimeLayout = "Mon 2 Jan 15:04"
+
+// notifyAbout queues e for the student's supervisor by email and, when
+// guardian is set, for the guardian by SMS. Failures are only logged so a
+// notification can never fail the request that raised it.
+func notifyAbout(notifier *notify.Service, supervisors store.SupervisorStore, e notify.Event, s *models.Student, guardian bool) {
+	e.StudentID = int(s.ID)
+	e.Student = strings.TrimSpace(s.FirstName + " " + s.LastName)
+
+	var recipients []notify.Recipient
+	if s.SupervisorID != nil {
+		if sup, err := supervisors.Get(int(*s.SupervisorID)); err == nil {
+			recipients = append(recipients, notify.Recipient{Channel: notify.ChannelEmail, Address: sup.EmailAddress})
+		} else {
+			log.Printf("Error loading supervisor of student %d: %v", s.ID, err)
+		}
+	}
+	if guardian {
+		recipients = append(recipients, notify.Recipient{Channel: notify.ChannelSMS, Address: s.ContactNumberGuardian})
+	}
+	if err := notifier.Notify(e, recipients...); err != nil {
+		log.Printf("Error queueing %s notification for student %d: %v", e.Kind, s.ID, err)
+	}
+}
+
+// notify queues e about the student, see notifyAbout
+func (h *Handler) notify(e notify.Event, s *models.Student, guardian bool) {
+	notifyAbout(h.notifier, h.store.Supervisors, e, s, guardian)
+}
+
+// employerName returns the employer's name, empty when there is none
+func employerName(e *models.Employer) string {
+	if e == nil {
+		return ""
+	}
+	return e.Name
+}
+
+// GetNotifications lists recorded notifications, newest first. status
+// narrows them to queued, sent or failed and limit defaults to 100.
+func (h *Handler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.NotificationQueued, models.NotificationSent, models.NotificationFailed:
+	default:
+		http.Error(w, "status must be queued, sent or failed", http.StatusBadRequest)
+		return
+	}
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 1000 {
+			http.Error(w, "limit must be between 1 and 1000", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	list, err := h.store.Notifications.List(status, limit)
+	if err != nil {
+		log.Printf("Error listing notifications: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []models.Notification{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// checkNegativeMoodStreak notifies the supervisor when the student's daily
+// moods have just become negative negativeMoodStreak times in a row
+func (h *Handler) checkNegativeMoodStreak(s *models.Student, latest models.Mood) {
+	if !latest.IsDaily || !isNegativeMood(latest) || h.negativeMoodStreak < 1 {
+		return
+	}
+	// One more than the streak, so a streak that keeps going is only reported once
+	recent, err := h.store.Moods.Recent(int(s.ID), true, h.negativeMoodStreak+1)
+	if err != nil {
+		log.Printf("Error fetching moods of student %d: %v", s.ID, err)
+		return
+	}
+	if streak, _ := negativeStreak(recent); streak != h.negativeMoodStreak {
+		return
+	}
+	h.notify(notify.Event{Kind: notify.NegativeMood, Streak: h.negativeMoodStreak, Emotion: latest.Emotion}, s, false)
+}
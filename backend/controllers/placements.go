@@ -0,0 +1,345 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"server/models"
+	"server/store"
+	"strings"
+	"time"
+)
+
+// placedPeriod is one of a student's placements with the timezone of its
+// employer and its weekly template by weekday
+type placedPeriod struct {
+	models.Placement
+	loc    *time.Location
+	weekly map[time.Weekday]models.ScheduleDay
+}
+
+// placementHistory is a student's placements, latest start first. Past
+// sessions and days are resolved against the placement they fell in rather
+// than the student's current employer.
+type placementHistory []placedPeriod
+
+// loadPlacementHistory loads the student's placements and their employers' zones
+func (h *Handler) loadPlacementHistory(studentID int) (placementHistory, error) {
+	placements, err := h.store.Placements.List(studentID)
+	if err != nil {
+		return nil, err
+	}
+	return h.placementHistory(placements, map[int]*models.Employer{})
+}
+
+// placementHistory resolves placements to their employers' zones, loading
+// employers missing from employers into it. A nil entry is an employer
+// known not to exist.
+func (h *Handler) placementHistory(placements []models.Placement, employers map[int]*models.Employer) (placementHistory, error) {
+	history := make(placementHistory, 0, len(placements))
+	for _, p := range placements {
+		period := placedPeriod{Placement: p, loc: h.defaultLocation, weekly: make(map[time.Weekday]models.ScheduleDay, len(p.Schedule))}
+		for _, d := range p.Schedule {
+			period.weekly[d.Weekday] = d
+		}
+		if p.EmployerID != nil {
+			e, ok := employers[*p.EmployerID]
+			if !ok {
+				var err error
+				e, err = h.store.Employers.Get(*p.EmployerID)
+				if errors.Is(err, store.ErrNotFound) {
+					e = nil
+				} else if err != nil {
+					return nil, err
+				}
+				employers[*p.EmployerID] = e
+			}
+			period.loc = h.locationOf(e)
+		}
+		history = append(history, period)
+	}
+	return history, nil
+}
+
+// on returns the placement covering the local date (YYYY-MM-DD). On the day
+// one placement ends and the next starts, the later one wins.
+func (ph placementHistory) on(date string) *placedPeriod {
+	for i := range ph {
+		p := &ph[i]
+		if p.StartDate <= date && (p.EndDate == nil || *p.EndDate >= date) {
+			return p
+		}
+	}
+	return nil
+}
+
+// byID returns the placement with id, nil when id is nil or not the student's
+func (ph placementHistory) byID(id *int) *placedPeriod {
+	if id == nil {
+		return nil
+	}
+	for i := range ph {
+		if ph[i].ID == *id {
+			return &ph[i]
+		}
+	}
+	return nil
+}
+
+// employedBy reports whether the student was ever placed with the employer
+func (ph placementHistory) employedBy(employerID int) bool {
+	for _, p := range ph {
+		if p.EmployerID != nil && *p.EmployerID == employerID {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionAt reports whether a was recorded during a placement with the employer
+func (ph placementHistory) sessionAt(a models.Attendance, employerID int) bool {
+	p := ph.byID(a.PlacementID)
+	return p != nil && p.EmployerID != nil && *p.EmployerID == employerID
+}
+
+// withEmployerOn reports whether the student was placed with the employer on
+// the local date (YYYY-MM-DD)
+func (ph placementHistory) withEmployerOn(date string, employerID int) bool {
+	p := ph.on(date)
+	return p != nil && p.EmployerID != nil && *p.EmployerID == employerID
+}
+
+// zoneOf returns the zone each session is counted in: that of its
+// placement's employer, or fallback for sessions without a placement
+func (ph placementHistory) zoneOf(fallback *time.Location) func(models.Attendance) *time.Location {
+	return func(a models.Attendance) *time.Location {
+		if p := ph.byID(a.PlacementID); p != nil {
+			return p.loc
+		}
+		return fallback
+	}
+}
+
+// placementDate reads an optional YYYY-MM-DD date, defaulting to today in
+// loc. Placements move the student's employer straight away, so dates in
+// the future are refused.
+func placementDate(v, name string, loc *time.Location) (string, error) {
+	today := time.Now().In(loc).Format(dateLayout)
+	if v == "" {
+		return today, nil
+	}
+	if _, err := time.Parse(dateLayout, v); err != nil {
+		return "", errors.New("Invalid " + name + ", expected YYYY-MM-DD")
+	}
+	if v > today {
+		return "", errors.New(name + " cannot be in the future")
+	}
+	return v, nil
+}
+
+// currentPlacementID returns the ID of the student's open placement, for
+// tagging the attendance recorded under it. Attendance is still recorded
+// when the lookup fails.
+func (h *Handler) currentPlacementID(studentID int) *int {
+	p, err := h.store.Placements.Current(studentID)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			log.Printf("Error loading placement of student %d: %v", studentID, err)
+		}
+		return nil
+	}
+	return &p.ID
+}
+
+// placeStudent stores p as the student's open placement. The one it replaces
+// ends the day before, or on the same day if it only started then.
+func (h *Handler) placeStudent(p *models.Placement) error {
+	previousEnd := p.StartDate
+	current, err := h.store.Placements.Current(p.StudentID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+	if current == nil || current.StartDate != p.StartDate {
+		start, err := time.Parse(dateLayout, p.StartDate)
+		if err != nil {
+			return err
+		}
+		previousEnd = start.AddDate(0, 0, -1).Format(dateLayout)
+	}
+	return h.store.Placements.Start(p, previousEnd)
+}
+
+// followEmployer records an employer change made by editing the student as
+// a placement starting or ending today, so the history stays complete
+func (h *Handler) followEmployer(studentID int, before, after *uint) {
+	if (before == nil && after == nil) || (before != nil && after != nil && *before == *after) {
+		return
+	}
+	loc, err := h.studentLocation(studentID)
+	if err != nil {
+		log.Printf("Error recording placement change for student %d: %v", studentID, err)
+		return
+	}
+	today := time.Now().In(loc).Format(dateLayout)
+	if after == nil {
+		_, err = h.store.Placements.End(studentID, today, "")
+		if errors.Is(err, store.ErrNotFound) {
+			err = nil
+		}
+	} else {
+		var days []models.ScheduleDay
+		days, err = h.store.Schedules.Weekly(studentID)
+		if err == nil {
+			employerID := int(*after)
+			err = h.placeStudent(&models.Placement{StudentID: studentID, EmployerID: &employerID, StartDate: today, Schedule: days})
+		}
+	}
+	if err != nil {
+		log.Printf("Error recording placement change for student %d: %v", studentID, err)
+	}
+}
+
+// StartPlacement places the student in the student-id header with an
+// employer, ending their current placement the day before. A schedule in
+// the request replaces the student's weekly template.
+func (h *Handler) StartPlacement(w http.ResponseWriter, r *http.Request) {
+	studentID, err := getStudentIDFromHeader(r)
+	if err != nil {
+		http.Error(w, "Invalid or missing student-id header", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.store.Students.Get(studentID); errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Student not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error loading student %d: %v", studentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var req models.PlacementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	employer, err := h.store.Employers.Get(req.EmployerID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Employer not found", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Printf("Error loading employer %d: %v", req.EmployerID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	start, err := placementDate(req.StartDate, "start_date", h.locationOf(employer))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	schedule := req.Schedule
+	if schedule != nil {
+		if err := validateWeekly(schedule); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else if schedule, err = h.store.Schedules.Weekly(studentID); err != nil {
+		log.Printf("Error fetching schedule for student %d: %v", studentID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	placement := models.Placement{
+		StudentID:  studentID,
+		EmployerID: &req.EmployerID,
+		Role:       strings.TrimSpace(req.Role),
+		StartDate:  start,
+		Schedule:   schedule,
+	}
+	err = h.placeStudent(&placement)
+	if errors.Is(err, store.ErrConflict) {
+		http.Error(w, "The current placement started after start_date", http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("Error starting placement for student %d: %v", studentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if req.Schedule != nil {
+		if err := h.store.Schedules.ReplaceWeekly(studentID, req.Schedule); err != nil {
+			log.Printf("Error saving schedule for student %d: %v", studentID, err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(placement)
+}
+
+// EndPlacement ends the open placement of the student in the student-id
+// header, leaving them without an employer
+func (h *Handler) EndPlacement(w http.ResponseWriter, r *http.Request) {
+	studentID, err := getStudentIDFromHeader(r)
+	if err != nil {
+		http.Error(w, "Invalid or missing student-id header", http.StatusBadRequest)
+		return
+	}
+	var req models.EndPlacementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	_, loc, err := h.studentWithLocation(studentID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Student not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error loading student %d: %v", studentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	end, err := placementDate(req.EndDate, "end_date", loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	placement, err := h.store.Placements.End(studentID, end, strings.TrimSpace(req.Reason))
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "The student has no open placement", http.StatusNotFound)
+		return
+	} else if errors.Is(err, store.ErrConflict) {
+		http.Error(w, "end_date is before the placement started", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Printf("Error ending placement for student %d: %v", studentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(placement)
+}
+
+// GetPlacements lists the student's placements, latest first
+func (h *Handler) GetPlacements(w http.ResponseWriter, r *http.Request) {
+	student, ok := h.viewableStudent(w, r)
+	if !ok {
+		return
+	}
+	placements, err := h.store.Placements.List(int(student.ID))
+	if err != nil {
+		log.Printf("Error fetching placements of student %d: %v", student.ID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if placements == nil {
+		placements = []models.Placement{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(placements)
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"log"
+	"server/models"
+	"time"
+)
+
+// gracePeriods returns how late a check-in and how early a check-out may be
+// while still counting as on time
+func (h *Handler) gracePeriods(e *models.Employer) (late, earlyLeave time.Duration) {
+	late, earlyLeave = h.lateGrace, h.earlyLeaveGrace
+	if e == nil {
+		return late, earlyLeave
+	}
+	if e.LateGraceMinutes != nil {
+		late = time.Duration(*e.LateGraceMinutes) * time.Minute
+	}
+	if e.EarlyLeaveGraceMinutes != nil {
+		earlyLeave = time.Duration(*e.EarlyLeaveGraceMinutes) * time.Minute
+	}
+	return late, earlyLeave
+}
+
+// minutesAfter returns how many whole minutes t is after ref, never negative
+func minutesAfter(t, ref time.Time) int {
+	if !t.After(ref) {
+		return 0
+	}
+	return int(t.Sub(ref).Minutes())
+}
+
+// evaluateCheckIn compares the first check-in of a shift with its start.
+// Sessions after a break, and check-ins on unscheduled days, are left
+// unevaluated.
+func evaluateCheckIn(a *models.Attendance, shift models.ExpectedShift, firstSession bool, grace time.Duration) {
+	if !shift.Working || shift.StartsAt == nil || !firstSession {
+		return
+	}
+	late := minutesAfter(a.CheckInDateTime, *shift.StartsAt)
+	a.MinutesLate = &late
+	a.Punctuality = models.PunctualityOnTime
+	if a.CheckInDateTime.After(shift.StartsAt.Add(grace)) {
+		a.Punctuality = models.PunctualityLate
+	}
+}
+
+// evaluateCheckOut compares a check-out with the end of the shift the
+// session started in. A late check-in keeps its status. Until the trainee
+// checks in again the session is taken to be the day's last; see
+// resumeShift for breaks.
+func evaluateCheckOut(a *models.Attendance, shift models.ExpectedShift, grace time.Duration) {
+	if !shift.Working || shift.EndsAt == nil {
+		return
+	}
+	checkOut := a.CheckOutDateTime.Time
+	early := minutesAfter(*shift.EndsAt, checkOut)
+	a.MinutesEarly = &early
+	if checkOut.Before(shift.EndsAt.Add(-grace)) {
+		if a.Punctuality != models.PunctualityLate {
+			a.Punctuality = models.PunctualityLeftEarly
+		}
+	} else if a.Punctuality == "" {
+		a.Punctuality = models.PunctualityOnTime
+	}
+}
+
+// shiftForSession resolves the expected shift on the local day a session
+// started, and whether an earlier session already started that day
+func (h *Handler) shiftForSession(student *models.Student, employer *models.Employer, start time.Time) (models.ExpectedShift, bool, error) {
+	loc := h.locationOf(employer)
+	plan, err := h.loadShiftPlan(student, loc, start, start)
+	if err != nil {
+		return models.ExpectedShift{}, false, err
+	}
+	dayStart, _ := getStartAndEndOfDay(start, loc)
+	earlier, err := h.store.Attendance.Between(int(student.ID), dayStart, start)
+	if err != nil {
+		return models.ExpectedShift{}, false, err
+	}
+	first := true
+	for _, a := range earlier {
+		if !a.OrphanCheckOut && !a.Absent {
+			first = false
+		}
+	}
+	return plan.shiftOn(start), first, nil
+}
+
+// evaluateSessionCheckOut evaluates a check-out against the shift of the day
+// the session started, logging rather than failing when the schedule is unavailable
+func (h *Handler) evaluateSessionCheckOut(student *models.Student, employer *models.Employer, a *models.Attendance, grace time.Duration) {
+	shift, _, err := h.shiftForSession(student, employer, a.Start())
+	if err != nil {
+		log.Printf("Skipping punctuality for student %d: %v", a.StudentID, err)
+		return
+	}
+	evaluateCheckOut(a, shift, grace)
+}
+
+// resumeShift withdraws the early-leave evaluation of the sessions closed
+// earlier on the day of a check-in after a break: only the day's last
+// check-out says whether the trainee left early
+func (h *Handler) resumeShift(studentID int, dayStart, checkIn time.Time) {
+	earlier, err := h.store.Attendance.Between(studentID, dayStart, checkIn)
+	if err != nil {
+		log.Printf("Skipping early-leave review for student %d: %v", studentID, err)
+		return
+	}
+	for _, a := range earlier {
+		if a.MinutesEarly == nil || a.Absent {
+			continue
+		}
+		// Keep what the check-in alone said, if it was judged
+		a.MinutesEarly = nil
+		if a.MinutesLate == nil {
+			a.Punctuality = ""
+		} else if a.Punctuality == models.PunctualityLeftEarly {
+			a.Punctuality = models.PunctualityOnTime
+		}
+		if err := h.store.Attendance.UpdatePunctuality(&a); err != nil {
+			log.Printf("Failed to update punctuality of attendance %d: %v", a.ID, err)
+		}
+	}
+}
@@ -0,0 +1,485 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"server/auth"
+	"server/models"
+	"server/store"
+	"strings"
+	"time"
+)
+
+// clockLayouts are accepted for the legacy check_in_time/check_out_time strings
+var clockLayouts = []string{"15:04", "15:04:05", "3:04 PM", "3:04PM", "3:04 pm", "3:04pm"}
+
+// parseClock reads an HH:MM time of day as minutes after midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parseLegacyClock is parseClock for the free-form strings stored on students
+func parseLegacyClock(s string) (int, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range clockLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Hour()*60 + t.Minute(), true
+		}
+	}
+	return 0, false
+}
+
+// validateShift checks that start and end are HH:MM and not the same time
+func validateShift(start, end string) error {
+	s, err := parseClock(start)
+	if err != nil {
+		return err
+	}
+	e, err := parseClock(end)
+	if err != nil {
+		return err
+	}
+	if s == e {
+		return errors.New("start and end must differ")
+	}
+	return nil
+}
+
+// shiftPlan resolves a student's expected shifts from their weekly template
+// and the overrides loaded for a range of dates. Days in a placement that
+// has ended use that placement's schedule and employer timezone.
+type shiftPlan struct {
+	student   *models.Student
+	loc       *time.Location
+	weekly    map[time.Weekday]models.ScheduleDay
+	overrides map[string]models.ScheduleOverride
+	history   placementHistory
+}
+
+// loadShiftPlan loads what is needed to resolve shifts on the local dates
+// from..to inclusive
+func (h *Handler) loadShiftPlan(student *models.Student, loc *time.Location, from, to time.Time) (*shiftPlan, error) {
+	days, err := h.store.Schedules.Weekly(int(student.ID))
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := h.store.Schedules.Overrides(int(student.ID), from.In(loc).Format(dateLayout), to.In(loc).Format(dateLayout))
+	if err != nil {
+		return nil, err
+	}
+	history, err := h.loadPlacementHistory(int(student.ID))
+	if err != nil {
+		return nil, err
+	}
+	return newShiftPlan(student, loc, days, overrides, history), nil
+}
+
+// loadShiftPlans loads the plans of every student in dir for the local
+// dates from..to inclusive, keyed by student ID, with one query per table
+// rather than per student
+func (h *Handler) loadShiftPlans(dir *directory, from, to time.Time) (map[int]*shiftPlan, error) {
+	plans := make(map[int]*shiftPlan, len(dir.students))
+	if len(dir.students) == 0 {
+		return plans, nil
+	}
+	locs := make(map[int]*time.Location, len(dir.students))
+	first, last := "", ""
+	for _, s := range dir.students {
+		loc := h.locationOf(dir.employerOf(s))
+		locs[int(s.ID)] = loc
+		if d := from.In(loc).Format(dateLayout); first == "" || d < first {
+			first = d
+		}
+		if d := to.In(loc).Format(dateLayout); d > last {
+			last = d
+		}
+	}
+
+	weekly, err := h.store.Schedules.WeeklyByStudent()
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := h.store.Schedules.OverridesByStudent(first, last)
+	if err != nil {
+		return nil, err
+	}
+	placements, err := h.store.Placements.ListByStudent()
+	if err != nil {
+		return nil, err
+	}
+	employers := make(map[int]*models.Employer, len(dir.employers))
+	for id, e := range dir.employers {
+		employers[int(id)] = &e
+	}
+
+	for i := range dir.students {
+		s := &dir.students[i]
+		id := int(s.ID)
+		history, err := h.placementHistory(placements[id], employers)
+		if err != nil {
+			return nil, err
+		}
+		plans[id] = newShiftPlan(s, locs[id], weekly[id], overrides[id], history)
+	}
+	return plans, nil
+}
+
+func newShiftPlan(student *models.Student, loc *time.Location, days []models.ScheduleDay, overrides []models.ScheduleOverride, history placementHistory) *shiftPlan {
+	p := &shiftPlan{
+		student:   student,
+		loc:       loc,
+		weekly:    make(map[time.Weekday]models.ScheduleDay, len(days)),
+		overrides: make(map[string]models.ScheduleOverride, len(overrides)),
+		history:   history,
+	}
+	for _, d := range days {
+		p.weekly[d.Weekday] = d
+	}
+	for _, o := range overrides {
+		p.overrides[o.Date] = o
+	}
+	return p
+}
+
+// pastPlacement returns the ended placement day fell in, with day's local
+// date in that placement's zone, or nil and the date in the plan's zone
+func (p *shiftPlan) pastPlacement(day time.Time) (*placedPeriod, string) {
+	date := day.In(p.loc).Format(dateLayout)
+	past := p.history.on(date)
+	if past == nil || past.EndDate == nil {
+		return nil, date
+	}
+	if past.loc.String() != p.loc.String() {
+		// Near midnight the date can differ in the placement's own zone
+		date = day.In(past.loc).Format(dateLayout)
+		if past = p.history.on(date); past == nil || past.EndDate == nil {
+			return nil, day.In(p.loc).Format(dateLayout)
+		}
+	}
+	return past, date
+}
+
+// shiftOn returns the expected shift on the local date of day. An override
+// wins over the weekly template; students without a template fall back to
+// their check_in_time/check_out_time every day.
+func (p *shiftPlan) shiftOn(day time.Time) models.ExpectedShift {
+	loc, weekly := p.loc, p.weekly
+	if past, _ := p.pastPlacement(day); past != nil {
+		loc, weekly = past.loc, past.weekly
+	}
+	day, _ = getStartAndEndOfDay(day, loc)
+	shift := models.ExpectedShift{StudentID: int(p.student.ID), Date: day.Format(dateLayout), Source: models.ShiftSourceNone}
+
+	if o, ok := p.overrides[shift.Date]; ok {
+		shift.Source = models.ShiftSourceOverride
+		shift.Reason = o.Reason
+		if !o.DayOff {
+			setHours(&shift, day, o.Start, o.End, parseClock)
+		}
+		return shift
+	}
+	if len(weekly) > 0 {
+		shift.Source = models.ShiftSourceWeekly
+		if d, ok := weekly[day.Weekday()]; ok {
+			setHours(&shift, day, d.Start, d.End, parseClock)
+		}
+		return shift
+	}
+	if p.student.CheckInTime != "" || p.student.CheckOutTime != "" {
+		shift.Source = models.ShiftSourceLegacy
+		setHours(&shift, day, p.student.CheckInTime, p.student.CheckOutTime, func(s string) (int, error) {
+			if m, ok := parseLegacyClock(s); ok {
+				return m, nil
+			}
+			return 0, fmt.Errorf("unrecognised time %q", s)
+		})
+	}
+	return shift
+}
+
+// shiftOnDate returns the expected shift on a local date (YYYY-MM-DD)
+func (p *shiftPlan) shiftOnDate(date string) models.ExpectedShift {
+	loc := p.loc
+	if past := p.history.on(date); past != nil && past.EndDate != nil {
+		loc = past.loc
+	}
+	day, _ := time.ParseInLocation(dateLayout, date, loc)
+	// Midday keeps the date whichever of the zones shiftOn settles on
+	return p.shiftOn(day.Add(12 * time.Hour))
+}
+
+// setHours marks the shift as working from start to end on day, a local
+// midnight. Times that cannot be parsed are kept as text without timestamps.
+func setHours(shift *models.ExpectedShift, day time.Time, start, end string, parse func(string) (int, error)) {
+	shift.Working = true
+	shift.Start, shift.End = start, end
+
+	s, err := parse(start)
+	if err != nil {
+		return
+	}
+	e, err := parse(end)
+	if err != nil {
+		return
+	}
+	loc := day.Location()
+	startsAt := time.Date(day.Year(), day.Month(), day.Day(), 0, s, 0, 0, loc)
+	endsAt := time.Date(day.Year(), day.Month(), day.Day(), 0, e, 0, 0, loc)
+	if !endsAt.After(startsAt) {
+		// Overnight shift
+		endsAt = time.Date(day.Year(), day.Month(), day.Day()+1, 0, e, 0, 0, loc)
+	}
+	shift.StartsAt, shift.EndsAt = &startsAt, &endsAt
+}
+
+// studentWithLocation loads the student and the timezone of their employer
+func (h *Handler) studentWithLocation(studentID int) (*models.Student, *time.Location, error) {
+	student, err := h.store.Students.Get(studentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if student.EmployerID == nil {
+		return student, h.defaultLocation, nil
+	}
+	employer, err := h.store.Employers.Get(int(*student.EmployerID))
+	if errors.Is(err, store.ErrNotFound) {
+		return student, h.defaultLocation, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+	return student, h.locationOf(employer), nil
+}
+
+// scheduleStudent reads the student-id header and loads the student if the
+// caller may see their records, writing the error response itself when that
+// fails
+func (h *Handler) scheduleStudent(w http.ResponseWriter, r *http.Request) (*models.Student, *time.Location, bool) {
+	studentID, err := getStudentIDFromHeader(r)
+	if err != nil {
+		http.Error(w, "Invalid or missing student-id header", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	student, loc, err := h.studentWithLocation(studentID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Student not found", http.StatusNotFound)
+		return nil, nil, false
+	} else if err != nil {
+		log.Printf("Error loading student %d: %v", studentID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if !mayView(auth.PrincipalFrom(r.Context()), student) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, nil, false
+	}
+	return student, loc, true
+}
+
+// GetSchedule returns the student's weekly template
+func (h *Handler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	student, loc, ok := h.scheduleStudent(w, r)
+	if !ok {
+		return
+	}
+	days, err := h.store.Schedules.Weekly(int(student.ID))
+	if err != nil {
+		log.Printf("Error fetching schedule for student %d: %v", student.ID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if days == nil {
+		days = []models.ScheduleDay{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.WeeklySchedule{StudentID: int(student.ID), Timezone: loc.String(), Days: days})
+}
+
+// validateWeekly checks a weekly template lists each weekday at most once
+// with a valid shift
+func validateWeekly(days []models.ScheduleDay) error {
+	seen := map[time.Weekday]bool{}
+	for _, d := range days {
+		if d.Weekday < time.Sunday || d.Weekday > time.Saturday {
+			return errors.New("weekday must be between 0 (Sunday) and 6 (Saturday)")
+		}
+		if seen[d.Weekday] {
+			return fmt.Errorf("weekday %d is listed more than once", d.Weekday)
+		}
+		seen[d.Weekday] = true
+		if err := validateShift(d.Start, d.End); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateSchedule replaces the student's weekly template. An empty list of
+// days removes it, so the legacy check-in and check-out times apply again.
+func (h *Handler) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	student, loc, ok := h.scheduleStudent(w, r)
+	if !ok {
+		return
+	}
+	var input models.WeeklySchedule
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateWeekly(input.Days); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.Schedules.ReplaceWeekly(int(student.ID), input.Days); err != nil {
+		log.Printf("Error saving schedule for student %d: %v", student.ID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	// The open placement keeps the schedule it was worked under
+	if placement, err := h.store.Placements.Current(int(student.ID)); err == nil {
+		if err := h.store.Placements.SetSchedule(placement.ID, input.Days); err != nil {
+			log.Printf("Error saving schedule of placement %d: %v", placement.ID, err)
+		}
+	} else if !errors.Is(err, store.ErrNotFound) {
+		log.Printf("Error loading placement of student %d: %v", student.ID, err)
+	}
+	days, err := h.store.Schedules.Weekly(int(student.ID))
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if days == nil {
+		days = []models.ScheduleDay{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.WeeklySchedule{StudentID: int(student.ID), Timezone: loc.String(), Days: days})
+}
+
+// GetScheduleOverrides lists the student's overrides between the from and
+// to dates, which default to today and 30 days ahead
+func (h *Handler) GetScheduleOverrides(w http.ResponseWriter, r *http.Request) {
+	student, loc, ok := h.scheduleStudent(w, r)
+	if !ok {
+		return
+	}
+	today, _ := getStartAndEndOfDay(time.Now(), loc)
+	from, to := today.Format(dateLayout), today.AddDate(0, 0, 30).Format(dateLayout)
+	for name, value := range map[string]*string{"from": &from, "to": &to} {
+		if v := r.URL.Query().Get(name); v != "" {
+			if _, err := time.Parse(dateLayout, v); err != nil {
+				http.Error(w, "Invalid "+name+" date, expected YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+			*value = v
+		}
+	}
+
+	overrides, err := h.store.Schedules.Overrides(int(student.ID), from, to)
+	if err != nil {
+		log.Printf("Error fetching schedule overrides for student %d: %v", student.ID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if overrides == nil {
+		overrides = []models.ScheduleOverride{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(overrides)
+}
+
+// PutScheduleOverride sets the student's hours, or a day off, on one date
+func (h *Handler) PutScheduleOverride(w http.ResponseWriter, r *http.Request) {
+	student, _, ok := h.scheduleStudent(w, r)
+	if !ok {
+		return
+	}
+	var o models.ScheduleOverride
+	if err := json.NewDecoder(r.Body).Decode(&o); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if _, err := time.Parse(dateLayout, o.Date); err != nil {
+		http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if o.DayOff {
+		o.Start, o.End = "", ""
+	} else if err := validateShift(o.Start, o.End); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	o.StudentID = int(student.ID)
+
+	if err := h.store.Schedules.PutOverride(&o); err != nil {
+		log.Printf("Error saving schedule override for student %d: %v", student.ID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(o)
+}
+
+// DeleteScheduleOverride removes the override on the date query parameter
+func (h *Handler) DeleteScheduleOverride(w http.ResponseWriter, r *http.Request) {
+	student, _, ok := h.scheduleStudent(w, r)
+	if !ok {
+		return
+	}
+	date := r.URL.Query().Get("date")
+	if _, err := time.Parse(dateLayout, date); err != nil {
+		http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	err := h.store.Schedules.DeleteOverride(int(student.ID), date)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "No override on that date", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error deleting schedule override for student %d: %v", student.ID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetExpectedShift resolves the student's shift on the date query parameter,
+// today in the employer's timezone by default
+func (h *Handler) GetExpectedShift(w http.ResponseWriter, r *http.Request) {
+	student, loc, ok := h.scheduleStudent(w, r)
+	if !ok {
+		return
+	}
+	day := time.Now()
+	date := day.In(loc).Format(dateLayout)
+	if v := r.URL.Query().Get("date"); v != "" {
+		var err error
+		if day, err = time.ParseInLocation(dateLayout, v, loc); err != nil {
+			http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		date = v
+	}
+
+	plan, err := h.loadShiftPlan(student, loc, day, day)
+	if err != nil {
+		log.Printf("Error loading schedule for student %d: %v", student.ID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan.shiftOnDate(date))
+}
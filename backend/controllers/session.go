@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"server/auth"
+	"server/models"
+	"server/store"
+	"strings"
+	"time"
+)
+
+const (
+	dashboardSessionTTL = 12 * time.Hour
+	deviceSessionTTL    = 30 * 24 * time.Hour
+)
+
+// HandleLogin opens a web dashboard session for an admin, supervisor or employer
+func (s *AuthService) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.users.GetByUsername(strings.TrimSpace(req.Username))
+	if errors.Is(err, store.ErrNotFound) || (err == nil && !auth.CheckPassword(user.PasswordHash, req.Password)) {
+		log.Printf("Failed login for username %q", req.Username)
+		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		log.Printf("Database error while fetching user: %v", err)
+		http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+
+	principal := auth.Principal{
+		Role:      auth.Role(user.Role),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(dashboardSessionTTL),
+	}
+	if user.SupervisorID != nil {
+		principal.SupervisorID = *user.SupervisorID
+	}
+	if user.EmployerID != nil {
+		principal.EmployerID = *user.EmployerID
+	}
+	s.writeSession(w, principal)
+}
+
+// HandleDeviceSession exchanges a paired device's credentials for a trainee session
+func (s *AuthService) HandleDeviceSession(w http.ResponseWriter, r *http.Request) {
+	var req models.AuthRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	deviceID, err := s.findDevice(req.StudentID, req.SecretCode)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Device is not authorized", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		log.Printf("Error verifying device authorization: %v", err)
+		http.Error(w, "Failed to verify device authorization", http.StatusInternalServerError)
+		return
+	}
+
+	s.writeSession(w, auth.Principal{
+		Role:      auth.RoleTrainee,
+		StudentID: req.StudentID,
+		DeviceID:  deviceID,
+		ExpiresAt: time.Now().Add(deviceSessionTTL),
+	})
+}
+
+// HandleCreateUser provisions a dashboard account (admin only)
+func (s *AuthService) HandleCreateUser(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.CreateUser(req)
+	if err != nil {
+		log.Printf("Error creating user: %v", err)
+		http.Error(w, "Failed to create user: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+}
+
+// HandleMe returns the principal attached to the current session
+func (s *AuthService) HandleMe(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(auth.PrincipalFrom(r.Context()))
+}
+
+// CreateUser validates and stores a new dashboard account
+func (s *AuthService) CreateUser(req models.CreateUserRequest) (*models.User, error) {
+	req.Username = strings.TrimSpace(req.Username)
+	role := auth.Role(req.Role)
+	if req.Username == "" {
+		return nil, errors.New("username is required")
+	}
+	if len(req.Password) < 8 {
+		return nil, errors.New("password must be at least 8 characters")
+	}
+	if role != auth.RoleAdmin && role != auth.RoleSupervisor && role != auth.RoleEmployer {
+		return nil, errors.New("role must be admin, supervisor or employer")
+	}
+	if role == auth.RoleSupervisor && req.SupervisorID == nil {
+		return nil, errors.New("supervisor accounts must reference a supervisor_id")
+	}
+	if role == auth.RoleEmployer {
+		if req.EmployerID == nil {
+			return nil, errors.New("employer accounts must reference an employer_id")
+		}
+		if _, err := s.employers.Get(*req.EmployerID); errors.Is(err, store.ErrNotFound) {
+			return nil, errors.New("employer not found")
+		} else if err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := models.User{
+		Username:     req.Username,
+		PasswordHash: hash,
+		Role:         string(role),
+		SupervisorID: req.SupervisorID,
+	}
+	if role == auth.RoleEmployer {
+		user.EmployerID = req.EmployerID
+	}
+	if err := s.users.Create(&user); errors.Is(err, store.ErrConflict) {
+		return nil, errors.New("username is already taken")
+	} else if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return &user, nil
+}
+
+// EnsureAdmin creates the bootstrap admin account if no user with that name exists yet
+func (s *AuthService) EnsureAdmin(username, password string) error {
+	if username == "" || password == "" {
+		return nil
+	}
+	if _, err := s.users.GetByUsername(username); err == nil {
+		return nil
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("database error: %w", err)
+	}
+	if _, err := s.CreateUser(models.CreateUserRequest{Username: username, Password: password, Role: string(auth.RoleAdmin)}); err != nil {
+		return err
+	}
+	log.Printf("Created bootstrap admin account %q", username)
+	return nil
+}
+
+func (s *AuthService) writeSession(w http.ResponseWriter, principal auth.Principal) {
+	token, err := auth.IssueToken(principal)
+	if err != nil {
+		log.Printf("Error issuing session token: %v", err)
+		http.Error(w, "Failed to issue session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.SessionResponse{
+		Token:        token,
+		Role:         string(principal.Role),
+		UserID:       principal.UserID,
+		StudentID:    principal.StudentID,
+		SupervisorID: principal.SupervisorID,
+		EmployerID:   principal.EmployerID,
+		ExpiresAt:    principal.ExpiresAt,
+	})
+}
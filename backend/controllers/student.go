@@ -2,10 +2,11 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
-	"server/database"
 	"server/models"
+	"server/store"
 	"strconv"
 )
 
@@ -29,23 +30,13 @@ func getStudentIDFromHeader(r *http.Request) (int, error) {
 // @Success 200 {array} models.Student
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /students [get]
-func GetStudents(w http.ResponseWriter, r *http.Request) {
-	var students []models.Student
-	rows, err := database.DB.Query("SELECT id, first_name, last_name, dob, gender, address_line1, address_line2, city, contact_number, contact_number_guardian, supervisor_id, remarks, home_long, home_lat, employer_id, check_in_time, check_out_time FROM student")
+func (h *Handler) GetStudents(w http.ResponseWriter, r *http.Request) {
+	students, err := h.store.Students.List()
 	if err != nil {
 		log.Printf("Error fetching students: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-	for rows.Next() {
-		var s models.Student
-		if err := rows.Scan(&s.ID, &s.FirstName, &s.LastName, &s.DOB, &s.Gender, &s.AddressLine1, &s.AddressLine2, &s.City, &s.ContactNumber, &s.ContactNumberGuardian, &s.SupervisorID, &s.Remarks, &s.HomeLong, &s.HomeLat, &s.EmployerID, &s.CheckInTime, &s.CheckOutTime); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		students = append(students, s)
-	}
 	log.Printf("Fetched %d students", len(students))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(students)
@@ -59,23 +50,15 @@ func GetStudents(w http.ResponseWriter, r *http.Request) {
 // @Param student-id header string true "Student ID"
 // @Success 200 {object} models.Student
 // @Failure 400 {string} string "Bad Request"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 404 {string} string "Not Found"
 // @Router /get-student [get]
-func GetStudent(w http.ResponseWriter, r *http.Request) {
-	studentID, err := getStudentIDFromHeader(r)
-	if err != nil {
-		log.Printf("Error extracting student-id: %v", err)
-		http.Error(w, "Invalid or missing student-id header", http.StatusBadRequest)
-		return
-	}
-	var s models.Student
-	err = database.DB.QueryRow("SELECT id, first_name, last_name, dob, gender, address_line1, address_line2, city, contact_number, contact_number_guardian, supervisor_id, remarks, home_long, home_lat, employer_id, check_in_time, check_out_time FROM student WHERE id = $1", studentID).Scan(&s.ID, &s.FirstName, &s.LastName, &s.DOB, &s.Gender, &s.AddressLine1, &s.AddressLine2, &s.City, &s.ContactNumber, &s.ContactNumberGuardian, &s.SupervisorID, &s.Remarks, &s.HomeLong, &s.HomeLat, &s.EmployerID, &s.CheckInTime, &s.CheckOutTime)
-	if err != nil {
-		log.Printf("Error fetching student with ID %d: %v", studentID, err)
-		http.Error(w, err.Error(), http.StatusNotFound)
+func (h *Handler) GetStudent(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.viewableStudent(w, r)
+	if !ok {
 		return
 	}
-	log.Printf("Fetched student with ID %d: %+v", studentID, s)
+	log.Printf("Fetched student with ID %d: %+v", s.ID, s)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(s)
 }
@@ -91,18 +74,17 @@ func GetStudent(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {string} string "Bad Request"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /students [post]
-func CreateStudent(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) CreateStudent(w http.ResponseWriter, r *http.Request) {
 	var s models.Student
 	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	query := `INSERT INTO student (first_name, last_name, dob, gender, address_line1, address_line2, city, contact_number, contact_number_guardian, supervisor_id, remarks, home_long, home_lat, employer_id, check_in_time, check_out_time) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16) RETURNING id`
-	err := database.DB.QueryRow(query, s.FirstName, s.LastName, s.DOB, s.Gender, s.AddressLine1, s.AddressLine2, s.City, s.ContactNumber, s.ContactNumberGuardian, s.SupervisorID, s.Remarks, s.HomeLong, s.HomeLat, s.EmployerID, s.CheckInTime, s.CheckOutTime).Scan(&s.ID)
-	if err != nil {
+	if err := h.store.Students.Create(&s); err != nil {
 		http.Error(w, "Failed to create student", http.StatusInternalServerError)
 		return
 	}
+	h.followEmployer(int(s.ID), nil, s.EmployerID)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]interface{}{"data": s})
@@ -121,7 +103,7 @@ func CreateStudent(w http.ResponseWriter, r *http.Request) {
 // @Failure 404 {string} string "Not Found"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /students/{id} [put]
-func UpdateStudent(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdateStudent(w http.ResponseWriter, r *http.Request) {
 	idStr := r.Header.Get("student-id")
 	if idStr == "" {
 		http.Error(w, "Missing student-id header", http.StatusBadRequest)
@@ -137,12 +119,23 @@ func UpdateStudent(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	query := `UPDATE student SET first_name=$1, last_name=$2, dob=$3, gender=$4, address_line1=$5, address_line2=$6, city=$7, contact_number=$8, contact_number_guardian=$9, supervisor_id=$10, remarks=$11, home_long=$12, home_lat=$13, employer_id=$14, check_in_time=$15, check_out_time=$16 WHERE id=$17`
-	_, err = database.DB.Exec(query, input.FirstName, input.LastName, input.DOB, input.Gender, input.AddressLine1, input.AddressLine2, input.City, input.ContactNumber, input.ContactNumberGuardian, input.SupervisorID, input.Remarks, input.HomeLong, input.HomeLat, input.EmployerID, input.CheckInTime, input.CheckOutTime, id)
-	if err != nil {
+	previous, err := h.store.Students.Get(int(id))
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Student not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to update student", http.StatusInternalServerError)
+		return
+	}
+	err = h.store.Students.Update(int(id), &input)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Student not found", http.StatusNotFound)
+		return
+	} else if err != nil {
 		http.Error(w, "Failed to update student", http.StatusInternalServerError)
 		return
 	}
+	h.followEmployer(int(id), previous.EmployerID, input.EmployerID)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{"data": input})
 }
@@ -153,9 +146,10 @@ func UpdateStudent(w http.ResponseWriter, r *http.Request) {
 // @Tags students
 // @Param id path string true "Student ID"
 // @Success 204 {string} string "No Content"
+// @Failure 404 {string} string "Not Found"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /students/{id} [delete]
-func DeleteStudent(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) DeleteStudent(w http.ResponseWriter, r *http.Request) {
 	idStr := r.Header.Get("student-id")
 	if idStr == "" {
 		http.Error(w, "Missing student-id header", http.StatusBadRequest)
@@ -166,8 +160,11 @@ func DeleteStudent(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid student-id header", http.StatusBadRequest)
 		return
 	}
-	_, err = database.DB.Exec("DELETE FROM student WHERE id = $1", id)
-	if err != nil {
+	err = h.store.Students.Delete(int(id))
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Student not found", http.StatusNotFound)
+		return
+	} else if err != nil {
 		http.Error(w, "Failed to delete student", http.StatusInternalServerError)
 		return
 	}
@@ -2,42 +2,32 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
-	"server/database"
 	"server/models"
+	"server/store"
 )
 
-func GetSupervisors(w http.ResponseWriter, r *http.Request) {
-	var supervisors []models.Supervisor
-	rows, err := database.DB.Query("SELECT supervisor_id, first_name, last_name, email_address, contact_number FROM supervisor")
+func (h *Handler) GetSupervisors(w http.ResponseWriter, r *http.Request) {
+	supervisors, err := h.store.Supervisors.List()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-	for rows.Next() {
-		var s models.Supervisor
-		if err := rows.Scan(&s.SupervisorID, &s.FirstName, &s.LastName, &s.EmailAddress, &s.ContactNumber); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		supervisors = append(supervisors, s)
-	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(supervisors)
 }
 
-func GetSupervisor(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetSupervisor(w http.ResponseWriter, r *http.Request) {
 	supervisorIDStr := r.Header.Get("supervisor-id")
 	id, err := strconv.Atoi(supervisorIDStr)
 	if err != nil {
 		http.Error(w, "Invalid supervisor ID", http.StatusBadRequest)
 		return
 	}
-	var s models.Supervisor
-	err = database.DB.QueryRow("SELECT supervisor_id, first_name, last_name, email_address, contact_number FROM supervisor WHERE supervisor_id = $1", id).Scan(&s.SupervisorID, &s.FirstName, &s.LastName, &s.EmailAddress, &s.ContactNumber)
+	s, err := h.store.Supervisors.Get(id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -46,15 +36,13 @@ func GetSupervisor(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(s)
 }
 
-func CreateSupervisor(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) CreateSupervisor(w http.ResponseWriter, r *http.Request) {
 	var s models.Supervisor
 	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	query := `INSERT INTO supervisor (first_name, last_name, email_address, contact_number) VALUES ($1, $2, $3, $4) RETURNING supervisor_id`
-	err := database.DB.QueryRow(query, s.FirstName, s.LastName, s.EmailAddress, s.ContactNumber).Scan(&s.SupervisorID)
-	if err != nil {
+	if err := h.store.Supervisors.Create(&s); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -62,7 +50,7 @@ func CreateSupervisor(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(s)
 }
 
-func UpdateSupervisor(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdateSupervisor(w http.ResponseWriter, r *http.Request) {
 	supervisorIDStr := r.Header.Get("supervisor-id")
 	id, err := strconv.Atoi(supervisorIDStr)
 	if err != nil {
@@ -74,9 +62,11 @@ func UpdateSupervisor(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	query := `UPDATE supervisor SET first_name=$1, last_name=$2, email_address=$3, contact_number=$4 WHERE supervisor_id=$5`
-	_, err = database.DB.Exec(query, s.FirstName, s.LastName, s.EmailAddress, s.ContactNumber, id)
-	if err != nil {
+	err = h.store.Supervisors.Update(id, &s)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Supervisor not found", http.StatusNotFound)
+		return
+	} else if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -85,41 +75,42 @@ func UpdateSupervisor(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(s)
 }
 
-func DeleteSupervisor(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) DeleteSupervisor(w http.ResponseWriter, r *http.Request) {
 	supervisorIDStr := r.Header.Get("supervisor-id")
 	id, err := strconv.Atoi(supervisorIDStr)
 	if err != nil {
 		http.Error(w, "Invalid supervisor ID", http.StatusBadRequest)
 		return
 	}
-	_, err = database.DB.Exec("DELETE FROM supervisor WHERE supervisor_id = $1", id)
-	if err != nil {
+	err = h.store.Supervisors.Delete(id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Supervisor not found", http.StatusNotFound)
+		return
+	} else if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func GetAllSupervisorIDsAndNames(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetAllSupervisorIDsAndNames(w http.ResponseWriter, r *http.Request) {
 	type SupervisorIDName struct {
 		SupervisorID uint64 `json:"supervisor_id"`
 		FirstName    string `json:"first_name"`
 		LastName     string `json:"last_name"`
 	}
-	rows, err := database.DB.Query("SELECT supervisor_id, first_name, last_name FROM supervisor")
+	all, err := h.store.Supervisors.List()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 	var supervisors []SupervisorIDName
-	for rows.Next() {
-		var s SupervisorIDName
-		if err := rows.Scan(&s.SupervisorID, &s.FirstName, &s.LastName); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		supervisors = append(supervisors, s)
+	for _, s := range all {
+		supervisors = append(supervisors, SupervisorIDName{
+			SupervisorID: uint64(s.SupervisorID),
+			FirstName:    s.FirstName,
+			LastName:     s.LastName,
+		})
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(supervisors)
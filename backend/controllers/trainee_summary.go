@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"server/models"
+	"server/store"
+	"server/summary"
+	"strings"
+	"time"
+)
+
+// UseSummaries replaces the service that writes trainee summaries
+func (h *Handler) UseSummaries(s *summary.Service) {
+	h.summaries = s
+}
+
+// summarizeAttendance counts sessions, oldest first, by the local day they
+// started in, in the zone zoneOf gives for each
+func summarizeAttendance(sessions []models.Attendance, zoneOf func(models.Attendance) *time.Location) models.AttendanceSummary {
+	var s models.AttendanceSummary
+	for _, day := range groupAttendanceDays(sessions, zoneOf) {
+		s.WorkedMinutes += day.WorkedMinutes
+		attended := false
+		for _, a := range day.Sessions {
+			if !a.Absent && !a.OrphanCheckOut {
+				attended = true
+			}
+		}
+		if attended {
+			s.DaysAttended++
+		} else if day.Absent {
+			s.DaysAbsent++
+		}
+	}
+
+	for _, a := range sessions {
+		switch a.Punctuality {
+		case models.PunctualityOnTime:
+			s.OnTime++
+		case models.PunctualityLate:
+			s.Late++
+		case models.PunctualityLeftEarly:
+			s.LeftEarly++
+		}
+		if a.CheckInInsideGeofence != nil && !*a.CheckInInsideGeofence {
+			s.OutsideGeofence++
+		}
+	}
+	// Leaving early still means the session started on time
+	if scheduled := s.OnTime + s.Late + s.LeftEarly; scheduled > 0 {
+		pct := math.Round(float64(s.OnTime+s.LeftEarly)/float64(scheduled)*1000) / 10
+		s.OnTimePercentage = &pct
+	}
+	return s
+}
+
+// summarizeMoods counts moods recorded over the period; recent holds the
+// latest daily moods, newest first, for the current negative streak
+func summarizeMoods(moods, recent []models.Mood) models.MoodSummary {
+	s := models.MoodSummary{Emotions: map[string]int{}}
+	sum, scored := 0, 0
+	for _, m := range moods {
+		s.Count++
+		s.Emotions[strings.ToLower(m.Emotion)]++
+		if m.IsDaily {
+			s.DailyCount++
+		}
+		if m.Valence != nil {
+			sum += *m.Valence
+			scored++
+		}
+		s.Latest = m.Emotion
+	}
+	if scored > 0 {
+		avg := math.Round(float64(sum)/float64(scored)*100) / 100
+		s.AverageValence = &avg
+	}
+	s.NegativeStreak, _ = negativeStreak(recent)
+	return s
+}
+
+// summarizeFeedback averages ratings per competency over feedback, which
+// is latest first, and keeps its comments in that order
+func summarizeFeedback(list []models.ManagerFeedback) models.FeedbackSummary {
+	s := models.FeedbackSummary{AverageRatings: map[string]float64{}, Comments: []string{}}
+	sums, counts := map[string]int{}, map[string]int{}
+	for _, f := range list {
+		s.Count++
+		for competency, rating := range f.Ratings {
+			sums[competency] += rating
+			counts[competency]++
+		}
+		if c := strings.TrimSpace(f.Comments); c != "" {
+			s.Comments = append(s.Comments, c)
+		}
+	}
+	for competency, sum := range sums {
+		s.AverageRatings[competency] = math.Round(float64(sum)/float64(counts[competency])*10) / 10
+	}
+	return s
+}
+
+// GetTraineeSummary writes a progress summary of the student in the
+// student-id header from their attendance, moods, manager feedback and
+// remarks. from and to are inclusive YYYY-MM-DD dates and default to the
+// last 90 days.
+func (h *Handler) GetTraineeSummary(w http.ResponseWriter, r *http.Request) {
+	student, ok := h.viewableStudent(w, r)
+	if !ok {
+		return
+	}
+	studentID := int(student.ID)
+
+	employer, err := h.employerFor(studentID)
+	if err != nil {
+		log.Printf("Error fetching employer of student %d: %v", studentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	loc := h.locationOf(employer)
+	from, to, err := h.parseDateRange(r, loc, 90)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	end := to.AddDate(0, 0, 1)
+
+	sessions, err := h.store.Attendance.Between(studentID, from, end)
+	if err != nil {
+		log.Printf("Error fetching attendance for student %d: %v", studentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	history, err := h.loadPlacementHistory(studentID)
+	if err != nil {
+		log.Printf("Error fetching placements of student %d: %v", studentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	moods, err := h.store.Moods.Between(studentID, from, end)
+	if err != nil {
+		log.Printf("Error fetching moods of student %d: %v", studentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	recent, err := h.recentDailyMoods(studentID)
+	if err != nil {
+		log.Printf("Error fetching moods of student %d: %v", studentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	feedback, err := h.store.Feedback.List(store.FeedbackQuery{StudentID: &studentID, From: from.Format(dateLayout), To: to.Format(dateLayout)})
+	if err != nil {
+		log.Printf("Error fetching feedback for student %d: %v", studentID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s := models.TraineeSummary{
+		StudentID:   studentID,
+		StudentName: strings.TrimSpace(student.FirstName + " " + student.LastName),
+		Remarks:     student.Remarks,
+		From:        from.Format(dateLayout),
+		To:          to.Format(dateLayout),
+		Attendance:  summarizeAttendance(sessions, history.zoneOf(loc)),
+		Moods:       summarizeMoods(moods, recent),
+		Feedback:    summarizeFeedback(feedback),
+		GeneratedAt: time.Now().UTC(),
+	}
+	if employer != nil {
+		s.EmployerName = employer.Name
+	}
+	h.summaries.Write(r.Context(), &s)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
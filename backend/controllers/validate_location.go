@@ -1,17 +1,12 @@
-//TODO
-// employer and the other models are updated
-
 package controllers
 
 import (
-	"database/sql"
 	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"math"
+	"errors"
+	"log"
 	"net/http"
-	"os"
-	"server/database"
+	"server/models"
+	"server/store"
 )
 
 type LocationResponse struct {
@@ -24,102 +19,25 @@ type LocationResponse struct {
 	Displacement    int     `json:"displacement_meters,omitempty"`
 }
 
-// Google Distance Matrix API response struct (partial)
-type googleDistanceMatrixResponse struct {
-	Rows []struct {
-		Elements []struct {
-			Distance struct {
-				Value int `json:"value"`
-			} `json:"distance"`
-			Status string `json:"status"`
-		} `json:"elements"`
-	} `json:"rows"`
-	Status string `json:"status"`
-}
-
-func getGoogleDistance(lat1, lon1, lat2, lon2 float64) (int, error) {
-	apiKey := os.Getenv("GOOGLE_MAPS_API_KEY")
-	if apiKey == "" {
-		return 0, fmt.Errorf("google Maps API key not set")
-	}
-	url := fmt.Sprintf(
-		"https://maps.googleapis.com/maps/api/distancematrix/json?origins=%f,%f&destinations=%f,%f&key=%s",
-		lat1, lon1, lat2, lon2, apiKey,
-	)
-	resp, err := http.Get(url)
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return 0, err
-	}
-	var apiResp googleDistanceMatrixResponse
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return 0, err
-	}
-	if apiResp.Status != "OK" ||
-		len(apiResp.Rows) == 0 ||
-		len(apiResp.Rows[0].Elements) == 0 ||
-		apiResp.Rows[0].Elements[0].Status != "OK" {
-		return 0, fmt.Errorf("google API error or no route found")
-	}
-	return apiResp.Rows[0].Elements[0].Distance.Value, nil
-}
-
-// Haversine formula to calculate straight-line distance in meters
-func haversine(lat1, lon1, lat2, lon2 float64) int {
-	const R = 6371000 // Earth radius in meters
-	toRad := func(deg float64) float64 { return deg * (3.141592653589793 / 180) }
-	dLat := toRad(lat2 - lat1)
-	dLon := toRad(lon2 - lon1)
-	lat1Rad := toRad(lat1)
-	lat2Rad := toRad(lat2)
-
-	a := (sin(dLat/2) * sin(dLat/2)) +
-		(cos(lat1Rad) * cos(lat2Rad) * sin(dLon/2) * sin(dLon/2))
-	c := 2 * atan2(sqrt(a), sqrt(1-a))
-	return int(R * c)
-}
-
-func sin(x float64) float64      { return math.Sin(x) }
-func cos(x float64) float64      { return math.Cos(x) }
-func atan2(y, x float64) float64 { return math.Atan2(y, x) }
-func sqrt(x float64) float64     { return math.Sqrt(x) }
-
-func ValidateLocationHandler() http.HandlerFunc {
+// ValidateLocationHandler reports whether the coordinates in the request body
+// are inside the geofence of the student's employer, so the app can warn
+// before a check-in is rejected
+func (h *Handler) ValidateLocationHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		studentID := r.Header.Get("student-id")
-		if studentID == "" {
-			http.Error(w, "student_id is required", http.StatusBadRequest)
+		// Supervisors and employers only probe their own trainees
+		student, ok := h.viewableStudent(w, r)
+		if !ok {
 			return
 		}
 
-		query := `
-			SELECT 
-				e.addr_long AS employer_long,
-				e.addr_lat AS employer_lat,
-				s.home_long AS student_long,
-				s.home_lat AS student_lat
-			FROM 
-				employer AS e
-			INNER JOIN 
-				student AS s 
-			ON 
-				e.id = s.employer_id
-			WHERE s.id = $1
-			LIMIT 1
-		`
+		var location models.GeoPoint
+		if err := json.NewDecoder(r.Body).Decode(&location); err != nil {
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
 
-		var resp LocationResponse
-		err := database.DB.QueryRow(query, studentID).Scan(
-			&resp.EmployerLong,
-			&resp.EmployerLat,
-			&resp.StudentLong,
-			&resp.StudentLat,
-		)
-		if err == sql.ErrNoRows {
+		employer, err := h.employerFor(int(student.ID))
+		if errors.Is(err, store.ErrNotFound) || (err == nil && employer == nil) {
 			http.Error(w, "No data found", http.StatusNotFound)
 			return
 		} else if err != nil {
@@ -127,18 +45,24 @@ func ValidateLocationHandler() http.HandlerFunc {
 			return
 		}
 
-		// Use Google Distance Matrix API for driving distance
-		drivingDistance, err := getGoogleDistance(resp.EmployerLat, resp.EmployerLong, resp.StudentLat, resp.StudentLong)
-		if err != nil {
-			http.Error(w, "Failed to get distance from Google API: "+err.Error(), http.StatusInternalServerError)
-			return
+		result := evaluateGeofence(employer, location.Lat, location.Long)
+		resp := LocationResponse{
+			EmployerLong: employer.Longitude,
+			EmployerLat:  employer.Latitude,
+			StudentLong:  location.Long,
+			StudentLat:   location.Lat,
+			InRange:      result.Inside,
+			Displacement: result.DistanceM,
 		}
-		resp.DrivingDistance = drivingDistance
 
-		// Calculate displacement (straight-line distance)
-		resp.Displacement = haversine(resp.EmployerLat, resp.EmployerLong, resp.StudentLat, resp.StudentLong)
-
-		resp.InRange = drivingDistance <= 500
+		// Driving distance is informational only, so a failed lookup does not
+		// fail the request
+		workplace := models.GeoPoint{Lat: employer.Latitude, Long: employer.Longitude}
+		if drivingDistance, err := h.distance.Distance(r.Context(), location, workplace); err != nil {
+			log.Printf("Skipping driving distance: %v", err)
+		} else {
+			resp.DrivingDistance = drivingDistance
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(resp); err != nil {
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"server/models"
+)
+
+func TestStudentCRUD(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Chamari")
+	header := studentHeader(studentID)
+
+	var student models.Student
+	api.mustDo("GET", "/get-student", api.adminToken, header, nil, http.StatusOK, &student)
+	if student.FirstName != "Chamari" {
+		t.Errorf("first name = %q, want Chamari", student.FirstName)
+	}
+
+	student.Remarks = "Prefers morning shifts"
+	api.mustDo("PUT", "/update-employee", api.adminToken, header, student, http.StatusOK, nil)
+	api.mustDo("GET", "/get-student", api.adminToken, header, nil, http.StatusOK, &student)
+	if student.Remarks != "Prefers morning shifts" {
+		t.Errorf("remarks = %q after update", student.Remarks)
+	}
+
+	var students []models.Student
+	api.mustDo("GET", "/get-students", api.adminToken, nil, nil, http.StatusOK, &students)
+	if len(students) != 1 {
+		t.Errorf("got %d students, want 1", len(students))
+	}
+
+	api.mustDo("DELETE", "/delete-employee", api.adminToken, header, nil, http.StatusOK, nil)
+	api.mustDo("GET", "/get-student", api.adminToken, header, nil, http.StatusNotFound, nil)
+}
+
+func TestStudentErrors(t *testing.T) {
+	api := newTestAPI(t)
+	missing := studentHeader(999)
+
+	api.mustDo("GET", "/get-student", api.adminToken, nil, nil, http.StatusBadRequest, nil)
+	api.mustDo("GET", "/get-student", api.adminToken, missing, nil, http.StatusNotFound, nil)
+	api.mustDo("PUT", "/update-employee", api.adminToken, nil, models.Student{}, http.StatusBadRequest, nil)
+	api.mustDo("PUT", "/update-employee", api.adminToken, missing, models.Student{FirstName: "Ghost"}, http.StatusNotFound, nil)
+	api.mustDo("DELETE", "/delete-employee", api.adminToken, nil, nil, http.StatusBadRequest, nil)
+	api.mustDo("DELETE", "/delete-employee", api.adminToken, missing, nil, http.StatusNotFound, nil)
+	api.mustDo("GET", "/trainee-profile", api.adminToken, nil, nil, http.StatusBadRequest, nil)
+	api.mustDo("GET", "/trainee-profile", api.adminToken, missing, nil, http.StatusNotFound, nil)
+}
+
+func TestSupervisorCRUD(t *testing.T) {
+	api := newTestAPI(t)
+
+	var created models.Supervisor
+	api.mustDo("POST", "/create-supervisor", api.adminToken, nil, models.Supervisor{FirstName: "Ruwani", LastName: "Silva", EmailAddress: "ruwani@example.com"}, http.StatusOK, &created)
+	if created.SupervisorID == 0 {
+		t.Fatalf("created supervisor has no ID")
+	}
+	header := map[string]string{"supervisor-id": strconv.Itoa(created.SupervisorID)}
+
+	var updated models.Supervisor
+	api.mustDo("PUT", "/update-supervisor", api.adminToken, header, models.Supervisor{FirstName: "Ruwani", LastName: "Fernando"}, http.StatusOK, &updated)
+	var fetched models.Supervisor
+	api.mustDo("GET", "/get-supervisor", api.adminToken, header, nil, http.StatusOK, &fetched)
+	if fetched.LastName != "Fernando" {
+		t.Errorf("last name = %q after update, want Fernando", fetched.LastName)
+	}
+
+	var supervisors []models.Supervisor
+	api.mustDo("GET", "/get-supervisors", api.adminToken, nil, nil, http.StatusOK, &supervisors)
+	if len(supervisors) != 1 {
+		t.Errorf("got %d supervisors, want 1", len(supervisors))
+	}
+
+	api.mustDo("DELETE", "/delete-supervisor", api.adminToken, header, nil, http.StatusNoContent, nil)
+	api.mustDo("GET", "/get-supervisor", api.adminToken, header, nil, http.StatusNotFound, nil)
+	api.mustDo("DELETE", "/delete-supervisor", api.adminToken, header, nil, http.StatusNotFound, nil)
+	api.mustDo("PUT", "/update-supervisor", api.adminToken, header, models.Supervisor{}, http.StatusNotFound, nil)
+	api.mustDo("GET", "/get-supervisor", api.adminToken, nil, nil, http.StatusBadRequest, nil)
+}
+
+func TestEmployerCRUD(t *testing.T) {
+	api := newTestAPI(t)
+
+	var created models.Employer
+	api.mustDo("POST", "/create-employer", api.adminToken, nil, models.Employer{Name: "Hotel Lanka", Latitude: 6.9, Longitude: 79.8}, http.StatusOK, &created)
+	if created.ID == 0 {
+		t.Fatalf("created employer has no ID")
+	}
+	header := map[string]string{"employer-id": strconv.Itoa(int(created.ID))}
+
+	var updated models.Employer
+	api.mustDo("PUT", "/update-employer", api.adminToken, header, models.Employer{Name: "Hotel Lanka Colombo"}, http.StatusOK, &updated)
+	if updated.Name != "Hotel Lanka Colombo" {
+		t.Errorf("name = %q after update", updated.Name)
+	}
+
+	// Assigned students show their employer on the management table
+	studentID := api.createStudent("Lahiru")
+	employerID := created.ID
+	api.mustDo("PUT", "/update-employee", api.adminToken, studentHeader(studentID), models.Student{FirstName: "Lahiru", EmployerID: &employerID}, http.StatusOK, nil)
+	var rows []struct {
+		StudentID    int     `json:"student_id"`
+		EmployerName *string `json:"employer_name"`
+	}
+	api.mustDo("GET", "/management", api.adminToken, nil, nil, http.StatusOK, &rows)
+	if len(rows) != 1 || rows[0].EmployerName == nil || *rows[0].EmployerName != "Hotel Lanka Colombo" {
+		t.Errorf("management rows = %+v, want student with employer", rows)
+	}
+
+	// Placements keep the employer; one nobody was placed with can go
+	api.mustDo("DELETE", "/delete-employer", api.adminToken, header, nil, http.StatusConflict, nil)
+	api.mustDo("GET", "/get-employer", api.adminToken, header, nil, http.StatusOK, nil)
+	var unused models.Employer
+	api.mustDo("POST", "/create-employer", api.adminToken, nil, models.Employer{Name: "Galle Face Hotel", Latitude: 6.9271, Longitude: 79.8612, GeofenceRadius: 100}, http.StatusOK, &unused)
+	header = map[string]string{"employer-id": strconv.Itoa(int(unused.ID))}
+	api.mustDo("DELETE", "/delete-employer", api.adminToken, header, nil, http.StatusNoContent, nil)
+	api.mustDo("GET", "/get-employer", api.adminToken, header, nil, http.StatusNotFound, nil)
+	api.mustDo("DELETE", "/delete-employer", api.adminToken, header, nil, http.StatusNotFound, nil)
+	api.mustDo("PUT", "/update-employer", api.adminToken, header, models.Employer{}, http.StatusNotFound, nil)
+	api.mustDo("GET", "/get-employer", api.adminToken, map[string]string{"employer-id": "x"}, nil, http.StatusBadRequest, nil)
+}
+
+func TestEmployerUpdateKeepsOmittedSettings(t *testing.T) {
+	api := newTestAPI(t)
+	grace := 20
+	polygon := []models.GeoPoint{{Lat: 6.92, Long: 79.86}, {Lat: 6.93, Long: 79.86}, {Lat: 6.93, Long: 79.87}}
+	var created models.Employer
+	employer := models.Employer{Name: "Galadari", Latitude: 6.9, Longitude: 79.8, GeofenceRadius: 350, GeofencePolygon: polygon, Timezone: "Asia/Dubai", LateGraceMinutes: &grace, EarlyLeaveGraceMinutes: &grace}
+	api.mustDo("POST", "/create-employer", api.adminToken, nil, employer, http.StatusOK, &created)
+	header := map[string]string{"employer-id": strconv.Itoa(int(created.ID))}
+
+	// What the web app sends: only the name, contact and address
+	edit := map[string]interface{}{"name": "Galadari Hotel", "contact_number": "0112544544", "address_line1": "64 Lotus Road", "address_line3": "Colombo", "addr_lat": 6.9, "addr_long": 79.8}
+	var updated models.Employer
+	api.mustDo("PUT", "/update-employer", api.adminToken, header, edit, http.StatusOK, &updated)
+	if updated.Name != "Galadari Hotel" || updated.AddressLine1 != "64 Lotus Road" {
+		t.Errorf("updated = %+v", updated)
+	}
+	if updated.GeofenceRadius != 350 || len(updated.GeofencePolygon) != 3 || updated.Timezone != "Asia/Dubai" {
+		t.Errorf("geofence and timezone after update = %v, %v, %q; want kept", updated.GeofenceRadius, updated.GeofencePolygon, updated.Timezone)
+	}
+	if updated.LateGraceMinutes == nil || *updated.LateGraceMinutes != 20 || updated.EarlyLeaveGraceMinutes == nil || *updated.EarlyLeaveGraceMinutes != 20 {
+		t.Errorf("grace after update = %v, %v; want 20", updated.LateGraceMinutes, updated.EarlyLeaveGraceMinutes)
+	}
+
+	// Fields that are sent still replace the stored ones
+	edit["geofence_radius_m"] = 150
+	edit["geofence_polygon"] = []models.GeoPoint{}
+	updated = models.Employer{}
+	api.mustDo("PUT", "/update-employer", api.adminToken, header, edit, http.StatusOK, &updated)
+	if updated.GeofenceRadius != 150 || len(updated.GeofencePolygon) != 0 || updated.Timezone != "Asia/Dubai" {
+		t.Errorf("after changing the geofence = %v, %v, %q", updated.GeofenceRadius, updated.GeofencePolygon, updated.Timezone)
+	}
+}
+
+func TestMasterDataIsAdminOnly(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Nadeesha")
+	token := api.pairDevice(studentID)
+
+	api.mustDo("POST", "/create-employer", token, nil, models.Employer{Name: "Nope"}, http.StatusForbidden, nil)
+	api.mustDo("DELETE", "/delete-employee", token, nil, nil, http.StatusForbidden, nil)
+}
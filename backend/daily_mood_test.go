@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"server/models"
+	"server/notify"
+)
+
+func TestOneDailyMoodPerLocalDay(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.supervisedStudent("Chamari")
+	token := api.pairDevice(studentID)
+	api.deliver()
+
+	colombo, _ := time.LoadLocation("Asia/Colombo")
+	post := func(emotion string, daily bool, at time.Time, wantStatus int) models.Mood {
+		t.Helper()
+		var m models.Mood
+		api.setClock(at)
+		defer api.setClock(time.Time{})
+		body := map[string]interface{}{"emotion": emotion, "is_daily": daily, "timestamp": at.Format(time.RFC3339)}
+		api.mustDo("POST", "/post-mood", token, nil, body, wantStatus, &m)
+		return m
+	}
+
+	first := post("sad", true, time.Date(2025, 4, 1, 8, 0, 0, 0, colombo), http.StatusOK)
+	if first.LocalDate != "2025-04-01" {
+		t.Errorf("local_date = %q", first.LocalDate)
+	}
+	// 23:30 in Colombo is still 1 April there, though it is 18:00 UTC
+	replaced := post("neutral", true, time.Date(2025, 4, 1, 23, 30, 0, 0, colombo), http.StatusOK)
+	if replaced.ID != first.ID || replaced.Emotion != "neutral" || replaced.UpdatedAt == nil {
+		t.Errorf("second daily mood gave %+v, want mood %d replaced", replaced, first.ID)
+	}
+	// Ad-hoc moods are kept separately
+	post("happy", false, time.Date(2025, 4, 1, 12, 0, 0, 0, colombo), http.StatusOK)
+	post("sad", true, time.Date(2025, 4, 2, 0, 30, 0, 0, colombo), http.StatusOK)
+
+	var daily models.MoodPage
+	api.mustDo("GET", "/moods?is_daily=true&sort=asc", token, nil, nil, http.StatusOK, &daily)
+	if len(daily.Moods) != 2 || daily.Moods[0].Emotion != "neutral" || daily.Moods[1].LocalDate != "2025-04-02" {
+		t.Errorf("daily moods = %+v", daily.Moods)
+	}
+
+	// Replacing a negative daily mood with another one does not report the streak again
+	post("sad", true, time.Date(2025, 4, 3, 8, 0, 0, 0, colombo), http.StatusOK)
+	post("sad", true, time.Date(2025, 4, 4, 8, 0, 0, 0, colombo), http.StatusOK)
+	if got := api.deliver()[notify.ChannelEmail]; len(got) != 1 {
+		t.Fatalf("got %d emails for the streak, want 1", len(got))
+	}
+	post("sad", true, time.Date(2025, 4, 4, 9, 0, 0, 0, colombo), http.StatusOK)
+	if got := api.deliver()[notify.ChannelEmail]; len(got) != 0 {
+		t.Errorf("replacing the mood notified again: %+v", got)
+	}
+}
+
+func TestDailyMoodEditWindow(t *testing.T) {
+	t.Setenv("MOOD_DAILY_EDIT_WINDOW", "0")
+	api := newTestAPI(t)
+	token := api.pairDevice(api.createStudent("Lahiru"))
+
+	now := time.Now()
+	postMood(api, token, "happy", true, now)
+	body := map[string]interface{}{"emotion": "sad", "is_daily": true, "timestamp": now.Format(time.RFC3339)}
+	api.mustDo("POST", "/post-mood", token, nil, body, http.StatusConflict, nil)
+	postMood(api, token, "sad", false, now)
+}
+
+func TestDailyMoodIgnoresDeviceClock(t *testing.T) {
+	api := newTestAPI(t)
+	token := api.pairDevice(api.createStudent("Nadeesha"))
+
+	colombo, _ := time.LoadLocation("Asia/Colombo")
+	received := time.Date(2025, 4, 1, 8, 0, 0, 0, colombo)
+	api.setClock(received)
+	defer api.setClock(time.Time{})
+
+	// A device claiming another day still files the mood under the day it arrived
+	var m models.Mood
+	body := map[string]interface{}{"emotion": "happy", "is_daily": true, "timestamp": received.AddDate(0, 0, -3).Format(time.RFC3339)}
+	api.mustDo("POST", "/post-mood", token, nil, body, http.StatusOK, &m)
+	if m.LocalDate != "2025-04-01" || !m.RecordedAt.Equal(received) {
+		t.Errorf("mood = %+v, want recorded at %v on 2025-04-01", m, received)
+	}
+}
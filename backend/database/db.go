@@ -25,7 +25,14 @@ func ConnectDB() {
 	password := os.Getenv("DB_PASSWORD")
 	dbname := os.Getenv("DB_NAME")
 	port := os.Getenv("DB_PORT")
-	sslrootcert := "./config/ca.pem"
+	sslmode := os.Getenv("DB_SSLMODE")
+	if sslmode == "" {
+		sslmode = "verify-ca"
+	}
+	sslrootcert := os.Getenv("DB_SSLROOTCERT")
+	if sslrootcert == "" {
+		sslrootcert = "./config/ca.pem"
+	}
 
 	if host == "" || user == "" || password == "" || dbname == "" || port == "" {
 		log.Fatal("❌ Database connection environment variables are not set properly")
@@ -33,14 +40,17 @@ func ConnectDB() {
 
 	// Define DSN connection string with SSL root certificate
 	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=verify-ca sslrootcert=%s",
+		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
 		host,
 		user,
 		password,
 		dbname,
 		port,
-		sslrootcert,
+		sslmode,
 	)
+	if sslmode != "disable" {
+		dsn += " sslrootcert=" + sslrootcert
+	}
 
 	log.Println("ℹ️ Attempting to connect to the database...")
 
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS emergency_contact;
+DROP TABLE IF EXISTS authorized_devices;
+DROP TABLE IF EXISTS otps;
+DROP TABLE IF EXISTS mood;
+DROP TABLE IF EXISTS attendance;
+DROP TABLE IF EXISTS student;
+DROP TABLE IF EXISTS employer;
+DROP TABLE IF EXISTS supervisor;
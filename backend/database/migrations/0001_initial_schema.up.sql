@@ -0,0 +1,86 @@
+-- Baseline schema as used by the controllers. IF NOT EXISTS lets databases
+-- created before migrations existed adopt this version without changes.
+
+CREATE TABLE IF NOT EXISTS supervisor (
+    supervisor_id  SERIAL PRIMARY KEY,
+    first_name     TEXT NOT NULL,
+    last_name      TEXT NOT NULL DEFAULT '',
+    email_address  TEXT NOT NULL DEFAULT '',
+    contact_number TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS employer (
+    id             SERIAL PRIMARY KEY,
+    name           TEXT NOT NULL,
+    contact_number TEXT NOT NULL DEFAULT '',
+    address_line1  TEXT NOT NULL DEFAULT '',
+    address_line2  TEXT NOT NULL DEFAULT '',
+    address_line3  TEXT NOT NULL DEFAULT '',
+    addr_long      DOUBLE PRECISION NOT NULL DEFAULT 0,
+    addr_lat       DOUBLE PRECISION NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS student (
+    id                      SERIAL PRIMARY KEY,
+    first_name              TEXT NOT NULL,
+    last_name               TEXT NOT NULL DEFAULT '',
+    dob                     DATE NOT NULL,
+    gender                  TEXT NOT NULL DEFAULT '',
+    address_line1           TEXT NOT NULL DEFAULT '',
+    address_line2           TEXT NOT NULL DEFAULT '',
+    city                    TEXT NOT NULL DEFAULT '',
+    contact_number          TEXT NOT NULL DEFAULT '',
+    contact_number_guardian TEXT NOT NULL DEFAULT '',
+    supervisor_id           INTEGER REFERENCES supervisor (supervisor_id) ON DELETE SET NULL,
+    remarks                 TEXT NOT NULL DEFAULT '',
+    home_long               DOUBLE PRECISION NOT NULL DEFAULT 0,
+    home_lat                DOUBLE PRECISION NOT NULL DEFAULT 0,
+    employer_id             INTEGER REFERENCES employer (id) ON DELETE SET NULL,
+    check_in_time           TEXT NOT NULL DEFAULT '',
+    check_out_time          TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS attendance (
+    id                  SERIAL PRIMARY KEY,
+    student_id          INTEGER NOT NULL REFERENCES student (id) ON DELETE CASCADE,
+    check_in_lat        DOUBLE PRECISION NOT NULL DEFAULT 0,
+    check_in_long       DOUBLE PRECISION NOT NULL DEFAULT 0,
+    check_in_date_time  TIMESTAMPTZ NOT NULL,
+    check_out_lat       DOUBLE PRECISION,
+    check_out_long      DOUBLE PRECISION,
+    check_out_date_time TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS attendance_student_check_in_idx ON attendance (student_id, check_in_date_time);
+
+CREATE TABLE IF NOT EXISTS mood (
+    id          SERIAL PRIMARY KEY,
+    student_id  INTEGER NOT NULL REFERENCES student (id) ON DELETE CASCADE,
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    emotion     TEXT NOT NULL,
+    is_daily    BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS mood_student_recorded_at_idx ON mood (student_id, recorded_at);
+
+CREATE TABLE IF NOT EXISTS otps (
+    id         SERIAL PRIMARY KEY,
+    student_id INTEGER NOT NULL REFERENCES student (id) ON DELETE CASCADE,
+    otp_code   TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    is_used    BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE TABLE IF NOT EXISTS authorized_devices (
+    id          SERIAL PRIMARY KEY,
+    student_id  INTEGER NOT NULL REFERENCES student (id) ON DELETE CASCADE,
+    secret_code TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS emergency_contact (
+    id           SERIAL PRIMARY KEY,
+    phone_number TEXT NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
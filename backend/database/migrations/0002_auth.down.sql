@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS otp_failed_attempts;
+DROP INDEX IF EXISTS otps_active_code_idx;
+DROP INDEX IF EXISTS authorized_devices_secret_code_idx;
+ALTER TABLE authorized_devices
+    DROP COLUMN IF EXISTS revoked_at,
+    DROP COLUMN IF EXISTS last_used_at,
+    DROP COLUMN IF EXISTS device_name;
+DROP TABLE IF EXISTS app_user;
@@ -0,0 +1,42 @@
+-- Dashboard accounts, device credential metadata and OTP audit log
+
+CREATE TABLE app_user (
+    id            SERIAL PRIMARY KEY,
+    username      TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    role          TEXT NOT NULL CHECK (role IN ('admin', 'supervisor')),
+    supervisor_id INTEGER REFERENCES supervisor (supervisor_id) ON DELETE CASCADE,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (role <> 'supervisor' OR supervisor_id IS NOT NULL)
+);
+
+-- secret_code now holds a SHA-256 hash; plain secrets from before pairing was
+-- completed can never match, so they are revoked rather than kept around
+ALTER TABLE authorized_devices
+    ADD COLUMN device_name  TEXT NOT NULL DEFAULT '',
+    ADD COLUMN last_used_at TIMESTAMPTZ,
+    ADD COLUMN revoked_at   TIMESTAMPTZ;
+
+UPDATE authorized_devices SET revoked_at = now() WHERE length(secret_code) <> 64;
+
+CREATE UNIQUE INDEX authorized_devices_secret_code_idx ON authorized_devices (secret_code);
+
+-- At most one unused code per value, so a code identifies a single student
+DELETE FROM otps WHERE expires_at <= now();
+UPDATE otps SET is_used = true
+WHERE is_used = false
+  AND id NOT IN (SELECT MAX(id) FROM otps WHERE is_used = false GROUP BY otp_code);
+
+CREATE UNIQUE INDEX otps_active_code_idx ON otps (otp_code) WHERE is_used = false;
+
+CREATE TABLE otp_failed_attempts (
+    id             SERIAL PRIMARY KEY,
+    attempted_code TEXT NOT NULL,
+    student_id     INTEGER REFERENCES student (id) ON DELETE SET NULL,
+    client_ip      TEXT NOT NULL DEFAULT '',
+    device_name    TEXT NOT NULL DEFAULT '',
+    reason         TEXT NOT NULL,
+    attempted_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX otp_failed_attempts_attempted_at_idx ON otp_failed_attempts (attempted_at);
@@ -0,0 +1,7 @@
+ALTER TABLE attendance DROP COLUMN IF EXISTS check_out_distance_m;
+ALTER TABLE attendance DROP COLUMN IF EXISTS check_out_inside_geofence;
+ALTER TABLE attendance DROP COLUMN IF EXISTS check_in_distance_m;
+ALTER TABLE attendance DROP COLUMN IF EXISTS check_in_inside_geofence;
+
+ALTER TABLE employer DROP COLUMN IF EXISTS geofence_polygon;
+ALTER TABLE employer DROP COLUMN IF EXISTS geofence_radius_m;
@@ -0,0 +1,11 @@
+-- Each employer gets a geofence: a circle around addr_lat/addr_long, or a
+-- polygon when geofence_polygon holds at least three {"lat","long"} points
+ALTER TABLE employer ADD COLUMN IF NOT EXISTS geofence_radius_m DOUBLE PRECISION NOT NULL DEFAULT 200;
+ALTER TABLE employer ADD COLUMN IF NOT EXISTS geofence_polygon JSONB;
+
+-- Where each check-in and check-out was relative to the geofence. NULL when
+-- the student had no employer at the time.
+ALTER TABLE attendance ADD COLUMN IF NOT EXISTS check_in_inside_geofence BOOLEAN;
+ALTER TABLE attendance ADD COLUMN IF NOT EXISTS check_in_distance_m INTEGER;
+ALTER TABLE attendance ADD COLUMN IF NOT EXISTS check_out_inside_geofence BOOLEAN;
+ALTER TABLE attendance ADD COLUMN IF NOT EXISTS check_out_distance_m INTEGER;
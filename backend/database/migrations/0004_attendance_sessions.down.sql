@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS attendance_open_session_idx;
+
+UPDATE attendance SET check_in_date_time = '0001-01-01T00:00:00Z' WHERE check_in_date_time IS NULL;
+ALTER TABLE attendance DROP COLUMN IF EXISTS orphan_check_out;
+ALTER TABLE attendance ALTER COLUMN check_in_date_time SET NOT NULL;
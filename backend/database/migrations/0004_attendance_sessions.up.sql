@@ -0,0 +1,14 @@
+-- Attendance rows are sessions: a check-in opens one and the next check-out
+-- closes it. A check-out without an open session is stored with a NULL
+-- check-in and flagged instead of inventing a check-in time.
+ALTER TABLE attendance ALTER COLUMN check_in_date_time DROP NOT NULL;
+ALTER TABLE attendance ADD COLUMN IF NOT EXISTS orphan_check_out BOOLEAN NOT NULL DEFAULT false;
+
+-- Earlier versions stored orphan check-outs with a zero check-in timestamp
+UPDATE attendance
+SET check_in_date_time = NULL, orphan_check_out = true
+WHERE check_in_date_time < '1971-01-01';
+
+CREATE INDEX IF NOT EXISTS attendance_open_session_idx
+    ON attendance (student_id, check_in_date_time)
+    WHERE check_out_date_time IS NULL;
@@ -0,0 +1,2 @@
+-- IANA zone used to decide which day a check-in belongs to
+ALTER TABLE employer ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'Asia/Colombo';
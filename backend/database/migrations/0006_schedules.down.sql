@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS schedule_override;
+DROP TABLE IF EXISTS schedule_day;
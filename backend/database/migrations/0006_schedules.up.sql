@@ -0,0 +1,21 @@
+-- Weekly shift templates: one row per working weekday (0 = Sunday). Times
+-- are HH:MM wall-clock times in the employer's timezone; an end at or before
+-- the start means the shift runs past midnight.
+CREATE TABLE IF NOT EXISTS schedule_day (
+    student_id INTEGER NOT NULL REFERENCES student (id) ON DELETE CASCADE,
+    weekday    SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    start_time TEXT NOT NULL,
+    end_time   TEXT NOT NULL,
+    PRIMARY KEY (student_id, weekday)
+);
+
+-- Date-specific changes that win over the weekly template
+CREATE TABLE IF NOT EXISTS schedule_override (
+    student_id INTEGER NOT NULL REFERENCES student (id) ON DELETE CASCADE,
+    date       DATE NOT NULL,
+    day_off    BOOLEAN NOT NULL DEFAULT false,
+    start_time TEXT NOT NULL DEFAULT '',
+    end_time   TEXT NOT NULL DEFAULT '',
+    reason     TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (student_id, date)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE attendance DROP COLUMN IF EXISTS minutes_early;
+ALTER TABLE attendance DROP COLUMN IF EXISTS minutes_late;
+ALTER TABLE attendance DROP COLUMN IF EXISTS punctuality;
+ALTER TABLE employer DROP COLUMN IF EXISTS early_leave_grace_minutes;
+ALTER TABLE employer DROP COLUMN IF EXISTS late_grace_minutes;
@@ -0,0 +1,8 @@
+-- Grace periods in minutes; NULL uses the server defaults
+ALTER TABLE employer ADD COLUMN IF NOT EXISTS late_grace_minutes INTEGER;
+ALTER TABLE employer ADD COLUMN IF NOT EXISTS early_leave_grace_minutes INTEGER;
+
+-- Punctuality of each session against the expected shift, computed by the server
+ALTER TABLE attendance ADD COLUMN IF NOT EXISTS punctuality TEXT NOT NULL DEFAULT '';
+ALTER TABLE attendance ADD COLUMN IF NOT EXISTS minutes_late INTEGER;
+ALTER TABLE attendance ADD COLUMN IF NOT EXISTS minutes_early INTEGER;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS attendance_absence_idx;
+DELETE FROM attendance WHERE absent;
+ALTER TABLE attendance DROP COLUMN IF EXISTS auto_closed;
+ALTER TABLE attendance DROP COLUMN IF EXISTS expected_start;
+ALTER TABLE attendance DROP COLUMN IF EXISTS absent;
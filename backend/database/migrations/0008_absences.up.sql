@@ -0,0 +1,11 @@
+-- Absences are attendance rows without check-in or check-out, recorded by
+-- the background job when a scheduled shift passes with no session
+ALTER TABLE attendance ADD COLUMN IF NOT EXISTS absent BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE attendance ADD COLUMN IF NOT EXISTS expected_start TIMESTAMPTZ;
+-- Sessions closed by the job instead of a check-out
+ALTER TABLE attendance ADD COLUMN IF NOT EXISTS auto_closed BOOLEAN NOT NULL DEFAULT false;
+
+-- One absence per shift, whichever replica records it first
+CREATE UNIQUE INDEX IF NOT EXISTS attendance_absence_idx
+    ON attendance (student_id, expected_start)
+    WHERE absent;
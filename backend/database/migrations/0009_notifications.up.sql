@@ -0,0 +1,20 @@
+-- Outgoing notifications double as the delivery queue and its history
+CREATE TABLE IF NOT EXISTS notification (
+    id              SERIAL PRIMARY KEY,
+    event           TEXT NOT NULL,
+    student_id      INTEGER REFERENCES student (id) ON DELETE SET NULL,
+    channel         TEXT NOT NULL,
+    recipient       TEXT NOT NULL,
+    subject         TEXT NOT NULL DEFAULT '',
+    body            TEXT NOT NULL,
+    status          TEXT NOT NULL DEFAULT 'queued',
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    last_error      TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    sent_at         TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS notification_due_idx
+    ON notification (next_attempt_at)
+    WHERE status = 'queued';
@@ -0,0 +1,22 @@
+-- SOS incidents raised from the mobile app and how they were handled
+CREATE TABLE IF NOT EXISTS incident (
+    id              SERIAL PRIMARY KEY,
+    student_id      INTEGER NOT NULL REFERENCES student (id) ON DELETE CASCADE,
+    latitude        DOUBLE PRECISION NOT NULL,
+    longitude       DOUBLE PRECISION NOT NULL,
+    accuracy_m      DOUBLE PRECISION,
+    message         TEXT NOT NULL DEFAULT '',
+    status          TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'acknowledged', 'resolved')),
+    raised_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    located_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    acknowledged_at TIMESTAMPTZ,
+    acknowledged_by INTEGER REFERENCES app_user (id) ON DELETE SET NULL,
+    resolved_at     TIMESTAMPTZ,
+    resolved_by     INTEGER REFERENCES app_user (id) ON DELETE SET NULL,
+    resolution      TEXT NOT NULL DEFAULT ''
+);
+
+-- One unresolved incident per student, so repeated SOS presses update it
+CREATE UNIQUE INDEX IF NOT EXISTS incident_unresolved_idx
+    ON incident (student_id)
+    WHERE status <> 'resolved';
@@ -0,0 +1,11 @@
+DELETE FROM emergency_contact WHERE retired_at IS NOT NULL OR employer_id IS NOT NULL OR supervisor_id IS NOT NULL;
+
+DROP INDEX IF EXISTS emergency_contact_current_idx;
+
+ALTER TABLE emergency_contact
+    DROP CONSTRAINT IF EXISTS emergency_contact_one_scope,
+    DROP COLUMN IF EXISTS employer_id,
+    DROP COLUMN IF EXISTS supervisor_id,
+    DROP COLUMN IF EXISTS changed_by,
+    DROP COLUMN IF EXISTS retired_at,
+    DROP COLUMN IF EXISTS retired_by;
@@ -0,0 +1,18 @@
+-- Emergency contacts are versioned instead of replaced: changing one retires
+-- the row in force and inserts its successor. A contact applies to everyone,
+-- or overrides it for one employer or one supervisor.
+ALTER TABLE emergency_contact
+    ADD COLUMN employer_id   INTEGER REFERENCES employer (id) ON DELETE CASCADE,
+    ADD COLUMN supervisor_id INTEGER REFERENCES supervisor (supervisor_id) ON DELETE CASCADE,
+    ADD COLUMN changed_by    INTEGER REFERENCES app_user (id) ON DELETE SET NULL,
+    ADD COLUMN retired_at    TIMESTAMPTZ,
+    ADD COLUMN retired_by    INTEGER REFERENCES app_user (id) ON DELETE SET NULL,
+    ADD CONSTRAINT emergency_contact_one_scope CHECK (employer_id IS NULL OR supervisor_id IS NULL);
+
+-- Replace kept a single row, but retire any strays so the index below holds
+UPDATE emergency_contact SET retired_at = now()
+WHERE id <> (SELECT MAX(id) FROM emergency_contact);
+
+CREATE UNIQUE INDEX emergency_contact_current_idx
+    ON emergency_contact (COALESCE(employer_id, 0), COALESCE(supervisor_id, 0))
+    WHERE retired_at IS NULL;
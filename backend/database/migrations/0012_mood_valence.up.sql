@@ -0,0 +1,11 @@
+-- Each mood keeps the valence of its emotion at the time it was recorded, so
+-- changing the vocabulary later does not rewrite history. Emotions outside
+-- the default vocabulary are kept unscored.
+ALTER TABLE mood ADD COLUMN valence INTEGER;
+
+UPDATE mood SET emotion = lower(trim(emotion));
+UPDATE mood SET valence = CASE emotion
+    WHEN 'happy' THEN 1
+    WHEN 'neutral' THEN 0
+    WHEN 'sad' THEN -1
+END;
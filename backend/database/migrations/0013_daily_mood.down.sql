@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS mood_daily_idx;
+
+ALTER TABLE mood
+    DROP COLUMN IF EXISTS local_date,
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,28 @@
+-- A student has at most one daily mood per local day. local_date is the day
+-- in the employer's timezone when the mood was recorded; created_at bounds
+-- how long the daily mood may still be replaced.
+ALTER TABLE mood
+    ADD COLUMN local_date DATE,
+    ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN updated_at TIMESTAMPTZ;
+
+UPDATE mood SET created_at = recorded_at;
+
+-- Students without an employer fall back to the default DEFAULT_TIMEZONE
+UPDATE mood m
+SET local_date = (m.recorded_at AT TIME ZONE COALESCE(e.timezone, 'Asia/Colombo'))::date
+FROM student s
+LEFT JOIN employer e ON e.id = s.employer_id
+WHERE s.id = m.student_id AND m.is_daily;
+
+-- Of several daily moods on one day the latest stays daily; the others are
+-- kept as ad-hoc moods
+UPDATE mood SET is_daily = false, local_date = NULL
+WHERE is_daily AND id NOT IN (
+    SELECT DISTINCT ON (student_id, local_date) id
+    FROM mood
+    WHERE is_daily
+    ORDER BY student_id, local_date, recorded_at DESC, id DESC
+);
+
+CREATE UNIQUE INDEX mood_daily_idx ON mood (student_id, local_date) WHERE is_daily;
@@ -0,0 +1,21 @@
+-- Structured feedback from workplace managers, submitted through the API or
+-- imported from the old Google Sheet
+CREATE TABLE IF NOT EXISTS manager_feedback (
+    id             SERIAL PRIMARY KEY,
+    student_id     INTEGER NOT NULL REFERENCES student (id) ON DELETE CASCADE,
+    employer_id    INTEGER REFERENCES employer (id) ON DELETE SET NULL,
+    author_id      INTEGER REFERENCES app_user (id) ON DELETE SET NULL,
+    author_name    TEXT NOT NULL DEFAULT '',
+    period_start   DATE NOT NULL,
+    period_end     DATE NOT NULL,
+    -- {"competency": rating} with ratings from 1 to 5
+    ratings        JSONB NOT NULL DEFAULT '{}',
+    comments       TEXT NOT NULL DEFAULT '',
+    source         TEXT NOT NULL DEFAULT 'api' CHECK (source IN ('api', 'sheet')),
+    external_ref   TEXT UNIQUE,
+    submitted_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (period_start <= period_end)
+);
+
+CREATE INDEX IF NOT EXISTS manager_feedback_student_idx ON manager_feedback (student_id, period_end DESC);
+CREATE INDEX IF NOT EXISTS manager_feedback_employer_idx ON manager_feedback (employer_id, period_end DESC);
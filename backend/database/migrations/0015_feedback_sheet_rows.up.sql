@@ -0,0 +1,14 @@
+-- The last pulled copy of every row of the manager feedback Google Sheet,
+-- so each pull only rewrites feedback whose row changed
+CREATE TABLE IF NOT EXISTS feedback_sheet_row (
+    ref           TEXT PRIMARY KEY,
+    row_number    INTEGER NOT NULL,
+    cells         JSONB NOT NULL DEFAULT '{}',
+    content_hash  TEXT NOT NULL,
+    student_id    INTEGER REFERENCES student (id) ON DELETE SET NULL,
+    feedback_id   INTEGER REFERENCES manager_feedback (id) ON DELETE SET NULL,
+    error         TEXT NOT NULL DEFAULT '',
+    first_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    removed_at    TIMESTAMPTZ
+);
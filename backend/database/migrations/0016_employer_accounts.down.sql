@@ -0,0 +1,13 @@
+ALTER TABLE attendance
+    DROP COLUMN reviewed_at,
+    DROP COLUMN reviewed_by,
+    DROP COLUMN review_note,
+    DROP COLUMN review_status;
+
+DELETE FROM app_user WHERE role = 'employer';
+
+ALTER TABLE app_user
+    DROP CONSTRAINT app_user_employer_check,
+    DROP CONSTRAINT app_user_role_check,
+    ADD CONSTRAINT app_user_role_check CHECK (role IN ('admin', 'supervisor')),
+    DROP COLUMN employer_id;
@@ -0,0 +1,14 @@
+-- Employer accounts log in to the dashboard and only reach the trainees
+-- placed with their employer
+ALTER TABLE app_user
+    ADD COLUMN employer_id INTEGER REFERENCES employer (id) ON DELETE CASCADE,
+    DROP CONSTRAINT app_user_role_check,
+    ADD CONSTRAINT app_user_role_check CHECK (role IN ('admin', 'supervisor', 'employer')),
+    ADD CONSTRAINT app_user_employer_check CHECK (role <> 'employer' OR employer_id IS NOT NULL);
+
+-- Employers confirm or dispute the sessions their trainees record
+ALTER TABLE attendance
+    ADD COLUMN review_status TEXT NOT NULL DEFAULT '' CHECK (review_status IN ('', 'confirmed', 'disputed')),
+    ADD COLUMN review_note   TEXT NOT NULL DEFAULT '',
+    ADD COLUMN reviewed_by   INTEGER REFERENCES app_user (id) ON DELETE SET NULL,
+    ADD COLUMN reviewed_at   TIMESTAMPTZ;
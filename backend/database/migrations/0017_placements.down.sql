@@ -0,0 +1,2 @@
+ALTER TABLE attendance DROP COLUMN IF EXISTS placement_id;
+DROP TABLE IF EXISTS placement;
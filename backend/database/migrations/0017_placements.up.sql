@@ -0,0 +1,35 @@
+-- Where each student worked and when. The open placement (no end_date)
+-- mirrors student.employer_id.
+CREATE TABLE IF NOT EXISTS placement (
+    id          SERIAL PRIMARY KEY,
+    student_id  INTEGER NOT NULL REFERENCES student (id) ON DELETE CASCADE,
+    employer_id INTEGER REFERENCES employer (id) ON DELETE SET NULL,
+    role        TEXT NOT NULL DEFAULT '',
+    start_date  DATE NOT NULL,
+    end_date    DATE,
+    end_reason  TEXT NOT NULL DEFAULT '',
+    -- [{"weekday": 1, "start": "08:30", "end": "16:30"}]
+    schedule    JSONB NOT NULL DEFAULT '[]',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (end_date IS NULL OR end_date >= start_date)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS placement_open_idx ON placement (student_id) WHERE end_date IS NULL;
+CREATE INDEX IF NOT EXISTS placement_employer_idx ON placement (employer_id, start_date DESC);
+
+ALTER TABLE attendance ADD COLUMN IF NOT EXISTS placement_id INTEGER REFERENCES placement (id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS attendance_placement_idx ON attendance (placement_id);
+
+-- Every student placed today gets an open placement from their first
+-- session, and all of their sessions are tagged with it; earlier employers
+-- were never recorded
+INSERT INTO placement (student_id, employer_id, start_date, schedule)
+SELECT s.id, s.employer_id,
+       COALESCE((SELECT MIN(COALESCE(a.check_in_date_time, a.check_out_date_time, a.expected_start))::date
+                 FROM attendance a WHERE a.student_id = s.id), CURRENT_DATE),
+       COALESCE((SELECT jsonb_agg(jsonb_build_object('weekday', d.weekday, 'start', d.start_time, 'end', d.end_time) ORDER BY d.weekday)
+                 FROM schedule_day d WHERE d.student_id = s.id), '[]')
+FROM student s
+WHERE s.employer_id IS NOT NULL;
+
+UPDATE attendance a SET placement_id = p.id FROM placement p WHERE p.student_id = a.student_id;
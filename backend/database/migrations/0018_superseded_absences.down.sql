@@ -0,0 +1,6 @@
+DELETE FROM attendance WHERE superseded_at IS NOT NULL;
+DROP INDEX IF EXISTS attendance_absence_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS attendance_absence_idx
+    ON attendance (student_id, expected_start)
+    WHERE absent;
+ALTER TABLE attendance DROP COLUMN IF EXISTS superseded_at;
@@ -0,0 +1,9 @@
+-- Absences replaced by a late check-in are kept and marked instead of
+-- deleted, so the record of the job's decision survives
+ALTER TABLE attendance ADD COLUMN IF NOT EXISTS superseded_at TIMESTAMPTZ;
+
+-- Only the live absence of a shift has to be unique
+DROP INDEX IF EXISTS attendance_absence_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS attendance_absence_idx
+    ON attendance (student_id, expected_start)
+    WHERE absent AND superseded_at IS NULL;
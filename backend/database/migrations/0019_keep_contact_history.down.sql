@@ -0,0 +1,6 @@
+DELETE FROM emergency_contact c
+WHERE (c.employer_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM employer e WHERE e.id = c.employer_id))
+   OR (c.supervisor_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM supervisor s WHERE s.supervisor_id = c.supervisor_id));
+ALTER TABLE emergency_contact
+    ADD CONSTRAINT emergency_contact_employer_id_fkey FOREIGN KEY (employer_id) REFERENCES employer (id) ON DELETE CASCADE,
+    ADD CONSTRAINT emergency_contact_supervisor_id_fkey FOREIGN KEY (supervisor_id) REFERENCES supervisor (supervisor_id) ON DELETE CASCADE;
@@ -0,0 +1,6 @@
+-- Deleting an employer or supervisor used to cascade to every version of
+-- their emergency contact override. The override is now retired by the
+-- delete instead, and its history keeps the id of the deleted scope.
+ALTER TABLE emergency_contact
+    DROP CONSTRAINT IF EXISTS emergency_contact_employer_id_fkey,
+    DROP CONSTRAINT IF EXISTS emergency_contact_supervisor_id_fkey;
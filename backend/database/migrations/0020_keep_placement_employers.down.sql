@@ -0,0 +1,3 @@
+ALTER TABLE placement
+    DROP CONSTRAINT IF EXISTS placement_employer_id_fkey,
+    ADD CONSTRAINT placement_employer_id_fkey FOREIGN KEY (employer_id) REFERENCES employer (id) ON DELETE SET NULL;
@@ -0,0 +1,6 @@
+-- Deleting an employer used to null the employer of every placement with
+-- it, erasing where trainees had worked. Employers with placements can no
+-- longer be deleted.
+ALTER TABLE placement
+    DROP CONSTRAINT IF EXISTS placement_employer_id_fkey,
+    ADD CONSTRAINT placement_employer_id_fkey FOREIGN KEY (employer_id) REFERENCES employer (id) ON DELETE RESTRICT;
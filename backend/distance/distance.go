@@ -0,0 +1,93 @@
+// Package distance estimates how far apart two coordinates are by road
+package distance
+
+import (
+	"context"
+	"log"
+	"math"
+	"server/config"
+	"server/models"
+	"strings"
+	"time"
+)
+
+// Provider returns the routing distance in metres between two points
+type Provider interface {
+	Distance(ctx context.Context, from, to models.GeoPoint) (int, error)
+}
+
+// Haversine returns the straight-line distance in metres between two points
+func Haversine(lat1, lon1, lat2, lon2 float64) int {
+	const R = 6371000 // Earth radius in meters
+	toRad := func(deg float64) float64 { return deg * (math.Pi / 180) }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	lat1Rad := toRad(lat1)
+	lat2Rad := toRad(lat2)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1Rad)*math.Cos(lat2Rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+	return int(R * c)
+}
+
+// Offline estimates road distance as the straight-line distance scaled by a
+// detour factor. It never fails, so it is used in tests and as a fallback.
+type Offline struct {
+	DetourFactor float64
+}
+
+func (o Offline) Distance(_ context.Context, from, to models.GeoPoint) (int, error) {
+	return int(math.Round(float64(Haversine(from.Lat, from.Long, to.Lat, to.Long)) * o.DetourFactor)), nil
+}
+
+// fallback asks primary first and uses secondary when primary fails
+type fallback struct {
+	primary, secondary Provider
+}
+
+// WithFallback returns a provider that answers from secondary whenever primary fails
+func WithFallback(primary, secondary Provider) Provider {
+	return fallback{primary: primary, secondary: secondary}
+}
+
+func (f fallback) Distance(ctx context.Context, from, to models.GeoPoint) (int, error) {
+	d, err := f.primary.Distance(ctx, from, to)
+	if err == nil {
+		return d, nil
+	}
+	log.Printf("⚠️ Distance provider failed, using fallback: %v", err)
+	return f.secondary.Distance(ctx, from, to)
+}
+
+// FromEnv builds the provider selected by DISTANCE_PROVIDER ("google" or
+// "offline"). It defaults to Google, falling back to the offline estimate,
+// when GOOGLE_MAPS_API_KEY is set and to the offline estimate otherwise.
+func FromEnv() Provider {
+	// Roads are never shorter than the straight line, nor wildly longer
+	offline := Offline{DetourFactor: min(max(config.Float("DISTANCE_DETOUR_FACTOR", 1.3), 1), 3)}
+	apiKey := config.String("GOOGLE_MAPS_API_KEY", "")
+
+	def := "offline"
+	if apiKey != "" {
+		def = "google"
+	}
+	switch name := strings.ToLower(config.String("DISTANCE_PROVIDER", def)); name {
+	case "google":
+		if apiKey == "" {
+			log.Printf("⚠️ DISTANCE_PROVIDER=google but GOOGLE_MAPS_API_KEY is not set, using offline distances")
+			return offline
+		}
+		return WithFallback(NewGoogle(GoogleConfig{
+			APIKey:   apiKey,
+			Timeout:  config.Duration("GOOGLE_DISTANCE_TIMEOUT", defaultGoogleTimeout),
+			Retries:  config.Int("GOOGLE_DISTANCE_RETRIES", 2),
+			CacheTTL: config.Duration("GOOGLE_DISTANCE_CACHE_TTL", 24*time.Hour),
+		}), offline)
+	case "offline":
+		return offline
+	default:
+		log.Printf("⚠️ Unknown DISTANCE_PROVIDER=%q, using offline distances", name)
+		return offline
+	}
+}
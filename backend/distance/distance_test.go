@@ -0,0 +1,138 @@
+package distance
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"server/models"
+)
+
+var (
+	colombo = models.GeoPoint{Lat: 6.9271, Long: 79.8612}
+	kandy   = models.GeoPoint{Lat: 7.2906, Long: 80.6337}
+)
+
+func TestHaversine(t *testing.T) {
+	// Colombo to Kandy is roughly 94 km as the crow flies
+	if d := Haversine(colombo.Lat, colombo.Long, kandy.Lat, kandy.Long); d < 93000 || d > 95000 {
+		t.Errorf("Haversine = %d, want about 94000", d)
+	}
+	if d := Haversine(colombo.Lat, colombo.Long, colombo.Lat, colombo.Long); d != 0 {
+		t.Errorf("distance to self = %d, want 0", d)
+	}
+}
+
+func TestOfflineAppliesDetourFactor(t *testing.T) {
+	straight := Haversine(colombo.Lat, colombo.Long, kandy.Lat, kandy.Long)
+	d, err := Offline{DetourFactor: 1.5}.Distance(context.Background(), colombo, kandy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := float64(straight) * 1.5; float64(d) < want-1 || float64(d) > want+1 {
+		t.Errorf("Distance = %d, want %v", d, want)
+	}
+}
+
+// routesServer fails the first failures requests with a 503 and then returns
+// metres as the route distance
+func routesServer(t *testing.T, failures int32, metres int, condition string) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if r.Header.Get("X-Goog-Api-Key") != "secret-key" {
+			t.Errorf("missing API key header")
+		}
+		if strings.Contains(r.URL.String(), "secret-key") {
+			t.Errorf("API key leaked into URL %s", r.URL)
+		}
+		if n <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"originIndex": 0, "destinationIndex": 0, "distanceMeters": metres, "condition": condition},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestSettingsAreClamped(t *testing.T) {
+	t.Setenv("DISTANCE_PROVIDER", "offline")
+	t.Setenv("DISTANCE_DETOUR_FACTOR", "-2")
+	if o, ok := FromEnv().(Offline); !ok || o.DetourFactor != 1 {
+		t.Errorf("offline provider = %+v, want a detour factor of 1", o)
+	}
+
+	g := NewGoogle(GoogleConfig{APIKey: "secret-key", Retries: -1})
+	if g.client.Timeout != defaultGoogleTimeout || g.cfg.Retries != 0 {
+		t.Errorf("timeout %v and %d retries, want %v and 0", g.client.Timeout, g.cfg.Retries, defaultGoogleTimeout)
+	}
+	g = NewGoogle(GoogleConfig{APIKey: "secret-key", Timeout: time.Hour, Retries: 1000})
+	if g.client.Timeout != maxGoogleTimeout || g.cfg.Retries != maxGoogleRetries {
+		t.Errorf("timeout %v and %d retries, want %v and %d", g.client.Timeout, g.cfg.Retries, maxGoogleTimeout, maxGoogleRetries)
+	}
+}
+
+func TestGoogleRetriesAndCaches(t *testing.T) {
+	server, calls := routesServer(t, 1, 115000, "ROUTE_EXISTS")
+	g := NewGoogle(GoogleConfig{APIKey: "secret-key", Endpoint: server.URL, Timeout: time.Second, Retries: 2, CacheTTL: time.Hour})
+
+	for i := 0; i < 2; i++ {
+		d, err := g.Distance(context.Background(), colombo, kandy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d != 115000 {
+			t.Errorf("Distance = %d, want 115000", d)
+		}
+	}
+	if n := atomic.LoadInt32(calls); n != 2 {
+		t.Errorf("server called %d times, want one failure, one success and then the cache", n)
+	}
+}
+
+func TestGoogleGivesUpAfterRetries(t *testing.T) {
+	server, calls := routesServer(t, 10, 0, "ROUTE_EXISTS")
+	g := NewGoogle(GoogleConfig{APIKey: "secret-key", Endpoint: server.URL, Timeout: time.Second, Retries: 1})
+
+	if _, err := g.Distance(context.Background(), colombo, kandy); err == nil {
+		t.Fatal("expected an error")
+	}
+	if n := atomic.LoadInt32(calls); n != 2 {
+		t.Errorf("server called %d times, want 2", n)
+	}
+}
+
+func TestGoogleDoesNotRetryMissingRoute(t *testing.T) {
+	server, calls := routesServer(t, 0, 0, "ROUTE_NOT_FOUND")
+	g := NewGoogle(GoogleConfig{APIKey: "secret-key", Endpoint: server.URL, Timeout: time.Second, Retries: 3})
+
+	if _, err := g.Distance(context.Background(), colombo, kandy); err == nil {
+		t.Fatal("expected an error")
+	}
+	if n := atomic.LoadInt32(calls); n != 1 {
+		t.Errorf("server called %d times, want 1", n)
+	}
+}
+
+func TestFallbackUsesSecondaryOnError(t *testing.T) {
+	server, _ := routesServer(t, 10, 0, "ROUTE_EXISTS")
+	primary := NewGoogle(GoogleConfig{APIKey: "secret-key", Endpoint: server.URL, Timeout: time.Second})
+	p := WithFallback(primary, Offline{DetourFactor: 1})
+
+	d, err := p.Distance(context.Background(), colombo, kandy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := Haversine(colombo.Lat, colombo.Long, kandy.Lat, kandy.Long); d != want {
+		t.Errorf("Distance = %d, want the offline estimate %d", d, want)
+	}
+}
@@ -0,0 +1,200 @@
+package distance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"server/models"
+	"sync"
+	"time"
+)
+
+const googleRouteMatrixURL = "https://routes.googleapis.com/distanceMatrix/v2:computeRouteMatrix"
+
+// maxCacheEntries bounds the memory used by cached distances
+const maxCacheEntries = 10000
+
+// Limits applied by NewGoogle
+const (
+	defaultGoogleTimeout = 5 * time.Second
+	maxGoogleTimeout     = 30 * time.Second
+	maxGoogleRetries     = 5
+)
+
+// GoogleConfig configures the Google Routes API provider
+type GoogleConfig struct {
+	APIKey string
+	// Endpoint overrides the Routes API URL, for tests
+	Endpoint string
+	// Timeout bounds each HTTP attempt, defaulting to defaultGoogleTimeout
+	// and at most maxGoogleTimeout
+	Timeout time.Duration
+	// Retries is how many times a failed attempt is repeated, between 0 and
+	// maxGoogleRetries
+	Retries int
+	// CacheTTL is how long a distance is reused; zero disables caching
+	CacheTTL time.Duration
+}
+
+// Google asks the Routes API for driving distances. The API key travels in a
+// request header so it never appears in URLs or in logged errors.
+type Google struct {
+	cfg    GoogleConfig
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[cacheKey]cacheEntry
+	now   func() time.Time
+}
+
+// cacheKey rounds coordinates to about a metre so nearby lookups share results
+type cacheKey struct {
+	fromLat, fromLong, toLat, toLong int64
+}
+
+type cacheEntry struct {
+	metres  int
+	expires time.Time
+}
+
+// errNoRoute is not retried since asking again will not find a route
+var errNoRoute = errors.New("no route found")
+
+func NewGoogle(cfg GoogleConfig) *Google {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = googleRouteMatrixURL
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultGoogleTimeout
+	}
+	cfg.Timeout = min(cfg.Timeout, maxGoogleTimeout)
+	cfg.Retries = min(max(cfg.Retries, 0), maxGoogleRetries)
+	return &Google{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		cache:  make(map[cacheKey]cacheEntry),
+		now:    time.Now,
+	}
+}
+
+func (g *Google) Distance(ctx context.Context, from, to models.GeoPoint) (int, error) {
+	key := cacheKey{round(from.Lat), round(from.Long), round(to.Lat), round(to.Long)}
+	if metres, ok := g.cached(key); ok {
+		return metres, nil
+	}
+
+	var err error
+	for attempt := 0; attempt <= g.cfg.Retries; attempt++ {
+		if attempt > 0 {
+			// Back off 200ms, 400ms, 800ms... between attempts
+			select {
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			case <-time.After(time.Duration(100<<attempt) * time.Millisecond):
+			}
+		}
+
+		var metres int
+		metres, err = g.fetch(ctx, from, to)
+		if err == nil {
+			g.store(key, metres)
+			return metres, nil
+		}
+		if errors.Is(err, errNoRoute) {
+			break
+		}
+	}
+	return 0, fmt.Errorf("google routes: %w", err)
+}
+
+func (g *Google) fetch(ctx context.Context, from, to models.GeoPoint) (int, error) {
+	waypoint := func(p models.GeoPoint) map[string]interface{} {
+		return map[string]interface{}{
+			"waypoint": map[string]interface{}{
+				"location": map[string]interface{}{
+					"latLng": map[string]float64{"latitude": p.Lat, "longitude": p.Long},
+				},
+			},
+		}
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"origins":      []interface{}{waypoint(from)},
+		"destinations": []interface{}{waypoint(to)},
+		"travelMode":   "DRIVE",
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Goog-Api-Key", g.cfg.APIKey)
+	req.Header.Set("X-Goog-FieldMask", "distanceMeters,condition")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var elements []struct {
+		DistanceMeters int    `json:"distanceMeters"`
+		Condition      string `json:"condition"`
+	}
+	if err := json.Unmarshal(payload, &elements); err != nil {
+		return 0, err
+	}
+	if len(elements) == 0 || elements[0].Condition != "ROUTE_EXISTS" {
+		return 0, errNoRoute
+	}
+	return elements[0].DistanceMeters, nil
+}
+
+func (g *Google) cached(key cacheKey) (int, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	entry, ok := g.cache[key]
+	if !ok || g.now().After(entry.expires) {
+		return 0, false
+	}
+	return entry.metres, true
+}
+
+func (g *Google) store(key cacheKey, metres int) {
+	if g.cfg.CacheTTL <= 0 {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.now()
+	if len(g.cache) >= maxCacheEntries {
+		for k, e := range g.cache {
+			if now.After(e.expires) {
+				delete(g.cache, k)
+			}
+		}
+		if len(g.cache) >= maxCacheEntries {
+			g.cache = make(map[cacheKey]cacheEntry)
+		}
+	}
+	g.cache[key] = cacheEntry{metres: metres, expires: now.Add(g.cfg.CacheTTL)}
+}
+
+func round(deg float64) int64 {
+	return int64(math.Round(deg * 1e5))
+}
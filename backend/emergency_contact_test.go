@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"server/models"
+)
+
+func TestEmergencyContactResolutionAndHistory(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.supervisedStudent("Chamari")
+	token := api.pairDevice(studentID)
+	var student models.Student
+	api.mustDo("GET", "/get-student", api.adminToken, studentHeader(studentID), nil, http.StatusOK, &student)
+	employerID, supervisorID := int(*student.EmployerID), int(*student.SupervisorID)
+
+	api.mustDo("GET", "/get-emergency-contact", token, nil, nil, http.StatusNotFound, nil)
+
+	update := func(phone string, employer, supervisor *int) {
+		t.Helper()
+		body := map[string]interface{}{"phone_number": phone, "employer_id": employer, "supervisor_id": supervisor}
+		api.mustDo("POST", "/update-emergency-contact", api.adminToken, nil, body, http.StatusOK, nil)
+	}
+	resolved := func() string {
+		t.Helper()
+		var c models.EmergencyContact
+		api.mustDo("GET", "/get-emergency-contact", token, nil, nil, http.StatusOK, &c)
+		return c.PhoneNumber
+	}
+
+	update("+94110000001", nil, nil)
+	update("+94110000002", nil, nil)
+	if got := resolved(); got != "+94110000002" {
+		t.Errorf("global contact = %s", got)
+	}
+	update("+94110000003", nil, &supervisorID)
+	if got := resolved(); got != "+94110000003" {
+		t.Errorf("with supervisor override got %s", got)
+	}
+	update("+94110000004", &employerID, nil)
+	if got := resolved(); got != "+94110000004" {
+		t.Errorf("with employer override got %s", got)
+	}
+
+	// Other trainees keep the global contact
+	other := api.pairDevice(api.createStudent("Lahiru"))
+	var c models.EmergencyContact
+	api.mustDo("GET", "/get-emergency-contact", other, nil, nil, http.StatusOK, &c)
+	if c.PhoneNumber != "+94110000002" {
+		t.Errorf("unassigned trainee got %s", c.PhoneNumber)
+	}
+
+	employerHeader := map[string]string{"employer-id": strconv.Itoa(employerID)}
+	api.mustDo("DELETE", "/delete-emergency-contact", api.adminToken, employerHeader, nil, http.StatusOK, nil)
+	api.mustDo("DELETE", "/delete-emergency-contact", api.adminToken, employerHeader, nil, http.StatusNotFound, nil)
+	api.mustDo("DELETE", "/delete-emergency-contact", api.adminToken, nil, nil, http.StatusBadRequest, nil)
+	if got := resolved(); got != "+94110000003" {
+		t.Errorf("after removing the employer override got %s", got)
+	}
+
+	var history []models.EmergencyContact
+	api.mustDo("GET", "/emergency-contact-history", api.adminToken, nil, nil, http.StatusOK, &history)
+	if len(history) != 4 {
+		t.Fatalf("history has %d versions, want 4", len(history))
+	}
+	first := history[len(history)-1]
+	if first.PhoneNumber != "+94110000001" || first.RetiredAt == nil || first.ChangedBy == nil || first.RetiredBy == nil {
+		t.Errorf("first version %+v, want retired with who changed it", first)
+	}
+	api.mustDo("GET", "/emergency-contact-history", api.adminToken, employerHeader, nil, http.StatusOK, &history)
+	if len(history) != 1 || history[0].RetiredAt == nil {
+		t.Errorf("employer history %+v, want one retired version", history)
+	}
+
+	api.mustDo("GET", "/emergency-contact-history", token, nil, nil, http.StatusForbidden, nil)
+
+	// Deleting the supervisor retires their override but keeps its history
+	supervisorHeader := map[string]string{"supervisor-id": strconv.Itoa(supervisorID)}
+	api.mustDo("DELETE", "/delete-supervisor", api.adminToken, supervisorHeader, nil, http.StatusNoContent, nil)
+	api.mustDo("GET", "/emergency-contact-history", api.adminToken, supervisorHeader, nil, http.StatusOK, &history)
+	if len(history) != 1 || history[0].PhoneNumber != "+94110000003" || history[0].RetiredAt == nil {
+		t.Errorf("deleted supervisor's history %+v, want one retired version", history)
+	}
+	if got := resolved(); got != "+94110000002" {
+		t.Errorf("after deleting the supervisor got %s", got)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"server/auth"
+	"server/models"
+	"server/notify"
+)
+
+// employerToken provisions a dashboard account for the employer and logs in
+func (api *testAPI) employerToken(username string, employerID int) string {
+	api.t.Helper()
+	const password = "correct horse battery staple"
+	api.mustDo("POST", "/create-user", api.adminToken, nil, models.CreateUserRequest{Username: username, Password: password, Role: "employer", EmployerID: &employerID}, http.StatusCreated, nil)
+	var session models.SessionResponse
+	api.mustDo("POST", "/login", "", nil, models.LoginRequest{Username: username, Password: password}, http.StatusOK, &session)
+	if session.Role != "employer" || session.EmployerID != employerID {
+		api.t.Fatalf("employer session = %+v", session)
+	}
+	return session.Token
+}
+
+func TestEmployerPortal(t *testing.T) {
+	api := newTestAPI(t)
+	placed := api.supervisedStudent("Chamari")
+	student, err := api.store.Students.Get(placed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	employerID := int(*student.EmployerID)
+	elsewhere := api.createStudent("Nimal")
+	api.assignEmployer(elsewhere, models.Employer{Name: "Cargills", Latitude: 6.9271, Longitude: 79.8612, GeofenceRadius: 100})
+
+	missing := 999
+	api.mustDo("POST", "/create-user", api.adminToken, nil, models.CreateUserRequest{Username: "nobody", Password: "long enough password", Role: "employer"}, http.StatusBadRequest, nil)
+	api.mustDo("POST", "/create-user", api.adminToken, nil, models.CreateUserRequest{Username: "nobody", Password: "long enough password", Role: "employer", EmployerID: &missing}, http.StatusBadRequest, nil)
+	token := api.employerToken("kingsbury", employerID)
+
+	var me auth.Principal
+	api.mustDo("GET", "/me", token, nil, nil, http.StatusOK, &me)
+	if me.Role != auth.RoleEmployer || me.EmployerID != employerID {
+		t.Errorf("me = %+v", me)
+	}
+	api.mustDo("GET", "/get-students", token, nil, nil, http.StatusForbidden, nil)
+	api.mustDo("GET", "/trainee-summary", token, studentHeader(placed), nil, http.StatusForbidden, nil)
+
+	placedToken := api.pairDevice(placed)
+	elsewhereToken := api.pairDevice(elsewhere)
+	checkIn := time.Now().UTC().Truncate(time.Second)
+	var session, other models.Attendance
+	api.attend(placedToken, true, checkIn, http.StatusOK, &session)
+	api.attend(elsewhereToken, true, checkIn, http.StatusOK, &other)
+	postMood(api, placedToken, "happy", true, checkIn)
+	api.deliver()
+
+	var cards []models.StudentCard
+	api.mustDo("GET", "/dashboard", token, nil, nil, http.StatusOK, &cards)
+	if len(cards) != 1 || cards[0].StudentID != int64(placed) || !cards[0].AttendedToday || cards[0].Emotion != "happy" {
+		t.Errorf("employer dashboard = %+v, want only the placed trainee", cards)
+	}
+	api.mustDo("GET", "/dashboard", api.adminToken, nil, nil, http.StatusOK, &cards)
+	if len(cards) != 2 {
+		t.Errorf("admin dashboard has %d cards, want 2", len(cards))
+	}
+	var rows []map[string]interface{}
+	api.mustDo("GET", "/management", token, nil, nil, http.StatusOK, &rows)
+	if len(rows) != 1 || rows[0]["student_id"] != float64(placed) {
+		t.Errorf("employer management = %+v", rows)
+	}
+
+	api.mustDo("GET", "/attendance-days", token, studentHeader(placed), nil, http.StatusOK, nil)
+	api.mustDo("GET", "/attendance-days", token, studentHeader(elsewhere), nil, http.StatusForbidden, nil)
+
+	review := func(id uint, status, note string) models.ReviewRequest {
+		return models.ReviewRequest{AttendanceID: int(id), Status: status, Note: note}
+	}
+	api.mustDo("POST", "/review-attendance", token, nil, review(session.ID, "approved", ""), http.StatusBadRequest, nil)
+	api.mustDo("POST", "/review-attendance", token, nil, review(session.ID, models.ReviewDisputed, " "), http.StatusBadRequest, nil)
+	api.mustDo("POST", "/review-attendance", token, nil, review(other.ID, models.ReviewConfirmed, ""), http.StatusForbidden, nil)
+	api.mustDo("POST", "/review-attendance", token, nil, review(9999, models.ReviewConfirmed, ""), http.StatusNotFound, nil)
+	api.mustDo("POST", "/review-attendance", api.adminToken, nil, review(session.ID, models.ReviewConfirmed, ""), http.StatusForbidden, nil)
+
+	var reviewed models.Attendance
+	api.mustDo("POST", "/review-attendance", token, nil, review(session.ID, models.ReviewConfirmed, ""), http.StatusOK, &reviewed)
+	if reviewed.ReviewStatus != models.ReviewConfirmed || reviewed.ReviewedBy == nil || reviewed.ReviewedAt == nil {
+		t.Errorf("confirmed = %+v", reviewed)
+	}
+	if got := api.deliver(); len(got) != 0 {
+		t.Errorf("confirming notified %+v", got)
+	}
+	api.mustDo("POST", "/review-attendance", token, nil, review(session.ID, models.ReviewDisputed, "Left at noon"), http.StatusOK, &reviewed)
+	if reviewed.ReviewStatus != models.ReviewDisputed || reviewed.ReviewNote != "Left at noon" {
+		t.Errorf("disputed = %+v", reviewed)
+	}
+	if got := api.deliver()[notify.ChannelEmail]; len(got) != 1 || got[0].Event != notify.AttendanceDisputed || got[0].To != "ruwani@example.com" {
+		t.Errorf("dispute emails = %+v", got)
+	}
+
+	var days []models.AttendanceDay
+	api.mustDo("GET", "/attendance-days", api.adminToken, studentHeader(placed), nil, http.StatusOK, &days)
+	if n := len(days); n == 0 || days[n-1].Sessions[0].ReviewStatus != models.ReviewDisputed {
+		t.Errorf("attendance days = %+v, want the dispute recorded", days)
+	}
+
+	var submitted models.ManagerFeedback
+	api.mustDo("POST", "/submit-feedback", token, studentHeader(placed), models.FeedbackRequest{
+		PeriodStart: "2025-01-06", PeriodEnd: "2025-01-12", Ratings: map[string]int{"punctuality": 3},
+	}, http.StatusCreated, &submitted)
+	if submitted.EmployerID == nil || *submitted.EmployerID != employerID || submitted.AuthorID == nil {
+		t.Errorf("submitted = %+v", submitted)
+	}
+	api.mustDo("POST", "/submit-feedback", token, studentHeader(elsewhere), models.FeedbackRequest{
+		PeriodStart: "2025-01-06", PeriodEnd: "2025-01-12", Comments: "Not mine",
+	}, http.StatusForbidden, nil)
+
+	var list []models.ManagerFeedback
+	api.mustDo("GET", "/employer-feedback", token, nil, nil, http.StatusOK, &list)
+	if len(list) != 1 || list[0].ID != submitted.ID {
+		t.Errorf("employer feedback = %+v", list)
+	}
+	api.mustDo("GET", "/employer-feedback", token, map[string]string{"employer-id": strconv.Itoa(employerID + 1)}, nil, http.StatusForbidden, nil)
+	api.mustDo("GET", "/student-feedback", token, studentHeader(placed), nil, http.StatusOK, &list)
+	if len(list) != 1 {
+		t.Errorf("student feedback = %+v", list)
+	}
+
+	// An employer with placements is kept; deleting one without closes its accounts
+	api.mustDo("DELETE", "/delete-employer", api.adminToken, map[string]string{"employer-id": strconv.Itoa(employerID)}, nil, http.StatusConflict, nil)
+	api.mustDo("GET", "/me", token, nil, nil, http.StatusOK, nil)
+	var unplaced models.Employer
+	api.mustDo("POST", "/create-employer", api.adminToken, nil, models.Employer{Name: "Galle Face Hotel", Latitude: 6.9271, Longitude: 79.8612, GeofenceRadius: 100}, http.StatusOK, &unplaced)
+	api.employerToken("gallefacehotel", int(unplaced.ID))
+	api.mustDo("DELETE", "/delete-employer", api.adminToken, map[string]string{"employer-id": strconv.Itoa(int(unplaced.ID))}, nil, http.StatusNoContent, nil)
+	api.mustDo("POST", "/login", "", nil, models.LoginRequest{Username: "gallefacehotel", Password: "correct horse battery staple"}, http.StatusUnauthorized, nil)
+}
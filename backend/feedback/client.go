@@ -0,0 +1,139 @@
+package feedback
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const sheetsURL = "https://sheets.googleapis.com/v4/spreadsheets/"
+
+// SheetClient reads cell values from a spreadsheet
+type SheetClient interface {
+	// Values returns the rows of rangeA1, e.g. "Sheet1!A1:Z500", without
+	// trailing empty rows or cells
+	Values(ctx context.Context, spreadsheetID, rangeA1 string) ([][]string, error)
+}
+
+// Range is a block of columns on one sheet, spanning every row
+type Range struct {
+	Sheet       string
+	FirstColumn string
+	LastColumn  string
+}
+
+var rangePattern = regexp.MustCompile(`^(?:(.+)!)?([A-Za-z]+)\d*:([A-Za-z]+)\d*$`)
+
+// ParseRange reads an A1 range such as "Sheet1!A:H". Row numbers are
+// ignored since pulls page through every row.
+func ParseRange(a1 string) (Range, error) {
+	m := rangePattern.FindStringSubmatch(strings.TrimSpace(a1))
+	if m == nil {
+		return Range{}, fmt.Errorf("invalid sheet range %q, expected e.g. Sheet1!A:Z", a1)
+	}
+	return Range{Sheet: strings.Trim(m[1], "'"), FirstColumn: strings.ToUpper(m[2]), LastColumn: strings.ToUpper(m[3])}, nil
+}
+
+// Rows returns the A1 notation for rows first..last, 1-based and inclusive
+func (r Range) Rows(first, last int) string {
+	a1 := r.FirstColumn + strconv.Itoa(first) + ":" + r.LastColumn + strconv.Itoa(last)
+	if r.Sheet != "" {
+		a1 = "'" + strings.ReplaceAll(r.Sheet, "'", "''") + "'!" + a1
+	}
+	return a1
+}
+
+// GoogleSheets reads values with the Sheets API v4. The API key travels in
+// a request header so it never appears in URLs or in logged errors.
+type GoogleSheets struct {
+	apiKey   string
+	endpoint string
+	client   *http.Client
+}
+
+// NewGoogleSheets returns a client authenticating with apiKey; endpoint
+// overrides the API URL, for tests
+func NewGoogleSheets(apiKey, endpoint string, timeout time.Duration) *GoogleSheets {
+	if endpoint == "" {
+		endpoint = sheetsURL
+	}
+	return &GoogleSheets{apiKey: apiKey, endpoint: endpoint, client: &http.Client{Timeout: timeout}}
+}
+
+func (g *GoogleSheets) Values(ctx context.Context, spreadsheetID, rangeA1 string) ([][]string, error) {
+	u := g.endpoint + url.PathEscape(spreadsheetID) + "/values/" + url.PathEscape(rangeA1)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Goog-Api-Key", g.apiKey)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return nil, fmt.Errorf("google sheets returned %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+	var body struct {
+		Values [][]string `json:"values"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding google sheets response: %w", err)
+	}
+	return body.Values, nil
+}
+
+// FakeSheet serves Rows, row 1 first, in place of a spreadsheet. It is
+// used in tests.
+type FakeSheet struct {
+	mu     sync.Mutex
+	rows   [][]string
+	ranges []string
+	Err    error
+}
+
+// SetRows replaces the sheet's contents
+func (f *FakeSheet) SetRows(rows [][]string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rows = rows
+}
+
+// Ranges returns every range requested so far
+func (f *FakeSheet) Ranges() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.ranges...)
+}
+
+var rowsPattern = regexp.MustCompile(`(\d+):[A-Za-z]+(\d+)$`)
+
+func (f *FakeSheet) Values(_ context.Context, _, rangeA1 string) ([][]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ranges = append(f.ranges, rangeA1)
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	m := rowsPattern.FindStringSubmatch(rangeA1)
+	if m == nil {
+		return nil, fmt.Errorf("range %q has no row bounds", rangeA1)
+	}
+	first, _ := strconv.Atoi(m[1])
+	last, _ := strconv.Atoi(m[2])
+	if first > len(f.rows) {
+		return nil, nil
+	}
+	return f.rows[first-1 : min(last, len(f.rows))], nil
+}
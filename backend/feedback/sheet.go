@@ -0,0 +1,312 @@
+// Package feedback maps rows of the manager feedback Google Sheet onto
+// students so they can be stored as structured manager feedback.
+package feedback
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"server/models"
+	"server/store"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sheet columns, matched case-insensitively. Either Timestamp or Date is
+// required, and either Student ID or Student Name.
+const (
+	ColumnTimestamp       = "timestamp"
+	ColumnDate            = "date"
+	ColumnStudentID       = "student id"
+	ColumnStudentName     = "student name"
+	ColumnDaysAttended    = "number of days attended this week"
+	ColumnOnTime          = "on time?"
+	ColumnWithoutPrompts  = "worked without prompts"
+	ColumnBehaviourChange = "change in behaviour noted?"
+	ColumnBehaviour       = "if yes, mention that behaviour"
+	ColumnComments        = "any other comments?"
+	ColumnManager         = "manager name"
+)
+
+// Competencies the sheet's yes/no answers are recorded under. Yes rates
+// models.FeedbackRatingMax and no models.FeedbackRatingMin.
+const (
+	CompetencyPunctuality  = "punctuality"
+	CompetencyIndependence = "independence"
+)
+
+// ErrNoStudent is returned for rows that name no known student, or more than one
+var ErrNoStudent = errors.New("no matching student")
+
+// timestampLayouts are the formats the sheet has used for its date column
+var timestampLayouts = []string{"1/2/2006 15:04:05", "1/2/2006", "2006-01-02 15:04:05", "2006-01-02"}
+
+// ParseColumns reads a comma-separated list of column=header pairs naming
+// the sheet header used for one of the columns above, e.g.
+// "student name=Trainee,timestamp=Submitted at"
+func ParseColumns(spec string) (map[string]string, error) {
+	columns := map[string]string{}
+	for _, pair := range strings.Split(spec, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		column, header, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(column) == "" || strings.TrimSpace(header) == "" {
+			return nil, fmt.Errorf("invalid column mapping %q, expected column=header", pair)
+		}
+		columns[normalize(column)] = header
+	}
+	return columns, nil
+}
+
+// Mapper turns sheet rows into feedback for the students it was built with
+type Mapper struct {
+	columns  map[string]int
+	students map[int]models.Student
+	byName   map[string][]models.Student
+	loc      *time.Location
+}
+
+// NewMapper reads the column positions from header, where columns renames
+// any of them (see ParseColumns). Timestamps without a zone are taken to be
+// in loc.
+func NewMapper(header []string, students []models.Student, loc *time.Location, columns map[string]string) (*Mapper, error) {
+	m := &Mapper{
+		columns:  map[string]int{},
+		students: map[int]models.Student{},
+		byName:   map[string][]models.Student{},
+		loc:      loc,
+	}
+	for i, h := range header {
+		m.columns[normalize(h)] = i
+	}
+	for column, h := range columns {
+		for i, name := range header {
+			if normalize(name) == normalize(h) {
+				m.columns[column] = i
+			}
+		}
+	}
+	if !m.has(ColumnTimestamp) && !m.has(ColumnDate) {
+		return nil, errors.New("sheet has neither a Timestamp nor a Date column")
+	}
+	if !m.has(ColumnStudentID) && !m.has(ColumnStudentName) {
+		return nil, errors.New("sheet has neither a Student ID nor a Student Name column")
+	}
+	for _, s := range students {
+		m.students[int(s.ID)] = s
+		full := normalize(s.FirstName + " " + s.LastName)
+		m.byName[full] = append(m.byName[full], s)
+		if first := normalize(s.FirstName); first != full {
+			m.byName[first] = append(m.byName[first], s)
+		}
+	}
+	return m, nil
+}
+
+func (m *Mapper) has(column string) bool {
+	_, ok := m.columns[column]
+	return ok
+}
+
+// cell returns the trimmed value of column in row, empty when absent
+func (m *Mapper) cell(row []string, column string) string {
+	i, ok := m.columns[column]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// Student finds the student a row is about: by Student ID when the sheet
+// has that column, otherwise by full name or, when unique, first name
+func (m *Mapper) Student(row []string) (*models.Student, error) {
+	if id := m.cell(row, ColumnStudentID); id != "" {
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid student ID %q", ErrNoStudent, id)
+		}
+		s, ok := m.students[n]
+		if !ok {
+			return nil, fmt.Errorf("%w: student %d", ErrNoStudent, n)
+		}
+		return &s, nil
+	}
+	name := m.cell(row, ColumnStudentName)
+	matches := m.byName[normalize(name)]
+	if len(matches) != 1 {
+		return nil, fmt.Errorf("%w: %q matches %d students", ErrNoStudent, name, len(matches))
+	}
+	return &matches[0], nil
+}
+
+// Map converts a row into feedback for its student. The period is the
+// week ending on the day the row was submitted.
+func (m *Mapper) Map(row []string) (*models.ManagerFeedback, error) {
+	student, err := m.Student(row)
+	if err != nil {
+		return nil, err
+	}
+	raw := m.cell(row, ColumnTimestamp)
+	if raw == "" {
+		raw = m.cell(row, ColumnDate)
+	}
+	submitted, err := parseTimestamp(raw, m.loc)
+	if err != nil {
+		return nil, err
+	}
+	end := time.Date(submitted.Year(), submitted.Month(), submitted.Day(), 0, 0, 0, 0, m.loc)
+
+	f := &models.ManagerFeedback{
+		StudentID:   int(student.ID),
+		AuthorName:  m.cell(row, ColumnManager),
+		PeriodStart: end.AddDate(0, 0, -6).Format("2006-01-02"),
+		PeriodEnd:   end.Format("2006-01-02"),
+		Ratings:     map[string]int{},
+		Source:      models.FeedbackSourceSheet,
+		ExternalRef: m.Ref(row),
+		SubmittedAt: submitted,
+	}
+	if student.EmployerID != nil {
+		id := int(*student.EmployerID)
+		f.EmployerID = &id
+	}
+	for column, competency := range map[string]string{ColumnOnTime: CompetencyPunctuality, ColumnWithoutPrompts: CompetencyIndependence} {
+		if rating, ok := yesNoRating(m.cell(row, column)); ok {
+			f.Ratings[competency] = rating
+		}
+	}
+
+	var comments []string
+	if days := m.cell(row, ColumnDaysAttended); days != "" {
+		comments = append(comments, "Days attended this week: "+days)
+	}
+	if behaviour := m.cell(row, ColumnBehaviour); behaviour != "" && isYes(m.cell(row, ColumnBehaviourChange)) {
+		comments = append(comments, "Change in behaviour: "+behaviour)
+	}
+	if other := m.cell(row, ColumnComments); other != "" {
+		comments = append(comments, other)
+	}
+	f.Comments = strings.Join(comments, "\n")
+	return f, nil
+}
+
+// Ref identifies a row by its timestamp and student, so importing the same
+// response twice is detected even after other cells were edited
+func (m *Mapper) Ref(row []string) string {
+	when := m.cell(row, ColumnTimestamp)
+	if when == "" {
+		when = m.cell(row, ColumnDate)
+	}
+	who := m.cell(row, ColumnStudentID)
+	if who == "" {
+		who = m.cell(row, ColumnStudentName)
+	}
+	return "sheet:" + hash(normalize(when), normalize(who))
+}
+
+// ContentHash changes whenever any cell of row does. Trailing empty cells,
+// which the Sheets API leaves out, do not count.
+func ContentHash(row []string) string {
+	cells := make([]string, len(row))
+	for i, c := range row {
+		cells[i] = strings.TrimSpace(c)
+	}
+	for len(cells) > 0 && cells[len(cells)-1] == "" {
+		cells = cells[:len(cells)-1]
+	}
+	return hash(cells...)
+}
+
+func hash(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x1f")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Report counts what an import did with each row
+type Report struct {
+	Imported   int `json:"imported"`
+	Duplicates int `json:"duplicates"`
+	// Skipped describes every row that could not be imported, by its
+	// 1-based row number in the sheet including the header
+	Skipped map[int]string `json:"skipped"`
+}
+
+// Import stores every row in rows, which follow the header row, as
+// feedback. Rows imported before are counted as duplicates; with dryRun
+// nothing is stored.
+func Import(st *store.Store, header []string, rows [][]string, loc *time.Location, columns map[string]string, dryRun bool) (*Report, error) {
+	students, err := st.Students.List()
+	if err != nil {
+		return nil, err
+	}
+	m, err := NewMapper(header, students, loc, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Skipped: map[int]string{}}
+	for i, row := range rows {
+		if blank(row) {
+			continue
+		}
+		f, err := m.Map(row)
+		if err != nil {
+			report.Skipped[i+2] = err.Error()
+			continue
+		}
+		if dryRun {
+			report.Imported++
+			continue
+		}
+		switch err := st.Feedback.Create(f); {
+		case errors.Is(err, store.ErrConflict):
+			report.Duplicates++
+		case err != nil:
+			return report, fmt.Errorf("row %d: %w", i+2, err)
+		default:
+			report.Imported++
+		}
+	}
+	return report, nil
+}
+
+func parseTimestamp(v string, loc *time.Location) (time.Time, error) {
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", v)
+}
+
+func yesNoRating(v string) (int, bool) {
+	switch strings.ToLower(v) {
+	case "yes", "y":
+		return models.FeedbackRatingMax, true
+	case "no", "n":
+		return models.FeedbackRatingMin, true
+	}
+	return 0, false
+}
+
+func isYes(v string) bool {
+	rating, ok := yesNoRating(v)
+	return ok && rating == models.FeedbackRatingMax
+}
+
+func blank(row []string) bool {
+	for _, c := range row {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// normalize lowercases s and collapses runs of whitespace
+func normalize(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
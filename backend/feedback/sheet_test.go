@@ -0,0 +1,83 @@
+package feedback
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"server/models"
+	"server/store"
+	"server/store/memory"
+)
+
+var header = []string{"Timestamp", "Student Name", "Number of days attended this week", "On time?", "Worked without prompts",
+	"Change in behaviour noted?", "If yes, mention that behaviour", "Any other comments?"}
+
+func TestImportMapsRowsOntoStudents(t *testing.T) {
+	st := memory.New()
+	employerID := uint(7)
+	for _, s := range []models.Student{
+		{FirstName: "Chamari", LastName: "Perera", EmployerID: &employerID},
+		{FirstName: "Kasun", LastName: "Silva"},
+		{FirstName: "Kasun", LastName: "Fernando"},
+	} {
+		if err := st.Students.Create(&s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	colombo, _ := time.LoadLocation("Asia/Colombo")
+	rows := [][]string{
+		{"1/12/2025 17:30:00", " chamari  perera ", "5", "Yes", "No", "Yes", "Quieter than usual", "Great week"},
+		{"1/19/2025", "Chamari", "4", "no", "yes", "No", "ignored", ""},
+		{"1/19/2025", "Kasun", "5", "Yes", "Yes", "No", "", ""},
+		{"", "", "", "", "", "", "", ""},
+		{"not a date", "Kasun Silva", "5", "Yes", "Yes", "No", "", ""},
+	}
+
+	report, err := Import(st, header, rows, colombo, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Imported != 2 || report.Duplicates != 0 || len(report.Skipped) != 2 || report.Skipped[4] == "" || report.Skipped[6] == "" {
+		t.Errorf("report = %+v", report)
+	}
+
+	chamari := 1
+	list, _ := st.Feedback.List(store.FeedbackQuery{StudentID: &chamari})
+	if len(list) != 2 {
+		t.Fatalf("imported %d rows for Chamari, want 2", len(list))
+	}
+	week := list[1]
+	if week.PeriodStart != "2025-01-06" || week.PeriodEnd != "2025-01-12" || week.EmployerID == nil || *week.EmployerID != 7 ||
+		week.Ratings[CompetencyPunctuality] != models.FeedbackRatingMax || week.Ratings[CompetencyIndependence] != models.FeedbackRatingMin ||
+		week.Comments != "Days attended this week: 5\nChange in behaviour: Quieter than usual\nGreat week" || week.Source != models.FeedbackSourceSheet {
+		t.Errorf("first week = %+v", week)
+	}
+	if list[0].Comments != "Days attended this week: 4" {
+		t.Errorf("behaviour without a noted change was kept: %q", list[0].Comments)
+	}
+
+	// Importing again only finds duplicates
+	report, err = Import(st, header, rows, colombo, nil, false)
+	if err != nil || report.Imported != 0 || report.Duplicates != 2 {
+		t.Errorf("second import = %+v, %v", report, err)
+	}
+}
+
+func TestMapperByStudentID(t *testing.T) {
+	students := []models.Student{{ID: 3, FirstName: "Chamari"}}
+	m, err := NewMapper([]string{"Date", "Trainee", "On time?"}, students, time.UTC, map[string]string{ColumnStudentID: "trainee"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := m.Map([]string{"2025-01-12", "3", "yes"})
+	if err != nil || f.StudentID != 3 {
+		t.Errorf("Map = %+v, %v", f, err)
+	}
+	if _, err := m.Map([]string{"2025-01-12", "4", "yes"}); !errors.Is(err, ErrNoStudent) {
+		t.Errorf("unknown student: %v", err)
+	}
+	if _, err := NewMapper([]string{"Student Name"}, students, time.UTC, nil); err == nil {
+		t.Error("sheet without a date column was accepted")
+	}
+}
@@ -0,0 +1,247 @@
+package feedback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"server/config"
+	"server/models"
+	"server/store"
+	"strconv"
+	"time"
+)
+
+// SyncConfig says which sheet to pull and how to read it
+type SyncConfig struct {
+	SpreadsheetID string
+	Range         Range
+	// PageRows is how many rows each request asks for
+	PageRows int
+	// Columns renames sheet columns, see ParseColumns
+	Columns map[string]string
+	// Location is the zone of timestamps without one
+	Location *time.Location
+}
+
+// Syncer mirrors the feedback sheet into the feedback store. Each pull
+// records every row it sees and only rewrites feedback whose row changed.
+type Syncer struct {
+	store  *store.Store
+	client SheetClient
+	cfg    SyncConfig
+}
+
+func NewSyncer(st *store.Store, client SheetClient, cfg SyncConfig) *Syncer {
+	if cfg.PageRows <= 0 {
+		cfg.PageRows = 500
+	}
+	return &Syncer{store: st, client: client, cfg: cfg}
+}
+
+// SyncFromEnv builds a syncer for FEEDBACK_SHEET_ID and FEEDBACK_SHEET_RANGE
+// read with GOOGLE_SHEET_API_KEY. It returns nil when no sheet is configured.
+func SyncFromEnv(st *store.Store, loc *time.Location) (*Syncer, error) {
+	id := config.String("FEEDBACK_SHEET_ID", "")
+	if id == "" {
+		return nil, nil
+	}
+	apiKey := config.String("GOOGLE_SHEET_API_KEY", "")
+	if apiKey == "" {
+		return nil, errors.New("FEEDBACK_SHEET_ID is set but GOOGLE_SHEET_API_KEY is not")
+	}
+	r, err := ParseRange(config.String("FEEDBACK_SHEET_RANGE", "Sheet1!A:Z"))
+	if err != nil {
+		return nil, err
+	}
+	columns, err := ParseColumns(config.String("FEEDBACK_SHEET_COLUMNS", ""))
+	if err != nil {
+		return nil, err
+	}
+	client := NewGoogleSheets(apiKey, "", config.Duration("FEEDBACK_SHEET_TIMEOUT", 30*time.Second))
+	return NewSyncer(st, client, SyncConfig{
+		SpreadsheetID: id,
+		Range:         r,
+		PageRows:      config.Int("FEEDBACK_SHEET_PAGE_ROWS", 500),
+		Columns:       columns,
+		Location:      loc,
+	}), nil
+}
+
+// fetch returns every row of the sheet, header first, a page at a time
+// until a page comes back empty
+func (s *Syncer) fetch(ctx context.Context) ([][]string, error) {
+	var rows [][]string
+	for first := 1; ; first += s.cfg.PageRows {
+		page, err := s.client.Values(ctx, s.cfg.SpreadsheetID, s.cfg.Range.Rows(first, first+s.cfg.PageRows-1))
+		if err != nil {
+			return nil, err
+		}
+		if len(page) == 0 {
+			return rows, nil
+		}
+		// Pages leave out trailing empty rows, so pad to keep row numbers
+		for len(rows) < first-1 {
+			rows = append(rows, nil)
+		}
+		rows = append(rows, page...)
+	}
+}
+
+// Sync pulls the sheet and brings stored feedback in line with it: new rows
+// are added and edited rows update their feedback. Stored feedback is never
+// deleted; rows that disappeared are only marked removed, and rows that no
+// longer match a student keep the feedback they were stored as.
+func (s *Syncer) Sync(ctx context.Context, now time.Time) (*models.FeedbackSyncReport, error) {
+	rows, err := s.fetch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reading feedback sheet: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("feedback sheet has no header row")
+	}
+	students, err := s.store.Students.List()
+	if err != nil {
+		return nil, err
+	}
+	header := rows[0]
+	m, err := NewMapper(header, students, s.cfg.Location, s.cfg.Columns)
+	if err != nil {
+		return nil, err
+	}
+	pulled, err := s.store.FeedbackSheet.List()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]models.FeedbackSheetRow, len(pulled))
+	for _, r := range pulled {
+		known[r.Ref] = r
+	}
+
+	report := &models.FeedbackSyncReport{}
+	seen := map[string]bool{}
+	for i, row := range rows[1:] {
+		if blank(row) {
+			continue
+		}
+		report.Rows++
+		ref := m.Ref(row)
+		// Identical timestamps and students are told apart by order
+		for n := 2; seen[ref]; n++ {
+			ref = m.Ref(row) + "#" + strconv.Itoa(n)
+		}
+		seen[ref] = true
+
+		r := models.FeedbackSheetRow{Ref: ref, RowNumber: i + 2, Cells: cells(header, row), ContentHash: ContentHash(row)}
+		previous, existed := known[ref]
+		// Unmatched rows are retried, since their student may have been added
+		if existed && previous.RemovedAt == nil && previous.ContentHash == r.ContentHash && previous.Error == "" {
+			report.Unchanged++
+			if previous.RowNumber != r.RowNumber {
+				previous.RowNumber = r.RowNumber
+				if err := s.store.FeedbackSheet.Put(&previous); err != nil {
+					return report, err
+				}
+			}
+			continue
+		}
+		if existed {
+			r.FeedbackID = previous.FeedbackID
+		}
+		if err := s.apply(m, row, &r); err != nil {
+			return report, fmt.Errorf("row %d: %w", r.RowNumber, err)
+		}
+		if err := s.store.FeedbackSheet.Put(&r); err != nil {
+			return report, err
+		}
+		switch {
+		case r.Error != "":
+			report.Unmatched++
+		case existed && previous.RemovedAt == nil && previous.FeedbackID != nil:
+			report.Updated++
+		default:
+			report.Added++
+		}
+	}
+
+	for _, previous := range pulled {
+		if seen[previous.Ref] || previous.RemovedAt != nil {
+			continue
+		}
+		removedAt := now
+		previous.RemovedAt = &removedAt
+		if err := s.store.FeedbackSheet.Put(&previous); err != nil {
+			return report, err
+		}
+		report.Removed++
+	}
+	return report, nil
+}
+
+// apply stores row as the feedback r points to, or records why it cannot.
+// Feedback already stored from r is left as it was in that case.
+func (s *Syncer) apply(m *Mapper, row []string, r *models.FeedbackSheetRow) error {
+	f, err := m.Map(row)
+	if err != nil {
+		r.Error = err.Error()
+		r.StudentID = nil
+		return nil
+	}
+	r.StudentID = &f.StudentID
+	f.ExternalRef = r.Ref
+	if r.FeedbackID != nil {
+		f.ID = *r.FeedbackID
+		err := s.store.Feedback.Update(f)
+		if !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+	}
+	err = s.store.Feedback.Create(f)
+	if errors.Is(err, store.ErrConflict) {
+		// Imported before the sheet was synced
+		existing, err := s.store.Feedback.ByExternalRef(f.ExternalRef)
+		if err != nil {
+			return err
+		}
+		f.ID = existing.ID
+		if err := s.store.Feedback.Update(f); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	r.FeedbackID = &f.ID
+	return nil
+}
+
+// Run pulls the sheet every interval until ctx is cancelled
+func (s *Syncer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report, err := s.Sync(ctx, time.Now())
+		if err != nil {
+			log.Printf("⚠️ Feedback sheet sync failed: %v", err)
+		} else if report.Added+report.Updated+report.Removed > 0 {
+			log.Printf("Feedback sheet sync: %d added, %d updated, %d removed, %d unmatched", report.Added, report.Updated, report.Removed, report.Unmatched)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// cells maps each header to the row's value
+func cells(header, row []string) map[string]string {
+	c := make(map[string]string, len(header))
+	for i, h := range header {
+		if i < len(row) {
+			c[h] = row[i]
+		} else {
+			c[h] = ""
+		}
+	}
+	return c
+}
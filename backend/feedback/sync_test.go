@@ -0,0 +1,146 @@
+package feedback
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"server/models"
+	"server/store"
+	"server/store/memory"
+)
+
+// sheetRow is a response submitted minute minutes after 17:00 on day
+func sheetRow(day, minute int, student, onTime, comments string) []string {
+	at := time.Date(2025, 1, day, 17, minute, 0, 0, time.UTC)
+	return []string{at.Format("1/2/2006 15:04:05"), student, "5", onTime, "Yes", "No", "", comments}
+}
+
+func TestSyncPagesAndDetectsChanges(t *testing.T) {
+	st := memory.New()
+	chamari := models.Student{FirstName: "Chamari", LastName: "Perera"}
+	if err := st.Students.Create(&chamari); err != nil {
+		t.Fatal(err)
+	}
+	// 250 responses, far past the old 100-row limit
+	rows := [][]string{header}
+	for i := 0; i < 250; i++ {
+		rows = append(rows, sheetRow(1+i%28, i, "Chamari Perera", "Yes", fmt.Sprintf("Response %d", i)))
+	}
+	rows = append(rows, sheetRow(3, 0, "Kasun", "No", "Unknown trainee"))
+	sheet := &FakeSheet{}
+	sheet.SetRows(rows)
+	r, _ := ParseRange("Form Responses 1!A1:H100")
+	syncer := NewSyncer(st, sheet, SyncConfig{SpreadsheetID: "sheet", Range: r, PageRows: 100, Location: time.UTC})
+
+	report, err := syncer.Sync(context.Background(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Rows != 251 || report.Added != 250 || report.Unmatched != 1 {
+		t.Errorf("first sync = %+v", report)
+	}
+	if ranges := sheet.Ranges(); len(ranges) != 4 || ranges[0] != "'Form Responses 1'!A1:H100" || ranges[3] != "'Form Responses 1'!A301:H400" {
+		t.Errorf("requested ranges %q", ranges)
+	}
+	all, _ := st.Feedback.List(store.FeedbackQuery{})
+	if len(all) != 250 {
+		t.Fatalf("stored %d feedback, want 250", len(all))
+	}
+
+	// Edit one response, delete another and add the missing trainee
+	rows[1] = sheetRow(1, 0, "Chamari Perera", "No", "Response 0, corrected")
+	rows = append(rows[:3], rows[4:]...)
+	sheet.SetRows(rows)
+	kasun := models.Student{FirstName: "Kasun", LastName: "Silva"}
+	if err := st.Students.Create(&kasun); err != nil {
+		t.Fatal(err)
+	}
+	report, err = syncer.Sync(context.Background(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Updated != 1 || report.Removed != 1 || report.Added != 1 || report.Unchanged != 248 || report.Unmatched != 0 {
+		t.Errorf("second sync = %+v", report)
+	}
+	// The deleted response's feedback is kept
+	all, _ = st.Feedback.List(store.FeedbackQuery{})
+	if len(all) != 251 {
+		t.Errorf("stored %d feedback after the second sync, want 251", len(all))
+	}
+	kasunID := int(kasun.ID)
+	if list, _ := st.Feedback.List(store.FeedbackQuery{StudentID: &kasunID}); len(list) != 1 || list[0].Ratings[CompetencyPunctuality] != models.FeedbackRatingMin {
+		t.Errorf("Kasun's feedback = %+v", list)
+	}
+
+	report, err = syncer.Sync(context.Background(), time.Now())
+	if err != nil || report.Unchanged != 250 || report.Added+report.Updated+report.Removed != 0 {
+		t.Errorf("third sync = %+v, %v", report, err)
+	}
+}
+
+func TestSyncAdoptsImportedFeedback(t *testing.T) {
+	st := memory.New()
+	if err := st.Students.Create(&models.Student{FirstName: "Chamari", LastName: "Perera"}); err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]string{header, sheetRow(12, 0, "Chamari Perera", "Yes", "Great week")}
+	if _, err := Import(st, header, rows[1:], time.UTC, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	rows[1][7] = "Great week, edited"
+	sheet := &FakeSheet{}
+	sheet.SetRows(rows)
+	r, _ := ParseRange("Sheet1!A:H")
+	report, err := NewSyncer(st, sheet, SyncConfig{SpreadsheetID: "sheet", Range: r, Location: time.UTC}).Sync(context.Background(), time.Now())
+	if err != nil || report.Added != 1 {
+		t.Fatalf("sync = %+v, %v", report, err)
+	}
+	all, _ := st.Feedback.List(store.FeedbackQuery{})
+	if len(all) != 1 || all[0].Comments != "Days attended this week: 5\nGreat week, edited" {
+		t.Errorf("feedback = %+v", all)
+	}
+}
+
+func TestSyncNeverDeletesFeedback(t *testing.T) {
+	st := memory.New()
+	chamari := models.Student{FirstName: "Chamari", LastName: "Perera"}
+	if err := st.Students.Create(&chamari); err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]string{header, sheetRow(6, 0, "Chamari Perera", "Yes", "First"), sheetRow(13, 0, "Chamari Perera", "Yes", "Second")}
+	sheet := &FakeSheet{}
+	sheet.SetRows(rows)
+	r, _ := ParseRange("Sheet1!A:H")
+	syncer := NewSyncer(st, sheet, SyncConfig{SpreadsheetID: "sheet", Range: r, Location: time.UTC})
+	if report, err := syncer.Sync(context.Background(), time.Now()); err != nil || report.Added != 2 {
+		t.Fatalf("first sync = %+v, %v", report, err)
+	}
+
+	// A row that stops matching a student keeps its feedback
+	rows[1] = sheetRow(6, 0, "Chamari Perera", "Yes", "First, edited")
+	rows[1][1] = "Chamari Fernando"
+	sheet.SetRows(rows)
+	if report, err := syncer.Sync(context.Background(), time.Now()); err != nil || report.Unmatched != 1 {
+		t.Fatalf("sync after renaming = %+v, %v", report, err)
+	}
+	if all, _ := st.Feedback.List(store.FeedbackQuery{}); len(all) != 2 {
+		t.Errorf("stored %d feedback after renaming, want 2", len(all))
+	}
+
+	// Clearing the sheet down to its header marks the rows removed
+	sheet.SetRows(rows[:1])
+	if report, err := syncer.Sync(context.Background(), time.Now()); err != nil || report.Removed != 2 {
+		t.Fatalf("sync after clearing = %+v, %v", report, err)
+	}
+	if all, _ := st.Feedback.List(store.FeedbackQuery{}); len(all) != 2 {
+		t.Errorf("stored %d feedback after clearing the sheet, want 2", len(all))
+	}
+	pulled, _ := st.FeedbackSheet.List()
+	for _, row := range pulled {
+		if row.RemovedAt == nil {
+			t.Errorf("row %s not marked removed", row.Ref)
+		}
+	}
+}
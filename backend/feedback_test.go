@@ -0,0 +1,135 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"server/feedback"
+	"server/models"
+)
+
+func TestManagerFeedback(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.supervisedStudent("Chamari")
+	student, err := api.store.Students.Get(studentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	employerHeader := map[string]string{"employer-id": strconv.Itoa(int(*student.EmployerID))}
+
+	var competencies struct {
+		Competencies []string `json:"competencies"`
+		RatingMax    int      `json:"rating_max"`
+	}
+	api.mustDo("GET", "/feedback-competencies", api.adminToken, nil, nil, http.StatusOK, &competencies)
+	if len(competencies.Competencies) != 5 || competencies.Competencies[0] != "punctuality" || competencies.RatingMax != 5 {
+		t.Errorf("competencies = %+v", competencies)
+	}
+
+	for name, bad := range map[string]models.FeedbackRequest{
+		"unknown competency": {PeriodStart: "2025-01-06", PeriodEnd: "2025-01-12", Ratings: map[string]int{"juggling": 3}},
+		"rating too high":    {PeriodStart: "2025-01-06", PeriodEnd: "2025-01-12", Ratings: map[string]int{"punctuality": 6}},
+		"reversed period":    {PeriodStart: "2025-01-12", PeriodEnd: "2025-01-06", Comments: "Fine"},
+		"empty":              {PeriodStart: "2025-01-06", PeriodEnd: "2025-01-12"},
+	} {
+		if status, body := api.do("POST", "/submit-feedback", api.adminToken, studentHeader(studentID), bad); status != http.StatusBadRequest {
+			t.Errorf("%s: status %d (%s), want 400", name, status, body)
+		}
+	}
+
+	supervisorToken := api.supervisorToken("ruwani", int(*student.SupervisorID))
+	var first models.ManagerFeedback
+	api.mustDo("POST", "/submit-feedback", supervisorToken, studentHeader(studentID), models.FeedbackRequest{
+		PeriodStart: "2025-01-06", PeriodEnd: "2025-01-12", AuthorName: "Mr Silva",
+		Ratings: map[string]int{"Punctuality": 4, "teamwork": 5}, Comments: "Helpful with customers",
+	}, http.StatusCreated, &first)
+	if first.StudentID != studentID || first.EmployerID == nil || *first.EmployerID != int(*student.EmployerID) ||
+		first.Ratings["punctuality"] != 4 || first.Source != models.FeedbackSourceAPI || first.AuthorID == nil {
+		t.Errorf("submitted %+v", first)
+	}
+	api.mustDo("POST", "/submit-feedback", api.adminToken, studentHeader(studentID), models.FeedbackRequest{
+		PeriodStart: "2025-01-13", PeriodEnd: "2025-01-19", Ratings: map[string]int{"punctuality": 2},
+	}, http.StatusCreated, nil)
+
+	var list []models.ManagerFeedback
+	api.mustDo("GET", "/student-feedback", api.adminToken, studentHeader(studentID), nil, http.StatusOK, &list)
+	if len(list) != 2 || list[0].PeriodEnd != "2025-01-19" || list[1].ID != first.ID {
+		t.Errorf("student feedback = %+v", list)
+	}
+	api.mustDo("GET", "/student-feedback?to=2025-01-12", api.adminToken, studentHeader(studentID), nil, http.StatusOK, &list)
+	if len(list) != 1 || list[0].ID != first.ID {
+		t.Errorf("feedback up to 12 January = %+v", list)
+	}
+	api.mustDo("GET", "/employer-feedback", supervisorToken, employerHeader, nil, http.StatusOK, &list)
+	if len(list) != 2 {
+		t.Errorf("employer feedback = %+v", list)
+	}
+
+	// Other supervisors neither see nor give feedback on the trainee
+	otherToken := api.supervisorToken("nimal", api.createSupervisor("Nimal"))
+	api.mustDo("GET", "/employer-feedback", otherToken, employerHeader, nil, http.StatusOK, &list)
+	if len(list) != 0 {
+		t.Errorf("other supervisor sees %+v", list)
+	}
+	api.mustDo("GET", "/student-feedback", otherToken, studentHeader(studentID), nil, http.StatusForbidden, nil)
+	api.mustDo("POST", "/submit-feedback", otherToken, studentHeader(studentID), models.FeedbackRequest{
+		PeriodStart: "2025-01-06", PeriodEnd: "2025-01-12", Comments: "Hello",
+	}, http.StatusForbidden, nil)
+
+	// Summaries average the ratings over the period
+	var summary models.TraineeSummary
+	api.mustDo("GET", "/trainee-summary?from=2025-01-01&to=2025-01-31", api.adminToken, studentHeader(studentID), nil, http.StatusOK, &summary)
+	if f := summary.Feedback; f.Count != 2 || f.AverageRatings["punctuality"] != 3 || len(f.Comments) != 1 {
+		t.Errorf("feedback summary = %+v", f)
+	}
+	if !strings.Contains(summary.Summary, "punctuality 3.0") || !strings.Contains(summary.Summary, "Helpful with customers") {
+		t.Errorf("summary %q lacks the feedback", summary.Summary)
+	}
+}
+
+func TestManagerFeedbackSheetSync(t *testing.T) {
+	api := newTestAPI(t)
+	api.mustDo("POST", "/sync-manager-feedback", api.adminToken, nil, nil, http.StatusNotFound, nil)
+
+	studentID := api.supervisedStudent("Chamari")
+	student, _ := api.store.Students.Get(studentID)
+	sheet := &feedback.FakeSheet{}
+	sheet.SetRows([][]string{
+		{"Timestamp", "Student Name", "On time?", "Any other comments?"},
+		{"1/12/2025 17:00:00", "Chamari Perera", "Yes", "Great week"},
+		{"1/12/2025 17:05:00", "Nobody", "No", ""},
+	})
+	r, _ := feedback.ParseRange("Sheet1!A:D")
+	api.handler.UseFeedbackSync(feedback.NewSyncer(api.store, sheet, feedback.SyncConfig{SpreadsheetID: "sheet", Range: r, Location: time.UTC}))
+
+	var report models.FeedbackSyncReport
+	api.mustDo("POST", "/sync-manager-feedback", api.adminToken, nil, nil, http.StatusOK, &report)
+	if report.Added != 1 || report.Unmatched != 1 {
+		t.Errorf("report = %+v", report)
+	}
+
+	var rows []map[string]string
+	api.mustDo("GET", "/manager-feedback", api.adminToken, nil, nil, http.StatusOK, &rows)
+	if len(rows) != 2 || rows[0]["Any other comments?"] != "Great week" {
+		t.Errorf("admin rows = %+v", rows)
+	}
+	// Supervisors only see rows matched to their trainees
+	api.mustDo("GET", "/manager-feedback", api.supervisorToken("ruwani", int(*student.SupervisorID)), nil, nil, http.StatusOK, &rows)
+	if len(rows) != 1 || rows[0]["Student Name"] != "Chamari Perera" {
+		t.Errorf("supervisor rows = %+v", rows)
+	}
+
+	var pulled []models.FeedbackSheetRow
+	api.mustDo("GET", "/feedback-sheet-rows", api.adminToken, nil, nil, http.StatusOK, &pulled)
+	if len(pulled) != 2 || pulled[0].FeedbackID == nil || pulled[1].Error == "" {
+		t.Errorf("sheet rows = %+v", pulled)
+	}
+	var list []models.ManagerFeedback
+	api.mustDo("GET", "/student-feedback", api.adminToken, studentHeader(studentID), nil, http.StatusOK, &list)
+	if len(list) != 1 || list[0].Source != models.FeedbackSourceSheet {
+		t.Errorf("student feedback = %+v", list)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"server/config"
+	"server/database"
+	"server/feedback"
+	"server/store/postgres"
+	"sort"
+	"time"
+)
+
+// runImportFeedbackCommand implements `main import-feedback -file sheet.csv`,
+// a one-off import of the manager feedback Google Sheet exported as CSV
+func runImportFeedbackCommand(args []string) {
+	fs := flag.NewFlagSet("import-feedback", flag.ExitOnError)
+	file := fs.String("file", "", "CSV export of the feedback sheet, header row first")
+	dryRun := fs.Bool("dry-run", false, "map every row without storing anything")
+	columnSpec := fs.String("columns", config.String("FEEDBACK_SHEET_COLUMNS", ""), "column=header pairs for sheet headers that differ from the defaults")
+	timezone := fs.String("timezone", config.String("DEFAULT_TIMEZONE", "Asia/Colombo"), "zone of the sheet's timestamps")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: main import-feedback -file sheet.csv [-dry-run] [-columns \"student name=Trainee\"] [-timezone Asia/Colombo]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *file == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	loc, err := time.LoadLocation(*timezone)
+	if err != nil {
+		log.Fatalf("❌ Unknown timezone %q: %v", *timezone, err)
+	}
+	columns, err := feedback.ParseColumns(*columnSpec)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("❌ Failed to open %s: %v", *file, err)
+	}
+	defer f.Close()
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		log.Fatalf("❌ Failed to read %s: %v", *file, err)
+	}
+	if len(records) == 0 {
+		log.Fatalf("❌ %s is empty", *file)
+	}
+
+	database.ConnectDB()
+	report, err := feedback.Import(postgres.New(database.DB), records[0], records[1:], loc, columns, *dryRun)
+	if err != nil {
+		log.Fatalf("❌ Import failed: %v", err)
+	}
+
+	rows := make([]int, 0, len(report.Skipped))
+	for row := range report.Skipped {
+		rows = append(rows, row)
+	}
+	sort.Ints(rows)
+	for _, row := range rows {
+		fmt.Printf("row %d skipped: %s\n", row, report.Skipped[row])
+	}
+	verb := "Imported"
+	if *dryRun {
+		verb = "Would import"
+	}
+	log.Printf("✅ %s %d row(s), %d already imported, %d skipped", verb, report.Imported, report.Duplicates, len(report.Skipped))
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"server/models"
+	"server/notify"
+)
+
+// supervisorToken provisions a dashboard account for the supervisor and logs in
+func (api *testAPI) supervisorToken(username string, supervisorID int) string {
+	api.t.Helper()
+	const password = "correct horse battery staple"
+	api.mustDo("POST", "/create-user", api.adminToken, nil, models.CreateUserRequest{Username: username, Password: password, Role: "supervisor", SupervisorID: &supervisorID}, http.StatusCreated, nil)
+	var session models.SessionResponse
+	api.mustDo("POST", "/login", "", nil, models.LoginRequest{Username: username, Password: password}, http.StatusOK, &session)
+	return session.Token
+}
+
+func sosRequest(lat, long float64, message string) map[string]interface{} {
+	return map[string]interface{}{"latitude": lat, "longitude": long, "message": message}
+}
+
+func TestSOSIncidentLifecycle(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.supervisedStudent("Chamari")
+	token := api.pairDevice(studentID)
+	api.mustDo("POST", "/update-emergency-contact", api.adminToken, nil, map[string]string{"phone_number": "+94112345678"}, http.StatusOK, nil)
+	api.deliver()
+
+	var raised models.Incident
+	api.mustDo("POST", "/sos", token, nil, sosRequest(6.93, 79.86, "Hurt my hand"), http.StatusCreated, &raised)
+	if raised.Status != models.IncidentOpen || raised.StudentID != studentID {
+		t.Fatalf("raised %+v", raised)
+	}
+
+	got := api.deliver()
+	if len(got[notify.ChannelEmail]) != 1 || got[notify.ChannelEmail][0].Event != notify.SOSRaised {
+		t.Errorf("supervisor emails: %+v", got[notify.ChannelEmail])
+	}
+	// The supervisor has no phone number, so only the emergency contact is texted
+	if sms := got[notify.ChannelSMS]; len(sms) != 1 || sms[0].To != "+94112345678" {
+		t.Errorf("SMS: %+v", sms)
+	}
+
+	// Pressing SOS again moves the same incident without alerting again
+	var again models.Incident
+	api.mustDo("POST", "/sos", token, nil, sosRequest(6.94, 79.87, ""), http.StatusOK, &again)
+	if again.ID != raised.ID || again.Latitude != 6.94 || again.Message != "Hurt my hand" {
+		t.Errorf("second SOS gave %+v, want incident %d moved", again, raised.ID)
+	}
+	if got := api.deliver(); len(got) != 0 {
+		t.Errorf("second SOS notified %+v", got)
+	}
+
+	var open []models.Incident
+	api.mustDo("GET", "/incidents", api.adminToken, nil, nil, http.StatusOK, &open)
+	if len(open) != 1 {
+		t.Fatalf("got %d unresolved incidents, want 1", len(open))
+	}
+
+	// Only the trainee's own supervisor may respond
+	other := api.supervisorToken("other", api.createSupervisor("Nadeesha"))
+	header := map[string]string{"incident-id": strconv.Itoa(raised.ID)}
+	api.mustDo("POST", "/acknowledge-incident", other, header, nil, http.StatusForbidden, nil)
+	api.mustDo("GET", "/incidents", other, nil, nil, http.StatusOK, &open)
+	if len(open) != 0 {
+		t.Errorf("another supervisor sees %d incidents", len(open))
+	}
+
+	var student models.Student
+	api.mustDo("GET", "/get-student", api.adminToken, studentHeader(studentID), nil, http.StatusOK, &student)
+	own := api.supervisorToken("own", int(*student.SupervisorID))
+	var acked models.Incident
+	api.mustDo("POST", "/acknowledge-incident", own, header, nil, http.StatusOK, &acked)
+	if acked.Status != models.IncidentAcknowledged || acked.AcknowledgedBy == nil {
+		t.Errorf("acknowledged %+v", acked)
+	}
+	api.mustDo("POST", "/acknowledge-incident", own, header, nil, http.StatusConflict, nil)
+
+	var current models.Incident
+	api.mustDo("GET", "/sos", token, nil, nil, http.StatusOK, &current)
+	if current.Status != models.IncidentAcknowledged {
+		t.Errorf("trainee sees status %q", current.Status)
+	}
+
+	api.mustDo("POST", "/resolve-incident", own, header, map[string]string{"resolution": ""}, http.StatusBadRequest, nil)
+	api.mustDo("POST", "/resolve-incident", own, header, map[string]string{"resolution": "First aid given on site"}, http.StatusOK, nil)
+	api.mustDo("POST", "/resolve-incident", own, header, map[string]string{"resolution": "Again"}, http.StatusConflict, nil)
+	api.mustDo("GET", "/sos", token, nil, nil, http.StatusNotFound, nil)
+	api.mustDo("GET", "/incidents", api.adminToken, nil, nil, http.StatusOK, &open)
+	if len(open) != 0 {
+		t.Errorf("got %d unresolved incidents after resolving", len(open))
+	}
+
+	// A new SOS after resolution opens a new incident
+	var next models.Incident
+	api.mustDo("POST", "/sos", token, nil, sosRequest(6.93, 79.86, ""), http.StatusCreated, &next)
+	if next.ID == raised.ID {
+		t.Errorf("SOS after resolution reused incident %d", raised.ID)
+	}
+}
+
+func TestSOSRequiresLocation(t *testing.T) {
+	api := newTestAPI(t)
+	token := api.pairDevice(api.createStudent("Lahiru"))
+	api.mustDo("POST", "/sos", token, nil, map[string]interface{}{"message": "help"}, http.StatusBadRequest, nil)
+	api.mustDo("POST", "/sos", api.adminToken, nil, sosRequest(6.93, 79.86, ""), http.StatusForbidden, nil)
+}
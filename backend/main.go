@@ -1,12 +1,22 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"net/http"
 	"os"
+	"server/auth"
+	"server/config"
 	"server/controllers"
 	"server/database"
+	"server/middleware"
+	"server/notify"
 	"server/routes"
+	"server/store"
+	"server/store/postgres"
+	"time"
+	_ "time/tzdata" // Employer timezones on images without zoneinfo
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
@@ -14,6 +24,18 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrateCommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import-feedback" {
+		runImportFeedbackCommand(os.Args[2:])
+		return
+	}
+
+	autoMigrate := flag.Bool("auto-migrate", config.Bool("AUTO_MIGRATE", false), "apply pending schema migrations on startup")
+	flag.Parse()
+
 	// Fetch port from environment (default to 8000 for Choreo)
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -23,7 +45,44 @@ func main() {
 	// Connect to DB with environment variables
 	database.ConnectDB()
 
-	// Define router
+	if *autoMigrate {
+		applied, err := database.MigrateUp(database.DB)
+		if err != nil {
+			log.Fatalf("❌ Failed to migrate database: %v", err)
+		}
+		log.Printf("✅ Database schema up to date (%d migration(s) applied)", len(applied))
+	}
+
+	// Session tokens are signed with AUTH_TOKEN_SECRET
+	auth.LoadSecretFromEnv()
+
+	stores := postgres.New(database.DB)
+	notifier := notify.FromEnv(stores.Notifications)
+	router, authService, handler := newRouter(stores, notifier)
+	if err := authService.EnsureAdmin(os.Getenv("ADMIN_USERNAME"), os.Getenv("ADMIN_PASSWORD")); err != nil {
+		log.Fatalf("❌ Failed to create bootstrap admin: %v", err)
+	}
+
+	// Absence detection and auto-close; ATTENDANCE_JOB_INTERVAL=0 disables them
+	if interval := config.Duration("ATTENDANCE_JOB_INTERVAL", 5*time.Minute); interval > 0 {
+		go handler.RunAttendanceJobs(context.Background(), interval)
+	}
+	// Manager feedback Google Sheet pulls; FEEDBACK_SYNC_INTERVAL=0 disables them
+	if interval := config.Duration("FEEDBACK_SYNC_INTERVAL", 15*time.Minute); interval > 0 {
+		go handler.RunFeedbackSync(context.Background(), interval)
+	}
+	// Notification delivery; NOTIFY_INTERVAL=0 disables it
+	if interval := config.Duration("NOTIFY_INTERVAL", 30*time.Second); interval > 0 {
+		go notifier.Run(context.Background(), interval)
+	}
+
+	// Start the server
+	log.Println("Server started on port", port)
+	log.Fatal(http.ListenAndServe(":"+port, router))
+}
+
+// newRouter wires every route against stores, reporting events to notifier
+func newRouter(stores *store.Store, notifier *notify.Service) (*mux.Router, *controllers.AuthService, *controllers.Handler) {
 	router := mux.NewRouter()
 
 	// CORS Setup with proper configuration
@@ -37,6 +96,7 @@ func main() {
 			"Test-Key",
 			"testkey",
 			"student-id",
+			"device-id",
 		}),
 		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "HEAD", "OPTIONS"}),
 		handlers.AllowedOrigins([]string{"*"}),
@@ -47,14 +107,13 @@ func main() {
 		handlers.MaxAge(86400), // 24 hours
 	)
 
-	authService := controllers.NewAuthService()
+	authService := controllers.NewAuthService(stores, notifier)
 	authService.RegisterRoutes(router)
 	router.Use(corsMiddleware)
+	router.Use(middleware.Authenticate)
 
 	// Register API routes
-	routes.RegisterStudentRoutes(router)
-
-	// Start the server
-	log.Println("Server started on port", port)
-	log.Fatal(http.ListenAndServe(":"+port, router))
+	handler := controllers.NewHandler(stores, notifier)
+	routes.RegisterStudentRoutes(router, handler, authService)
+	return router, authService, handler
 }
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"server/auth"
+	"server/controllers"
+	"server/database"
+	"server/models"
+	"server/notify"
+	"server/store"
+	"server/store/memory"
+	"server/store/postgres"
+)
+
+const (
+	testAdminUsername = "admin"
+	testAdminPassword = "correct horse battery"
+)
+
+func TestMain(m *testing.M) {
+	auth.SetSecret([]byte("test-secret"))
+	// Never call external services from tests
+	os.Setenv("DISTANCE_PROVIDER", "offline")
+	os.Setenv("SUMMARY_PROVIDER", "template")
+	if os.Getenv("TEST_VERBOSE_LOGS") == "" {
+		log.SetOutput(io.Discard)
+	}
+	os.Exit(m.Run())
+}
+
+// testAPI is the full router served over HTTP with an admin session
+type testAPI struct {
+	t          *testing.T
+	server     *httptest.Server
+	store      *store.Store
+	handler    *controllers.Handler
+	adminToken string
+
+	// clock, when set, is the server's time instead of the real one
+	clockMu sync.Mutex
+	clock   time.Time
+
+	// notifier delivers to fake channels keyed by channel name
+	notifier *notify.Service
+	outbox   map[string]*notify.Fake
+}
+
+// newTestAPI starts the router against an in-memory store, or against the
+// Postgres database in TEST_DATABASE_URL after migrating and emptying it
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+
+	stores := memory.New()
+	if url := os.Getenv("TEST_DATABASE_URL"); url != "" {
+		stores = newPostgresTestStore(t, url)
+	}
+
+	outbox := map[string]*notify.Fake{}
+	for _, name := range []string{notify.ChannelEmail, notify.ChannelSMS, notify.ChannelWebhook} {
+		outbox[name] = &notify.Fake{Channel: name}
+	}
+	notifier := notify.NewService(stores.Notifications, outbox[notify.ChannelEmail], outbox[notify.ChannelSMS], outbox[notify.ChannelWebhook])
+
+	router, authService, handler := newRouter(stores, notifier)
+	if err := authService.EnsureAdmin(testAdminUsername, testAdminPassword); err != nil {
+		t.Fatalf("creating admin: %v", err)
+	}
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	api := &testAPI{t: t, server: server, store: stores, handler: handler, notifier: notifier, outbox: outbox}
+	handler.UseClock(api.now)
+	var session models.SessionResponse
+	api.mustDo("POST", "/login", "", nil, models.LoginRequest{Username: testAdminUsername, Password: testAdminPassword}, http.StatusOK, &session)
+	api.adminToken = session.Token
+	return api
+}
+
+func newPostgresTestStore(t *testing.T, url string) *store.Store {
+	t.Helper()
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+	_, err = db.Exec(`TRUNCATE attendance, mood, otps, otp_failed_attempts, authorized_devices,
+		emergency_contact, notification, incident, feedback_sheet_row, manager_feedback, placement, app_user, student, employer, supervisor RESTART IDENTITY CASCADE`)
+	if err != nil {
+		t.Fatalf("emptying test database: %v", err)
+	}
+	return postgres.New(db)
+}
+
+// now is the server's clock
+func (api *testAPI) now() time.Time {
+	api.clockMu.Lock()
+	defer api.clockMu.Unlock()
+	if api.clock.IsZero() {
+		return time.Now()
+	}
+	return api.clock
+}
+
+// setClock stops the server's clock at t, or restarts it when t is zero
+func (api *testAPI) setClock(t time.Time) {
+	api.clockMu.Lock()
+	defer api.clockMu.Unlock()
+	api.clock = t
+}
+
+// do sends a request and returns the status code and raw body. body is
+// encoded as JSON unless it is nil.
+func (api *testAPI) do(method, path, token string, headers map[string]string, body interface{}) (int, []byte) {
+	api.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			api.t.Fatalf("encoding request body: %v", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(method, api.server.URL+path, reader)
+	if err != nil {
+		api.t.Fatalf("building request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := api.server.Client().Do(req)
+	if err != nil {
+		api.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		api.t.Fatalf("reading response: %v", err)
+	}
+	return resp.StatusCode, respBody
+}
+
+// mustDo is do that fails the test on an unexpected status and decodes the
+// response into out when it is not nil
+func (api *testAPI) mustDo(method, path, token string, headers map[string]string, body interface{}, wantStatus int, out interface{}) {
+	api.t.Helper()
+	status, respBody := api.do(method, path, token, headers, body)
+	if status != wantStatus {
+		api.t.Fatalf("%s %s: status %d, want %d (body %q)", method, path, status, wantStatus, respBody)
+	}
+	if out != nil {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			api.t.Fatalf("%s %s: decoding %q: %v", method, path, respBody, err)
+		}
+	}
+}
+
+// createStudent adds a student through the admin API and returns its ID
+func (api *testAPI) createStudent(firstName string) int {
+	api.t.Helper()
+	var created struct {
+		Data models.Student `json:"data"`
+	}
+	student := models.Student{FirstName: firstName, LastName: "Perera", CheckInTime: "08:30", CheckOutTime: "16:30"}
+	api.mustDo("POST", "/create-employee", api.adminToken, nil, student, http.StatusCreated, &created)
+	if created.Data.ID == 0 {
+		api.t.Fatalf("created student has no ID")
+	}
+	return int(created.Data.ID)
+}
+
+// createSupervisor adds a supervisor with an email address and returns its ID
+func (api *testAPI) createSupervisor(firstName string) int {
+	api.t.Helper()
+	var created models.Supervisor
+	supervisor := models.Supervisor{FirstName: firstName, EmailAddress: strings.ToLower(firstName) + "@example.com"}
+	api.mustDo("POST", "/create-supervisor", api.adminToken, nil, supervisor, http.StatusOK, &created)
+	return created.SupervisorID
+}
+
+// pairDevice runs the OTP pairing flow for a student and returns a trainee
+// session token for the new device
+func (api *testAPI) pairDevice(studentID int) string {
+	api.t.Helper()
+	var otp models.OTPResponse
+	api.mustDo("POST", "/generate-otp", api.adminToken, studentHeader(studentID), nil, http.StatusOK, &otp)
+
+	var validation models.OTPValidationResponse
+	api.mustDo("POST", "/validate-otp", "", map[string]string{"otp-code": otp.OTPCode, "device-name": "test phone"}, nil, http.StatusOK, &validation)
+	if !validation.Success {
+		api.t.Fatalf("validating OTP %s: %s", otp.OTPCode, validation.Message)
+	}
+
+	var session models.SessionResponse
+	api.mustDo("POST", "/device-session", "", nil, models.AuthRequest{StudentID: studentID, SecretCode: validation.SecretCode}, http.StatusOK, &session)
+	return session.Token
+}
+
+func studentHeader(studentID int) map[string]string {
+	return map[string]string{"student-id": strconv.Itoa(studentID)}
+}
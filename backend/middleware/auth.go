@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"server/auth"
+	"strconv"
+	"strings"
+)
+
+// Authenticate parses a bearer token when one is present and stores the
+// resulting principal on the request context. Requests without a token pass
+// through anonymously; RequireRoles decides whether that is acceptable.
+func Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			http.Error(w, "Invalid Authorization header", http.StatusUnauthorized)
+			return
+		}
+
+		principal, err := auth.ParseToken(strings.TrimSpace(token))
+		if err != nil {
+			log.Printf("Rejected bearer token: %v", err)
+			http.Error(w, "Invalid or expired session", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// RequireRoles rejects requests whose principal holds none of the given roles.
+// Trainees are additionally pinned to their own student-id: a mismatching
+// header is refused and a missing one is filled in from the session.
+func RequireRoles(roles ...auth.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := auth.PrincipalFrom(r.Context())
+			if principal == nil {
+				http.Error(w, "Authentication required", http.StatusUnauthorized)
+				return
+			}
+			if !principal.HasRole(roles...) {
+				log.Printf("Denied %s %s for role %s", r.Method, r.URL.Path, principal.Role)
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			if principal.Role == auth.RoleTrainee {
+				own := strconv.Itoa(principal.StudentID)
+				if header := r.Header.Get("student-id"); header != "" && strings.TrimSpace(header) != own {
+					log.Printf("Trainee %d attempted to act as student %s", principal.StudentID, header)
+					http.Error(w, "Forbidden", http.StatusForbidden)
+					return
+				}
+				r.Header.Set("student-id", own)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"server/database"
+)
+
+// runMigrateCommand implements `main migrate up|down|status`
+func runMigrateCommand(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	steps := fs.Int("steps", 1, "number of migrations to revert with down")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: main migrate up|down|status [-steps N]")
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	command := args[0]
+	fs.Parse(args[1:])
+
+	database.ConnectDB()
+
+	switch command {
+	case "up":
+		applied, err := database.MigrateUp(database.DB)
+		if err != nil {
+			log.Fatalf("❌ Migration failed: %v", err)
+		}
+		log.Printf("✅ Applied %d migration(s)", len(applied))
+	case "down":
+		reverted, err := database.MigrateDown(database.DB, *steps)
+		if err != nil {
+			log.Fatalf("❌ Migration failed: %v", err)
+		}
+		log.Printf("✅ Reverted %d migration(s)", len(reverted))
+	case "status":
+		states, err := database.MigrationStatus(database.DB)
+		if err != nil {
+			log.Fatalf("❌ Failed to read migration status: %v", err)
+		}
+		for _, s := range states {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-30s %s\n", s.Version, s.Name, applied)
+		}
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+}
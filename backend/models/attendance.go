@@ -5,6 +5,9 @@ import (
 	"time"
 )
 
+// Attendance is one work session. CheckInDateTime is zero for an orphan
+// check-out, a check-out that arrived without an open session, and for an
+// absence, a shift nobody checked in to.
 type Attendance struct {
 	ID               uint            `json:"id"`
 	StudentID        int             `json:"student_id"`
@@ -14,4 +17,94 @@ type Attendance struct {
 	CheckOutDateTime sql.NullTime    `json:"check_out_date_time"`
 	CheckOutLong     sql.NullFloat64 `json:"check_out_long"`
 	CheckOutLat      sql.NullFloat64 `json:"check_out_lat"`
+
+	// Geofence results are nil when the student had no employer
+	CheckInInsideGeofence  *bool `json:"check_in_inside_geofence"`
+	CheckInDistanceM       *int  `json:"check_in_distance_m"`
+	CheckOutInsideGeofence *bool `json:"check_out_inside_geofence"`
+	CheckOutDistanceM      *int  `json:"check_out_distance_m"`
+
+	OrphanCheckOut bool `json:"orphan_check_out"`
+
+	// Punctuality is computed against the expected shift and is empty when
+	// the student was not scheduled. MinutesLate and MinutesEarly are set
+	// once the check-in and check-out have been evaluated.
+	Punctuality  string `json:"punctuality"`
+	MinutesLate  *int   `json:"minutes_late"`
+	MinutesEarly *int   `json:"minutes_early"`
+
+	// Absent rows have no check-in or check-out; ExpectedStart is the start
+	// of the missed shift
+	Absent        bool       `json:"absent"`
+	ExpectedStart *time.Time `json:"expected_start,omitempty"`
+	// AutoClosed sessions were checked out by the server after the shift ended
+	AutoClosed bool `json:"auto_closed"`
+	// PlacementID is the placement the student was on when the row was recorded
+	PlacementID *int `json:"placement_id"`
+
+	// ReviewStatus is the employer's confirmation of the session, empty
+	// until they review it
+	ReviewStatus string     `json:"review_status"`
+	ReviewNote   string     `json:"review_note,omitempty"`
+	ReviewedBy   *int       `json:"reviewed_by,omitempty"`
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty"`
+}
+
+// Punctuality statuses. A late check-in stays late even if the trainee also
+// leaves early; MinutesEarly still records the early leave.
+const (
+	PunctualityOnTime    = "on_time"
+	PunctualityLate      = "late"
+	PunctualityLeftEarly = "left_early"
+	PunctualityAbsent    = "absent"
+)
+
+// Review statuses an employer can give a session
+const (
+	ReviewConfirmed = "confirmed"
+	ReviewDisputed  = "disputed"
+)
+
+// ReviewRequest confirms or disputes one attendance session; disputes need a note
+type ReviewRequest struct {
+	AttendanceID int    `json:"attendance_id"`
+	Status       string `json:"status"`
+	Note         string `json:"note"`
+}
+
+// Start returns when the session began, the check-out time of an orphan
+// check-out, or the start of the missed shift for an absence
+func (a Attendance) Start() time.Time {
+	if a.Absent && a.ExpectedStart != nil {
+		return *a.ExpectedStart
+	}
+	if a.OrphanCheckOut && a.CheckOutDateTime.Valid {
+		return a.CheckOutDateTime.Time
+	}
+	return a.CheckInDateTime
+}
+
+// Open reports whether the session has been checked in to but not out of
+func (a Attendance) Open() bool {
+	return !a.OrphanCheckOut && !a.Absent && !a.CheckOutDateTime.Valid
+}
+
+// WorkedMinutes is the length of a closed session, zero for open sessions, orphans and absences
+func (a Attendance) WorkedMinutes() int {
+	if a.Open() || a.OrphanCheckOut || a.Absent || a.CheckOutDateTime.Time.Before(a.CheckInDateTime) {
+		return 0
+	}
+	return int(a.CheckOutDateTime.Time.Sub(a.CheckInDateTime).Minutes())
+}
+
+// AttendanceDay groups a student's sessions that started on the same day
+type AttendanceDay struct {
+	Date            string       `json:"date"`
+	Sessions        []Attendance `json:"sessions"`
+	WorkedMinutes   int          `json:"worked_minutes"`
+	HasOpenSession  bool         `json:"has_open_session"`
+	OrphanCheckOuts int          `json:"orphan_check_outs"`
+	Absent          bool         `json:"absent"`
+
+	ExpectedShift *ExpectedShift `json:"expected_shift,omitempty"`
 }
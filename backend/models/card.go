@@ -12,6 +12,21 @@ type StudentCard struct {
 	Emotion          string    `json:"emotion"`
 	CheckInTime      string    `json:"check_in_time"`
 	CheckOutTime     string    `json:"check_out_time"`
+
+	CheckInInsideGeofence  *bool `json:"check_in_inside_geofence"`
+	CheckInDistanceM       *int  `json:"check_in_distance_m"`
+	CheckOutInsideGeofence *bool `json:"check_out_inside_geofence"`
+	CheckOutDistanceM      *int  `json:"check_out_distance_m"`
+
+	// Timezone is the employer's zone; AttendedToday reports whether the
+	// latest session started on the current day in that zone
+	Timezone      string `json:"timezone"`
+	AttendedToday bool   `json:"attended_today"`
+	// ScheduledToday reports whether today's expected shift is a working
+	// day; CheckInTime and CheckOutTime hold its hours
+	ScheduledToday bool `json:"scheduled_today"`
+	// Punctuality of the latest session
+	Punctuality string `json:"punctuality"`
 }
 
 func (StudentCard) TableName() string {
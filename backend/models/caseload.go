@@ -0,0 +1,45 @@
+package models
+
+// CaseloadExceptions flags what about a trainee's day needs the supervisor's attention
+type CaseloadExceptions struct {
+	// LateToday is set when a session today started late
+	LateToday bool `json:"late_today"`
+	// NotCheckedIn is set once today's shift is past its late grace period
+	// without a check-in
+	NotCheckedIn bool `json:"not_checked_in"`
+	// NegativeMood is set when today's daily mood is negative, or the
+	// current run of negative daily moods has reached the alert streak
+	NegativeMood bool `json:"negative_mood"`
+	// OutsideGeofence is set when a check-in or check-out today was outside
+	// the employer's geofence
+	OutsideGeofence bool `json:"outside_geofence"`
+}
+
+// Any reports whether any exception is flagged
+func (e CaseloadExceptions) Any() bool {
+	return e.LateToday || e.NotCheckedIn || e.NegativeMood || e.OutsideGeofence
+}
+
+// CaseloadCard is a dashboard card with the trainee's exceptions today
+type CaseloadCard struct {
+	StudentCard
+	Exceptions CaseloadExceptions `json:"exceptions"`
+	Flagged    bool               `json:"flagged"`
+}
+
+// CaseloadCounts counts a caseload's trainees by exception
+type CaseloadCounts struct {
+	Trainees        int `json:"trainees"`
+	Flagged         int `json:"flagged"`
+	LateToday       int `json:"late_today"`
+	NotCheckedIn    int `json:"not_checked_in"`
+	NegativeMood    int `json:"negative_mood"`
+	OutsideGeofence int `json:"outside_geofence"`
+}
+
+// CaseloadDashboard is one supervisor's trainees as of today
+type CaseloadDashboard struct {
+	SupervisorID int            `json:"supervisor_id"`
+	Counts       CaseloadCounts `json:"counts"`
+	Trainees     []CaseloadCard `json:"trainees"`
+}
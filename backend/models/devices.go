@@ -2,11 +2,16 @@ package models
 
 import "time"
 
+// AuthorizedDevice is a mobile device paired to a student through an OTP.
+// SecretCode holds the SHA-256 hash of the credential and is never serialised.
 type AuthorizedDevice struct {
-	ID         uint      `json:"id"`
-	StudentID  int       `json:"student_id"`
-	SecretCode string    `json:"secret_code"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID         uint       `json:"id"`
+	StudentID  int        `json:"student_id"`
+	SecretCode string     `json:"-"`
+	DeviceName string     `json:"device_name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
 }
 
 func (AuthorizedDevice) TableName() string {
@@ -2,8 +2,16 @@ package models
 
 import "time"
 
+// EmergencyContact is one version of the number the app dials in an
+// emergency. It applies to every trainee unless EmployerID or SupervisorID
+// narrows it; RetiredAt is set once a newer version replaced or removed it.
 type EmergencyContact struct {
-	ID          int       `json:"id" db:"id"`
-	PhoneNumber string    `json:"phone_number" db:"phone_number"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID           int        `json:"id" db:"id"`
+	PhoneNumber  string     `json:"phone_number" db:"phone_number"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+	EmployerID   *int       `json:"employer_id,omitempty" db:"employer_id"`
+	SupervisorID *int       `json:"supervisor_id,omitempty" db:"supervisor_id"`
+	ChangedBy    *int       `json:"changed_by,omitempty" db:"changed_by"`
+	RetiredAt    *time.Time `json:"retired_at,omitempty" db:"retired_at"`
+	RetiredBy    *int       `json:"retired_by,omitempty" db:"retired_by"`
 }
@@ -10,4 +10,23 @@ type Employer struct {
 	AddressLine3  string  `json:"address_line3,omitempty"`
 	Longitude     float64 `json:"addr_long"`
 	Latitude      float64 `json:"addr_lat"`
+
+	// Trainees must be within GeofenceRadius metres of the address
+	// coordinates, or inside GeofencePolygon when it has at least three points
+	GeofenceRadius  float64    `json:"geofence_radius_m"`
+	GeofencePolygon []GeoPoint `json:"geofence_polygon,omitempty"`
+
+	// Timezone is the IANA zone attendance days are counted in
+	Timezone string `json:"timezone"`
+
+	// Minutes after the scheduled start, and before the scheduled end, that
+	// still count as on time; nil uses the server defaults
+	LateGraceMinutes       *int `json:"late_grace_minutes"`
+	EarlyLeaveGraceMinutes *int `json:"early_leave_grace_minutes"`
+}
+
+// GeoPoint is a latitude/longitude pair in degrees
+type GeoPoint struct {
+	Lat  float64 `json:"lat"`
+	Long float64 `json:"long"`
 }
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Incident states; an incident moves from open to acknowledged to resolved
+const (
+	IncidentOpen         = "open"
+	IncidentAcknowledged = "acknowledged"
+	IncidentResolved     = "resolved"
+)
+
+// Incident is an SOS raised by a trainee from the mobile app. A student has
+// at most one unresolved incident; raising another SOS refreshes its location.
+type Incident struct {
+	ID        int       `json:"id"`
+	StudentID int       `json:"student_id"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	AccuracyM *float64  `json:"accuracy_m"`
+	Message   string    `json:"message"`
+	Status    string    `json:"status"`
+	RaisedAt  time.Time `json:"raised_at"`
+	// LocatedAt is when the location was last reported
+	LocatedAt      time.Time  `json:"located_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy *int       `json:"acknowledged_by,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy     *int       `json:"resolved_by,omitempty"`
+	Resolution     string     `json:"resolution,omitempty"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// Feedback rating scale
+const (
+	FeedbackRatingMin = 1
+	FeedbackRatingMax = 5
+)
+
+// Where feedback came from
+const (
+	FeedbackSourceAPI   = "api"
+	FeedbackSourceSheet = "sheet"
+)
+
+// ManagerFeedback is a workplace manager's assessment of a trainee over a
+// period, rated per competency on the FeedbackRatingMin..FeedbackRatingMax scale
+type ManagerFeedback struct {
+	ID        int `json:"id"`
+	StudentID int `json:"student_id"`
+	// EmployerID is the employer the trainee was placed with when the
+	// feedback was given
+	EmployerID *int `json:"employer_id"`
+	// AuthorID is the user who submitted it, nil for imported feedback
+	AuthorID   *int   `json:"author_id,omitempty"`
+	AuthorName string `json:"author_name"`
+	// PeriodStart and PeriodEnd are inclusive YYYY-MM-DD dates
+	PeriodStart string         `json:"period_start"`
+	PeriodEnd   string         `json:"period_end"`
+	Ratings     map[string]int `json:"ratings"`
+	Comments    string         `json:"comments"`
+	Source      string         `json:"source"`
+	// ExternalRef identifies imported feedback in its source so it is only
+	// imported once
+	ExternalRef string    `json:"external_ref,omitempty"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// FeedbackRequest is the body of POST /submit-feedback
+type FeedbackRequest struct {
+	PeriodStart string         `json:"period_start"`
+	PeriodEnd   string         `json:"period_end"`
+	Ratings     map[string]int `json:"ratings"`
+	Comments    string         `json:"comments"`
+	AuthorName  string         `json:"author_name"`
+}
+
+// FeedbackSheetRow is the last pulled copy of a row of the manager feedback
+// Google Sheet and what it was stored as
+type FeedbackSheetRow struct {
+	// Ref identifies the row by its timestamp and student cells, so it
+	// survives rows being sorted or inserted above it
+	Ref       string `json:"ref"`
+	RowNumber int    `json:"row_number"`
+	// Cells maps each header to the row's value
+	Cells       map[string]string `json:"cells"`
+	ContentHash string            `json:"content_hash"`
+	StudentID   *int              `json:"student_id"`
+	FeedbackID  *int              `json:"feedback_id"`
+	// Error says why the row could not be stored as feedback
+	Error       string     `json:"error,omitempty"`
+	FirstSeenAt time.Time  `json:"first_seen_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	RemovedAt   *time.Time `json:"removed_at,omitempty"`
+}
+
+// FeedbackSyncReport counts what a pull of the feedback sheet changed
+type FeedbackSyncReport struct {
+	Rows      int `json:"rows"`
+	Added     int `json:"added"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Removed   int `json:"removed"`
+	// Unmatched rows could not be stored as feedback, see their Error
+	Unmatched int `json:"unmatched"`
+}
@@ -10,4 +10,61 @@ type Mood struct {
 	RecordedAt time.Time `json:"recorded_at"`
 	Emotion    string    `json:"emotion"`
 	IsDaily    bool      `json:"is_daily"`
+	// Valence scores the emotion when it was recorded: above zero is
+	// positive, below zero negative, nil for emotions outside the vocabulary
+	Valence *int `json:"valence"`
+	// LocalDate is the day, YYYY-MM-DD in the employer's timezone, a daily
+	// mood counts for; empty for ad-hoc moods
+	LocalDate string     `json:"local_date,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// MoodPage is one page of a student's mood history
+type MoodPage struct {
+	Moods []Mood `json:"moods"`
+	// NextCursor fetches the following page, empty on the last one
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// MoodOption is one emotion the app may offer and its valence
+type MoodOption struct {
+	Emotion string `json:"emotion"`
+	Valence int    `json:"valence"`
+}
+
+// MoodTrendBucket aggregates a student's moods over one day or week
+type MoodTrendBucket struct {
+	// Start is the first day of the bucket, YYYY-MM-DD
+	Start      string `json:"start"`
+	Count      int    `json:"count"`
+	DailyCount int    `json:"daily_count"`
+	// AverageValence is nil when no mood in the bucket was scored
+	AverageValence *float64       `json:"average_valence"`
+	NegativeCount  int            `json:"negative_count"`
+	Emotions       map[string]int `json:"emotions"`
+}
+
+// MoodTrends is a student's mood history in buckets, with the current run
+// of negative daily moods
+type MoodTrends struct {
+	StudentID      int               `json:"student_id"`
+	Period         string            `json:"period"`
+	From           string            `json:"from"`
+	To             string            `json:"to"`
+	Buckets        []MoodTrendBucket `json:"buckets"`
+	NegativeStreak int               `json:"negative_streak"`
+	// StreakAlert is set once the streak reaches the alert threshold
+	StreakAlert bool `json:"streak_alert"`
+}
+
+// MoodStreak is a student whose latest daily moods are all negative
+type MoodStreak struct {
+	StudentID  int       `json:"student_id"`
+	FirstName  string    `json:"first_name"`
+	LastName   string    `json:"last_name"`
+	Streak     int       `json:"streak"`
+	Since      time.Time `json:"since"`
+	LastMood   string    `json:"last_mood"`
+	LastMoodAt time.Time `json:"last_mood_at"`
 }
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Notification delivery states
+const (
+	NotificationQueued = "queued"
+	NotificationSent   = "sent"
+	NotificationFailed = "failed"
+)
+
+// Notification is one message to one recipient over one channel, kept as a
+// record of every delivery attempt
+type Notification struct {
+	ID            int        `json:"id"`
+	Event         string     `json:"event"`
+	StudentID     *int       `json:"student_id"`
+	Channel       string     `json:"channel"`
+	Recipient     string     `json:"recipient"`
+	Subject       string     `json:"subject"`
+	Body          string     `json:"body"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+}
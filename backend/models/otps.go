@@ -26,15 +26,35 @@ type OTPResponse struct {
 	ExpiresAt time.Time `json:"expires_at"`
 }
 
-// OTPValidationRequest is used when validating OTP from a mobile app
+// OTPValidationRequest is used when validating OTP from a mobile app.
+// StudentID is optional and narrows the lookup to that student's codes.
 type OTPValidationRequest struct {
-	OTPCode string `json:"otp_code"`
+	OTPCode    string `json:"otp_code"`
+	StudentID  int    `json:"student_id,omitempty"`
+	DeviceName string `json:"device_name,omitempty"`
+	ClientIP   string `json:"-"`
+}
+
+// OTPFailedAttempt is an audit record for a rejected OTP validation
+type OTPFailedAttempt struct {
+	ID            uint      `json:"id"`
+	AttemptedCode string    `json:"attempted_code"`
+	StudentID     *int      `json:"student_id,omitempty"`
+	ClientIP      string    `json:"client_ip"`
+	DeviceName    string    `json:"device_name"`
+	Reason        string    `json:"reason"`
+	AttemptedAt   time.Time `json:"attempted_at"`
+}
+
+func (OTPFailedAttempt) TableName() string {
+	return "otp_failed_attempts"
 }
 
 // OTPValidationResponse is returned after successful OTP validation
 type OTPValidationResponse struct {
 	Success    bool   `json:"success"`
 	StudentID  int    `json:"student_id"`
+	DeviceID   int    `json:"device_id,omitempty"`
 	SecretCode string `json:"secret_code,omitempty"`
 	Message    string `json:"message,omitempty"`
 }
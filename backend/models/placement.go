@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Placement is a period a student works at an employer. The open placement,
+// the one without an EndDate, is the student's current employer.
+type Placement struct {
+	ID         int    `json:"id"`
+	StudentID  int    `json:"student_id"`
+	EmployerID *int   `json:"employer_id"`
+	Role       string `json:"role"`
+	// StartDate and EndDate are inclusive YYYY-MM-DD dates
+	StartDate string  `json:"start_date"`
+	EndDate   *string `json:"end_date"`
+	EndReason string  `json:"end_reason,omitempty"`
+	// Schedule is the weekly template agreed for the placement
+	Schedule  []ScheduleDay `json:"schedule"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// PlacementRequest starts a placement. StartDate defaults to today and a
+// nil Schedule keeps the student's current weekly template.
+type PlacementRequest struct {
+	EmployerID int           `json:"employer_id"`
+	Role       string        `json:"role"`
+	StartDate  string        `json:"start_date"`
+	Schedule   []ScheduleDay `json:"schedule"`
+}
+
+// EndPlacementRequest ends the student's open placement; EndDate defaults to today
+type EndPlacementRequest struct {
+	EndDate string `json:"end_date"`
+	Reason  string `json:"reason"`
+}
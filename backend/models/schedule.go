@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// ScheduleDay is one working weekday of a student's weekly template. Start
+// and End are HH:MM in the employer's timezone; an End at or before Start
+// means the shift finishes the next day.
+type ScheduleDay struct {
+	Weekday time.Weekday `json:"weekday"` // 0 = Sunday
+	Start   string       `json:"start"`
+	End     string       `json:"end"`
+}
+
+// WeeklySchedule is a student's template; weekdays without an entry are days off
+type WeeklySchedule struct {
+	StudentID int           `json:"student_id"`
+	Timezone  string        `json:"timezone"`
+	Days      []ScheduleDay `json:"days"`
+}
+
+// ScheduleOverride replaces the weekly template on one date, either with
+// different hours or with a day off
+type ScheduleOverride struct {
+	StudentID int    `json:"student_id"`
+	Date      string `json:"date"` // YYYY-MM-DD
+	DayOff    bool   `json:"day_off"`
+	Start     string `json:"start,omitempty"`
+	End       string `json:"end,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Where an expected shift came from
+const (
+	ShiftSourceOverride = "override"
+	ShiftSourceWeekly   = "weekly"
+	ShiftSourceLegacy   = "legacy" // the student's check_in_time/check_out_time
+	ShiftSourceNone     = "none"
+)
+
+// ExpectedShift is the resolved shift of a student on one local date
+type ExpectedShift struct {
+	StudentID int        `json:"student_id"`
+	Date      string     `json:"date"`
+	Working   bool       `json:"working"`
+	Start     string     `json:"start,omitempty"`
+	End       string     `json:"end,omitempty"`
+	StartsAt  *time.Time `json:"starts_at,omitempty"`
+	EndsAt    *time.Time `json:"ends_at,omitempty"`
+	Source    string     `json:"source"`
+	Reason    string     `json:"reason,omitempty"`
+}
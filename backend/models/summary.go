@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// AttendanceSummary counts a student's sessions over a summary period
+type AttendanceSummary struct {
+	DaysAttended  int `json:"days_attended"`
+	DaysAbsent    int `json:"days_absent"`
+	WorkedMinutes int `json:"worked_minutes"`
+	OnTime        int `json:"on_time"`
+	Late          int `json:"late"`
+	LeftEarly     int `json:"left_early"`
+	// OnTimePercentage is the share of scheduled sessions started on time,
+	// nil when none were scheduled
+	OnTimePercentage *float64 `json:"on_time_percentage"`
+	OutsideGeofence  int      `json:"outside_geofence"`
+}
+
+// MoodSummary counts a student's moods over a summary period
+type MoodSummary struct {
+	Count      int            `json:"count"`
+	DailyCount int            `json:"daily_count"`
+	Emotions   map[string]int `json:"emotions"`
+	// AverageValence is nil when no mood in the period was scored
+	AverageValence *float64 `json:"average_valence"`
+	// NegativeStreak is the current run of negative daily moods
+	NegativeStreak int    `json:"negative_streak"`
+	Latest         string `json:"latest,omitempty"`
+}
+
+// FeedbackSummary condenses manager feedback over a summary period
+type FeedbackSummary struct {
+	Count int `json:"count"`
+	// AverageRatings is the mean rating per competency
+	AverageRatings map[string]float64 `json:"average_ratings"`
+	// Comments are the latest comments first
+	Comments []string `json:"comments"`
+}
+
+// TraineeSummary is the facts a trainee summary is written from and the
+// written summary itself
+type TraineeSummary struct {
+	StudentID    int               `json:"student_id"`
+	StudentName  string            `json:"student_name"`
+	EmployerName string            `json:"employer_name,omitempty"`
+	Remarks      string            `json:"remarks"`
+	From         string            `json:"from"`
+	To           string            `json:"to"`
+	Attendance   AttendanceSummary `json:"attendance"`
+	Moods        MoodSummary       `json:"moods"`
+	Feedback     FeedbackSummary   `json:"feedback"`
+
+	Summary string `json:"summary"`
+	// Source names the provider that wrote Summary, "template" for the
+	// built-in fallback
+	Source      string    `json:"source"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
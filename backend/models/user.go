@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// User is a web dashboard account (admin, supervisor or employer)
+type User struct {
+	ID           int       `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"-"`
+	Role         string    `json:"role"`
+	SupervisorID *int      `json:"supervisor_id,omitempty"`
+	EmployerID   *int      `json:"employer_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (User) TableName() string {
+	return "app_user"
+}
+
+// LoginRequest is used by the web dashboard to open a session
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// CreateUserRequest is used by admins to provision dashboard accounts
+type CreateUserRequest struct {
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	Role         string `json:"role"`
+	SupervisorID *int   `json:"supervisor_id,omitempty"`
+	EmployerID   *int   `json:"employer_id,omitempty"`
+}
+
+// SessionResponse is returned whenever a bearer token is issued
+type SessionResponse struct {
+	Token        string    `json:"token"`
+	Role         string    `json:"role"`
+	UserID       int       `json:"user_id,omitempty"`
+	StudentID    int       `json:"student_id,omitempty"`
+	SupervisorID int       `json:"supervisor_id,omitempty"`
+	EmployerID   int       `json:"employer_id,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"server/models"
+)
+
+func TestMoodHistoryPaginationAndFilters(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Chamari")
+	token := api.pairDevice(studentID)
+
+	colombo, _ := time.LoadLocation("Asia/Colombo")
+	for d := 1; d <= 5; d++ {
+		postMood(api, token, "happy", true, time.Date(2025, 3, d, 8, 0, 0, 0, colombo))
+		postMood(api, token, "neutral", false, time.Date(2025, 3, d, 17, 0, 0, 0, colombo))
+	}
+
+	// Walk newest first in pages of three
+	var seen []models.Mood
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("pagination does not end")
+		}
+		var page models.MoodPage
+		api.mustDo("GET", "/moods?limit=3&cursor="+url.QueryEscape(cursor), token, nil, nil, http.StatusOK, &page)
+		seen = append(seen, page.Moods...)
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if len(seen) != 10 {
+		t.Fatalf("paged through %d moods, want 10", len(seen))
+	}
+	for i := 1; i < len(seen); i++ {
+		if !seen[i].RecordedAt.Before(seen[i-1].RecordedAt) {
+			t.Fatalf("mood %d is not older than mood %d", i, i-1)
+		}
+	}
+
+	var page models.MoodPage
+	api.mustDo("GET", "/moods?from=2025-03-02&to=2025-03-03&is_daily=true&sort=asc", token, nil, nil, http.StatusOK, &page)
+	if len(page.Moods) != 2 || page.Moods[0].RecordedAt.In(colombo).Day() != 2 || !page.Moods[0].IsDaily || page.NextCursor != "" {
+		t.Errorf("filtered page = %+v", page)
+	}
+
+	var mood models.Mood
+	path := "/mood/" + strconv.Itoa(seen[0].ID)
+	api.mustDo("GET", path, token, nil, nil, http.StatusOK, &mood)
+	if mood.ID != seen[0].ID {
+		t.Errorf("got mood %d, want %d", mood.ID, seen[0].ID)
+	}
+
+	for _, bad := range []string{"sort=up", "is_daily=maybe", "limit=0", "cursor=nope", "from=03-01-2025"} {
+		api.mustDo("GET", "/moods?"+bad, token, nil, nil, http.StatusBadRequest, nil)
+	}
+
+	// A trainee cannot read another trainee's moods
+	other := api.pairDevice(api.createStudent("Lahiru"))
+	api.mustDo("GET", "/moods", other, studentHeader(studentID), nil, http.StatusForbidden, nil)
+	api.mustDo("GET", path, other, nil, nil, http.StatusNotFound, nil)
+}
+
+func TestMoodHistorySupervisorScope(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.supervisedStudent("Chamari")
+	postMood(api, api.pairDevice(studentID), "sad", true, time.Now())
+	var student models.Student
+	api.mustDo("GET", "/get-student", api.adminToken, studentHeader(studentID), nil, http.StatusOK, &student)
+
+	own := api.supervisorToken("own", int(*student.SupervisorID))
+	other := api.supervisorToken("other", api.createSupervisor("Nadeesha"))
+
+	var page models.MoodPage
+	api.mustDo("GET", "/moods", own, studentHeader(studentID), nil, http.StatusOK, &page)
+	if len(page.Moods) != 1 {
+		t.Errorf("own supervisor sees %d moods, want 1", len(page.Moods))
+	}
+	api.mustDo("GET", "/moods", other, studentHeader(studentID), nil, http.StatusForbidden, nil)
+	api.mustDo("GET", "/mood-trends", other, studentHeader(studentID), nil, http.StatusForbidden, nil)
+
+	var all []models.Mood
+	api.mustDo("GET", "/get-mood", other, nil, nil, http.StatusOK, &all)
+	if len(all) != 0 {
+		t.Errorf("another supervisor sees %d moods on /get-mood", len(all))
+	}
+	api.mustDo("GET", "/get-mood", own, nil, nil, http.StatusOK, &all)
+	if len(all) != 1 {
+		t.Errorf("own supervisor sees %d moods on /get-mood, want 1", len(all))
+	}
+}
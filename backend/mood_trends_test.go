@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"server/models"
+)
+
+// postMood records a mood with the server clock stopped at at
+func postMood(api *testAPI, token, emotion string, daily bool, at time.Time) {
+	api.t.Helper()
+	api.setClock(at)
+	defer api.setClock(time.Time{})
+	mood := map[string]interface{}{"emotion": emotion, "is_daily": daily, "timestamp": at.Format(time.RFC3339)}
+	api.mustDo("POST", "/post-mood", token, nil, mood, http.StatusOK, nil)
+}
+
+func TestMoodVocabularyAndTrends(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Chamari")
+	token := api.pairDevice(studentID)
+
+	var vocabulary []models.MoodOption
+	api.mustDo("GET", "/mood-vocabulary", token, nil, nil, http.StatusOK, &vocabulary)
+	if len(vocabulary) != 3 || vocabulary[0] != (models.MoodOption{Emotion: "happy", Valence: 1}) {
+		t.Errorf("vocabulary = %+v", vocabulary)
+	}
+	api.mustDo("POST", "/post-mood", token, nil, map[string]interface{}{"emotion": "ecstatic", "timestamp": time.Now().Format(time.RFC3339)}, http.StatusBadRequest, nil)
+
+	// Monday 6 and Tuesday 7 January, then Monday 13 January 2025 in Colombo
+	colombo, _ := time.LoadLocation("Asia/Colombo")
+	day := func(d, hour int) time.Time { return time.Date(2025, 1, d, hour, 0, 0, 0, colombo) }
+	postMood(api, token, "Happy", true, day(6, 8))
+	postMood(api, token, "neutral", false, day(6, 17))
+	postMood(api, token, "sad", true, day(7, 8))
+	// Just after midnight local time, still 12 January in UTC
+	postMood(api, token, "sad", true, day(13, 1))
+
+	var daily models.MoodTrends
+	api.mustDo("GET", "/mood-trends?from=2025-01-06&to=2025-01-13", token, nil, nil, http.StatusOK, &daily)
+	if len(daily.Buckets) != 3 {
+		t.Fatalf("got %d daily buckets, want 3: %+v", len(daily.Buckets), daily.Buckets)
+	}
+	first := daily.Buckets[0]
+	if first.Start != "2025-01-06" || first.Count != 2 || first.DailyCount != 1 || first.Emotions["happy"] != 1 || first.AverageValence == nil || *first.AverageValence != 0.5 {
+		t.Errorf("first day = %+v", first)
+	}
+	if last := daily.Buckets[2]; last.Start != "2025-01-13" || last.NegativeCount != 1 {
+		t.Errorf("last day = %+v", last)
+	}
+	if daily.NegativeStreak != 2 || daily.StreakAlert {
+		t.Errorf("streak = %d (alert %v), want 2 without alert", daily.NegativeStreak, daily.StreakAlert)
+	}
+
+	var weekly models.MoodTrends
+	api.mustDo("GET", "/mood-trends?period=week&from=2025-01-01&to=2025-01-19", api.adminToken, studentHeader(studentID), nil, http.StatusOK, &weekly)
+	if len(weekly.Buckets) != 2 || weekly.Buckets[0].Start != "2025-01-06" || weekly.Buckets[0].Count != 3 || weekly.Buckets[1].Count != 1 {
+		t.Errorf("weekly buckets = %+v", weekly.Buckets)
+	}
+	api.mustDo("GET", "/mood-trends?period=month", token, nil, nil, http.StatusBadRequest, nil)
+	api.mustDo("GET", "/mood-trends?from=2025-02-01&to=2025-01-01", token, nil, nil, http.StatusBadRequest, nil)
+
+	var streaks []models.MoodStreak
+	api.mustDo("GET", "/mood-streaks", api.adminToken, nil, nil, http.StatusOK, &streaks)
+	if len(streaks) != 0 {
+		t.Errorf("streaks before the third negative mood: %+v", streaks)
+	}
+	postMood(api, token, "sad", true, day(14, 8))
+	api.mustDo("GET", "/mood-streaks", api.adminToken, nil, nil, http.StatusOK, &streaks)
+	if len(streaks) != 1 || streaks[0].StudentID != studentID || streaks[0].Streak != 3 || !streaks[0].Since.Equal(day(7, 8)) {
+		t.Errorf("streaks = %+v", streaks)
+	}
+	api.mustDo("GET", "/mood-streaks", token, nil, nil, http.StatusForbidden, nil)
+}
+
+func TestMoodVocabularyFromEnv(t *testing.T) {
+	t.Setenv("MOOD_VOCABULARY", "great:2, fine:0, stressed:-1, awful:-2")
+	api := newTestAPI(t)
+	token := api.pairDevice(api.createStudent("Lahiru"))
+	postMood(api, token, "stressed", true, time.Now())
+	api.mustDo("POST", "/post-mood", token, nil, map[string]interface{}{"emotion": "happy", "timestamp": time.Now().Format(time.RFC3339)}, http.StatusBadRequest, nil)
+
+	var trends models.MoodTrends
+	api.mustDo("GET", "/mood-trends", token, nil, nil, http.StatusOK, &trends)
+	if len(trends.Buckets) != 1 || *trends.Buckets[0].AverageValence != -1 || trends.NegativeStreak != 1 {
+		t.Errorf("trends = %+v", trends)
+	}
+}
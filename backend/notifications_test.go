@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"server/models"
+	"server/notify"
+)
+
+// supervisedStudent creates a student with a guardian number, supervised by
+// a supervisor with an email address and working at an employer
+func (api *testAPI) supervisedStudent(firstName string) int {
+	api.t.Helper()
+	studentID := api.createStudent(firstName)
+
+	supervisorID := uint(api.createSupervisor("Ruwani"))
+	var employer models.Employer
+	api.mustDo("POST", "/create-employer", api.adminToken, nil, models.Employer{Name: "Kingsbury", Latitude: 6.9271, Longitude: 79.8612, GeofenceRadius: 100}, http.StatusOK, &employer)
+
+	student := models.Student{FirstName: firstName, LastName: "Perera", ContactNumberGuardian: "+94771234567", SupervisorID: &supervisorID, EmployerID: &employer.ID}
+	api.mustDo("PUT", "/update-employee", api.adminToken, studentHeader(studentID), student, http.StatusOK, nil)
+	return studentID
+}
+
+// deliver sends everything queued and returns what each fake channel received since the last call
+func (api *testAPI) deliver() map[string][]notify.Message {
+	api.t.Helper()
+	before := map[string]int{}
+	for name, fake := range api.outbox {
+		before[name] = len(fake.Messages())
+	}
+	if _, err := api.notifier.Deliver(context.Background(), time.Now()); err != nil {
+		api.t.Fatalf("delivering notifications: %v", err)
+	}
+	got := map[string][]notify.Message{}
+	for name, fake := range api.outbox {
+		if msgs := fake.Messages()[before[name]:]; len(msgs) > 0 {
+			got[name] = msgs
+		}
+	}
+	return got
+}
+
+func TestNotifyOutsideGeofenceAndOTP(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.supervisedStudent("Chamari")
+	token := api.pairDevice(studentID)
+
+	got := api.deliver()
+	if len(got[notify.ChannelEmail]) != 1 || got[notify.ChannelEmail][0].Event != notify.OTPIssued || got[notify.ChannelEmail][0].To != "ruwani@example.com" {
+		t.Fatalf("after pairing got %+v, want one otp_issued email", got)
+	}
+	if len(got[notify.ChannelSMS]) != 0 {
+		t.Errorf("guardian was sent %+v for a pairing code", got[notify.ChannelSMS])
+	}
+
+	// About 1.1 km away
+	api.mustDo("POST", "/attendance", token, nil, locationRequest(true, 6.9371, 79.8612), http.StatusForbidden, nil)
+	got = api.deliver()
+	if len(got[notify.ChannelEmail]) != 1 || got[notify.ChannelEmail][0].Event != notify.OutsideGeofence {
+		t.Fatalf("after outside check-in got %+v, want one outside_geofence email", got)
+	}
+	if len(got[notify.ChannelWebhook]) != 1 {
+		t.Errorf("webhook got %d messages, want 1", len(got[notify.ChannelWebhook]))
+	}
+
+	var sent []models.Notification
+	api.mustDo("GET", "/notifications?status=sent", api.adminToken, nil, nil, http.StatusOK, &sent)
+	if len(sent) != 4 {
+		t.Errorf("recorded %d sent notifications, want 4", len(sent))
+	}
+}
+
+func TestNotifyNegativeMoodStreak(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.supervisedStudent("Lahiru")
+	token := api.pairDevice(studentID)
+	api.deliver()
+
+	start := time.Now().Add(-96 * time.Hour)
+	for i, emotion := range []string{"sad", "sad", "sad", "sad"} {
+		postMood(api, token, emotion, true, start.Add(time.Duration(i)*24*time.Hour))
+
+		got := api.deliver()[notify.ChannelEmail]
+		// Only the mood that completes the streak of three is reported
+		if want := i == 2; (len(got) == 1 && got[0].Event == notify.NegativeMood) != want {
+			t.Errorf("after mood %d got %+v, want notification %v", i+1, got, want)
+		}
+	}
+}
+
+func TestNotifyMissedCheckIn(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.supervisedStudent("Sanduni")
+	week := models.WeeklySchedule{Days: []models.ScheduleDay{{Weekday: time.Monday, Start: "08:00", End: "16:00"}}}
+	api.mustDo("PUT", "/schedule", api.adminToken, studentHeader(studentID), week, http.StatusOK, nil)
+
+	colombo, _ := time.LoadLocation("Asia/Colombo")
+	if _, err := api.handler.DetectAbsences(time.Date(2030, 1, 7, 10, 30, 0, 0, colombo)); err != nil {
+		t.Fatal(err)
+	}
+	got := api.deliver()
+	if len(got[notify.ChannelEmail]) != 1 || len(got[notify.ChannelSMS]) != 1 || got[notify.ChannelSMS][0].To != "+94771234567" {
+		t.Fatalf("got %+v, want the supervisor emailed and the guardian texted", got)
+	}
+}
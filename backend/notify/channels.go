@@ -0,0 +1,204 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SMTP sends email through a mail server with PLAIN authentication when a
+// username is set, upgrading to TLS when the server offers STARTTLS
+type SMTP struct {
+	Addr     string // host:port
+	Username string
+	Password string
+	From     string
+	// Timeout bounds the whole exchange, defaulting to defaultSMTPTimeout
+	Timeout time.Duration
+}
+
+const defaultSMTPTimeout = 30 * time.Second
+
+func (*SMTP) Name() string { return ChannelEmail }
+
+func (c *SMTP) Send(ctx context.Context, m Message) error {
+	// A line break would let the value inject headers of its own
+	for _, v := range []string{c.From, m.To, m.Subject} {
+		if strings.ContainsAny(v, "\r\n") {
+			return fmt.Errorf("email header value %q contains a line break", v)
+		}
+	}
+	host := c.Addr
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultSMTPTimeout
+	}
+
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.Addr)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	// Cancelling ctx cuts a slow server off straight away
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if c.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.Username, c.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(c.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(m.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	msg := "From: " + c.From + "\r\n" +
+		"To: " + m.To + "\r\n" +
+		"Subject: " + mime.QEncoding.Encode("UTF-8", m.Subject) + "\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + m.Body + "\r\n"
+	if _, err := io.WriteString(w, msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// postJSON sends payload to url and fails on any non-2xx response. A
+// non-empty secret signs the body with HMAC-SHA256 in X-Signature-SHA256.
+func postJSON(ctx context.Context, client *http.Client, url string, payload interface{}, header http.Header, secret string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = header.Clone()
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		req.Header.Set("X-Signature-SHA256", hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("%s returned %d: %s", url, resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+	return nil
+}
+
+// SMSGateway posts {"to", "message"} to an HTTP SMS gateway with a bearer API key
+type SMSGateway struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+func NewSMSGateway(url, apiKey string, timeout time.Duration) *SMSGateway {
+	return &SMSGateway{url: url, apiKey: apiKey, client: &http.Client{Timeout: timeout}}
+}
+
+func (*SMSGateway) Name() string { return ChannelSMS }
+
+func (c *SMSGateway) Send(ctx context.Context, m Message) error {
+	header := http.Header{}
+	if c.apiKey != "" {
+		header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	return postJSON(ctx, c.client, c.url, map[string]string{"to": m.To, "message": m.Body}, header, "")
+}
+
+// Webhook posts every message as JSON to one URL. With a secret, the body
+// is signed with HMAC-SHA256 in the X-Signature-SHA256 header.
+type Webhook struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewWebhook(url, secret string, timeout time.Duration) *Webhook {
+	return &Webhook{url: url, secret: secret, client: &http.Client{Timeout: timeout}}
+}
+
+func (*Webhook) Name() string { return ChannelWebhook }
+
+func (c *Webhook) Send(ctx context.Context, m Message) error {
+	return postJSON(ctx, c.client, c.url, m, http.Header{}, c.secret)
+}
+
+// Fake captures messages in memory instead of sending them. Set Err to make
+// every send fail.
+type Fake struct {
+	Channel string
+
+	mu       sync.Mutex
+	messages []Message
+	Err      error
+}
+
+func (f *Fake) Name() string { return f.Channel }
+
+func (f *Fake) Send(_ context.Context, m Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return f.Err
+	}
+	f.messages = append(f.messages, m)
+	return nil
+}
+
+// Messages returns a copy of everything sent so far
+func (f *Fake) Messages() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Message(nil), f.messages...)
+}
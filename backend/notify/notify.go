@@ -0,0 +1,294 @@
+// Package notify tells supervisors and guardians about attendance, mood and
+// pairing events. Messages are rendered from templates, queued in the
+// notification store and delivered over pluggable channels with retries.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"server/config"
+	"server/models"
+	"server/store"
+	"text/template"
+	"time"
+)
+
+// Channel names used in recipients and stored notifications
+const (
+	ChannelEmail   = "email"
+	ChannelSMS     = "sms"
+	ChannelWebhook = "webhook"
+)
+
+// Message is what a channel delivers
+type Message struct {
+	Event     string `json:"event"`
+	StudentID *int   `json:"student_id,omitempty"`
+	To        string `json:"to"`
+	Subject   string `json:"subject"`
+	Body      string `json:"body"`
+}
+
+// Channel delivers messages to one kind of address
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, m Message) error
+}
+
+// Recipient is an address on a channel
+type Recipient struct {
+	Channel string
+	Address string
+}
+
+// Event kinds
+const (
+	MissedCheckIn   = "missed_check_in"
+	OutsideGeofence = "outside_geofence"
+	NegativeMood    = "negative_mood"
+	OTPIssued       = "otp_issued"
+	SOSRaised       = "sos_raised"
+	// AttendanceDisputed is raised when an employer disputes a session
+	AttendanceDisputed = "attendance_disputed"
+)
+
+// Event is the data the templates are rendered with
+type Event struct {
+	Kind      string
+	StudentID int
+	Student   string
+	Employer  string
+	// At is when it happened, already formatted in the employer's timezone
+	At        string
+	DistanceM int
+	Streak    int
+	Emotion   string
+	// SOS details; Location is a map link. Note is also the reason given
+	// for a disputed session.
+	IncidentID int
+	Location   string
+	Note       string
+}
+
+type eventTemplate struct {
+	subject, body *template.Template
+}
+
+func mustTemplates(subject, body string) eventTemplate {
+	return eventTemplate{
+		subject: template.Must(template.New("subject").Parse(subject)),
+		body:    template.Must(template.New("body").Parse(body)),
+	}
+}
+
+var templates = map[string]eventTemplate{
+	MissedCheckIn: mustTemplates(
+		"{{.Student}} has not checked in",
+		"{{.Student}} has not checked in for the shift at {{.Employer}} that started at {{.At}}.",
+	),
+	OutsideGeofence: mustTemplates(
+		"{{.Student}} checked in away from work",
+		"{{.Student}} tried to check in at {{.At}} {{.DistanceM}} m away from {{.Employer}}.",
+	),
+	NegativeMood: mustTemplates(
+		"{{.Student}} has reported feeling {{.Emotion}} {{.Streak}} days running",
+		"{{.Student}} has reported feeling {{.Emotion}} on each of the last {{.Streak}} daily check-ins. Please get in touch.",
+	),
+	OTPIssued: mustTemplates(
+		"Pairing code issued for {{.Student}}",
+		"A device pairing code was issued for {{.Student}}. If this was not expected, check the student's paired devices.",
+	),
+	SOSRaised: mustTemplates(
+		"SOS from {{.Student}}",
+		"{{.Student}} raised an SOS at {{.At}}{{with .Employer}} while placed at {{.}}{{end}}. Location: {{.Location}}{{with .Note}} Message: {{.}}{{end}} Acknowledge incident {{.IncidentID}} on the dashboard.",
+	),
+	AttendanceDisputed: mustTemplates(
+		"{{.Employer}} disputed attendance by {{.Student}}",
+		"{{.Employer}} disputed the attendance {{.Student}} recorded for {{.At}}. Reason: {{.Note}}",
+	),
+}
+
+// Retry limits, whatever NOTIFY_MAX_ATTEMPTS and NOTIFY_RETRY_BACKOFF say
+const (
+	maxNotifyAttempts = 20
+	minRetryBackoff   = time.Second
+	maxRetryDelay     = 24 * time.Hour
+)
+
+// Service queues notifications and delivers them
+type Service struct {
+	store    store.NotificationStore
+	channels map[string]Channel
+
+	maxAttempts int
+	backoff     time.Duration
+	// lease is how long a claimed notification is hidden from other workers
+	lease time.Duration
+	// wake makes Run deliver before its next tick
+	wake chan struct{}
+}
+
+// NewService delivers over channels, retrying failed deliveries up to
+// NOTIFY_MAX_ATTEMPTS times with a doubling NOTIFY_RETRY_BACKOFF
+func NewService(st store.NotificationStore, channels ...Channel) *Service {
+	s := &Service{
+		store:       st,
+		channels:    map[string]Channel{},
+		maxAttempts: min(max(config.Int("NOTIFY_MAX_ATTEMPTS", 5), 1), maxNotifyAttempts),
+		backoff:     min(max(config.Duration("NOTIFY_RETRY_BACKOFF", time.Minute), minRetryBackoff), maxRetryDelay),
+		lease:       time.Minute,
+		wake:        make(chan struct{}, 1),
+	}
+	for _, c := range channels {
+		s.channels[c.Name()] = c
+	}
+	return s
+}
+
+// Notify renders the event and queues it for every recipient on a
+// configured channel, plus the webhook when one is configured. Recipients
+// on other channels or with an empty address are skipped.
+func (s *Service) Notify(e Event, recipients ...Recipient) error {
+	tmpl, ok := templates[e.Kind]
+	if !ok {
+		return fmt.Errorf("unknown notification event %q", e.Kind)
+	}
+	var subject, body bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, e); err != nil {
+		return err
+	}
+	if err := tmpl.body.Execute(&body, e); err != nil {
+		return err
+	}
+
+	if _, ok := s.channels[ChannelWebhook]; ok {
+		recipients = append(recipients, Recipient{Channel: ChannelWebhook, Address: ChannelWebhook})
+	}
+	var studentID *int
+	if e.StudentID != 0 {
+		studentID = &e.StudentID
+	}
+	for _, r := range recipients {
+		if _, ok := s.channels[r.Channel]; !ok || r.Address == "" {
+			continue
+		}
+		n := models.Notification{
+			Event:     e.Kind,
+			StudentID: studentID,
+			Channel:   r.Channel,
+			Recipient: r.Address,
+			Subject:   subject.String(),
+			Body:      body.String(),
+		}
+		if err := s.store.Enqueue(&n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deliver sends every notification due at now and records the outcome. It
+// returns how many were sent.
+func (s *Service) Deliver(ctx context.Context, now time.Time) (int, error) {
+	due, err := s.store.Claim(now, s.lease, 50)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, n := range due {
+		n.Attempts++
+		err := s.send(ctx, n)
+		if err == nil {
+			sentAt := time.Now()
+			n.Status, n.LastError, n.SentAt = models.NotificationSent, "", &sentAt
+			sent++
+		} else {
+			n.LastError = err.Error()
+			if n.Attempts >= s.maxAttempts {
+				n.Status = models.NotificationFailed
+				log.Printf("⚠️ Giving up on notification %d after %d attempts: %v", n.ID, n.Attempts, err)
+			} else {
+				n.NextAttemptAt = now.Add(s.retryDelay(n.Attempts))
+			}
+		}
+		if err := s.store.Record(&n); err != nil {
+			log.Printf("Error recording notification %d: %v", n.ID, err)
+		}
+	}
+	return sent, nil
+}
+
+// retryDelay is the backoff doubled for each attempt after the first, capped
+// at maxRetryDelay
+func (s *Service) retryDelay(attempts int) time.Duration {
+	delay := s.backoff
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+func (s *Service) send(ctx context.Context, n models.Notification) error {
+	c, ok := s.channels[n.Channel]
+	if !ok {
+		return fmt.Errorf("channel %q is not configured", n.Channel)
+	}
+	return c.Send(ctx, Message{Event: n.Event, StudentID: n.StudentID, To: n.Recipient, Subject: n.Subject, Body: n.Body})
+}
+
+// Run delivers due notifications every interval until ctx is done
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.Deliver(ctx, time.Now()); err != nil {
+			log.Printf("⚠️ Delivering notifications: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// Wake asks Run to deliver queued notifications now rather than at its next
+// tick, for events that should not wait
+func (s *Service) Wake() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// FromEnv builds a service with every channel that has its settings:
+// SMTP_HOST for email, SMS_GATEWAY_URL for SMS and NOTIFY_WEBHOOK_URL for
+// the webhook
+func FromEnv(st store.NotificationStore) *Service {
+	var channels []Channel
+	if host := config.String("SMTP_HOST", ""); host != "" {
+		channels = append(channels, &SMTP{
+			Addr:     fmt.Sprintf("%s:%d", host, config.Int("SMTP_PORT", 587)),
+			Username: config.String("SMTP_USERNAME", ""),
+			Password: config.String("SMTP_PASSWORD", ""),
+			From:     config.String("SMTP_FROM", ""),
+			Timeout:  config.Duration("SMTP_TIMEOUT", defaultSMTPTimeout),
+		})
+	}
+	// A zero timeout would let a hung gateway stall delivery forever
+	timeout := max(config.Duration("NOTIFY_HTTP_TIMEOUT", 10*time.Second), time.Second)
+	if url := config.String("SMS_GATEWAY_URL", ""); url != "" {
+		channels = append(channels, NewSMSGateway(url, config.String("SMS_GATEWAY_API_KEY", ""), timeout))
+	}
+	if url := config.String("NOTIFY_WEBHOOK_URL", ""); url != "" {
+		channels = append(channels, NewWebhook(url, config.String("NOTIFY_WEBHOOK_SECRET", ""), timeout))
+	}
+	if len(channels) == 0 {
+		log.Println("No notification channels configured; notifications are disabled")
+	}
+	return NewService(st, channels...)
+}
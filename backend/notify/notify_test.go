@@ -0,0 +1,189 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"server/models"
+	"server/store/memory"
+)
+
+func TestDeliverRetriesWithBackoff(t *testing.T) {
+	t.Setenv("NOTIFY_MAX_ATTEMPTS", "3")
+	t.Setenv("NOTIFY_RETRY_BACKOFF", "1m")
+	st := memory.New().Notifications
+	email := &Fake{Channel: ChannelEmail, Err: errors.New("mailbox unavailable")}
+	s := NewService(st, email)
+
+	if err := s.Notify(Event{Kind: OTPIssued, StudentID: 7, Student: "Nimal"}, Recipient{ChannelEmail, "sup@example.com"}, Recipient{ChannelSMS, "+94770000000"}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Add(time.Second)
+	for attempt, wait := range []time.Duration{time.Minute, 2 * time.Minute, 0} {
+		if n, err := s.Deliver(context.Background(), now); err != nil || n != 0 {
+			t.Fatalf("attempt %d: sent %d, %v", attempt+1, n, err)
+		}
+		// Nothing is due again before the backoff has passed
+		if due, _ := st.Claim(now.Add(wait-time.Second), time.Minute, 0); len(due) != 0 {
+			t.Fatalf("attempt %d: retried before %v", attempt+1, wait)
+		}
+		now = now.Add(wait)
+	}
+
+	list, err := st.List("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The SMS recipient is dropped since no SMS channel is configured
+	if len(list) != 1 {
+		t.Fatalf("queued %d notifications, want 1", len(list))
+	}
+	if n := list[0]; n.Status != models.NotificationFailed || n.Attempts != 3 || n.LastError != "mailbox unavailable" {
+		t.Errorf("got status %q after %d attempts (%q), want failed after 3", n.Status, n.Attempts, n.LastError)
+	}
+
+	email.Err = nil
+	if err := s.Notify(Event{Kind: OTPIssued, Student: "Nimal"}, Recipient{ChannelEmail, "sup@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.Deliver(context.Background(), time.Now().Add(time.Second)); err != nil || n != 1 {
+		t.Fatalf("sent %d, %v; want 1", n, err)
+	}
+	if got := email.Messages(); len(got) != 1 || got[0].Subject != "Pairing code issued for Nimal" {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestRetrySettingsAreClamped(t *testing.T) {
+	t.Setenv("NOTIFY_MAX_ATTEMPTS", "1000")
+	t.Setenv("NOTIFY_RETRY_BACKOFF", "-1m")
+	s := NewService(memory.New().Notifications)
+	if s.maxAttempts != maxNotifyAttempts {
+		t.Errorf("max attempts = %d, want %d", s.maxAttempts, maxNotifyAttempts)
+	}
+	if got := s.retryDelay(1); got != minRetryBackoff {
+		t.Errorf("first retry after %v, want %v", got, minRetryBackoff)
+	}
+	// Doubling never overflows into a negative or unbounded wait
+	if got := s.retryDelay(200); got != maxRetryDelay {
+		t.Errorf("retry after 200 attempts = %v, want %v", got, maxRetryDelay)
+	}
+}
+
+func TestWebhookSignsBody(t *testing.T) {
+	var body []byte
+	var signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get("X-Signature-SHA256")
+	}))
+	defer srv.Close()
+
+	err := NewWebhook(srv.URL, "s3cret", time.Second).Send(context.Background(), Message{Event: MissedCheckIn, To: ChannelWebhook, Subject: "x", Body: "y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	if want := hex.EncodeToString(mac.Sum(nil)); signature != want {
+		t.Errorf("signature = %q, want %q", signature, want)
+	}
+}
+
+// fakeSMTP accepts one session and returns what was sent in DATA. With
+// silent set it accepts the connection and never answers.
+func fakeSMTP(t *testing.T, silent bool) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	data := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if silent {
+			io.Copy(io.Discard, conn)
+			return
+		}
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 localhost ready")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch strings.ToUpper(strings.Fields(line)[0]) {
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				lines, _ := tp.ReadDotLines()
+				data <- strings.Join(lines, "\n")
+				tp.PrintfLine("250 queued")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				return
+			default:
+				tp.PrintfLine("250 ok")
+			}
+		}
+	}()
+	return ln.Addr().String(), data
+}
+
+func TestSMTPSendsMessage(t *testing.T) {
+	addr, data := fakeSMTP(t, false)
+	c := &SMTP{Addr: addr, From: "alerts@example.com", Timeout: time.Second}
+	if err := c.Send(context.Background(), Message{To: "ruwani@example.com", Subject: "Nimal’s check-in", Body: "Missed"}); err != nil {
+		t.Fatal(err)
+	}
+	got := <-data
+	if !strings.Contains(got, "To: ruwani@example.com") || !strings.Contains(got, "Subject: =?UTF-8?q?") || !strings.HasSuffix(got, "Missed") {
+		t.Errorf("message = %q", got)
+	}
+}
+
+func TestSMTPRejectsLineBreaksInHeaders(t *testing.T) {
+	c := &SMTP{Addr: "127.0.0.1:1", From: "alerts@example.com"}
+	for _, m := range []Message{
+		{To: "ruwani@example.com", Subject: "Hi\r\nBcc: everyone@example.com"},
+		{To: "ruwani@example.com\nBcc: everyone@example.com", Subject: "Hi"},
+	} {
+		if err := c.Send(context.Background(), m); err == nil || !strings.Contains(err.Error(), "line break") {
+			t.Errorf("Send(%q) = %v, want a line break error", m.To+m.Subject, err)
+		}
+	}
+}
+
+func TestSMTPGivesUpOnSilentServer(t *testing.T) {
+	addr, _ := fakeSMTP(t, true)
+	start := time.Now()
+	if err := (&SMTP{Addr: addr, Timeout: 100 * time.Millisecond}).Send(context.Background(), Message{To: "a@example.com"}); err == nil {
+		t.Error("send to a silent server succeeded")
+	}
+
+	addr, _ = fakeSMTP(t, true)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := (&SMTP{Addr: addr, Timeout: time.Minute}).Send(ctx, Message{To: "a@example.com"}); err == nil {
+		t.Error("send past the context deadline succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("sends took %v", elapsed)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"server/models"
+)
+
+func TestPlacementHistory(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Dilani")
+	headers := studentHeader(studentID)
+
+	var first, second models.Employer
+	api.mustDo("POST", "/create-employer", api.adminToken, nil, models.Employer{Name: "Kingsbury", Latitude: 6.9271, Longitude: 79.8612, GeofenceRadius: 100}, http.StatusOK, &first)
+	api.mustDo("POST", "/create-employer", api.adminToken, nil, models.Employer{Name: "Cinnamon Grand", Latitude: 6.9271, Longitude: 79.8612, GeofenceRadius: 100}, http.StatusOK, &second)
+	firstID, secondID := int(first.ID), int(second.ID)
+
+	colombo, _ := time.LoadLocation("Asia/Colombo")
+	now := time.Now().In(colombo)
+	day := func(offset int) string { return now.AddDate(0, 0, offset).Format("2006-01-02") }
+
+	var started models.Placement
+	api.mustDo("POST", "/start-placement", api.adminToken, headers, models.PlacementRequest{EmployerID: firstID, Role: " Barista ", StartDate: day(-30)}, http.StatusCreated, &started)
+	if started.ID == 0 || started.Role != "Barista" || started.StartDate != day(-30) || started.EndDate != nil || *started.EmployerID != firstID {
+		t.Fatalf("started = %+v", started)
+	}
+	if s, _ := api.store.Students.Get(studentID); s.EmployerID == nil || int(*s.EmployerID) != firstID {
+		t.Fatalf("student employer = %v, want %d", s.EmployerID, firstID)
+	}
+
+	// Attendance is tagged with the placement it was recorded under
+	token := api.pairDevice(studentID)
+	var session models.Attendance
+	api.mustDo("POST", "/attendance", token, nil, attendanceRequest(true, time.Now().UTC()), http.StatusOK, &session)
+	if session.PlacementID == nil || *session.PlacementID != started.ID {
+		t.Fatalf("session placement = %v, want %d", session.PlacementID, started.ID)
+	}
+	api.mustDo("POST", "/attendance", token, nil, attendanceRequest(false, time.Now().UTC()), http.StatusOK, nil)
+	postMood(api, token, "sad", false, time.Now().AddDate(0, 0, -2))
+
+	// Moving employer ends the first placement the day before
+	schedule := []models.ScheduleDay{{Weekday: time.Monday, Start: "09:00", End: "17:00"}}
+	api.mustDo("POST", "/start-placement", api.adminToken, headers, models.PlacementRequest{EmployerID: secondID, StartDate: day(-60)}, http.StatusConflict, nil)
+	api.mustDo("POST", "/start-placement", api.adminToken, headers, models.PlacementRequest{EmployerID: secondID, StartDate: day(1)}, http.StatusBadRequest, nil)
+	api.mustDo("POST", "/start-placement", api.adminToken, headers, models.PlacementRequest{EmployerID: 9999}, http.StatusBadRequest, nil)
+	api.mustDo("POST", "/start-placement", api.adminToken, headers, models.PlacementRequest{EmployerID: secondID, Schedule: []models.ScheduleDay{{Weekday: 9, Start: "09:00", End: "17:00"}}}, http.StatusBadRequest, nil)
+	var moved models.Placement
+	api.mustDo("POST", "/start-placement", api.adminToken, headers, models.PlacementRequest{EmployerID: secondID, Role: "Kitchen assistant", Schedule: schedule}, http.StatusCreated, &moved)
+	if moved.StartDate != day(0) || len(moved.Schedule) != 1 {
+		t.Fatalf("moved = %+v", moved)
+	}
+	var weekly models.WeeklySchedule
+	api.mustDo("GET", "/schedule", api.adminToken, headers, nil, http.StatusOK, &weekly)
+	if len(weekly.Days) != 1 || weekly.Days[0].Start != "09:00" {
+		t.Errorf("schedule after move = %+v", weekly.Days)
+	}
+
+	var placements []models.Placement
+	api.mustDo("GET", "/placements", api.adminToken, headers, nil, http.StatusOK, &placements)
+	if len(placements) != 2 || placements[0].ID != moved.ID || placements[1].ID != started.ID {
+		t.Fatalf("placements = %+v", placements)
+	}
+	if end := placements[1].EndDate; end == nil || *end != day(-1) {
+		t.Errorf("first placement ended %v, want %s", end, day(-1))
+	}
+
+	// The old employer still reviews sessions from its placement; the new one cannot
+	firstToken := api.employerToken("kingsbury", firstID)
+	secondToken := api.employerToken("cinnamon", secondID)
+	review := models.ReviewRequest{AttendanceID: int(session.ID), Status: models.ReviewConfirmed}
+	api.mustDo("POST", "/review-attendance", secondToken, nil, review, http.StatusForbidden, nil)
+	api.mustDo("POST", "/review-attendance", firstToken, nil, review, http.StatusOK, nil)
+
+	// Attendance views follow the placement each session was recorded under
+	var days []models.AttendanceDay
+	api.mustDo("GET", "/attendance-days?from="+day(-1)+"&to="+day(0), firstToken, headers, nil, http.StatusOK, &days)
+	if len(days) != 1 || len(days[0].Sessions) != 1 || days[0].Sessions[0].ID != session.ID {
+		t.Errorf("old employer days = %+v", days)
+	}
+	api.mustDo("GET", "/attendance-days?from="+day(-1)+"&to="+day(0), secondToken, headers, nil, http.StatusOK, &days)
+	if len(days) != 0 {
+		t.Errorf("new employer days = %+v, want none", days)
+	}
+	var cards []models.StudentCard
+	api.mustDo("GET", "/dashboard", secondToken, nil, nil, http.StatusOK, &cards)
+	if len(cards) != 1 || !cards[0].CheckInDateTime.IsZero() || cards[0].Emotion != "" {
+		t.Errorf("new employer cards = %+v, want no attendance or mood from the old placement", cards)
+	}
+	api.mustDo("GET", "/dashboard", api.adminToken, nil, nil, http.StatusOK, &cards)
+	if len(cards) != 1 || cards[0].Emotion != "sad" {
+		t.Errorf("admin cards = %+v, want the latest mood", cards)
+	}
+
+	// Days in the ended placement keep its schedule, not the new template
+	monday := -2
+	for now.AddDate(0, 0, monday).Weekday() != time.Monday {
+		monday--
+	}
+	var shift models.ExpectedShift
+	api.mustDo("GET", "/expected-shift?date="+day(monday), api.adminToken, headers, nil, http.StatusOK, &shift)
+	if shift.Source != models.ShiftSourceLegacy || shift.Start != "08:30" {
+		t.Errorf("shift in the old placement = %+v, want the legacy 08:30 start", shift)
+	}
+
+	// Ending leaves the student without an employer
+	api.mustDo("POST", "/end-placement", api.adminToken, headers, models.EndPlacementRequest{EndDate: day(-1)}, http.StatusBadRequest, nil)
+	var ended models.Placement
+	api.mustDo("POST", "/end-placement", api.adminToken, headers, models.EndPlacementRequest{Reason: "Contract finished"}, http.StatusOK, &ended)
+	if ended.ID != moved.ID || ended.EndDate == nil || *ended.EndDate != day(0) || ended.EndReason != "Contract finished" {
+		t.Errorf("ended = %+v", ended)
+	}
+	if s, _ := api.store.Students.Get(studentID); s.EmployerID != nil {
+		t.Errorf("student employer = %d after ending, want none", *s.EmployerID)
+	}
+	api.mustDo("POST", "/end-placement", api.adminToken, headers, nil, http.StatusNotFound, nil)
+	api.mustDo("GET", "/placements", api.adminToken, studentHeader(9999), nil, http.StatusNotFound, nil)
+}
+
+func TestPlacementFollowsEmployeeUpdates(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Kasun")
+	employer := api.assignEmployer(studentID, models.Employer{Name: "Kingsbury", Latitude: 6.9271, Longitude: 79.8612, GeofenceRadius: 100})
+
+	var placements []models.Placement
+	api.mustDo("GET", "/placements", api.adminToken, studentHeader(studentID), nil, http.StatusOK, &placements)
+	if len(placements) != 1 || placements[0].EmployerID == nil || *placements[0].EmployerID != int(employer.ID) || placements[0].EndDate != nil {
+		t.Fatalf("placements after assigning = %+v", placements)
+	}
+
+	// Clearing the employer ends the placement
+	api.mustDo("PUT", "/update-employee", api.adminToken, studentHeader(studentID), models.Student{FirstName: "Kasun"}, http.StatusOK, nil)
+	api.mustDo("GET", "/placements", api.adminToken, studentHeader(studentID), nil, http.StatusOK, &placements)
+	if len(placements) != 1 || placements[0].EndDate == nil {
+		t.Fatalf("placements after clearing = %+v", placements)
+	}
+
+	// Supervisors see the history of their own trainees only
+	supervised := api.supervisedStudent("Nadeesha")
+	s, err := api.store.Students.Get(supervised)
+	if err != nil {
+		t.Fatal(err)
+	}
+	supervisorToken := api.supervisorToken("ruwani", int(*s.SupervisorID))
+	api.mustDo("GET", "/placements", supervisorToken, studentHeader(supervised), nil, http.StatusOK, &placements)
+	if len(placements) != 1 {
+		t.Errorf("supervised placements = %+v", placements)
+	}
+	api.mustDo("GET", "/placements", supervisorToken, studentHeader(studentID), nil, http.StatusForbidden, nil)
+}
+
+func TestReviewNeedsTheSessionsPlacement(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Sachini")
+	token := api.pairDevice(studentID)
+
+	// Recorded before the student was placed anywhere
+	var unplaced models.Attendance
+	api.attend(token, true, time.Now().UTC(), http.StatusOK, &unplaced)
+	api.attend(token, false, time.Now().UTC(), http.StatusOK, nil)
+	if unplaced.PlacementID != nil {
+		t.Fatalf("unplaced session has placement %d", *unplaced.PlacementID)
+	}
+
+	employer := api.assignEmployer(studentID, models.Employer{Name: "Kingsbury", Latitude: 6.9271, Longitude: 79.8612, GeofenceRadius: 100})
+	employerToken := api.employerToken("kingsbury", int(employer.ID))
+	dispute := models.ReviewRequest{AttendanceID: int(unplaced.ID), Status: models.ReviewDisputed, Note: "Not ours"}
+	api.mustDo("POST", "/review-attendance", employerToken, nil, dispute, http.StatusForbidden, nil)
+
+	// Sessions under the placement are reviewed at the server's time
+	var placed, reviewed models.Attendance
+	api.attend(token, true, time.Now().UTC(), http.StatusOK, &placed)
+	reviewedAt := time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC)
+	api.setClock(reviewedAt)
+	defer api.setClock(time.Time{})
+	api.mustDo("POST", "/review-attendance", employerToken, nil, models.ReviewRequest{AttendanceID: int(placed.ID), Status: models.ReviewConfirmed}, http.StatusOK, &reviewed)
+	if reviewed.ReviewedAt == nil || !reviewed.ReviewedAt.Equal(reviewedAt) {
+		t.Errorf("reviewed at %v, want %v", reviewed.ReviewedAt, reviewedAt)
+	}
+}
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter counts events per key in a fixed window and locks a key out once it
+// has recorded limit events in that window. State is kept in memory, so
+// each server replica enforces its own limits.
+type Limiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	lockout time.Duration
+	entries map[string]*entry
+	now     func() time.Time
+}
+
+type entry struct {
+	count       int
+	windowStart time.Time
+	lockedUntil time.Time
+}
+
+// New returns a limiter allowing limit events per window, after which the key
+// is locked out for lockout. Callers Check a key before acting and Record the
+// events that count against it. A limit below 1 is taken as 1, so the first
+// event is always allowed.
+func New(limit int, window, lockout time.Duration) *Limiter {
+	return &Limiter{
+		limit:   max(limit, 1),
+		window:  window,
+		lockout: lockout,
+		entries: make(map[string]*entry),
+		now:     time.Now,
+	}
+}
+
+// Check returns how long key remains locked out, or zero if it is not locked.
+// It does not record an event.
+func (l *Limiter) Check(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.entries[key]; ok {
+		if remaining := e.lockedUntil.Sub(l.now()); remaining > 0 {
+			return remaining
+		}
+	}
+	return 0
+}
+
+// Record counts one event for key and returns how long the key is now locked
+// out, or zero if it is still within its limit
+func (l *Limiter) Record(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	e, ok := l.entries[key]
+	if !ok {
+		if len(l.entries) > 10000 {
+			l.prune(now)
+		}
+		e = &entry{windowStart: now}
+		l.entries[key] = e
+	}
+	if remaining := e.lockedUntil.Sub(now); remaining > 0 {
+		return remaining
+	}
+	if now.Sub(e.windowStart) >= l.window {
+		e.count = 0
+		e.windowStart = now
+	}
+
+	e.count++
+	if e.count >= l.limit {
+		e.lockedUntil = now.Add(l.lockout)
+		e.count = 0
+		e.windowStart = now
+		return l.lockout
+	}
+	return 0
+}
+
+// Reset forgets everything recorded for key
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, key)
+}
+
+// prune drops entries whose window and lockout have both passed
+func (l *Limiter) prune(now time.Time) {
+	for key, e := range l.entries {
+		if now.After(e.lockedUntil) && now.Sub(e.windowStart) >= l.window {
+			delete(l.entries, key)
+		}
+	}
+}
@@ -1,56 +1,132 @@
 package routes
 
 import (
+	"net/http"
+	"server/auth"
 	"server/controllers"
+	"server/middleware"
 
 	"github.com/gorilla/mux"
 )
 
-func RegisterStudentRoutes(router *mux.Router) {
-	router.HandleFunc("/get-students", controllers.GetStudents).Methods("GET")
+var (
+	// adminOnly routes change master data or expose pairing codes
+	adminOnly = middleware.RequireRoles(auth.RoleAdmin)
+	// dashboard routes are read by admins and supervisors from the web app
+	dashboard = middleware.RequireRoles(auth.RoleAdmin, auth.RoleSupervisor)
+	// anyRole routes are also called by the mobile app; trainees are pinned to their own student-id
+	anyRole = middleware.RequireRoles(auth.RoleAdmin, auth.RoleSupervisor, auth.RoleTrainee)
+	// trainee routes are only reachable from the mobile app
+	trainee = middleware.RequireRoles(auth.RoleTrainee)
+	// employerPortal routes are dashboard routes employer accounts also use;
+	// the handlers keep employers to the trainees placed with them
+	employerPortal = middleware.RequireRoles(auth.RoleAdmin, auth.RoleSupervisor, auth.RoleEmployer)
+	// anyRoleOrEmployer routes are anyRole routes employer accounts also use
+	anyRoleOrEmployer = middleware.RequireRoles(auth.RoleAdmin, auth.RoleSupervisor, auth.RoleTrainee, auth.RoleEmployer)
+	// employerOnly routes record an employer's own say on their trainees
+	employerOnly = middleware.RequireRoles(auth.RoleEmployer)
+)
+
+func handle(router *mux.Router, path string, guard func(http.Handler) http.Handler, h http.HandlerFunc) *mux.Route {
+	return router.Handle(path, guard(h))
+}
+
+func RegisterStudentRoutes(router *mux.Router, h *controllers.Handler, authService *controllers.AuthService) {
+	// pairedDevice routes record data on behalf of a trainee and need a session
+	// from a device that has not been revoked since it was paired
+	pairedDevice := func(h http.Handler) http.Handler {
+		return trainee(authService.RequireDevice(h))
+	}
+
+	handle(router, "/get-students", dashboard, h.GetStudents).Methods("GET")
 
-	router.HandleFunc("/create-employee", controllers.CreateStudent).Methods("POST")
-	router.HandleFunc("/update-employee", controllers.UpdateStudent).Methods("PUT")
-	router.HandleFunc("/delete-employee", controllers.DeleteStudent).Methods("DELETE")
+	handle(router, "/create-employee", adminOnly, h.CreateStudent).Methods("POST")
+	handle(router, "/update-employee", adminOnly, h.UpdateStudent).Methods("PUT")
+	handle(router, "/delete-employee", adminOnly, h.DeleteStudent).Methods("DELETE")
 
-	router.HandleFunc("/get-student", controllers.GetStudent).Methods("GET")
+	handle(router, "/get-student", anyRole, h.GetStudent).Methods("GET")
 
 	//supervisor routes
-	router.HandleFunc("/get-supervisors", controllers.GetSupervisors).Methods("GET")
-	router.HandleFunc("/get-supervisor", controllers.GetSupervisor).Methods("GET")
-	router.HandleFunc("/create-supervisor", controllers.CreateSupervisor).Methods("POST")
-	router.HandleFunc("/update-supervisor", controllers.UpdateSupervisor).Methods("PUT")
-	router.HandleFunc("/delete-supervisor", controllers.DeleteSupervisor).Methods("DELETE")
+	handle(router, "/get-supervisors", dashboard, h.GetSupervisors).Methods("GET")
+	handle(router, "/get-supervisor", dashboard, h.GetSupervisor).Methods("GET")
+	handle(router, "/create-supervisor", adminOnly, h.CreateSupervisor).Methods("POST")
+	handle(router, "/update-supervisor", adminOnly, h.UpdateSupervisor).Methods("PUT")
+	handle(router, "/delete-supervisor", adminOnly, h.DeleteSupervisor).Methods("DELETE")
 
 	// employer routes
-	router.HandleFunc("/get-employers", controllers.GetAllEmployerIDsAndNames).Methods("GET")
-	router.HandleFunc("/create-employer", controllers.CreateEmployer).Methods("POST")
-	router.HandleFunc("/get-employer", controllers.GetEmployer).Methods("GET")
-	router.HandleFunc("/update-employer", controllers.UpdateEmployer).Methods("PUT")
-	router.HandleFunc("/delete-employer", controllers.DeleteEmployer).Methods("DELETE")
-	router.HandleFunc("/get-employer-ids", controllers.GetAllEmployerIDsAndNames).Methods("GET")
+	handle(router, "/get-employers", dashboard, h.GetAllEmployerIDsAndNames).Methods("GET")
+	handle(router, "/create-employer", adminOnly, h.CreateEmployer).Methods("POST")
+	handle(router, "/get-employer", dashboard, h.GetEmployer).Methods("GET")
+	handle(router, "/update-employer", adminOnly, h.UpdateEmployer).Methods("PUT")
+	handle(router, "/delete-employer", adminOnly, h.DeleteEmployer).Methods("DELETE")
+	handle(router, "/get-employer-ids", dashboard, h.GetAllEmployerIDsAndNames).Methods("GET")
 
 	// Add attendance routes
-	router.HandleFunc("/attendance", controllers.PostAttendance).Methods("POST")
+	handle(router, "/attendance", pairedDevice, h.PostAttendance).Methods("POST")
+	handle(router, "/attendance-days", anyRoleOrEmployer, h.GetAttendanceDays).Methods("GET")
+	handle(router, "/review-attendance", employerOnly, h.ReviewAttendance).Methods("POST")
+	handle(router, "/validate-location", anyRole, h.ValidateLocationHandler()).Methods("POST")
+
+	// Schedule routes
+	handle(router, "/schedule", anyRole, h.GetSchedule).Methods("GET")
+	handle(router, "/schedule", adminOnly, h.UpdateSchedule).Methods("PUT")
+	handle(router, "/schedule-overrides", anyRole, h.GetScheduleOverrides).Methods("GET")
+	handle(router, "/schedule-override", adminOnly, h.PutScheduleOverride).Methods("PUT")
+	handle(router, "/schedule-override", adminOnly, h.DeleteScheduleOverride).Methods("DELETE")
+	handle(router, "/expected-shift", anyRole, h.GetExpectedShift).Methods("GET")
+
+	// Placement history
+	handle(router, "/placements", dashboard, h.GetPlacements).Methods("GET")
+	handle(router, "/start-placement", adminOnly, h.StartPlacement).Methods("POST")
+	handle(router, "/end-placement", adminOnly, h.EndPlacement).Methods("POST")
 
 	// Add mood routes
-	router.HandleFunc("/post-mood", controllers.CreateMood).Methods("POST")
-	router.HandleFunc("/get-mood", controllers.GetMoods).Methods("GET")
+	handle(router, "/post-mood", pairedDevice, h.CreateMood).Methods("POST")
+	handle(router, "/get-mood", dashboard, h.GetMoods).Methods("GET")
+	handle(router, "/moods", anyRole, h.GetMoodHistory).Methods("GET")
+	handle(router, "/mood/{id}", anyRole, h.GetMood).Methods("GET")
+	handle(router, "/mood-vocabulary", anyRole, h.GetMoodVocabulary).Methods("GET")
+	handle(router, "/mood-trends", anyRole, h.GetMoodTrends).Methods("GET")
+	handle(router, "/mood-streaks", dashboard, h.GetMoodStreaks).Methods("GET")
 
 	// Add card routes
-	router.HandleFunc("/dashboard", controllers.GetStudentDetails).Methods("GET")
+	handle(router, "/dashboard", employerPortal, h.GetStudentDetails).Methods("GET")
 
-	router.HandleFunc("/employees", controllers.GetEmployeeData).Methods("GET")
-	router.HandleFunc("/management", controllers.GetManagementTable).Methods("GET")
-	router.HandleFunc("/trainee-profile", controllers.GetTraineeProfile).Methods("GET")
+	// /employees includes the latest pairing OTP for every student
+	handle(router, "/employees", adminOnly, h.GetEmployeeData).Methods("GET")
+	handle(router, "/management", employerPortal, h.GetManagementTable).Methods("GET")
+	// A supervisor's own trainees; admins pick the supervisor-id
+	handle(router, "/caseload-dashboard", dashboard, h.GetCaseloadDashboard).Methods("GET")
+	handle(router, "/caseload-management", dashboard, h.GetCaseloadManagement).Methods("GET")
+	handle(router, "/caseload-employees", dashboard, h.GetCaseloadEmployees).Methods("GET")
+	handle(router, "/trainee-profile", dashboard, h.GetTraineeProfile).Methods("GET")
+	handle(router, "/trainee-summary", dashboard, h.GetTraineeSummary).Methods("GET")
 
-	router.HandleFunc("/get-supervisor-ids", controllers.GetAllSupervisorIDsAndNames).Methods("GET")
-	// router.HandleFunc("/get-employer-ids", controllers.GetAllEmployerIDsAndNames).Methods("GET")
+	handle(router, "/get-supervisor-ids", dashboard, h.GetAllSupervisorIDsAndNames).Methods("GET")
+	// router.HandleFunc("/get-employer-ids", h.GetAllEmployerIDsAndNames).Methods("GET")
 
 	// Manager feedback route
-	router.HandleFunc("/manager-feedback", controllers.FetchManagerFeedback).Methods("GET")
+	handle(router, "/manager-feedback", dashboard, h.FetchManagerFeedback).Methods("GET")
+	handle(router, "/feedback-sheet-rows", dashboard, h.GetFeedbackSheetRows).Methods("GET")
+	handle(router, "/sync-manager-feedback", adminOnly, h.SyncManagerFeedback).Methods("POST")
+	handle(router, "/feedback-competencies", employerPortal, h.GetFeedbackCompetencies).Methods("GET")
+	handle(router, "/submit-feedback", employerPortal, h.SubmitFeedback).Methods("POST")
+	handle(router, "/student-feedback", employerPortal, h.GetStudentFeedback).Methods("GET")
+	handle(router, "/employer-feedback", employerPortal, h.GetEmployerFeedback).Methods("GET")
+
+	// Notification history
+	handle(router, "/notifications", adminOnly, h.GetNotifications).Methods("GET")
+
+	// SOS incidents
+	handle(router, "/sos", pairedDevice, h.RaiseSOS).Methods("POST")
+	handle(router, "/sos", trainee, h.GetCurrentIncident).Methods("GET")
+	handle(router, "/incidents", dashboard, h.GetIncidents).Methods("GET")
+	handle(router, "/acknowledge-incident", dashboard, h.AcknowledgeIncident).Methods("POST")
+	handle(router, "/resolve-incident", dashboard, h.ResolveIncident).Methods("POST")
 
 	// Emergency contact routes
-	router.HandleFunc("/get-emergency-contact", controllers.GetEmergencyContact).Methods("GET")
-	router.HandleFunc("/update-emergency-contact", controllers.UpdateEmergencyContact).Methods("POST")
+	handle(router, "/get-emergency-contact", anyRole, h.GetEmergencyContact).Methods("GET")
+	handle(router, "/update-emergency-contact", adminOnly, h.UpdateEmergencyContact).Methods("POST")
+	handle(router, "/delete-emergency-contact", adminOnly, h.DeleteEmergencyContact).Methods("DELETE")
+	handle(router, "/emergency-contact-history", adminOnly, h.GetEmergencyContactHistory).Methods("GET")
 }
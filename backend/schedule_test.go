@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"server/models"
+)
+
+func TestScheduleWeeklyAndOverrides(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Tharindu")
+	headers := studentHeader(studentID)
+
+	week := models.WeeklySchedule{Days: []models.ScheduleDay{
+		{Weekday: time.Monday, Start: "08:00", End: "16:00"},
+		{Weekday: time.Saturday, Start: "08:00", End: "12:00"},
+	}}
+	api.mustDo("PUT", "/schedule", api.adminToken, headers, week, http.StatusOK, nil)
+
+	var saved models.WeeklySchedule
+	api.mustDo("GET", "/schedule", api.adminToken, headers, nil, http.StatusOK, &saved)
+	if len(saved.Days) != 2 || saved.Timezone != "Asia/Colombo" {
+		t.Fatalf("schedule = %+v, want two days in Asia/Colombo", saved)
+	}
+
+	// 2030-01-07 is a Monday, 2030-01-08 a Tuesday and 2030-01-12 a Saturday
+	shiftOn := func(date string) models.ExpectedShift {
+		var shift models.ExpectedShift
+		api.mustDo("GET", "/expected-shift?date="+date, api.adminToken, headers, nil, http.StatusOK, &shift)
+		return shift
+	}
+	monday := shiftOn("2030-01-07")
+	if !monday.Working || monday.Start != "08:00" || monday.Source != models.ShiftSourceWeekly {
+		t.Errorf("monday = %+v, want weekly 08:00 shift", monday)
+	}
+	if want := time.Date(2030, 1, 7, 2, 30, 0, 0, time.UTC); monday.StartsAt == nil || !monday.StartsAt.Equal(want) {
+		t.Errorf("monday starts at %v, want %v", monday.StartsAt, want)
+	}
+	if tuesday := shiftOn("2030-01-08"); tuesday.Working {
+		t.Errorf("tuesday = %+v, want a day off", tuesday)
+	}
+	if saturday := shiftOn("2030-01-12"); saturday.End != "12:00" {
+		t.Errorf("saturday = %+v, want half day", saturday)
+	}
+
+	// Overrides win over the template
+	api.mustDo("PUT", "/schedule-override", api.adminToken, headers, models.ScheduleOverride{Date: "2030-01-07", DayOff: true, Reason: "Poya"}, http.StatusOK, nil)
+	api.mustDo("PUT", "/schedule-override", api.adminToken, headers, models.ScheduleOverride{Date: "2030-01-08", Start: "22:00", End: "06:00"}, http.StatusOK, nil)
+	if monday := shiftOn("2030-01-07"); monday.Working || monday.Source != models.ShiftSourceOverride || monday.Reason != "Poya" {
+		t.Errorf("monday = %+v, want the Poya day off", monday)
+	}
+	night := shiftOn("2030-01-08")
+	if !night.Working || night.EndsAt == nil || night.EndsAt.Sub(*night.StartsAt) != 8*time.Hour {
+		t.Errorf("night shift = %+v, want eight hours past midnight", night)
+	}
+
+	var overrides []models.ScheduleOverride
+	api.mustDo("GET", "/schedule-overrides?from=2030-01-01&to=2030-01-31", api.adminToken, headers, nil, http.StatusOK, &overrides)
+	if len(overrides) != 2 || overrides[0].Date != "2030-01-07" {
+		t.Fatalf("overrides = %+v, want two starting 2030-01-07", overrides)
+	}
+	api.mustDo("DELETE", "/schedule-override?date=2030-01-07", api.adminToken, headers, nil, http.StatusNoContent, nil)
+	api.mustDo("DELETE", "/schedule-override?date=2030-01-07", api.adminToken, headers, nil, http.StatusNotFound, nil)
+	if monday := shiftOn("2030-01-07"); !monday.Working {
+		t.Errorf("monday = %+v, want the weekly shift back", monday)
+	}
+}
+
+func TestScheduleFallsBackToLegacyTimes(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.createStudent("Nadeesha")
+
+	// createStudent sets 08:30 to 16:30 and no weekly template exists
+	var shift models.ExpectedShift
+	api.mustDo("GET", "/expected-shift?date=2030-01-12", api.adminToken, studentHeader(studentID), nil, http.StatusOK, &shift)
+	if !shift.Working || shift.Start != "08:30" || shift.Source != models.ShiftSourceLegacy {
+		t.Errorf("shift = %+v, want the legacy 08:30 shift", shift)
+	}
+}
+
+func TestScheduleValidation(t *testing.T) {
+	api := newTestAPI(t)
+	headers := studentHeader(api.createStudent("Ruwan"))
+
+	for _, days := range [][]models.ScheduleDay{
+		{{Weekday: 7, Start: "08:00", End: "16:00"}},
+		{{Weekday: time.Monday, Start: "8am", End: "16:00"}},
+		{{Weekday: time.Monday, Start: "08:00", End: "08:00"}},
+		{{Weekday: time.Monday, Start: "08:00", End: "16:00"}, {Weekday: time.Monday, Start: "09:00", End: "17:00"}},
+	} {
+		api.mustDo("PUT", "/schedule", api.adminToken, headers, models.WeeklySchedule{Days: days}, http.StatusBadRequest, nil)
+	}
+	api.mustDo("PUT", "/schedule-override", api.adminToken, headers, models.ScheduleOverride{Date: "07/01/2030", DayOff: true}, http.StatusBadRequest, nil)
+	api.mustDo("GET", "/expected-shift", api.adminToken, studentHeader(999), nil, http.StatusNotFound, nil)
+}
@@ -0,0 +1,193 @@
+package memory
+
+import (
+	"server/models"
+	"server/store"
+	"sort"
+	"time"
+)
+
+type attendanceStore struct{ *db }
+
+// byStudent returns the student's records, newest first
+func (st *attendanceStore) byStudent(studentID int, keep func(models.Attendance) bool) []models.Attendance {
+	var records []models.Attendance
+	for _, a := range st.attendance {
+		if a.StudentID == studentID && (keep == nil || keep(a)) {
+			records = append(records, a)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Start().After(records[j].Start())
+	})
+	return records
+}
+
+// openSince reports whether a is an open session checked in at or after since
+func openSince(a models.Attendance, since time.Time) bool {
+	return a.Open() && !a.CheckInDateTime.Before(since)
+}
+
+func (st *attendanceStore) OpenSession(studentID int, since time.Time) (*models.Attendance, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	records := st.byStudent(studentID, func(a models.Attendance) bool { return openSince(a, since) })
+	if len(records) == 0 {
+		return nil, store.ErrNotFound
+	}
+	return &records[0], nil
+}
+
+func (st *attendanceStore) CheckIn(a *models.Attendance, since time.Time) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if len(st.byStudent(a.StudentID, func(r models.Attendance) bool { return openSince(r, since) })) > 0 {
+		return store.ErrConflict
+	}
+	a.ID = uint(st.id("attendance"))
+	st.attendance[int(a.ID)] = *a
+	return nil
+}
+
+func (st *attendanceStore) Create(a *models.Attendance) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	a.ID = uint(st.id("attendance"))
+	st.attendance[int(a.ID)] = *a
+	return nil
+}
+
+func (st *attendanceStore) UpdateCheckOut(a *models.Attendance) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	existing, ok := st.attendance[int(a.ID)]
+	if !ok || existing.CheckOutDateTime.Valid || existing.Absent {
+		return store.ErrNotFound
+	}
+	existing.CheckOutLat = a.CheckOutLat
+	existing.CheckOutLong = a.CheckOutLong
+	existing.CheckOutDateTime = a.CheckOutDateTime
+	existing.CheckOutInsideGeofence = a.CheckOutInsideGeofence
+	existing.CheckOutDistanceM = a.CheckOutDistanceM
+	existing.Punctuality = a.Punctuality
+	existing.MinutesEarly = a.MinutesEarly
+	existing.AutoClosed = a.AutoClosed
+	st.attendance[int(a.ID)] = existing
+	return nil
+}
+
+func (st *attendanceStore) Between(studentID int, start, end time.Time) ([]models.Attendance, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	records := st.byStudent(studentID, func(a models.Attendance) bool {
+		return !a.Start().Before(start) && a.Start().Before(end)
+	})
+	// Oldest first
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records, nil
+}
+
+func (st *attendanceStore) Recent(studentID int, before time.Time, limit int) ([]models.Attendance, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	records := st.byStudent(studentID, func(a models.Attendance) bool {
+		return before.IsZero() || a.Start().Before(before)
+	})
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+func (st *attendanceStore) LatestByStudent() (map[int]models.Attendance, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	latest := map[int]models.Attendance{}
+	for _, a := range st.attendance {
+		if cur, ok := latest[a.StudentID]; !ok || a.Start().After(cur.Start()) {
+			latest[a.StudentID] = a
+		}
+	}
+	return latest, nil
+}
+
+func (st *attendanceStore) OpenSessions() ([]models.Attendance, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var records []models.Attendance
+	for _, a := range st.attendance {
+		if a.Open() {
+			records = append(records, a)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].CheckInDateTime.Before(records[j].CheckInDateTime)
+	})
+	return records, nil
+}
+
+func (st *attendanceStore) MarkAbsent(a *models.Attendance, dayStart, dayEnd time.Time) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	existing := st.byStudent(a.StudentID, func(r models.Attendance) bool {
+		return !r.Start().Before(dayStart) && r.Start().Before(dayEnd)
+	})
+	if len(existing) > 0 {
+		return store.ErrConflict
+	}
+	a.ID = uint(st.id("attendance"))
+	st.attendance[int(a.ID)] = *a
+	return nil
+}
+
+func (st *attendanceStore) ClearAbsence(studentID int, dayStart, dayEnd time.Time) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for k, a := range st.attendance {
+		if a.StudentID == studentID && a.Absent && !a.Start().Before(dayStart) && a.Start().Before(dayEnd) {
+			st.superseded[k] = a
+			delete(st.attendance, k)
+		}
+	}
+	return nil
+}
+
+func (st *attendanceStore) Get(id int) (*models.Attendance, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	a, ok := st.attendance[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &a, nil
+}
+
+func (st *attendanceStore) Review(a *models.Attendance) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	existing, ok := st.attendance[int(a.ID)]
+	if !ok {
+		return store.ErrNotFound
+	}
+	existing.ReviewStatus = a.ReviewStatus
+	existing.ReviewNote = a.ReviewNote
+	existing.ReviewedBy = a.ReviewedBy
+	existing.ReviewedAt = a.ReviewedAt
+	st.attendance[int(a.ID)] = existing
+	return nil
+}
+
+func (st *attendanceStore) UpdatePunctuality(a *models.Attendance) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	existing, ok := st.attendance[int(a.ID)]
+	if !ok {
+		return store.ErrNotFound
+	}
+	existing.Punctuality = a.Punctuality
+	existing.MinutesEarly = a.MinutesEarly
+	st.attendance[int(a.ID)] = existing
+	return nil
+}
@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"server/models"
+	"server/store"
+	"sort"
+	"time"
+)
+
+type deviceStore struct{ *db }
+
+func (st *deviceStore) FindActive(studentID int, secretHash string) (*models.AuthorizedDevice, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, d := range st.devices {
+		if d.StudentID == studentID && d.SecretCode == secretHash && d.RevokedAt == nil {
+			return &d, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (st *deviceStore) Touch(id int, at time.Time) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if d, ok := st.devices[id]; ok {
+		d.LastUsedAt = &at
+		st.devices[id] = d
+	}
+	return nil
+}
+
+func (st *deviceStore) IsActive(id, studentID int) (bool, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	d, ok := st.devices[id]
+	return ok && d.StudentID == studentID && d.RevokedAt == nil, nil
+}
+
+func (st *deviceStore) List(studentID int) ([]models.AuthorizedDevice, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	devices := []models.AuthorizedDevice{}
+	for _, d := range st.devices {
+		if studentID == 0 || d.StudentID == studentID {
+			devices = append(devices, d)
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].ID > devices[j].ID })
+	return devices, nil
+}
+
+func (st *deviceStore) Revoke(id int, at time.Time) (*models.AuthorizedDevice, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	d, ok := st.devices[id]
+	if !ok || d.RevokedAt != nil {
+		return nil, store.ErrNotFound
+	}
+	d.RevokedAt = &at
+	st.devices[id] = d
+	return &d, nil
+}
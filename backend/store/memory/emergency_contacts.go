@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"server/models"
+	"server/store"
+	"time"
+)
+
+type emergencyContactStore struct{ *db }
+
+func sameID(a, b *int) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+// current returns the key of the contact in force for the scope, or zero
+func (st *emergencyContactStore) current(employerID, supervisorID *int) int {
+	for k, c := range st.emergencyContacts {
+		if c.RetiredAt == nil && sameID(c.EmployerID, employerID) && sameID(c.SupervisorID, supervisorID) {
+			return k
+		}
+	}
+	return 0
+}
+
+func (st *emergencyContactStore) Current(employerID, supervisorID *int) (*models.EmergencyContact, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	k := st.current(employerID, supervisorID)
+	if k == 0 {
+		return nil, store.ErrNotFound
+	}
+	c := st.emergencyContacts[k]
+	return &c, nil
+}
+
+func (st *emergencyContactStore) Put(c *models.EmergencyContact) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	now := time.Now()
+	if k := st.current(c.EmployerID, c.SupervisorID); k != 0 {
+		old := st.emergencyContacts[k]
+		old.RetiredAt, old.RetiredBy = &now, c.ChangedBy
+		st.emergencyContacts[k] = old
+	}
+	c.ID = st.id("emergency_contact")
+	c.UpdatedAt, c.RetiredAt, c.RetiredBy = now, nil, nil
+	st.emergencyContacts[c.ID] = *c
+	return nil
+}
+
+func (st *emergencyContactStore) Retire(employerID, supervisorID *int, by *int, at time.Time) (*models.EmergencyContact, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	k := st.current(employerID, supervisorID)
+	if k == 0 {
+		return nil, store.ErrNotFound
+	}
+	c := st.emergencyContacts[k]
+	c.RetiredAt, c.RetiredBy = &at, by
+	st.emergencyContacts[k] = c
+	return &c, nil
+}
+
+func (st *emergencyContactStore) History() ([]models.EmergencyContact, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	keys := sortedKeys(st.emergencyContacts)
+	list := make([]models.EmergencyContact, 0, len(keys))
+	for n := len(keys) - 1; n >= 0; n-- {
+		list = append(list, st.emergencyContacts[keys[n]])
+	}
+	return list, nil
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"server/models"
+	"server/store"
+	"time"
+)
+
+type employerStore struct{ *db }
+
+func (st *employerStore) List() ([]models.Employer, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var employers []models.Employer
+	for _, id := range sortedKeys(st.employers) {
+		employers = append(employers, st.employers[id])
+	}
+	return employers, nil
+}
+
+func (st *employerStore) Get(id int) (*models.Employer, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	e, ok := st.employers[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &e, nil
+}
+
+func (st *employerStore) Create(e *models.Employer) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	e.ID = uint(st.id("employer"))
+	st.employers[int(e.ID)] = *e
+	return nil
+}
+
+func (st *employerStore) Update(id int, e *models.Employer) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.employers[id]; !ok {
+		return store.ErrNotFound
+	}
+	e.ID = uint(id)
+	st.employers[id] = *e
+	return nil
+}
+
+func (st *employerStore) Delete(id int) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.employers[id]; !ok {
+		return store.ErrNotFound
+	}
+	// ON DELETE RESTRICT
+	for _, p := range st.placements {
+		if p.EmployerID != nil && *p.EmployerID == id {
+			return store.ErrConflict
+		}
+	}
+	delete(st.employers, id)
+	// ON DELETE SET NULL
+	for k, s := range st.students {
+		if s.EmployerID != nil && int(*s.EmployerID) == id {
+			s.EmployerID = nil
+			st.students[k] = s
+		}
+	}
+	for k, f := range st.feedback {
+		if f.EmployerID != nil && *f.EmployerID == id {
+			f.EmployerID = nil
+			st.feedback[k] = f
+		}
+	}
+	// The current override is retired; its history is kept
+	now := time.Now()
+	for k, c := range st.emergencyContacts {
+		if c.EmployerID != nil && *c.EmployerID == id && c.RetiredAt == nil {
+			c.RetiredAt = &now
+			st.emergencyContacts[k] = c
+		}
+	}
+	for k, u := range st.users {
+		if u.EmployerID != nil && *u.EmployerID == id {
+			delete(st.users, k)
+		}
+	}
+	return nil
+}
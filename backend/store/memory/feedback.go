@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"maps"
+	"server/models"
+	"server/store"
+	"sort"
+	"time"
+)
+
+type feedbackStore struct{ *db }
+
+func (st *feedbackStore) Create(f *models.ManagerFeedback) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if f.ExternalRef != "" {
+		for _, existing := range st.feedback {
+			if existing.ExternalRef == f.ExternalRef {
+				return store.ErrConflict
+			}
+		}
+	}
+	f.ID = st.id("manager_feedback")
+	if f.SubmittedAt.IsZero() {
+		f.SubmittedAt = time.Now()
+	}
+	if f.Ratings == nil {
+		f.Ratings = map[string]int{}
+	}
+	stored := *f
+	stored.Ratings = maps.Clone(f.Ratings)
+	st.feedback[f.ID] = stored
+	return nil
+}
+
+func (st *feedbackStore) Get(id int) (*models.ManagerFeedback, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	f, ok := st.feedback[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &f, nil
+}
+
+func (st *feedbackStore) ByExternalRef(ref string) (*models.ManagerFeedback, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, id := range sortedKeys(st.feedback) {
+		if f := st.feedback[id]; f.ExternalRef == ref {
+			return &f, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (st *feedbackStore) Update(f *models.ManagerFeedback) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	existing, ok := st.feedback[f.ID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	f.Source, f.ExternalRef = existing.Source, existing.ExternalRef
+	stored := *f
+	stored.Ratings = maps.Clone(f.Ratings)
+	st.feedback[f.ID] = stored
+	return nil
+}
+
+func (st *feedbackStore) Delete(id int) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.feedback[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(st.feedback, id)
+	// ON DELETE SET NULL
+	for ref, r := range st.feedbackSheet {
+		if r.FeedbackID != nil && *r.FeedbackID == id {
+			r.FeedbackID = nil
+			st.feedbackSheet[ref] = r
+		}
+	}
+	return nil
+}
+
+func (st *feedbackStore) List(q store.FeedbackQuery) ([]models.ManagerFeedback, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	list := []models.ManagerFeedback{}
+	for _, id := range sortedKeys(st.feedback) {
+		f := st.feedback[id]
+		if q.StudentID != nil && f.StudentID != *q.StudentID {
+			continue
+		}
+		if q.EmployerID != nil && (f.EmployerID == nil || *f.EmployerID != *q.EmployerID) {
+			continue
+		}
+		// Dates are YYYY-MM-DD, so they compare as strings
+		if (q.From != "" && f.PeriodEnd < q.From) || (q.To != "" && f.PeriodStart > q.To) {
+			continue
+		}
+		list = append(list, f)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].PeriodEnd != list[j].PeriodEnd {
+			return list[i].PeriodEnd > list[j].PeriodEnd
+		}
+		return list[i].ID > list[j].ID
+	})
+	return list, nil
+}
+
+type feedbackSheetStore struct{ *db }
+
+func (st *feedbackSheetStore) List() ([]models.FeedbackSheetRow, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	list := make([]models.FeedbackSheetRow, 0, len(st.feedbackSheet))
+	for _, r := range st.feedbackSheet {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].RowNumber != list[j].RowNumber {
+			return list[i].RowNumber < list[j].RowNumber
+		}
+		return list[i].Ref < list[j].Ref
+	})
+	return list, nil
+}
+
+func (st *feedbackSheetStore) Put(r *models.FeedbackSheetRow) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	now := time.Now()
+	if existing, ok := st.feedbackSheet[r.Ref]; ok {
+		r.FirstSeenAt = existing.FirstSeenAt
+	} else {
+		r.FirstSeenAt = now
+	}
+	r.UpdatedAt = now
+	stored := *r
+	stored.Cells = maps.Clone(r.Cells)
+	st.feedbackSheet[r.Ref] = stored
+	return nil
+}
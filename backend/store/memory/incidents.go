@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"server/models"
+	"server/store"
+	"slices"
+	"sort"
+	"time"
+)
+
+type incidentStore struct{ *db }
+
+// unresolved returns the student's open or acknowledged incident
+func (st *incidentStore) unresolved(studentID int) (models.Incident, bool) {
+	for _, i := range st.incidents {
+		if i.StudentID == studentID && i.Status != models.IncidentResolved {
+			return i, true
+		}
+	}
+	return models.Incident{}, false
+}
+
+func (st *incidentStore) Raise(i *models.Incident) (bool, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	now := time.Now()
+	if existing, ok := st.unresolved(i.StudentID); ok {
+		existing.Latitude, existing.Longitude, existing.AccuracyM = i.Latitude, i.Longitude, i.AccuracyM
+		if i.Message != "" {
+			existing.Message = i.Message
+		}
+		existing.LocatedAt = now
+		st.incidents[existing.ID] = existing
+		*i = existing
+		return false, nil
+	}
+	i.ID = st.id("incident")
+	i.Status = models.IncidentOpen
+	i.RaisedAt, i.LocatedAt = now, now
+	st.incidents[i.ID] = *i
+	return true, nil
+}
+
+func (st *incidentStore) Get(id int) (*models.Incident, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	i, ok := st.incidents[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &i, nil
+}
+
+func (st *incidentStore) Unresolved(studentID int) (*models.Incident, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	i, ok := st.unresolved(studentID)
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &i, nil
+}
+
+func (st *incidentStore) List(statuses ...string) ([]models.Incident, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var list []models.Incident
+	for _, i := range st.incidents {
+		if len(statuses) == 0 || slices.Contains(statuses, i.Status) {
+			list = append(list, i)
+		}
+	}
+	sort.Slice(list, func(a, b int) bool {
+		if !list[a].RaisedAt.Equal(list[b].RaisedAt) {
+			return list[a].RaisedAt.After(list[b].RaisedAt)
+		}
+		return list[a].ID > list[b].ID
+	})
+	return list, nil
+}
+
+func (st *incidentStore) Acknowledge(id int, by *int, at time.Time) (*models.Incident, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	i, ok := st.incidents[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	if i.Status != models.IncidentOpen {
+		return nil, store.ErrConflict
+	}
+	i.Status, i.AcknowledgedAt, i.AcknowledgedBy = models.IncidentAcknowledged, &at, by
+	st.incidents[id] = i
+	return &i, nil
+}
+
+func (st *incidentStore) Resolve(id int, by *int, resolution string, at time.Time) (*models.Incident, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	i, ok := st.incidents[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	if i.Status == models.IncidentResolved {
+		return nil, store.ErrConflict
+	}
+	if i.AcknowledgedAt == nil {
+		i.AcknowledgedAt, i.AcknowledgedBy = &at, by
+	}
+	i.Status, i.ResolvedAt, i.ResolvedBy, i.Resolution = models.IncidentResolved, &at, by, resolution
+	st.incidents[id] = i
+	return &i, nil
+}
@@ -0,0 +1,93 @@
+// Package memory implements the store interfaces in process. It is used by
+// the HTTP tests and for running the API locally without Postgres.
+package memory
+
+import (
+	"server/models"
+	"server/store"
+	"sort"
+	"sync"
+)
+
+// db holds every table behind one mutex so cross-table operations such as
+// OTP redemption stay atomic, like a transaction would in Postgres
+type db struct {
+	mu     sync.Mutex
+	nextID map[string]int
+
+	students          map[int]models.Student
+	supervisors       map[int]models.Supervisor
+	employers         map[int]models.Employer
+	attendance        map[int]models.Attendance
+	superseded        map[int]models.Attendance // absences a late check-in replaced, out of every read
+	moods             map[int]models.Mood
+	otps              map[int]models.OTP
+	otpFailures       []models.OTPFailedAttempt
+	devices           map[int]models.AuthorizedDevice
+	users             map[int]models.User
+	emergencyContacts map[int]models.EmergencyContact
+	scheduleDays      map[int][]models.ScheduleDay
+	scheduleOverrides map[int]map[string]models.ScheduleOverride
+	notifications     map[int]models.Notification
+	incidents         map[int]models.Incident
+	feedback          map[int]models.ManagerFeedback
+	feedbackSheet     map[string]models.FeedbackSheetRow
+	placements        map[int]models.Placement
+}
+
+// New returns an empty in-memory Store
+func New() *store.Store {
+	d := &db{
+		nextID:            map[string]int{},
+		students:          map[int]models.Student{},
+		supervisors:       map[int]models.Supervisor{},
+		employers:         map[int]models.Employer{},
+		attendance:        map[int]models.Attendance{},
+		superseded:        map[int]models.Attendance{},
+		moods:             map[int]models.Mood{},
+		otps:              map[int]models.OTP{},
+		devices:           map[int]models.AuthorizedDevice{},
+		users:             map[int]models.User{},
+		emergencyContacts: map[int]models.EmergencyContact{},
+		scheduleDays:      map[int][]models.ScheduleDay{},
+		scheduleOverrides: map[int]map[string]models.ScheduleOverride{},
+		notifications:     map[int]models.Notification{},
+		incidents:         map[int]models.Incident{},
+		feedback:          map[int]models.ManagerFeedback{},
+		feedbackSheet:     map[string]models.FeedbackSheetRow{},
+		placements:        map[int]models.Placement{},
+	}
+	return &store.Store{
+		Students:          &studentStore{d},
+		Supervisors:       &supervisorStore{d},
+		Employers:         &employerStore{d},
+		Attendance:        &attendanceStore{d},
+		Moods:             &moodStore{d},
+		OTPs:              &otpStore{d},
+		Devices:           &deviceStore{d},
+		Users:             &userStore{d},
+		EmergencyContacts: &emergencyContactStore{d},
+		Schedules:         &scheduleStore{d},
+		Notifications:     &notificationStore{d},
+		Incidents:         &incidentStore{d},
+		Feedback:          &feedbackStore{d},
+		FeedbackSheet:     &feedbackSheetStore{d},
+		Placements:        &placementStore{d},
+	}
+}
+
+// id returns the next serial value for table
+func (d *db) id(table string) int {
+	d.nextID[table]++
+	return d.nextID[table]
+}
+
+// sortedKeys returns the keys of m in ascending order, mirroring ORDER BY id
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
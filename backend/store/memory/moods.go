@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"server/models"
+	"server/store"
+	"sort"
+	"time"
+)
+
+type moodStore struct{ *db }
+
+func (st *moodStore) List() ([]models.Mood, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var moods []models.Mood
+	for _, m := range st.moods {
+		moods = append(moods, m)
+	}
+	sort.Slice(moods, func(i, j int) bool { return moods[i].RecordedAt.Before(moods[j].RecordedAt) })
+	return moods, nil
+}
+
+func (st *moodStore) Get(id, studentID int) (*models.Mood, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	m, ok := st.moods[id]
+	if !ok || m.StudentID != studentID {
+		return nil, store.ErrNotFound
+	}
+	return &m, nil
+}
+
+func (st *moodStore) Create(m *models.Mood) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	m.ID = st.id("mood")
+	m.IsDaily, m.LocalDate = false, ""
+	m.CreatedAt, m.UpdatedAt = time.Now(), nil
+	st.moods[m.ID] = *m
+	return nil
+}
+
+// dailyOn returns the student's daily mood for date
+func (st *moodStore) dailyOn(studentID int, date string) (models.Mood, bool) {
+	for _, m := range st.moods {
+		if m.StudentID == studentID && m.IsDaily && m.LocalDate == date {
+			return m, true
+		}
+	}
+	return models.Mood{}, false
+}
+
+func (st *moodStore) DailyOn(studentID int, date string) (*models.Mood, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	m, ok := st.dailyOn(studentID, date)
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &m, nil
+}
+
+func (st *moodStore) PutDaily(m *models.Mood, editableSince time.Time) (bool, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	now := time.Now()
+	m.IsDaily = true
+	if existing, ok := st.dailyOn(m.StudentID, m.LocalDate); ok {
+		if existing.CreatedAt.Before(editableSince) {
+			return false, store.ErrConflict
+		}
+		existing.Emotion, existing.Valence, existing.RecordedAt = m.Emotion, m.Valence, m.RecordedAt
+		existing.UpdatedAt = &now
+		st.moods[existing.ID] = existing
+		*m = existing
+		return false, nil
+	}
+	m.ID = st.id("mood")
+	m.CreatedAt, m.UpdatedAt = now, nil
+	st.moods[m.ID] = *m
+	return true, nil
+}
+
+func (st *moodStore) Recent(studentID int, dailyOnly bool, limit int) ([]models.Mood, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var moods []models.Mood
+	for _, m := range st.moods {
+		if m.StudentID == studentID && (!dailyOnly || m.IsDaily) {
+			moods = append(moods, m)
+		}
+	}
+	sort.Slice(moods, func(i, j int) bool { return moods[i].RecordedAt.After(moods[j].RecordedAt) })
+	if limit > 0 && len(moods) > limit {
+		moods = moods[:limit]
+	}
+	return moods, nil
+}
+
+func (st *moodStore) Between(studentID int, start, end time.Time) ([]models.Mood, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var moods []models.Mood
+	for _, m := range st.moods {
+		if m.StudentID == studentID && !m.RecordedAt.Before(start) && m.RecordedAt.Before(end) {
+			moods = append(moods, m)
+		}
+	}
+	sort.Slice(moods, func(i, j int) bool { return moods[i].RecordedAt.Before(moods[j].RecordedAt) })
+	return moods, nil
+}
+
+func (st *moodStore) Page(q store.MoodQuery) ([]models.Mood, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	// before orders a ahead of b in the requested direction
+	before := func(a, b models.Mood) bool {
+		if !q.Ascending {
+			a, b = b, a
+		}
+		if !a.RecordedAt.Equal(b.RecordedAt) {
+			return a.RecordedAt.Before(b.RecordedAt)
+		}
+		return a.ID < b.ID
+	}
+	cursor := models.Mood{ID: q.AfterID, RecordedAt: q.AfterTime}
+	var moods []models.Mood
+	for _, m := range st.moods {
+		if m.StudentID != q.StudentID ||
+			(!q.From.IsZero() && m.RecordedAt.Before(q.From)) ||
+			(!q.To.IsZero() && !m.RecordedAt.Before(q.To)) ||
+			(q.Daily != nil && m.IsDaily != *q.Daily) ||
+			(q.AfterID != 0 && !before(cursor, m)) {
+			continue
+		}
+		moods = append(moods, m)
+	}
+	sort.Slice(moods, func(i, j int) bool { return before(moods[i], moods[j]) })
+	if q.Limit > 0 && len(moods) > q.Limit {
+		moods = moods[:q.Limit]
+	}
+	return moods, nil
+}
+
+func (st *moodStore) LatestByStudent() (map[int]models.Mood, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	latest := map[int]models.Mood{}
+	for _, m := range st.moods {
+		if cur, ok := latest[m.StudentID]; !ok || m.RecordedAt.After(cur.RecordedAt) {
+			latest[m.StudentID] = m
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"server/models"
+	"server/store"
+	"sort"
+	"time"
+)
+
+type notificationStore struct{ *db }
+
+func (st *notificationStore) Enqueue(n *models.Notification) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	n.ID = st.id("notification")
+	if n.Status == "" {
+		n.Status = models.NotificationQueued
+	}
+	n.CreatedAt = time.Now()
+	if n.NextAttemptAt.IsZero() {
+		n.NextAttemptAt = n.CreatedAt
+	}
+	st.notifications[n.ID] = *n
+	return nil
+}
+
+func (st *notificationStore) Claim(now time.Time, lease time.Duration, limit int) ([]models.Notification, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var due []models.Notification
+	for _, n := range st.notifications {
+		if n.Status == models.NotificationQueued && !n.NextAttemptAt.After(now) {
+			due = append(due, n)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	for i := range due {
+		due[i].NextAttemptAt = now.Add(lease)
+		st.notifications[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+func (st *notificationStore) Record(n *models.Notification) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	existing, ok := st.notifications[n.ID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	existing.Status = n.Status
+	existing.Attempts = n.Attempts
+	existing.LastError = n.LastError
+	existing.NextAttemptAt = n.NextAttemptAt
+	existing.SentAt = n.SentAt
+	st.notifications[n.ID] = existing
+	return nil
+}
+
+func (st *notificationStore) List(status string, limit int) ([]models.Notification, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	keys := sortedKeys(st.notifications)
+	var list []models.Notification
+	for i := len(keys) - 1; i >= 0; i-- {
+		n := st.notifications[keys[i]]
+		if status != "" && n.Status != status {
+			continue
+		}
+		list = append(list, n)
+		if limit > 0 && len(list) == limit {
+			break
+		}
+	}
+	return list, nil
+}
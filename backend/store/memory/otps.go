@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"server/models"
+	"server/store"
+	"time"
+)
+
+type otpStore struct{ *db }
+
+func (st *otpStore) DeleteExpired(now time.Time) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for id, o := range st.otps {
+		if !o.ExpiresAt.After(now) {
+			delete(st.otps, id)
+		}
+	}
+	return nil
+}
+
+func (st *otpStore) ActiveForStudent(studentID int, now time.Time) (*models.OTP, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, o := range st.otps {
+		if o.StudentID == studentID && !o.IsUsed && o.ExpiresAt.After(now) {
+			return &o, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (st *otpStore) InvalidateForStudent(studentID int) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for id, o := range st.otps {
+		if o.StudentID == studentID && !o.IsUsed {
+			o.IsUsed = true
+			st.otps[id] = o
+		}
+	}
+	return nil
+}
+
+func (st *otpStore) CodeInUse(code string, now time.Time) (bool, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, o := range st.otps {
+		if o.OTPCode == code && !o.IsUsed && o.ExpiresAt.After(now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (st *otpStore) Create(o *models.OTP) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	// otps_active_code_idx
+	if !o.IsUsed {
+		for _, existing := range st.otps {
+			if existing.OTPCode == o.OTPCode && !existing.IsUsed {
+				return store.ErrConflict
+			}
+		}
+	}
+	o.ID = uint(st.id("otps"))
+	o.CreatedAt = time.Now()
+	st.otps[int(o.ID)] = *o
+	return nil
+}
+
+func (st *otpStore) FindActive(code string, studentID int, now time.Time) (*models.OTP, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, o := range st.otps {
+		if o.OTPCode == code && !o.IsUsed && o.ExpiresAt.After(now) && (studentID == 0 || o.StudentID == studentID) {
+			return &o, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (st *otpStore) Redeem(code string, studentID int, device *models.AuthorizedDevice) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	redeemed := false
+	for id, o := range st.otps {
+		if o.OTPCode == code && o.StudentID == studentID && !o.IsUsed {
+			o.IsUsed = true
+			st.otps[id] = o
+			redeemed = true
+		}
+	}
+	if !redeemed {
+		return store.ErrConflict
+	}
+	device.ID = uint(st.id("authorized_devices"))
+	device.CreatedAt = time.Now()
+	st.devices[int(device.ID)] = *device
+	return nil
+}
+
+func (st *otpStore) RecordFailure(a *models.OTPFailedAttempt) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	a.ID = uint(st.id("otp_failed_attempts"))
+	a.AttemptedAt = time.Now()
+	st.otpFailures = append(st.otpFailures, *a)
+	return nil
+}
+
+func (st *otpStore) LatestByStudent() (map[int]models.OTP, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	latest := map[int]models.OTP{}
+	for _, o := range st.otps {
+		if cur, ok := latest[o.StudentID]; !ok || o.ID > cur.ID {
+			latest[o.StudentID] = o
+		}
+	}
+	return latest, nil
+}
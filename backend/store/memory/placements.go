@@ -0,0 +1,146 @@
+package memory
+
+import (
+	"errors"
+	"server/models"
+	"server/store"
+	"sort"
+	"time"
+)
+
+type placementStore struct{ *db }
+
+// open returns the student's open placement
+func (st *placementStore) open(studentID int) (models.Placement, bool) {
+	for _, p := range st.placements {
+		if p.StudentID == studentID && p.EndDate == nil {
+			return p, true
+		}
+	}
+	return models.Placement{}, false
+}
+
+func (st *placementStore) List(studentID int) ([]models.Placement, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var list []models.Placement
+	for _, p := range st.placements {
+		if p.StudentID == studentID {
+			list = append(list, p)
+		}
+	}
+	sortPlacements(list)
+	return list, nil
+}
+
+func (st *placementStore) ListByStudent() (map[int][]models.Placement, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	byStudent := map[int][]models.Placement{}
+	for _, p := range st.placements {
+		byStudent[p.StudentID] = append(byStudent[p.StudentID], p)
+	}
+	for _, list := range byStudent {
+		sortPlacements(list)
+	}
+	return byStudent, nil
+}
+
+// sortPlacements orders placements latest start first
+func sortPlacements(list []models.Placement) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].StartDate != list[j].StartDate {
+			return list[i].StartDate > list[j].StartDate
+		}
+		return list[i].ID > list[j].ID
+	})
+}
+
+func (st *placementStore) Get(id int) (*models.Placement, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	p, ok := st.placements[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &p, nil
+}
+
+func (st *placementStore) Current(studentID int) (*models.Placement, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	p, ok := st.open(studentID)
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &p, nil
+}
+
+// close ends the student's open placement, see PlacementStore.End
+func (st *placementStore) close(studentID int, endDate, reason string) (*models.Placement, error) {
+	p, ok := st.open(studentID)
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	if p.StartDate > endDate {
+		return nil, store.ErrConflict
+	}
+	p.EndDate = &endDate
+	p.EndReason = reason
+	st.placements[p.ID] = p
+	return &p, nil
+}
+
+// setEmployer points the student at employerID, like UPDATE student SET employer_id
+func (st *placementStore) setEmployer(studentID int, employerID *int) {
+	s, ok := st.students[studentID]
+	if !ok {
+		return
+	}
+	s.EmployerID = nil
+	if employerID != nil {
+		id := uint(*employerID)
+		s.EmployerID = &id
+	}
+	st.students[studentID] = s
+}
+
+func (st *placementStore) Start(p *models.Placement, previousEnd string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, err := st.close(p.StudentID, previousEnd, ""); errors.Is(err, store.ErrConflict) {
+		return err
+	}
+	p.ID = st.id("placement")
+	p.EndDate, p.EndReason = nil, ""
+	if p.Schedule == nil {
+		p.Schedule = []models.ScheduleDay{}
+	}
+	p.CreatedAt = time.Now()
+	st.placements[p.ID] = *p
+	st.setEmployer(p.StudentID, p.EmployerID)
+	return nil
+}
+
+func (st *placementStore) End(studentID int, endDate, reason string) (*models.Placement, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	p, err := st.close(studentID, endDate, reason)
+	if err != nil {
+		return nil, err
+	}
+	st.setEmployer(studentID, nil)
+	return p, nil
+}
+
+func (st *placementStore) SetSchedule(id int, days []models.ScheduleDay) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	p, ok := st.placements[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+	p.Schedule = append([]models.ScheduleDay{}, days...)
+	st.placements[id] = p
+	return nil
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"server/models"
+	"server/store"
+	"sort"
+)
+
+type scheduleStore struct{ *db }
+
+func (st *scheduleStore) Weekly(studentID int) ([]models.ScheduleDay, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return append([]models.ScheduleDay(nil), st.scheduleDays[studentID]...), nil
+}
+
+func (st *scheduleStore) WeeklyByStudent() (map[int][]models.ScheduleDay, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	days := map[int][]models.ScheduleDay{}
+	for studentID, d := range st.scheduleDays {
+		if len(d) > 0 {
+			days[studentID] = append([]models.ScheduleDay(nil), d...)
+		}
+	}
+	return days, nil
+}
+
+func (st *scheduleStore) ReplaceWeekly(studentID int, days []models.ScheduleDay) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	sorted := append([]models.ScheduleDay(nil), days...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Weekday < sorted[j].Weekday })
+	st.scheduleDays[studentID] = sorted
+	return nil
+}
+
+func (st *scheduleStore) Overrides(studentID int, from, to string) ([]models.ScheduleOverride, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var overrides []models.ScheduleOverride
+	for date, o := range st.scheduleOverrides[studentID] {
+		// YYYY-MM-DD strings sort like the dates they hold
+		if date >= from && date <= to {
+			overrides = append(overrides, o)
+		}
+	}
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].Date < overrides[j].Date })
+	return overrides, nil
+}
+
+func (st *scheduleStore) OverridesByStudent(from, to string) (map[int][]models.ScheduleOverride, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	overrides := map[int][]models.ScheduleOverride{}
+	for studentID, byDate := range st.scheduleOverrides {
+		for date, o := range byDate {
+			if date >= from && date <= to {
+				overrides[studentID] = append(overrides[studentID], o)
+			}
+		}
+	}
+	for _, list := range overrides {
+		sort.Slice(list, func(i, j int) bool { return list[i].Date < list[j].Date })
+	}
+	return overrides, nil
+}
+
+func (st *scheduleStore) PutOverride(o *models.ScheduleOverride) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.scheduleOverrides[o.StudentID] == nil {
+		st.scheduleOverrides[o.StudentID] = map[string]models.ScheduleOverride{}
+	}
+	st.scheduleOverrides[o.StudentID][o.Date] = *o
+	return nil
+}
+
+func (st *scheduleStore) DeleteOverride(studentID int, date string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.scheduleOverrides[studentID][date]; !ok {
+		return store.ErrNotFound
+	}
+	delete(st.scheduleOverrides[studentID], date)
+	return nil
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"server/models"
+	"server/store"
+)
+
+type studentStore struct{ *db }
+
+func (st *studentStore) List() ([]models.Student, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var students []models.Student
+	for _, id := range sortedKeys(st.students) {
+		students = append(students, st.students[id])
+	}
+	return students, nil
+}
+
+func (st *studentStore) Get(id int) (*models.Student, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s, ok := st.students[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &s, nil
+}
+
+func (st *studentStore) Create(s *models.Student) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s.ID = uint64(st.id("student"))
+	st.students[int(s.ID)] = *s
+	return nil
+}
+
+func (st *studentStore) Update(id int, s *models.Student) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.students[id]; !ok {
+		return store.ErrNotFound
+	}
+	s.ID = uint64(id)
+	st.students[id] = *s
+	return nil
+}
+
+func (st *studentStore) Delete(id int) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.students[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(st.students, id)
+	// ON DELETE CASCADE
+	for k, a := range st.attendance {
+		if a.StudentID == id {
+			delete(st.attendance, k)
+		}
+	}
+	for k, a := range st.superseded {
+		if a.StudentID == id {
+			delete(st.superseded, k)
+		}
+	}
+	for k, m := range st.moods {
+		if m.StudentID == id {
+			delete(st.moods, k)
+		}
+	}
+	for k, o := range st.otps {
+		if o.StudentID == id {
+			delete(st.otps, k)
+		}
+	}
+	for k, d := range st.devices {
+		if d.StudentID == id {
+			delete(st.devices, k)
+		}
+	}
+	delete(st.scheduleDays, id)
+	delete(st.scheduleOverrides, id)
+	for k, i := range st.incidents {
+		if i.StudentID == id {
+			delete(st.incidents, k)
+		}
+	}
+	for k, f := range st.feedback {
+		if f.StudentID == id {
+			delete(st.feedback, k)
+		}
+	}
+	for k, p := range st.placements {
+		if p.StudentID == id {
+			delete(st.placements, k)
+		}
+	}
+	// ON DELETE SET NULL
+	for ref, r := range st.feedbackSheet {
+		if r.StudentID != nil && *r.StudentID == id {
+			r.StudentID = nil
+			if r.FeedbackID != nil {
+				if _, ok := st.feedback[*r.FeedbackID]; !ok {
+					r.FeedbackID = nil
+				}
+			}
+			st.feedbackSheet[ref] = r
+		}
+	}
+	return nil
+}
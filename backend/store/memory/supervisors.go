@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"server/models"
+	"server/store"
+	"time"
+)
+
+type supervisorStore struct{ *db }
+
+func (st *supervisorStore) List() ([]models.Supervisor, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var supervisors []models.Supervisor
+	for _, id := range sortedKeys(st.supervisors) {
+		supervisors = append(supervisors, st.supervisors[id])
+	}
+	return supervisors, nil
+}
+
+func (st *supervisorStore) Get(id int) (*models.Supervisor, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s, ok := st.supervisors[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &s, nil
+}
+
+func (st *supervisorStore) Create(s *models.Supervisor) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s.SupervisorID = st.id("supervisor")
+	st.supervisors[s.SupervisorID] = *s
+	return nil
+}
+
+func (st *supervisorStore) Update(id int, s *models.Supervisor) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.supervisors[id]; !ok {
+		return store.ErrNotFound
+	}
+	s.SupervisorID = id
+	st.supervisors[id] = *s
+	return nil
+}
+
+func (st *supervisorStore) Delete(id int) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.supervisors[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(st.supervisors, id)
+	// ON DELETE SET NULL
+	for k, s := range st.students {
+		if s.SupervisorID != nil && int(*s.SupervisorID) == id {
+			s.SupervisorID = nil
+			st.students[k] = s
+		}
+	}
+	// The current override is retired; its history is kept
+	now := time.Now()
+	for k, c := range st.emergencyContacts {
+		if c.SupervisorID != nil && *c.SupervisorID == id && c.RetiredAt == nil {
+			c.RetiredAt = &now
+			st.emergencyContacts[k] = c
+		}
+	}
+	return nil
+}
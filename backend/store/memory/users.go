@@ -0,0 +1,34 @@
+package memory
+
+import (
+	"server/models"
+	"server/store"
+	"time"
+)
+
+type userStore struct{ *db }
+
+func (st *userStore) GetByUsername(username string) (*models.User, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, u := range st.users {
+		if u.Username == username {
+			return &u, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (st *userStore) Create(u *models.User) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, existing := range st.users {
+		if existing.Username == u.Username {
+			return store.ErrConflict
+		}
+	}
+	u.ID = st.id("app_user")
+	u.CreatedAt = time.Now()
+	st.users[u.ID] = *u
+	return nil
+}
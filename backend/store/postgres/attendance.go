@@ -0,0 +1,204 @@
+package postgres
+
+import (
+	"database/sql"
+	"server/models"
+	"server/store"
+	"time"
+)
+
+type attendanceStore struct {
+	db *sql.DB
+}
+
+const attendanceColumns = "id, student_id, check_in_lat, check_in_long, check_in_date_time, check_out_lat, check_out_long, check_out_date_time, check_in_inside_geofence, check_in_distance_m, check_out_inside_geofence, check_out_distance_m, orphan_check_out, punctuality, minutes_late, minutes_early, absent, expected_start, auto_closed, placement_id, review_status, review_note, reviewed_by, reviewed_at"
+
+// sessionStart orders sessions by check-in, by check-out for orphan
+// check-outs and by the missed shift's start for absences
+const sessionStart = "COALESCE(check_in_date_time, check_out_date_time, expected_start)"
+
+// live leaves out absences superseded by a late check-in; they are kept for
+// the record but no longer count anywhere
+const live = "superseded_at IS NULL"
+
+// attendanceLockSpace is the first key of the advisory lock taken per student
+// while opening a session, so concurrent check-ins cannot both succeed
+const attendanceLockSpace = 7239
+
+func scanAttendance(row scanner, a *models.Attendance) error {
+	var checkIn sql.NullTime
+	err := row.Scan(&a.ID, &a.StudentID, &a.CheckInLat, &a.CheckInLong, &checkIn, &a.CheckOutLat, &a.CheckOutLong, &a.CheckOutDateTime, &a.CheckInInsideGeofence, &a.CheckInDistanceM, &a.CheckOutInsideGeofence, &a.CheckOutDistanceM, &a.OrphanCheckOut, &a.Punctuality, &a.MinutesLate, &a.MinutesEarly, &a.Absent, &a.ExpectedStart, &a.AutoClosed, &a.PlacementID, &a.ReviewStatus, &a.ReviewNote, &a.ReviewedBy, &a.ReviewedAt)
+	a.CheckInDateTime = checkIn.Time
+	return err
+}
+
+// checkInValue stores orphan check-outs and absences with a NULL check-in
+func checkInValue(a *models.Attendance) interface{} {
+	if a.OrphanCheckOut || a.Absent {
+		return nil
+	}
+	return a.CheckInDateTime
+}
+
+func (st *attendanceStore) OpenSession(studentID int, since time.Time) (*models.Attendance, error) {
+	var a models.Attendance
+	query := `SELECT ` + attendanceColumns + ` FROM attendance
+		WHERE student_id = $1 AND check_in_date_time >= $2 AND check_out_date_time IS NULL
+		ORDER BY check_in_date_time DESC LIMIT 1`
+	if err := scanAttendance(st.db.QueryRow(query, studentID, since), &a); err != nil {
+		return nil, notFound(err)
+	}
+	return &a, nil
+}
+
+func (st *attendanceStore) CheckIn(a *models.Attendance, since time.Time) error {
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1, $2)`, attendanceLockSpace, a.StudentID); err != nil {
+		return err
+	}
+	var open bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM attendance
+		WHERE student_id = $1 AND check_in_date_time >= $2 AND check_out_date_time IS NULL)`,
+		a.StudentID, since).Scan(&open)
+	if err != nil {
+		return err
+	}
+	if open {
+		return store.ErrConflict
+	}
+	if err := insertAttendance(tx, a); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (st *attendanceStore) Create(a *models.Attendance) error {
+	return insertAttendance(st.db, a)
+}
+
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func insertAttendance(db queryRower, a *models.Attendance) error {
+	query := `INSERT INTO attendance (student_id, check_in_lat, check_in_long, check_in_date_time, check_out_lat, check_out_long, check_out_date_time, check_in_inside_geofence, check_in_distance_m, check_out_inside_geofence, check_out_distance_m, orphan_check_out, punctuality, minutes_late, minutes_early, absent, expected_start, placement_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18) RETURNING id`
+	return db.QueryRow(query, a.StudentID, a.CheckInLat, a.CheckInLong, checkInValue(a), a.CheckOutLat, a.CheckOutLong, a.CheckOutDateTime, a.CheckInInsideGeofence, a.CheckInDistanceM, a.CheckOutInsideGeofence, a.CheckOutDistanceM, a.OrphanCheckOut, a.Punctuality, a.MinutesLate, a.MinutesEarly, a.Absent, a.ExpectedStart, a.PlacementID).Scan(&a.ID)
+}
+
+func (st *attendanceStore) UpdateCheckOut(a *models.Attendance) error {
+	query := `UPDATE attendance SET check_out_lat = $1, check_out_long = $2, check_out_date_time = $3, check_out_inside_geofence = $4, check_out_distance_m = $5, punctuality = $6, minutes_early = $7, auto_closed = $8
+		WHERE id = $9 AND check_out_date_time IS NULL AND NOT absent`
+	return requireRow(st.db.Exec(query, a.CheckOutLat, a.CheckOutLong, a.CheckOutDateTime, a.CheckOutInsideGeofence, a.CheckOutDistanceM, a.Punctuality, a.MinutesEarly, a.AutoClosed, a.ID))
+}
+
+func (st *attendanceStore) Between(studentID int, start, end time.Time) ([]models.Attendance, error) {
+	return st.query(`SELECT `+attendanceColumns+` FROM attendance
+		WHERE student_id = $1 AND `+live+` AND `+sessionStart+` >= $2 AND `+sessionStart+` < $3
+		ORDER BY `+sessionStart, studentID, start, end)
+}
+
+func (st *attendanceStore) Recent(studentID int, before time.Time, limit int) ([]models.Attendance, error) {
+	query := `SELECT ` + attendanceColumns + ` FROM attendance WHERE student_id = $1 AND ` + live
+	args := []interface{}{studentID}
+	if !before.IsZero() {
+		query += ` AND ` + sessionStart + ` < $2`
+		args = append(args, before)
+	}
+	query += ` ORDER BY ` + sessionStart + ` DESC`
+	if limit > 0 {
+		query += ` LIMIT ` + itoa(limit)
+	}
+	return st.query(query, args...)
+}
+
+func (st *attendanceStore) LatestByStudent() (map[int]models.Attendance, error) {
+	records, err := st.query(`SELECT DISTINCT ON (student_id) ` + attendanceColumns + ` FROM attendance WHERE ` + live + ` ORDER BY student_id, ` + sessionStart + ` DESC`)
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[int]models.Attendance, len(records))
+	for _, a := range records {
+		latest[a.StudentID] = a
+	}
+	return latest, nil
+}
+
+func (st *attendanceStore) OpenSessions() ([]models.Attendance, error) {
+	return st.query(`SELECT ` + attendanceColumns + ` FROM attendance
+		WHERE check_in_date_time IS NOT NULL AND check_out_date_time IS NULL
+		ORDER BY check_in_date_time`)
+}
+
+func (st *attendanceStore) MarkAbsent(a *models.Attendance, dayStart, dayEnd time.Time) error {
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The same lock as CheckIn, so a check-in and the absence job cannot both
+	// decide the day is theirs
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1, $2)`, attendanceLockSpace, a.StudentID); err != nil {
+		return err
+	}
+	var exists bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM attendance
+		WHERE student_id = $1 AND `+live+` AND `+sessionStart+` >= $2 AND `+sessionStart+` < $3)`,
+		a.StudentID, dayStart, dayEnd).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return store.ErrConflict
+	}
+	if err := insertAttendance(tx, a); err != nil {
+		return conflict(err)
+	}
+	return tx.Commit()
+}
+
+func (st *attendanceStore) ClearAbsence(studentID int, dayStart, dayEnd time.Time) error {
+	_, err := st.db.Exec(`UPDATE attendance SET superseded_at = now() WHERE student_id = $1 AND absent AND `+live+`
+		AND expected_start >= $2 AND expected_start < $3`, studentID, dayStart, dayEnd)
+	return err
+}
+
+func (st *attendanceStore) Get(id int) (*models.Attendance, error) {
+	var a models.Attendance
+	if err := scanAttendance(st.db.QueryRow(`SELECT `+attendanceColumns+` FROM attendance WHERE id = $1 AND `+live, id), &a); err != nil {
+		return nil, notFound(err)
+	}
+	return &a, nil
+}
+
+func (st *attendanceStore) Review(a *models.Attendance) error {
+	query := `UPDATE attendance SET review_status = $1, review_note = $2, reviewed_by = $3, reviewed_at = $4 WHERE id = $5`
+	return requireRow(st.db.Exec(query, a.ReviewStatus, a.ReviewNote, a.ReviewedBy, a.ReviewedAt, a.ID))
+}
+
+func (st *attendanceStore) UpdatePunctuality(a *models.Attendance) error {
+	query := `UPDATE attendance SET punctuality = $1, minutes_early = $2 WHERE id = $3`
+	return requireRow(st.db.Exec(query, a.Punctuality, a.MinutesEarly, a.ID))
+}
+
+func (st *attendanceStore) query(query string, args ...interface{}) ([]models.Attendance, error) {
+	rows, err := st.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var records []models.Attendance
+	for rows.Next() {
+		var a models.Attendance
+		if err := scanAttendance(rows, &a); err != nil {
+			return nil, err
+		}
+		records = append(records, a)
+	}
+	return records, rows.Err()
+}
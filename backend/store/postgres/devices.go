@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"database/sql"
+	"server/models"
+	"time"
+)
+
+type deviceStore struct {
+	db *sql.DB
+}
+
+const deviceColumns = "id, student_id, device_name, created_at, last_used_at, revoked_at"
+
+func scanDevice(row scanner, d *models.AuthorizedDevice) error {
+	return row.Scan(&d.ID, &d.StudentID, &d.DeviceName, &d.CreatedAt, &d.LastUsedAt, &d.RevokedAt)
+}
+
+func (st *deviceStore) FindActive(studentID int, secretHash string) (*models.AuthorizedDevice, error) {
+	var d models.AuthorizedDevice
+	err := scanDevice(st.db.QueryRow(
+		"SELECT "+deviceColumns+" FROM authorized_devices WHERE student_id = $1 AND secret_code = $2 AND revoked_at IS NULL",
+		studentID, secretHash,
+	), &d)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &d, nil
+}
+
+func (st *deviceStore) Touch(id int, at time.Time) error {
+	_, err := st.db.Exec("UPDATE authorized_devices SET last_used_at = $1 WHERE id = $2", at, id)
+	return err
+}
+
+func (st *deviceStore) IsActive(id, studentID int) (bool, error) {
+	var count int
+	err := st.db.QueryRow(
+		"SELECT COUNT(*) FROM authorized_devices WHERE id = $1 AND student_id = $2 AND revoked_at IS NULL",
+		id, studentID,
+	).Scan(&count)
+	return count > 0, err
+}
+
+func (st *deviceStore) List(studentID int) ([]models.AuthorizedDevice, error) {
+	query := "SELECT " + deviceColumns + " FROM authorized_devices"
+	var args []interface{}
+	if studentID != 0 {
+		query += " WHERE student_id = $1"
+		args = append(args, studentID)
+	}
+	query += " ORDER BY created_at DESC"
+
+	rows, err := st.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	devices := []models.AuthorizedDevice{}
+	for rows.Next() {
+		var d models.AuthorizedDevice
+		if err := scanDevice(rows, &d); err != nil {
+			return nil, err
+		}
+		devices = append(devices, d)
+	}
+	return devices, rows.Err()
+}
+
+func (st *deviceStore) Revoke(id int, at time.Time) (*models.AuthorizedDevice, error) {
+	var d models.AuthorizedDevice
+	err := scanDevice(st.db.QueryRow(
+		"UPDATE authorized_devices SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL RETURNING "+deviceColumns,
+		at, id,
+	), &d)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &d, nil
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"database/sql"
+	"server/models"
+	"time"
+)
+
+type emergencyContactStore struct {
+	db *sql.DB
+}
+
+const emergencyContactColumns = "id, phone_number, updated_at, employer_id, supervisor_id, changed_by, retired_at, retired_by"
+
+// currentContact matches the row in force for an employer, a supervisor or everyone
+const currentContact = "retired_at IS NULL AND employer_id IS NOT DISTINCT FROM $1 AND supervisor_id IS NOT DISTINCT FROM $2"
+
+func scanEmergencyContact(row scanner, c *models.EmergencyContact) error {
+	return row.Scan(&c.ID, &c.PhoneNumber, &c.UpdatedAt, &c.EmployerID, &c.SupervisorID, &c.ChangedBy, &c.RetiredAt, &c.RetiredBy)
+}
+
+func (st *emergencyContactStore) Current(employerID, supervisorID *int) (*models.EmergencyContact, error) {
+	var c models.EmergencyContact
+	err := scanEmergencyContact(st.db.QueryRow(`SELECT `+emergencyContactColumns+` FROM emergency_contact WHERE `+currentContact, employerID, supervisorID), &c)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &c, nil
+}
+
+func (st *emergencyContactStore) Put(c *models.EmergencyContact) error {
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE emergency_contact SET retired_at = now(), retired_by = $3 WHERE `+currentContact,
+		c.EmployerID, c.SupervisorID, c.ChangedBy); err != nil {
+		return err
+	}
+	err = scanEmergencyContact(tx.QueryRow(
+		`INSERT INTO emergency_contact (phone_number, employer_id, supervisor_id, changed_by)
+		VALUES ($1, $2, $3, $4) RETURNING `+emergencyContactColumns,
+		c.PhoneNumber, c.EmployerID, c.SupervisorID, c.ChangedBy,
+	), c)
+	if err != nil {
+		return conflict(err)
+	}
+	return conflict(tx.Commit())
+}
+
+func (st *emergencyContactStore) Retire(employerID, supervisorID *int, by *int, at time.Time) (*models.EmergencyContact, error) {
+	var c models.EmergencyContact
+	err := scanEmergencyContact(st.db.QueryRow(
+		`UPDATE emergency_contact SET retired_at = $3, retired_by = $4 WHERE `+currentContact+` RETURNING `+emergencyContactColumns,
+		employerID, supervisorID, at, by,
+	), &c)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &c, nil
+}
+
+func (st *emergencyContactStore) History() ([]models.EmergencyContact, error) {
+	rows, err := st.db.Query(`SELECT ` + emergencyContactColumns + ` FROM emergency_contact ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []models.EmergencyContact
+	for rows.Next() {
+		var c models.EmergencyContact
+		if err := scanEmergencyContact(rows, &c); err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"server/models"
+)
+
+type employerStore struct {
+	db *sql.DB
+}
+
+const employerColumns = "id, name, contact_number, address_line1, address_line2, address_line3, addr_long, addr_lat, geofence_radius_m, geofence_polygon, timezone, late_grace_minutes, early_leave_grace_minutes"
+
+func scanEmployer(row scanner, e *models.Employer) error {
+	var polygon []byte
+	if err := row.Scan(&e.ID, &e.Name, &e.ContactNumber, &e.AddressLine1, &e.AddressLine2, &e.AddressLine3, &e.Longitude, &e.Latitude, &e.GeofenceRadius, &polygon, &e.Timezone, &e.LateGraceMinutes, &e.EarlyLeaveGraceMinutes); err != nil {
+		return err
+	}
+	e.GeofencePolygon = nil
+	if polygon == nil {
+		return nil
+	}
+	return json.Unmarshal(polygon, &e.GeofencePolygon)
+}
+
+// encodePolygon returns the JSONB value for a geofence polygon, NULL when empty
+func encodePolygon(points []models.GeoPoint) (interface{}, error) {
+	if len(points) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(points)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (st *employerStore) List() ([]models.Employer, error) {
+	rows, err := st.db.Query("SELECT " + employerColumns + " FROM employer ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var employers []models.Employer
+	for rows.Next() {
+		var e models.Employer
+		if err := scanEmployer(rows, &e); err != nil {
+			return nil, err
+		}
+		employers = append(employers, e)
+	}
+	return employers, rows.Err()
+}
+
+func (st *employerStore) Get(id int) (*models.Employer, error) {
+	var e models.Employer
+	if err := scanEmployer(st.db.QueryRow("SELECT "+employerColumns+" FROM employer WHERE id = $1", id), &e); err != nil {
+		return nil, notFound(err)
+	}
+	return &e, nil
+}
+
+func (st *employerStore) Create(e *models.Employer) error {
+	polygon, err := encodePolygon(e.GeofencePolygon)
+	if err != nil {
+		return err
+	}
+	return scanEmployer(st.db.QueryRow(
+		`INSERT INTO employer (name, contact_number, address_line1, address_line2, address_line3, addr_long, addr_lat, geofence_radius_m, geofence_polygon, timezone, late_grace_minutes, early_leave_grace_minutes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING `+employerColumns,
+		e.Name, e.ContactNumber, e.AddressLine1, e.AddressLine2, e.AddressLine3, e.Longitude, e.Latitude, e.GeofenceRadius, polygon, e.Timezone, e.LateGraceMinutes, e.EarlyLeaveGraceMinutes,
+	), e)
+}
+
+func (st *employerStore) Update(id int, e *models.Employer) error {
+	polygon, err := encodePolygon(e.GeofencePolygon)
+	if err != nil {
+		return err
+	}
+	err = scanEmployer(st.db.QueryRow(
+		`UPDATE employer SET name = $1, contact_number = $2, address_line1 = $3, address_line2 = $4, address_line3 = $5, addr_long = $6, addr_lat = $7, geofence_radius_m = $8, geofence_polygon = $9, timezone = $10, late_grace_minutes = $11, early_leave_grace_minutes = $12 WHERE id = $13 RETURNING `+employerColumns,
+		e.Name, e.ContactNumber, e.AddressLine1, e.AddressLine2, e.AddressLine3, e.Longitude, e.Latitude, e.GeofenceRadius, polygon, e.Timezone, e.LateGraceMinutes, e.EarlyLeaveGraceMinutes, id,
+	), e)
+	return notFound(err)
+}
+
+// Delete removes the employer and retires its emergency contact override,
+// keeping the override's history. Placements restrict the delete.
+func (st *employerStore) Delete(id int) error {
+	return inUse(deleteContactScope(st.db, `UPDATE emergency_contact SET retired_at = now() WHERE employer_id = $1 AND retired_at IS NULL`,
+		`DELETE FROM employer WHERE id = $1`, id))
+}
+
+// deleteContactScope retires the scope's current emergency contact and
+// deletes the scope in one transaction
+func deleteContactScope(db *sql.DB, retire, remove string, id int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(retire, id); err != nil {
+		return err
+	}
+	if err := requireRow(tx.Exec(remove, id)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
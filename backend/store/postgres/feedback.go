@@ -0,0 +1,176 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"server/models"
+	"server/store"
+	"time"
+)
+
+type feedbackStore struct {
+	db *sql.DB
+}
+
+const feedbackColumns = `id, student_id, employer_id, author_id, author_name, period_start, period_end,
+	ratings, comments, source, external_ref, submitted_at`
+
+func scanFeedback(row scanner, f *models.ManagerFeedback) error {
+	var start, end time.Time
+	var ratings []byte
+	var ref sql.NullString
+	if err := row.Scan(&f.ID, &f.StudentID, &f.EmployerID, &f.AuthorID, &f.AuthorName, &start, &end,
+		&ratings, &f.Comments, &f.Source, &ref, &f.SubmittedAt); err != nil {
+		return err
+	}
+	f.PeriodStart, f.PeriodEnd = start.Format("2006-01-02"), end.Format("2006-01-02")
+	f.ExternalRef = ref.String
+	f.Ratings = map[string]int{}
+	return json.Unmarshal(ratings, &f.Ratings)
+}
+
+func (st *feedbackStore) Create(f *models.ManagerFeedback) error {
+	ratings, err := json.Marshal(f.Ratings)
+	if err != nil {
+		return err
+	}
+	var ref interface{}
+	if f.ExternalRef != "" {
+		ref = f.ExternalRef
+	}
+	submittedAt := f.SubmittedAt
+	if submittedAt.IsZero() {
+		submittedAt = time.Now()
+	}
+	err = scanFeedback(st.db.QueryRow(
+		`INSERT INTO manager_feedback (student_id, employer_id, author_id, author_name, period_start, period_end, ratings, comments, source, external_ref, submitted_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING `+feedbackColumns,
+		f.StudentID, f.EmployerID, f.AuthorID, f.AuthorName, f.PeriodStart, f.PeriodEnd, string(ratings), f.Comments, f.Source, ref, submittedAt,
+	), f)
+	return conflict(err)
+}
+
+func (st *feedbackStore) Get(id int) (*models.ManagerFeedback, error) {
+	var f models.ManagerFeedback
+	if err := scanFeedback(st.db.QueryRow(`SELECT `+feedbackColumns+` FROM manager_feedback WHERE id = $1`, id), &f); err != nil {
+		return nil, notFound(err)
+	}
+	return &f, nil
+}
+
+func (st *feedbackStore) ByExternalRef(ref string) (*models.ManagerFeedback, error) {
+	var f models.ManagerFeedback
+	if err := scanFeedback(st.db.QueryRow(`SELECT `+feedbackColumns+` FROM manager_feedback WHERE external_ref = $1`, ref), &f); err != nil {
+		return nil, notFound(err)
+	}
+	return &f, nil
+}
+
+func (st *feedbackStore) Update(f *models.ManagerFeedback) error {
+	ratings, err := json.Marshal(f.Ratings)
+	if err != nil {
+		return err
+	}
+	err = scanFeedback(st.db.QueryRow(
+		`UPDATE manager_feedback SET student_id = $2, employer_id = $3, author_id = $4, author_name = $5, period_start = $6, period_end = $7,
+			ratings = $8, comments = $9, submitted_at = $10
+		WHERE id = $1
+		RETURNING `+feedbackColumns,
+		f.ID, f.StudentID, f.EmployerID, f.AuthorID, f.AuthorName, f.PeriodStart, f.PeriodEnd, string(ratings), f.Comments, f.SubmittedAt,
+	), f)
+	return notFound(err)
+}
+
+func (st *feedbackStore) Delete(id int) error {
+	return requireRow(st.db.Exec(`DELETE FROM manager_feedback WHERE id = $1`, id))
+}
+
+func (st *feedbackStore) List(q store.FeedbackQuery) ([]models.ManagerFeedback, error) {
+	query := `SELECT ` + feedbackColumns + ` FROM manager_feedback WHERE true`
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + itoa(len(args))
+	}
+	if q.StudentID != nil {
+		query += ` AND student_id = ` + arg(*q.StudentID)
+	}
+	if q.EmployerID != nil {
+		query += ` AND employer_id = ` + arg(*q.EmployerID)
+	}
+	if q.From != "" {
+		query += ` AND period_end >= ` + arg(q.From)
+	}
+	if q.To != "" {
+		query += ` AND period_start <= ` + arg(q.To)
+	}
+	rows, err := st.db.Query(query+` ORDER BY period_end DESC, id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []models.ManagerFeedback{}
+	for rows.Next() {
+		var f models.ManagerFeedback
+		if err := scanFeedback(rows, &f); err != nil {
+			return nil, err
+		}
+		list = append(list, f)
+	}
+	return list, rows.Err()
+}
+
+type feedbackSheetStore struct {
+	db *sql.DB
+}
+
+const feedbackSheetColumns = `ref, row_number, cells, content_hash, student_id, feedback_id, error, first_seen_at, updated_at, removed_at`
+
+func scanFeedbackSheetRow(row scanner, r *models.FeedbackSheetRow) error {
+	var cells []byte
+	if err := row.Scan(&r.Ref, &r.RowNumber, &cells, &r.ContentHash, &r.StudentID, &r.FeedbackID, &r.Error, &r.FirstSeenAt, &r.UpdatedAt, &r.RemovedAt); err != nil {
+		return err
+	}
+	r.Cells = map[string]string{}
+	return json.Unmarshal(cells, &r.Cells)
+}
+
+func (st *feedbackSheetStore) List() ([]models.FeedbackSheetRow, error) {
+	rows, err := st.db.Query(`SELECT ` + feedbackSheetColumns + ` FROM feedback_sheet_row ORDER BY row_number, ref`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []models.FeedbackSheetRow{}
+	for rows.Next() {
+		var r models.FeedbackSheetRow
+		if err := scanFeedbackSheetRow(rows, &r); err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+func (st *feedbackSheetStore) Put(r *models.FeedbackSheetRow) error {
+	cells, err := json.Marshal(r.Cells)
+	if err != nil {
+		return err
+	}
+	return scanFeedbackSheetRow(st.db.QueryRow(
+		`INSERT INTO feedback_sheet_row (ref, row_number, cells, content_hash, student_id, feedback_id, error, removed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (ref) DO UPDATE SET
+			row_number = EXCLUDED.row_number,
+			cells = EXCLUDED.cells,
+			content_hash = EXCLUDED.content_hash,
+			student_id = EXCLUDED.student_id,
+			feedback_id = EXCLUDED.feedback_id,
+			error = EXCLUDED.error,
+			removed_at = EXCLUDED.removed_at,
+			updated_at = now()
+		RETURNING `+feedbackSheetColumns,
+		r.Ref, r.RowNumber, string(cells), r.ContentHash, r.StudentID, r.FeedbackID, r.Error, r.RemovedAt,
+	), r)
+}
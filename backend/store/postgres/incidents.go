@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"server/models"
+	"server/store"
+	"strings"
+	"time"
+)
+
+type incidentStore struct {
+	db *sql.DB
+}
+
+const incidentColumns = `id, student_id, latitude, longitude, accuracy_m, message, status, raised_at, located_at,
+	acknowledged_at, acknowledged_by, resolved_at, resolved_by, resolution`
+
+func scanIncident(row scanner, i *models.Incident, extra ...interface{}) error {
+	return row.Scan(append([]interface{}{&i.ID, &i.StudentID, &i.Latitude, &i.Longitude, &i.AccuracyM, &i.Message, &i.Status, &i.RaisedAt, &i.LocatedAt,
+		&i.AcknowledgedAt, &i.AcknowledgedBy, &i.ResolvedAt, &i.ResolvedBy, &i.Resolution}, extra...)...)
+}
+
+func (st *incidentStore) Raise(i *models.Incident) (bool, error) {
+	// xmax is zero only for freshly inserted rows
+	var created bool
+	err := scanIncident(st.db.QueryRow(
+		`INSERT INTO incident (student_id, latitude, longitude, accuracy_m, message)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (student_id) WHERE status <> 'resolved' DO UPDATE SET
+			latitude = EXCLUDED.latitude,
+			longitude = EXCLUDED.longitude,
+			accuracy_m = EXCLUDED.accuracy_m,
+			message = CASE WHEN EXCLUDED.message <> '' THEN EXCLUDED.message ELSE incident.message END,
+			located_at = now()
+		RETURNING `+incidentColumns+`, xmax = 0`,
+		i.StudentID, i.Latitude, i.Longitude, i.AccuracyM, i.Message,
+	), i, &created)
+	return created, err
+}
+
+func (st *incidentStore) Get(id int) (*models.Incident, error) {
+	var i models.Incident
+	err := scanIncident(st.db.QueryRow(`SELECT `+incidentColumns+` FROM incident WHERE id = $1`, id), &i)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &i, nil
+}
+
+func (st *incidentStore) Unresolved(studentID int) (*models.Incident, error) {
+	var i models.Incident
+	err := scanIncident(st.db.QueryRow(`SELECT `+incidentColumns+` FROM incident WHERE student_id = $1 AND status <> 'resolved'`, studentID), &i)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &i, nil
+}
+
+func (st *incidentStore) List(statuses ...string) ([]models.Incident, error) {
+	query := `SELECT ` + incidentColumns + ` FROM incident`
+	args := make([]interface{}, len(statuses))
+	if len(statuses) > 0 {
+		placeholders := make([]string, len(statuses))
+		for n, status := range statuses {
+			placeholders[n] = "$" + itoa(n+1)
+			args[n] = status
+		}
+		query += ` WHERE status IN (` + strings.Join(placeholders, ", ") + `)`
+	}
+	rows, err := st.db.Query(query+` ORDER BY raised_at DESC, id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []models.Incident
+	for rows.Next() {
+		var i models.Incident
+		if err := scanIncident(rows, &i); err != nil {
+			return nil, err
+		}
+		list = append(list, i)
+	}
+	return list, rows.Err()
+}
+
+func (st *incidentStore) Acknowledge(id int, by *int, at time.Time) (*models.Incident, error) {
+	return st.transition(id, `UPDATE incident SET status = 'acknowledged', acknowledged_at = $2, acknowledged_by = $3
+		WHERE id = $1 AND status = 'open'
+		RETURNING `+incidentColumns, id, at, by)
+}
+
+func (st *incidentStore) Resolve(id int, by *int, resolution string, at time.Time) (*models.Incident, error) {
+	// SET expressions see the row as it was, so an open incident is
+	// acknowledged by whoever resolves it
+	return st.transition(id, `UPDATE incident SET status = 'resolved', resolved_at = $2, resolved_by = $3, resolution = $4,
+			acknowledged_at = COALESCE(acknowledged_at, $2),
+			acknowledged_by = CASE WHEN acknowledged_at IS NULL THEN $3 ELSE acknowledged_by END
+		WHERE id = $1 AND status <> 'resolved'
+		RETURNING `+incidentColumns, id, at, by, resolution)
+}
+
+// transition runs a conditional UPDATE, telling a missing incident apart
+// from one that is no longer in the state the update expects
+func (st *incidentStore) transition(id int, query string, args ...interface{}) (*models.Incident, error) {
+	var i models.Incident
+	err := scanIncident(st.db.QueryRow(query, args...), &i)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := st.Get(id); err != nil {
+			return nil, err
+		}
+		return nil, store.ErrConflict
+	} else if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"server/models"
+	"server/store"
+	"time"
+)
+
+type moodStore struct {
+	db *sql.DB
+}
+
+const moodColumns = "id, student_id, recorded_at, emotion, is_daily, valence, local_date, created_at, updated_at"
+
+func scanMood(row scanner, m *models.Mood, extra ...interface{}) error {
+	var localDate *time.Time
+	err := row.Scan(append([]interface{}{&m.ID, &m.StudentID, &m.RecordedAt, &m.Emotion, &m.IsDaily, &m.Valence, &localDate, &m.CreatedAt, &m.UpdatedAt}, extra...)...)
+	if localDate != nil {
+		m.LocalDate = localDate.Format("2006-01-02")
+	}
+	return err
+}
+
+func (st *moodStore) List() ([]models.Mood, error) {
+	return st.query("SELECT " + moodColumns + " FROM mood ORDER BY recorded_at")
+}
+
+func (st *moodStore) Get(id, studentID int) (*models.Mood, error) {
+	var m models.Mood
+	if err := scanMood(st.db.QueryRow("SELECT "+moodColumns+" FROM mood WHERE id = $1 AND student_id = $2", id, studentID), &m); err != nil {
+		return nil, notFound(err)
+	}
+	return &m, nil
+}
+
+func (st *moodStore) Create(m *models.Mood) error {
+	query := "INSERT INTO mood (student_id, emotion, is_daily, recorded_at, valence) VALUES ($1, $2, false, $3, $4) RETURNING " + moodColumns
+	return scanMood(st.db.QueryRow(query, m.StudentID, m.Emotion, m.RecordedAt, m.Valence), m)
+}
+
+func (st *moodStore) DailyOn(studentID int, date string) (*models.Mood, error) {
+	var m models.Mood
+	err := scanMood(st.db.QueryRow("SELECT "+moodColumns+" FROM mood WHERE student_id = $1 AND local_date = $2 AND is_daily", studentID, date), &m)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &m, nil
+}
+
+func (st *moodStore) PutDaily(m *models.Mood, editableSince time.Time) (bool, error) {
+	// The conditional DO UPDATE returns no row when the existing mood is too
+	// old to replace; xmax is zero only for freshly inserted rows
+	var created bool
+	err := scanMood(st.db.QueryRow(
+		`INSERT INTO mood (student_id, emotion, is_daily, recorded_at, valence, local_date)
+		VALUES ($1, $2, true, $3, $4, $5)
+		ON CONFLICT (student_id, local_date) WHERE is_daily DO UPDATE SET
+			emotion = EXCLUDED.emotion,
+			valence = EXCLUDED.valence,
+			recorded_at = EXCLUDED.recorded_at,
+			updated_at = now()
+		WHERE mood.created_at >= $6
+		RETURNING `+moodColumns+`, xmax = 0`,
+		m.StudentID, m.Emotion, m.RecordedAt, m.Valence, m.LocalDate, editableSince,
+	), m, &created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, store.ErrConflict
+	}
+	return created, err
+}
+
+func (st *moodStore) Recent(studentID int, dailyOnly bool, limit int) ([]models.Mood, error) {
+	query := "SELECT " + moodColumns + " FROM mood WHERE student_id = $1"
+	if dailyOnly {
+		query += " AND is_daily = true"
+	}
+	query += " ORDER BY recorded_at DESC"
+	if limit > 0 {
+		query += " LIMIT " + itoa(limit)
+	}
+	return st.query(query, studentID)
+}
+
+func (st *moodStore) Between(studentID int, start, end time.Time) ([]models.Mood, error) {
+	return st.query("SELECT "+moodColumns+" FROM mood WHERE student_id = $1 AND recorded_at >= $2 AND recorded_at < $3 ORDER BY recorded_at", studentID, start, end)
+}
+
+func (st *moodStore) Page(q store.MoodQuery) ([]models.Mood, error) {
+	query := "SELECT " + moodColumns + " FROM mood WHERE student_id = $1"
+	args := []interface{}{q.StudentID}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + itoa(len(args))
+	}
+	if !q.From.IsZero() {
+		query += " AND recorded_at >= " + arg(q.From)
+	}
+	if !q.To.IsZero() {
+		query += " AND recorded_at < " + arg(q.To)
+	}
+	if q.Daily != nil {
+		query += " AND is_daily = " + arg(*q.Daily)
+	}
+	order, after := " DESC", " < "
+	if q.Ascending {
+		order, after = " ASC", " > "
+	}
+	if q.AfterID != 0 {
+		query += " AND (recorded_at, id)" + after + "(" + arg(q.AfterTime) + ", " + arg(q.AfterID) + ")"
+	}
+	query += " ORDER BY recorded_at" + order + ", id" + order
+	if q.Limit > 0 {
+		query += " LIMIT " + itoa(q.Limit)
+	}
+	return st.query(query, args...)
+}
+
+func (st *moodStore) LatestByStudent() (map[int]models.Mood, error) {
+	moods, err := st.query("SELECT DISTINCT ON (student_id) " + moodColumns + " FROM mood ORDER BY student_id, recorded_at DESC")
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[int]models.Mood, len(moods))
+	for _, m := range moods {
+		latest[m.StudentID] = m
+	}
+	return latest, nil
+}
+
+func (st *moodStore) query(query string, args ...interface{}) ([]models.Mood, error) {
+	rows, err := st.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var moods []models.Mood
+	for rows.Next() {
+		var m models.Mood
+		if err := scanMood(rows, &m); err != nil {
+			return nil, err
+		}
+		moods = append(moods, m)
+	}
+	return moods, rows.Err()
+}
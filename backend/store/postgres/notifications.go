@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"database/sql"
+	"server/models"
+	"time"
+)
+
+type notificationStore struct {
+	db *sql.DB
+}
+
+const notificationColumns = "id, event, student_id, channel, recipient, subject, body, status, attempts, last_error, next_attempt_at, created_at, sent_at"
+
+func scanNotification(row scanner, n *models.Notification) error {
+	return row.Scan(&n.ID, &n.Event, &n.StudentID, &n.Channel, &n.Recipient, &n.Subject, &n.Body, &n.Status, &n.Attempts, &n.LastError, &n.NextAttemptAt, &n.CreatedAt, &n.SentAt)
+}
+
+func (st *notificationStore) Enqueue(n *models.Notification) error {
+	if n.Status == "" {
+		n.Status = models.NotificationQueued
+	}
+	next := n.NextAttemptAt
+	if next.IsZero() {
+		next = time.Now()
+	}
+	return scanNotification(st.db.QueryRow(
+		`INSERT INTO notification (event, student_id, channel, recipient, subject, body, status, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING `+notificationColumns,
+		n.Event, n.StudentID, n.Channel, n.Recipient, n.Subject, n.Body, n.Status, next,
+	), n)
+}
+
+func (st *notificationStore) Claim(now time.Time, lease time.Duration, limit int) ([]models.Notification, error) {
+	// SKIP LOCKED lets every replica claim a different batch
+	return st.query(`UPDATE notification SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM notification
+			WHERE status = 'queued' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+notificationColumns, now, now.Add(lease), limit)
+}
+
+func (st *notificationStore) Record(n *models.Notification) error {
+	return requireRow(st.db.Exec(
+		`UPDATE notification SET status = $1, attempts = $2, last_error = $3, next_attempt_at = $4, sent_at = $5 WHERE id = $6`,
+		n.Status, n.Attempts, n.LastError, n.NextAttemptAt, n.SentAt, n.ID,
+	))
+}
+
+func (st *notificationStore) List(status string, limit int) ([]models.Notification, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notification`
+	var args []interface{}
+	if status != "" {
+		query += ` WHERE status = $1`
+		args = append(args, status)
+	}
+	query += ` ORDER BY id DESC`
+	if limit > 0 {
+		query += ` LIMIT ` + itoa(limit)
+	}
+	return st.query(query, args...)
+}
+
+func (st *notificationStore) query(query string, args ...interface{}) ([]models.Notification, error) {
+	rows, err := st.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []models.Notification
+	for rows.Next() {
+		var n models.Notification
+		if err := scanNotification(rows, &n); err != nil {
+			return nil, err
+		}
+		list = append(list, n)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"database/sql"
+	"server/models"
+	"server/store"
+	"time"
+)
+
+type otpStore struct {
+	db *sql.DB
+}
+
+func (st *otpStore) DeleteExpired(now time.Time) error {
+	_, err := st.db.Exec("DELETE FROM otps WHERE expires_at <= $1", now)
+	return err
+}
+
+func (st *otpStore) ActiveForStudent(studentID int, now time.Time) (*models.OTP, error) {
+	o := models.OTP{StudentID: studentID}
+	err := st.db.QueryRow(
+		"SELECT id, otp_code, created_at, expires_at, is_used FROM otps WHERE student_id = $1 AND is_used = false AND expires_at > $2",
+		studentID, now,
+	).Scan(&o.ID, &o.OTPCode, &o.CreatedAt, &o.ExpiresAt, &o.IsUsed)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &o, nil
+}
+
+func (st *otpStore) InvalidateForStudent(studentID int) error {
+	_, err := st.db.Exec("UPDATE otps SET is_used = true WHERE student_id = $1 AND is_used = false", studentID)
+	return err
+}
+
+func (st *otpStore) CodeInUse(code string, now time.Time) (bool, error) {
+	var count int
+	err := st.db.QueryRow(
+		"SELECT COUNT(*) FROM otps WHERE otp_code = $1 AND is_used = false AND expires_at > $2",
+		code, now,
+	).Scan(&count)
+	return count > 0, err
+}
+
+func (st *otpStore) Create(o *models.OTP) error {
+	err := st.db.QueryRow(
+		"INSERT INTO otps (student_id, otp_code, expires_at, is_used) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		o.StudentID, o.OTPCode, o.ExpiresAt, o.IsUsed,
+	).Scan(&o.ID, &o.CreatedAt)
+	return conflict(err)
+}
+
+func (st *otpStore) FindActive(code string, studentID int, now time.Time) (*models.OTP, error) {
+	query := "SELECT id, student_id, otp_code, created_at, expires_at, is_used FROM otps WHERE otp_code = $1 AND is_used = false AND expires_at > $2"
+	args := []interface{}{code, now}
+	if studentID != 0 {
+		query += " AND student_id = $3"
+		args = append(args, studentID)
+	}
+	var o models.OTP
+	if err := st.db.QueryRow(query, args...).Scan(&o.ID, &o.StudentID, &o.OTPCode, &o.CreatedAt, &o.ExpiresAt, &o.IsUsed); err != nil {
+		return nil, notFound(err)
+	}
+	return &o, nil
+}
+
+func (st *otpStore) Redeem(code string, studentID int, device *models.AuthorizedDevice) error {
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The is_used guard stops two devices redeeming the same code
+	res, err := tx.Exec("UPDATE otps SET is_used = true WHERE otp_code = $1 AND student_id = $2 AND is_used = false", code, studentID)
+	if err := requireRow(res, err); err == store.ErrNotFound {
+		return store.ErrConflict
+	} else if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(
+		"INSERT INTO authorized_devices (student_id, secret_code, device_name) VALUES ($1, $2, $3) RETURNING id, created_at",
+		device.StudentID, device.SecretCode, device.DeviceName,
+	).Scan(&device.ID, &device.CreatedAt)
+	if err != nil {
+		return conflict(err)
+	}
+	return tx.Commit()
+}
+
+func (st *otpStore) RecordFailure(a *models.OTPFailedAttempt) error {
+	return st.db.QueryRow(
+		"INSERT INTO otp_failed_attempts (attempted_code, student_id, client_ip, device_name, reason) VALUES ($1, $2, $3, $4, $5) RETURNING id, attempted_at",
+		a.AttemptedCode, a.StudentID, a.ClientIP, a.DeviceName, a.Reason,
+	).Scan(&a.ID, &a.AttemptedAt)
+}
+
+func (st *otpStore) LatestByStudent() (map[int]models.OTP, error) {
+	rows, err := st.db.Query("SELECT DISTINCT ON (student_id) id, student_id, otp_code, created_at, expires_at, is_used FROM otps ORDER BY student_id, created_at DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	latest := map[int]models.OTP{}
+	for rows.Next() {
+		var o models.OTP
+		if err := rows.Scan(&o.ID, &o.StudentID, &o.OTPCode, &o.CreatedAt, &o.ExpiresAt, &o.IsUsed); err != nil {
+			return nil, err
+		}
+		latest[o.StudentID] = o
+	}
+	return latest, rows.Err()
+}
@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"server/models"
+	"server/store"
+	"time"
+)
+
+type placementStore struct {
+	db *sql.DB
+}
+
+const placementColumns = "id, student_id, employer_id, role, start_date, end_date, end_reason, schedule, created_at"
+
+func scanPlacement(row scanner, p *models.Placement) error {
+	var start time.Time
+	var end sql.NullTime
+	var schedule []byte
+	if err := row.Scan(&p.ID, &p.StudentID, &p.EmployerID, &p.Role, &start, &end, &p.EndReason, &schedule, &p.CreatedAt); err != nil {
+		return err
+	}
+	p.StartDate = start.Format("2006-01-02")
+	p.EndDate = nil
+	if end.Valid {
+		date := end.Time.Format("2006-01-02")
+		p.EndDate = &date
+	}
+	p.Schedule = []models.ScheduleDay{}
+	return json.Unmarshal(schedule, &p.Schedule)
+}
+
+func (st *placementStore) List(studentID int) ([]models.Placement, error) {
+	rows, err := st.db.Query(`SELECT `+placementColumns+` FROM placement WHERE student_id = $1 ORDER BY start_date DESC, id DESC`, studentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []models.Placement
+	for rows.Next() {
+		var p models.Placement
+		if err := scanPlacement(rows, &p); err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+	return list, rows.Err()
+}
+
+func (st *placementStore) ListByStudent() (map[int][]models.Placement, error) {
+	rows, err := st.db.Query(`SELECT ` + placementColumns + ` FROM placement ORDER BY start_date DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	byStudent := map[int][]models.Placement{}
+	for rows.Next() {
+		var p models.Placement
+		if err := scanPlacement(rows, &p); err != nil {
+			return nil, err
+		}
+		byStudent[p.StudentID] = append(byStudent[p.StudentID], p)
+	}
+	return byStudent, rows.Err()
+}
+
+func (st *placementStore) Get(id int) (*models.Placement, error) {
+	var p models.Placement
+	if err := scanPlacement(st.db.QueryRow(`SELECT `+placementColumns+` FROM placement WHERE id = $1`, id), &p); err != nil {
+		return nil, notFound(err)
+	}
+	return &p, nil
+}
+
+func (st *placementStore) Current(studentID int) (*models.Placement, error) {
+	var p models.Placement
+	if err := scanPlacement(st.db.QueryRow(`SELECT `+placementColumns+` FROM placement WHERE student_id = $1 AND end_date IS NULL`, studentID), &p); err != nil {
+		return nil, notFound(err)
+	}
+	return &p, nil
+}
+
+// closeOpen ends the student's open placement inside tx, see PlacementStore.End
+func closeOpen(tx *sql.Tx, studentID int, endDate, reason string) (*models.Placement, error) {
+	var p models.Placement
+	err := scanPlacement(tx.QueryRow(`SELECT `+placementColumns+` FROM placement WHERE student_id = $1 AND end_date IS NULL FOR UPDATE`, studentID), &p)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if p.StartDate > endDate {
+		return nil, store.ErrConflict
+	}
+	err = scanPlacement(tx.QueryRow(`UPDATE placement SET end_date = $1, end_reason = $2 WHERE id = $3 RETURNING `+placementColumns, endDate, reason, p.ID), &p)
+	return &p, err
+}
+
+func (st *placementStore) Start(p *models.Placement, previousEnd string) error {
+	if p.Schedule == nil {
+		p.Schedule = []models.ScheduleDay{}
+	}
+	schedule, err := json.Marshal(p.Schedule)
+	if err != nil {
+		return err
+	}
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := closeOpen(tx, p.StudentID, previousEnd, ""); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+	err = scanPlacement(tx.QueryRow(`INSERT INTO placement (student_id, employer_id, role, start_date, schedule) VALUES ($1, $2, $3, $4, $5) RETURNING `+placementColumns,
+		p.StudentID, p.EmployerID, p.Role, p.StartDate, string(schedule)), p)
+	if err != nil {
+		// A placement started concurrently
+		return conflict(err)
+	}
+	if _, err := tx.Exec(`UPDATE student SET employer_id = $1 WHERE id = $2`, p.EmployerID, p.StudentID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (st *placementStore) End(studentID int, endDate, reason string) (*models.Placement, error) {
+	tx, err := st.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	p, err := closeOpen(tx, studentID, endDate, reason)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`UPDATE student SET employer_id = NULL WHERE id = $1`, studentID); err != nil {
+		return nil, err
+	}
+	return p, tx.Commit()
+}
+
+func (st *placementStore) SetSchedule(id int, days []models.ScheduleDay) error {
+	if days == nil {
+		days = []models.ScheduleDay{}
+	}
+	schedule, err := json.Marshal(days)
+	if err != nil {
+		return err
+	}
+	return requireRow(st.db.Exec(`UPDATE placement SET schedule = $1 WHERE id = $2`, string(schedule), id))
+}
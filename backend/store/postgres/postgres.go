@@ -0,0 +1,78 @@
+// Package postgres implements the store interfaces on top of database/sql and lib/pq
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"server/store"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+// New returns a Store whose repositories all share db
+func New(db *sql.DB) *store.Store {
+	return &store.Store{
+		Students:          &studentStore{db: db},
+		Supervisors:       &supervisorStore{db: db},
+		Employers:         &employerStore{db: db},
+		Attendance:        &attendanceStore{db: db},
+		Moods:             &moodStore{db: db},
+		OTPs:              &otpStore{db: db},
+		Devices:           &deviceStore{db: db},
+		Users:             &userStore{db: db},
+		EmergencyContacts: &emergencyContactStore{db: db},
+		Schedules:         &scheduleStore{db: db},
+		Notifications:     &notificationStore{db: db},
+		Incidents:         &incidentStore{db: db},
+		Feedback:          &feedbackStore{db: db},
+		FeedbackSheet:     &feedbackSheetStore{db: db},
+		Placements:        &placementStore{db: db},
+	}
+}
+
+// notFound maps sql.ErrNoRows to store.ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.ErrNotFound
+	}
+	return err
+}
+
+// conflict maps unique violations to store.ErrConflict
+func conflict(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return store.ErrConflict
+	}
+	return err
+}
+
+// inUse maps foreign key violations, such as deleting a row others still
+// reference, to store.ErrConflict
+func inUse(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return store.ErrConflict
+	}
+	return err
+}
+
+// requireRow returns store.ErrNotFound when an UPDATE or DELETE touched nothing
+func requireRow(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+func itoa(n int) string {
+	return strconv.Itoa(n)
+}
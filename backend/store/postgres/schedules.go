@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"database/sql"
+	"server/models"
+	"time"
+)
+
+type scheduleStore struct {
+	db *sql.DB
+}
+
+func (st *scheduleStore) Weekly(studentID int) ([]models.ScheduleDay, error) {
+	rows, err := st.db.Query("SELECT weekday, start_time, end_time FROM schedule_day WHERE student_id = $1 ORDER BY weekday", studentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []models.ScheduleDay
+	for rows.Next() {
+		var d models.ScheduleDay
+		if err := rows.Scan(&d.Weekday, &d.Start, &d.End); err != nil {
+			return nil, err
+		}
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
+
+func (st *scheduleStore) WeeklyByStudent() (map[int][]models.ScheduleDay, error) {
+	rows, err := st.db.Query("SELECT student_id, weekday, start_time, end_time FROM schedule_day ORDER BY student_id, weekday")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := map[int][]models.ScheduleDay{}
+	for rows.Next() {
+		var studentID int
+		var d models.ScheduleDay
+		if err := rows.Scan(&studentID, &d.Weekday, &d.Start, &d.End); err != nil {
+			return nil, err
+		}
+		days[studentID] = append(days[studentID], d)
+	}
+	return days, rows.Err()
+}
+
+func (st *scheduleStore) ReplaceWeekly(studentID int, days []models.ScheduleDay) error {
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM schedule_day WHERE student_id = $1", studentID); err != nil {
+		return err
+	}
+	for _, d := range days {
+		_, err := tx.Exec("INSERT INTO schedule_day (student_id, weekday, start_time, end_time) VALUES ($1, $2, $3, $4)",
+			studentID, int(d.Weekday), d.Start, d.End)
+		if err != nil {
+			return conflict(err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (st *scheduleStore) Overrides(studentID int, from, to string) ([]models.ScheduleOverride, error) {
+	rows, err := st.db.Query(`SELECT date, day_off, start_time, end_time, reason FROM schedule_override
+		WHERE student_id = $1 AND date BETWEEN $2 AND $3 ORDER BY date`, studentID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overrides []models.ScheduleOverride
+	for rows.Next() {
+		o := models.ScheduleOverride{StudentID: studentID}
+		var date time.Time
+		if err := rows.Scan(&date, &o.DayOff, &o.Start, &o.End, &o.Reason); err != nil {
+			return nil, err
+		}
+		o.Date = date.Format("2006-01-02")
+		overrides = append(overrides, o)
+	}
+	return overrides, rows.Err()
+}
+
+func (st *scheduleStore) OverridesByStudent(from, to string) (map[int][]models.ScheduleOverride, error) {
+	rows, err := st.db.Query(`SELECT student_id, date, day_off, start_time, end_time, reason FROM schedule_override
+		WHERE date BETWEEN $1 AND $2 ORDER BY student_id, date`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := map[int][]models.ScheduleOverride{}
+	for rows.Next() {
+		var o models.ScheduleOverride
+		var date time.Time
+		if err := rows.Scan(&o.StudentID, &date, &o.DayOff, &o.Start, &o.End, &o.Reason); err != nil {
+			return nil, err
+		}
+		o.Date = date.Format("2006-01-02")
+		overrides[o.StudentID] = append(overrides[o.StudentID], o)
+	}
+	return overrides, rows.Err()
+}
+
+func (st *scheduleStore) PutOverride(o *models.ScheduleOverride) error {
+	_, err := st.db.Exec(`INSERT INTO schedule_override (student_id, date, day_off, start_time, end_time, reason)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (student_id, date) DO UPDATE
+		SET day_off = EXCLUDED.day_off, start_time = EXCLUDED.start_time,
+			end_time = EXCLUDED.end_time, reason = EXCLUDED.reason`,
+		o.StudentID, o.Date, o.DayOff, o.Start, o.End, o.Reason)
+	return err
+}
+
+func (st *scheduleStore) DeleteOverride(studentID int, date string) error {
+	return requireRow(st.db.Exec("DELETE FROM schedule_override WHERE student_id = $1 AND date = $2", studentID, date))
+}
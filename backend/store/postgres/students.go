@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"database/sql"
+	"server/models"
+)
+
+type studentStore struct {
+	db *sql.DB
+}
+
+const studentColumns = "id, first_name, last_name, dob, gender, address_line1, address_line2, city, contact_number, contact_number_guardian, supervisor_id, remarks, home_long, home_lat, employer_id, check_in_time, check_out_time"
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanStudent(row scanner, s *models.Student) error {
+	return row.Scan(&s.ID, &s.FirstName, &s.LastName, &s.DOB, &s.Gender, &s.AddressLine1, &s.AddressLine2, &s.City, &s.ContactNumber, &s.ContactNumberGuardian, &s.SupervisorID, &s.Remarks, &s.HomeLong, &s.HomeLat, &s.EmployerID, &s.CheckInTime, &s.CheckOutTime)
+}
+
+func (st *studentStore) List() ([]models.Student, error) {
+	rows, err := st.db.Query("SELECT " + studentColumns + " FROM student ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var students []models.Student
+	for rows.Next() {
+		var s models.Student
+		if err := scanStudent(rows, &s); err != nil {
+			return nil, err
+		}
+		students = append(students, s)
+	}
+	return students, rows.Err()
+}
+
+func (st *studentStore) Get(id int) (*models.Student, error) {
+	var s models.Student
+	err := scanStudent(st.db.QueryRow("SELECT "+studentColumns+" FROM student WHERE id = $1", id), &s)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &s, nil
+}
+
+func (st *studentStore) Create(s *models.Student) error {
+	query := `INSERT INTO student (first_name, last_name, dob, gender, address_line1, address_line2, city, contact_number, contact_number_guardian, supervisor_id, remarks, home_long, home_lat, employer_id, check_in_time, check_out_time) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16) RETURNING id`
+	return st.db.QueryRow(query, s.FirstName, s.LastName, s.DOB, s.Gender, s.AddressLine1, s.AddressLine2, s.City, s.ContactNumber, s.ContactNumberGuardian, s.SupervisorID, s.Remarks, s.HomeLong, s.HomeLat, s.EmployerID, s.CheckInTime, s.CheckOutTime).Scan(&s.ID)
+}
+
+func (st *studentStore) Update(id int, s *models.Student) error {
+	query := `UPDATE student SET first_name=$1, last_name=$2, dob=$3, gender=$4, address_line1=$5, address_line2=$6, city=$7, contact_number=$8, contact_number_guardian=$9, supervisor_id=$10, remarks=$11, home_long=$12, home_lat=$13, employer_id=$14, check_in_time=$15, check_out_time=$16 WHERE id=$17`
+	return requireRow(st.db.Exec(query, s.FirstName, s.LastName, s.DOB, s.Gender, s.AddressLine1, s.AddressLine2, s.City, s.ContactNumber, s.ContactNumberGuardian, s.SupervisorID, s.Remarks, s.HomeLong, s.HomeLat, s.EmployerID, s.CheckInTime, s.CheckOutTime, id))
+}
+
+func (st *studentStore) Delete(id int) error {
+	return requireRow(st.db.Exec("DELETE FROM student WHERE id = $1", id))
+}
@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"database/sql"
+	"server/models"
+)
+
+type supervisorStore struct {
+	db *sql.DB
+}
+
+func (st *supervisorStore) List() ([]models.Supervisor, error) {
+	rows, err := st.db.Query("SELECT supervisor_id, first_name, last_name, email_address, contact_number FROM supervisor ORDER BY supervisor_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var supervisors []models.Supervisor
+	for rows.Next() {
+		var s models.Supervisor
+		if err := rows.Scan(&s.SupervisorID, &s.FirstName, &s.LastName, &s.EmailAddress, &s.ContactNumber); err != nil {
+			return nil, err
+		}
+		supervisors = append(supervisors, s)
+	}
+	return supervisors, rows.Err()
+}
+
+func (st *supervisorStore) Get(id int) (*models.Supervisor, error) {
+	var s models.Supervisor
+	err := st.db.QueryRow("SELECT supervisor_id, first_name, last_name, email_address, contact_number FROM supervisor WHERE supervisor_id = $1", id).Scan(&s.SupervisorID, &s.FirstName, &s.LastName, &s.EmailAddress, &s.ContactNumber)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &s, nil
+}
+
+func (st *supervisorStore) Create(s *models.Supervisor) error {
+	query := `INSERT INTO supervisor (first_name, last_name, email_address, contact_number) VALUES ($1, $2, $3, $4) RETURNING supervisor_id`
+	return st.db.QueryRow(query, s.FirstName, s.LastName, s.EmailAddress, s.ContactNumber).Scan(&s.SupervisorID)
+}
+
+func (st *supervisorStore) Update(id int, s *models.Supervisor) error {
+	query := `UPDATE supervisor SET first_name=$1, last_name=$2, email_address=$3, contact_number=$4 WHERE supervisor_id=$5`
+	if err := requireRow(st.db.Exec(query, s.FirstName, s.LastName, s.EmailAddress, s.ContactNumber, id)); err != nil {
+		return err
+	}
+	s.SupervisorID = id
+	return nil
+}
+
+// Delete removes the supervisor and retires their emergency contact
+// override, keeping the override's history
+func (st *supervisorStore) Delete(id int) error {
+	return deleteContactScope(st.db, `UPDATE emergency_contact SET retired_at = now() WHERE supervisor_id = $1 AND retired_at IS NULL`,
+		"DELETE FROM supervisor WHERE supervisor_id = $1", id)
+}
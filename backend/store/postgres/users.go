@@ -0,0 +1,30 @@
+package postgres
+
+import (
+	"database/sql"
+	"server/models"
+)
+
+type userStore struct {
+	db *sql.DB
+}
+
+func (st *userStore) GetByUsername(username string) (*models.User, error) {
+	var u models.User
+	err := st.db.QueryRow(
+		"SELECT id, username, password_hash, role, supervisor_id, employer_id, created_at FROM app_user WHERE username = $1",
+		username,
+	).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Role, &u.SupervisorID, &u.EmployerID, &u.CreatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &u, nil
+}
+
+func (st *userStore) Create(u *models.User) error {
+	err := st.db.QueryRow(
+		"INSERT INTO app_user (username, password_hash, role, supervisor_id, employer_id) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at",
+		u.Username, u.PasswordHash, u.Role, u.SupervisorID, u.EmployerID,
+	).Scan(&u.ID, &u.CreatedAt)
+	return conflict(err)
+}
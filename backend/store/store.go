@@ -0,0 +1,285 @@
+// Package store defines the persistence interfaces used by the HTTP handlers.
+// The postgres subpackage backs them with the production database and the
+// memory subpackage keeps everything in process for tests and local runs.
+package store
+
+import (
+	"errors"
+	"server/models"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when the requested row does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a write loses a race or violates a uniqueness rule
+	ErrConflict = errors.New("conflict")
+)
+
+// Store bundles every repository the handlers depend on
+type Store struct {
+	Students          StudentStore
+	Supervisors       SupervisorStore
+	Employers         EmployerStore
+	Attendance        AttendanceStore
+	Moods             MoodStore
+	OTPs              OTPStore
+	Devices           DeviceStore
+	Users             UserStore
+	EmergencyContacts EmergencyContactStore
+	Schedules         ScheduleStore
+	Notifications     NotificationStore
+	Incidents         IncidentStore
+	Feedback          FeedbackStore
+	FeedbackSheet     FeedbackSheetStore
+	Placements        PlacementStore
+}
+
+type StudentStore interface {
+	List() ([]models.Student, error)
+	Get(id int) (*models.Student, error)
+	Create(s *models.Student) error
+	Update(id int, s *models.Student) error
+	Delete(id int) error
+}
+
+type SupervisorStore interface {
+	List() ([]models.Supervisor, error)
+	Get(id int) (*models.Supervisor, error)
+	Create(s *models.Supervisor) error
+	Update(id int, s *models.Supervisor) error
+	Delete(id int) error
+}
+
+type EmployerStore interface {
+	List() ([]models.Employer, error)
+	Get(id int) (*models.Employer, error)
+	Create(e *models.Employer) error
+	Update(id int, e *models.Employer) error
+	// Delete returns ErrConflict while any placement, past or present, is
+	// with the employer
+	Delete(id int) error
+}
+
+type AttendanceStore interface {
+	// OpenSession returns the student's latest session checked in at or after
+	// since that has not been checked out of
+	OpenSession(studentID int, since time.Time) (*models.Attendance, error)
+	// CheckIn stores a as a new session unless the student already has an
+	// open session checked in at or after since, in which case it returns ErrConflict
+	CheckIn(a *models.Attendance, since time.Time) error
+	Create(a *models.Attendance) error
+	// UpdateCheckOut closes an open session. It returns ErrNotFound if the
+	// session does not exist or was already closed.
+	UpdateCheckOut(a *models.Attendance) error
+	// Between returns the student's sessions starting in [start, end), oldest first
+	Between(studentID int, start, end time.Time) ([]models.Attendance, error)
+	// Recent returns up to limit records, newest first. A non-zero before
+	// only includes sessions that started earlier than it.
+	Recent(studentID int, before time.Time, limit int) ([]models.Attendance, error)
+	// LatestByStudent returns each student's most recent record keyed by student ID
+	LatestByStudent() (map[int]models.Attendance, error)
+	// OpenSessions returns every open session of every student, oldest first
+	OpenSessions() ([]models.Attendance, error)
+	// MarkAbsent stores the absence a unless the student already has a record
+	// starting in [dayStart, dayEnd), in which case it returns ErrConflict
+	MarkAbsent(a *models.Attendance, dayStart, dayEnd time.Time) error
+	// ClearAbsence marks absences starting in [dayStart, dayEnd) superseded.
+	// The rows are kept, but no other method returns or counts them.
+	ClearAbsence(studentID int, dayStart, dayEnd time.Time) error
+	Get(id int) (*models.Attendance, error)
+	// Review stores a's review status, note, reviewer and time
+	Review(a *models.Attendance) error
+	// UpdatePunctuality stores a's punctuality and minutes early
+	UpdatePunctuality(a *models.Attendance) error
+}
+
+type MoodStore interface {
+	List() ([]models.Mood, error)
+	// Get returns a mood only if it belongs to studentID
+	Get(id, studentID int) (*models.Mood, error)
+	// Create stores an ad-hoc mood
+	Create(m *models.Mood) error
+	// DailyOn returns the student's daily mood for a local date (YYYY-MM-DD)
+	DailyOn(studentID int, date string) (*models.Mood, error)
+	// PutDaily stores m as the daily mood for m.LocalDate, replacing the one
+	// already recorded that day if it was created at or after editableSince.
+	// It returns ErrConflict if the existing one can no longer be replaced.
+	PutDaily(m *models.Mood, editableSince time.Time) (created bool, err error)
+	// Recent returns up to limit moods, newest first
+	Recent(studentID int, dailyOnly bool, limit int) ([]models.Mood, error)
+	// Between returns the student's moods recorded in [start, end), oldest first
+	Between(studentID int, start, end time.Time) ([]models.Mood, error)
+	// Page returns up to q.Limit of the student's moods matching q in q's order
+	Page(q MoodQuery) ([]models.Mood, error)
+	// LatestByStudent returns each student's most recent mood keyed by student ID
+	LatestByStudent() (map[int]models.Mood, error)
+}
+
+// MoodQuery selects a page of one student's moods. Zero times leave that end
+// of the range open and a nil Daily includes every mood.
+type MoodQuery struct {
+	StudentID int
+	From, To  time.Time
+	Daily     *bool
+	// Ascending orders oldest first; moods are otherwise newest first
+	Ascending bool
+	// After continues from the mood recorded at AfterTime with AfterID,
+	// when AfterID is non-zero
+	AfterTime time.Time
+	AfterID   int
+	Limit     int
+}
+
+type OTPStore interface {
+	DeleteExpired(now time.Time) error
+	// ActiveForStudent returns the student's unused, unexpired code
+	ActiveForStudent(studentID int, now time.Time) (*models.OTP, error)
+	// InvalidateForStudent marks every unused code of the student as used
+	InvalidateForStudent(studentID int) error
+	// CodeInUse reports whether any student holds code as an active OTP
+	CodeInUse(code string, now time.Time) (bool, error)
+	Create(o *models.OTP) error
+	// FindActive looks up an active code, narrowed to studentID when it is non-zero
+	FindActive(code string, studentID int, now time.Time) (*models.OTP, error)
+	// Redeem marks the code used and stores device in one transaction. It
+	// returns ErrConflict if the code was redeemed concurrently.
+	Redeem(code string, studentID int, device *models.AuthorizedDevice) error
+	RecordFailure(a *models.OTPFailedAttempt) error
+	// LatestByStudent returns each student's most recently created code keyed by student ID
+	LatestByStudent() (map[int]models.OTP, error)
+}
+
+type DeviceStore interface {
+	// FindActive returns the non-revoked device whose stored secret hash matches
+	FindActive(studentID int, secretHash string) (*models.AuthorizedDevice, error)
+	Touch(id int, at time.Time) error
+	IsActive(id, studentID int) (bool, error)
+	// List returns devices newest first, all of them when studentID is zero
+	List(studentID int) ([]models.AuthorizedDevice, error)
+	// Revoke returns ErrNotFound if the device does not exist or is already revoked
+	Revoke(id int, at time.Time) (*models.AuthorizedDevice, error)
+}
+
+type UserStore interface {
+	GetByUsername(username string) (*models.User, error)
+	// Create returns ErrConflict if the username is taken
+	Create(u *models.User) error
+}
+
+// EmergencyContactStore keeps every version of every contact. The contact
+// in force for an employer, a supervisor or, when both IDs are nil, everyone
+// is the one not yet retired.
+type EmergencyContactStore interface {
+	Current(employerID, supervisorID *int) (*models.EmergencyContact, error)
+	// Put retires the contact in force for c's employer or supervisor and
+	// stores c as its successor. It returns ErrConflict if another change won
+	// the race.
+	Put(c *models.EmergencyContact) error
+	// Retire ends the contact in force without a successor. It returns
+	// ErrNotFound if there is none.
+	Retire(employerID, supervisorID *int, by *int, at time.Time) (*models.EmergencyContact, error)
+	// History returns every version, newest first
+	History() ([]models.EmergencyContact, error)
+}
+
+type ScheduleStore interface {
+	// Weekly returns the student's template ordered by weekday, empty when none is saved
+	Weekly(studentID int) ([]models.ScheduleDay, error)
+	// WeeklyByStudent returns every saved template keyed by student ID
+	WeeklyByStudent() (map[int][]models.ScheduleDay, error)
+	// ReplaceWeekly swaps the student's whole template for days
+	ReplaceWeekly(studentID int, days []models.ScheduleDay) error
+	// Overrides returns the student's overrides dated from..to inclusive
+	// (YYYY-MM-DD), oldest first
+	Overrides(studentID int, from, to string) ([]models.ScheduleOverride, error)
+	// OverridesByStudent returns every student's overrides dated from..to
+	// inclusive keyed by student ID, oldest first
+	OverridesByStudent(from, to string) (map[int][]models.ScheduleOverride, error)
+	// PutOverride creates or replaces the override on o.Date
+	PutOverride(o *models.ScheduleOverride) error
+	// DeleteOverride returns ErrNotFound if there is no override on date
+	DeleteOverride(studentID int, date string) error
+}
+
+type NotificationStore interface {
+	Enqueue(n *models.Notification) error
+	// Claim returns up to limit queued notifications due at now and pushes
+	// their next attempt back by lease, so no other worker picks them up
+	// while they are being sent
+	Claim(now time.Time, lease time.Duration, limit int) ([]models.Notification, error)
+	// Record saves the status, attempts, error and timestamps of n
+	Record(n *models.Notification) error
+	// List returns notifications newest first, only those in status when it is not empty
+	List(status string, limit int) ([]models.Notification, error)
+}
+
+type IncidentStore interface {
+	// Raise stores i as a new open incident, or, when the student already has
+	// an unresolved one, moves that one to i's location and loads it into i.
+	// created reports which of the two happened.
+	Raise(i *models.Incident) (created bool, err error)
+	Get(id int) (*models.Incident, error)
+	// Unresolved returns the student's open or acknowledged incident
+	Unresolved(studentID int) (*models.Incident, error)
+	// List returns incidents newest first, only those in one of statuses when any are given
+	List(statuses ...string) ([]models.Incident, error)
+	// Acknowledge returns ErrConflict if the incident is no longer open
+	Acknowledge(id int, by *int, at time.Time) (*models.Incident, error)
+	// Resolve also acknowledges an open incident. It returns ErrConflict if
+	// the incident is already resolved.
+	Resolve(id int, by *int, resolution string, at time.Time) (*models.Incident, error)
+}
+
+// FeedbackQuery filters manager feedback. Nil IDs and empty dates match
+// everything; From and To (YYYY-MM-DD) keep feedback whose period overlaps them.
+type FeedbackQuery struct {
+	StudentID  *int
+	EmployerID *int
+	From, To   string
+}
+
+type FeedbackStore interface {
+	// Create returns ErrConflict if feedback with f.ExternalRef was already stored
+	Create(f *models.ManagerFeedback) error
+	Get(id int) (*models.ManagerFeedback, error)
+	// ByExternalRef returns the feedback imported from ref
+	ByExternalRef(ref string) (*models.ManagerFeedback, error)
+	// List returns matching feedback, latest period first
+	List(q FeedbackQuery) ([]models.ManagerFeedback, error)
+	// Update replaces everything but the ID and source of f.ID
+	Update(f *models.ManagerFeedback) error
+	Delete(id int) error
+}
+
+// FeedbackSheetStore keeps the rows pulled from the feedback Google Sheet
+type FeedbackSheetStore interface {
+	// List returns every row ever pulled, removed ones included, by row number
+	List() ([]models.FeedbackSheetRow, error)
+	// Put creates or replaces the row with r.Ref
+	Put(r *models.FeedbackSheetRow) error
+}
+
+// PlacementStore keeps every placement of every student. Starting and
+// ending placements also moves student.employer_id, so the open placement
+// and the student's employer always agree.
+type PlacementStore interface {
+	// List returns the student's placements, latest start first
+	List(studentID int) ([]models.Placement, error)
+	// ListByStudent returns every student's placements keyed by student ID,
+	// latest start first
+	ListByStudent() (map[int][]models.Placement, error)
+	Get(id int) (*models.Placement, error)
+	// Current returns the student's open placement
+	Current(studentID int) (*models.Placement, error)
+	// Start ends the student's open placement, if any, on previousEnd and
+	// stores p as the open one. It returns ErrConflict if the open placement
+	// started after previousEnd.
+	Start(p *models.Placement, previousEnd string) error
+	// End closes the student's open placement and clears their employer. It
+	// returns ErrNotFound if none is open and ErrConflict if it started
+	// after endDate.
+	End(studentID int, endDate, reason string) (*models.Placement, error)
+	// SetSchedule replaces the weekly template recorded for the placement
+	SetSchedule(id int, days []models.ScheduleDay) error
+}
@@ -0,0 +1,112 @@
+package summary
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const geminiBaseURL = "https://generativelanguage.googleapis.com/v1beta/models/"
+
+// GeminiConfig configures the Gemini provider
+type GeminiConfig struct {
+	APIKey string
+	// Model is the Gemini model name, e.g. gemini-1.5-flash
+	Model string
+	// Endpoint overrides the generateContent URL, for tests
+	Endpoint string
+	// Timeout bounds each request, defaulting to defaultTimeout
+	Timeout time.Duration
+}
+
+// Gemini completes prompts with Google's Gemini API. The API key travels in
+// a request header so it never appears in URLs or in logged errors.
+type Gemini struct {
+	cfg    GeminiConfig
+	client *http.Client
+}
+
+func NewGemini(cfg GeminiConfig) *Gemini {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = geminiBaseURL + cfg.Model + ":generateContent"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	return &Gemini{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+func (*Gemini) Name() string { return "gemini" }
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiContent struct {
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiRequest struct {
+	Contents         []geminiContent `json:"contents"`
+	GenerationConfig struct {
+		Temperature     float64 `json:"temperature"`
+		TopK            int     `json:"topK"`
+		TopP            float64 `json:"topP"`
+		MaxOutputTokens int     `json:"maxOutputTokens"`
+	} `json:"generationConfig"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content geminiContent `json:"content"`
+	} `json:"candidates"`
+}
+
+// Complete sends prompt as a single user turn and returns the first candidate
+func (g *Gemini) Complete(ctx context.Context, prompt string) (string, error) {
+	payload := geminiRequest{Contents: []geminiContent{{Parts: []geminiPart{{Text: prompt}}}}}
+	payload.GenerationConfig.Temperature = 0.7
+	payload.GenerationConfig.TopK = 40
+	payload.GenerationConfig.TopP = 0.95
+	payload.GenerationConfig.MaxOutputTokens = 800
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Goog-Api-Key", g.cfg.APIKey)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return "", fmt.Errorf("gemini returned %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+
+	var result geminiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decoding gemini response: %w", err)
+	}
+	if len(result.Candidates) == 0 || len(result.Candidates[0].Content.Parts) == 0 {
+		return "", errors.New("gemini returned no candidates")
+	}
+	var text strings.Builder
+	for _, p := range result.Candidates[0].Content.Parts {
+		text.WriteString(p.Text)
+	}
+	return text.String(), nil
+}
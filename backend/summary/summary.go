@@ -0,0 +1,283 @@
+// Package summary writes short progress summaries of trainees for their
+// managers and supervisors. A language model provider drafts the text when
+// one is configured; a deterministic template is used otherwise and
+// whenever the provider fails.
+package summary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"server/config"
+	"server/models"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SourceTemplate is the source of summaries written by the built-in template
+const SourceTemplate = "template"
+
+// Provider completes a prompt with a language model
+type Provider interface {
+	Name() string
+	Complete(ctx context.Context, prompt string) (string, error)
+}
+
+// defaultTimeout bounds a summary when no positive timeout is given
+const defaultTimeout = 30 * time.Second
+
+// Service writes summaries with a provider, falling back to the template
+type Service struct {
+	provider Provider
+	timeout  time.Duration
+}
+
+// New returns a service that asks provider, which may be nil to always use
+// the template, giving it timeout per summary
+func New(provider Provider, timeout time.Duration) *Service {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Service{provider: provider, timeout: timeout}
+}
+
+// Write fills in s.Summary and s.Source from the facts in s
+func (svc *Service) Write(ctx context.Context, s *models.TraineeSummary) {
+	if svc.provider != nil {
+		ctx, cancel := context.WithTimeout(ctx, svc.timeout)
+		defer cancel()
+		text, err := svc.provider.Complete(ctx, Prompt(s))
+		text = strings.TrimSpace(text)
+		if err == nil && text != "" {
+			s.Summary, s.Source = text, svc.provider.Name()
+			return
+		}
+		if err == nil {
+			err = errors.New("empty response")
+		}
+		log.Printf("⚠️ Summary provider %s failed for student %d, using template: %v", svc.provider.Name(), s.StudentID, err)
+	}
+	s.Summary, s.Source = Template(s), SourceTemplate
+}
+
+// Prompt asks for a sectioned summary of the facts in s
+func Prompt(s *models.TraineeSummary) string {
+	var b strings.Builder
+	b.WriteString("You are an employment coach summarising the progress of a trainee with intellectual disabilities for their workplace manager and school supervisor.\n")
+	b.WriteString("Use only the facts below. Be concise, factual and encouraging.\n\n")
+	fmt.Fprintf(&b, "Trainee: %s\n", s.StudentName)
+	if s.EmployerName != "" {
+		fmt.Fprintf(&b, "Employer: %s\n", s.EmployerName)
+	}
+	fmt.Fprintf(&b, "Period: %s to %s\n\n", s.From, s.To)
+	b.WriteString("Attendance:\n")
+	for _, line := range attendanceFacts(s.Attendance) {
+		fmt.Fprintf(&b, "- %s\n", line)
+	}
+	b.WriteString("Mood:\n")
+	for _, line := range moodFacts(s.Moods) {
+		fmt.Fprintf(&b, "- %s\n", line)
+	}
+	if s.Feedback.Count > 0 {
+		b.WriteString("Manager feedback:\n")
+		for _, line := range feedbackFacts(s.Feedback) {
+			fmt.Fprintf(&b, "- %s\n", line)
+		}
+		for _, c := range s.Feedback.Comments {
+			fmt.Fprintf(&b, "- Comment: %s\n", c)
+		}
+	}
+	if remarks := strings.TrimSpace(s.Remarks); remarks != "" {
+		fmt.Fprintf(&b, "Remarks: %s\n", remarks)
+	}
+	b.WriteString("\nWrite these sections:\n")
+	b.WriteString("1. Overall performance: a brief, factual overview.\n")
+	b.WriteString("2. Strengths: key areas of success.\n")
+	b.WriteString("3. Growth areas: specific, actionable points for improvement.\n")
+	b.WriteString("4. Recommendations: practical advice for the manager and supervisor.\n")
+	return b.String()
+}
+
+// Template writes a plain summary of the facts in s. The same facts always
+// give the same text.
+func Template(s *models.TraineeSummary) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Summary for %s from %s to %s", s.StudentName, s.From, s.To)
+	if s.EmployerName != "" {
+		fmt.Fprintf(&b, " at %s", s.EmployerName)
+	}
+	b.WriteString(".\n\n")
+
+	a := s.Attendance
+	if a.DaysAttended == 0 && a.DaysAbsent == 0 {
+		b.WriteString("No attendance was recorded in this period.")
+	} else {
+		b.WriteString(strings.Join(attendanceFacts(a), ". ") + ".")
+		if a.OnTimePercentage != nil {
+			if *a.OnTimePercentage >= 85 {
+				b.WriteString(" Punctuality is good.")
+			} else {
+				b.WriteString(" Punctuality needs focus.")
+			}
+		}
+	}
+	b.WriteString("\n\n")
+
+	if s.Moods.Count == 0 {
+		b.WriteString("No moods were recorded in this period.")
+	} else {
+		b.WriteString(strings.Join(moodFacts(s.Moods), ". ") + ".")
+	}
+
+	if f := s.Feedback; f.Count > 0 {
+		b.WriteString("\n\n" + strings.Join(feedbackFacts(f), ". ") + ".")
+		if n := min(len(f.Comments), templateComments); n > 0 {
+			fmt.Fprintf(&b, " Latest comments: %s.", strings.Join(f.Comments[:n], "; "))
+		}
+	}
+
+	if remarks := strings.TrimSpace(s.Remarks); remarks != "" {
+		fmt.Fprintf(&b, "\n\nRemarks: %s", remarks)
+	}
+	return b.String()
+}
+
+// attendanceFacts describes attendance counts as short sentences
+func attendanceFacts(a models.AttendanceSummary) []string {
+	facts := []string{
+		fmt.Sprintf("Attended %s and missed %s", plural(a.DaysAttended, "day"), plural(a.DaysAbsent, "scheduled day")),
+		fmt.Sprintf("Worked %d hours %d minutes in total", a.WorkedMinutes/60, a.WorkedMinutes%60),
+	}
+	if a.OnTimePercentage != nil {
+		facts = append(facts, fmt.Sprintf("Arrived on time for %.0f%% of scheduled sessions (%s late, %s left early)",
+			*a.OnTimePercentage, plural(a.Late, "session"), plural(a.LeftEarly, "session")))
+	}
+	if a.OutsideGeofence > 0 {
+		facts = append(facts, fmt.Sprintf("Checked in away from the workplace %s", plural(a.OutsideGeofence, "time")))
+	}
+	return facts
+}
+
+// moodFacts describes mood counts as short sentences, emotions most
+// frequent first
+func moodFacts(m models.MoodSummary) []string {
+	if m.Count == 0 {
+		return []string{"No moods recorded"}
+	}
+	emotions := make([]string, 0, len(m.Emotions))
+	for e := range m.Emotions {
+		emotions = append(emotions, e)
+	}
+	sort.Slice(emotions, func(i, j int) bool {
+		if m.Emotions[emotions[i]] != m.Emotions[emotions[j]] {
+			return m.Emotions[emotions[i]] > m.Emotions[emotions[j]]
+		}
+		return emotions[i] < emotions[j]
+	})
+	counts := make([]string, len(emotions))
+	for i, e := range emotions {
+		counts[i] = fmt.Sprintf("%s %d", e, m.Emotions[e])
+	}
+
+	facts := []string{fmt.Sprintf("Recorded %s (%d daily): %s", plural(m.Count, "mood"), m.DailyCount, strings.Join(counts, ", "))}
+	if m.AverageValence != nil {
+		facts = append(facts, fmt.Sprintf("Average mood score %.2f on a scale where negative is low", *m.AverageValence))
+	}
+	if m.Latest != "" {
+		facts = append(facts, fmt.Sprintf("Latest mood %s", m.Latest))
+	}
+	if m.NegativeStreak > 1 {
+		facts = append(facts, fmt.Sprintf("The last %d daily moods were negative", m.NegativeStreak))
+	}
+	return facts
+}
+
+// templateComments is how many feedback comments the template quotes
+const templateComments = 3
+
+// feedbackFacts describes feedback counts and average ratings, competencies
+// in alphabetical order
+func feedbackFacts(f models.FeedbackSummary) []string {
+	facts := []string{fmt.Sprintf("Managers gave feedback %s", plural(f.Count, "time"))}
+	competencies := make([]string, 0, len(f.AverageRatings))
+	for c := range f.AverageRatings {
+		competencies = append(competencies, c)
+	}
+	sort.Strings(competencies)
+	if len(competencies) > 0 {
+		ratings := make([]string, len(competencies))
+		for i, c := range competencies {
+			ratings[i] = fmt.Sprintf("%s %.1f", strings.ReplaceAll(c, "_", " "), f.AverageRatings[c])
+		}
+		facts = append(facts, fmt.Sprintf("Average ratings out of %d: %s", models.FeedbackRatingMax, strings.Join(ratings, ", ")))
+	}
+	return facts
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(n) + " " + noun + "s"
+}
+
+// Stub answers every prompt with Text, or fails with Err, and keeps the
+// prompts it was given. It is used in tests.
+type Stub struct {
+	Text string
+	Err  error
+
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (*Stub) Name() string { return "stub" }
+
+func (s *Stub) Complete(_ context.Context, prompt string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prompts = append(s.prompts, prompt)
+	return s.Text, s.Err
+}
+
+// Prompts returns the prompts completed so far
+func (s *Stub) Prompts() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.prompts...)
+}
+
+// FromEnv builds the service selected by SUMMARY_PROVIDER ("gemini" or
+// "template"). It defaults to Gemini when GEMINI_API_KEY is set and to the
+// template otherwise.
+func FromEnv() *Service {
+	// A slow model must not hold the trainee summary request for long
+	timeout := min(max(config.Duration("SUMMARY_TIMEOUT", defaultTimeout), time.Second), 2*time.Minute)
+	apiKey := config.String("GEMINI_API_KEY", "")
+
+	def := SourceTemplate
+	if apiKey != "" {
+		def = "gemini"
+	}
+	switch name := strings.ToLower(config.String("SUMMARY_PROVIDER", def)); name {
+	case "gemini":
+		if apiKey == "" {
+			log.Printf("⚠️ SUMMARY_PROVIDER=gemini but GEMINI_API_KEY is not set, using the summary template")
+			return New(nil, timeout)
+		}
+		return New(NewGemini(GeminiConfig{
+			APIKey:  apiKey,
+			Model:   config.String("GEMINI_MODEL", "gemini-1.5-flash"),
+			Timeout: timeout,
+		}), timeout)
+	case SourceTemplate:
+		return New(nil, timeout)
+	default:
+		log.Printf("⚠️ Unknown SUMMARY_PROVIDER %q, using the summary template", name)
+		return New(nil, timeout)
+	}
+}
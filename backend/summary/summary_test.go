@@ -0,0 +1,67 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"server/models"
+)
+
+func TestGeminiComplete(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Goog-Api-Key") != "secret-key" {
+			t.Errorf("missing API key header")
+		}
+		if strings.Contains(r.URL.String(), "secret-key") {
+			t.Errorf("API key leaked into URL %s", r.URL)
+		}
+		var req geminiRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Contents) != 1 || req.Contents[0].Parts[0].Text != "Summarise Chamari" {
+			t.Errorf("request = %+v (%v)", req, err)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"candidates": []map[string]interface{}{
+				{"content": map[string]interface{}{"parts": []map[string]string{{"text": "Chamari "}, {"text": "is doing well."}}}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	g := NewGemini(GeminiConfig{APIKey: "secret-key", Endpoint: server.URL})
+	text, err := g.Complete(context.Background(), "Summarise Chamari")
+	if err != nil || text != "Chamari is doing well." {
+		t.Errorf("Complete = %q, %v", text, err)
+	}
+}
+
+func TestGeminiFailureFallsBackToTemplate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	s := &models.TraineeSummary{StudentName: "Chamari Perera", From: "2025-01-06", To: "2025-01-12"}
+	New(NewGemini(GeminiConfig{APIKey: "secret-key", Endpoint: server.URL}), time.Second).Write(context.Background(), s)
+	if s.Source != SourceTemplate || s.Summary != Template(s) {
+		t.Errorf("summary = %q from %s", s.Summary, s.Source)
+	}
+	if !strings.Contains(s.Summary, "No attendance was recorded") {
+		t.Errorf("template %q does not mention missing attendance", s.Summary)
+	}
+}
+
+func TestTimeoutsAreClamped(t *testing.T) {
+	if g := NewGemini(GeminiConfig{APIKey: "secret-key"}); g.client.Timeout != defaultTimeout {
+		t.Errorf("gemini client timeout = %v, want %v", g.client.Timeout, defaultTimeout)
+	}
+	t.Setenv("SUMMARY_PROVIDER", "template")
+	t.Setenv("SUMMARY_TIMEOUT", "0s")
+	if svc := FromEnv(); svc.timeout != time.Second {
+		t.Errorf("summary timeout = %v, want %v", svc.timeout, time.Second)
+	}
+}
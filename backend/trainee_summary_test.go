@@ -0,0 +1,96 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"server/models"
+	"server/summary"
+)
+
+func TestTraineeSummary(t *testing.T) {
+	api := newTestAPI(t)
+	studentID := api.supervisedStudent("Chamari")
+	token := api.pairDevice(studentID)
+
+	student, err := api.store.Students.Get(studentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	student.Remarks = "Prefers morning shifts"
+	if err := api.store.Students.Update(studentID, student); err != nil {
+		t.Fatal(err)
+	}
+
+	// Three days in January 2025 in Colombo: on time, late and absent
+	colombo, _ := time.LoadLocation("Asia/Colombo")
+	day := func(d, hour int) time.Time { return time.Date(2025, 1, d, hour, 0, 0, 0, colombo) }
+	outside := false
+	missedShift := day(8, 8)
+	sessions := []models.Attendance{
+		{StudentID: studentID, CheckInDateTime: day(6, 8), CheckOutDateTime: sql.NullTime{Time: day(6, 16), Valid: true}, Punctuality: models.PunctualityOnTime},
+		{StudentID: studentID, CheckInDateTime: day(7, 9), CheckOutDateTime: sql.NullTime{Time: day(7, 16), Valid: true}, Punctuality: models.PunctualityLate, CheckInInsideGeofence: &outside},
+		{StudentID: studentID, Absent: true, ExpectedStart: &missedShift, Punctuality: models.PunctualityAbsent},
+	}
+	for i := range sessions {
+		if err := api.store.Attendance.Create(&sessions[i]); err != nil {
+			t.Fatalf("creating attendance: %v", err)
+		}
+	}
+	postMood(api, token, "happy", true, day(6, 8))
+	postMood(api, token, "sad", true, day(7, 8))
+	postMood(api, token, "sad", true, day(8, 8))
+
+	const query = "/trainee-summary?from=2025-01-06&to=2025-01-12"
+	var got models.TraineeSummary
+	api.mustDo("GET", query, api.adminToken, studentHeader(studentID), nil, http.StatusOK, &got)
+	a := got.Attendance
+	if a.DaysAttended != 2 || a.DaysAbsent != 1 || a.WorkedMinutes != 15*60 || a.OnTime != 1 || a.Late != 1 || a.OutsideGeofence != 1 ||
+		a.OnTimePercentage == nil || *a.OnTimePercentage != 50 {
+		t.Errorf("attendance = %+v", a)
+	}
+	if m := got.Moods; m.Count != 3 || m.Emotions["sad"] != 2 || m.NegativeStreak != 2 || m.Latest != "sad" {
+		t.Errorf("moods = %+v", m)
+	}
+	if got.Source != summary.SourceTemplate || got.EmployerName != "Kingsbury" || got.Remarks != "Prefers morning shifts" {
+		t.Errorf("summary %+v", got)
+	}
+	for _, want := range []string{"Chamari Perera", "Attended 2 days and missed 1 scheduled day", "Prefers morning shifts"} {
+		if !strings.Contains(got.Summary, want) {
+			t.Errorf("template summary %q lacks %q", got.Summary, want)
+		}
+	}
+
+	// The template is deterministic
+	var again models.TraineeSummary
+	api.mustDo("GET", query, api.adminToken, studentHeader(studentID), nil, http.StatusOK, &again)
+	if again.Summary != got.Summary {
+		t.Errorf("template summary changed:\n%s\n%s", got.Summary, again.Summary)
+	}
+
+	stub := &summary.Stub{Text: "Chamari is settling in well."}
+	api.handler.UseSummaries(summary.New(stub, time.Second))
+	api.mustDo("GET", query, api.adminToken, studentHeader(studentID), nil, http.StatusOK, &got)
+	if got.Source != "stub" || got.Summary != stub.Text {
+		t.Errorf("stub summary = %q from %s", got.Summary, got.Source)
+	}
+	if prompts := stub.Prompts(); len(prompts) != 1 || !strings.Contains(prompts[0], "Trainee: Chamari Perera") || !strings.Contains(prompts[0], "Remarks: Prefers morning shifts") {
+		t.Errorf("prompts = %q", prompts)
+	}
+
+	// A failing provider falls back to the template
+	api.handler.UseSummaries(summary.New(&summary.Stub{Err: errors.New("quota exceeded")}, time.Second))
+	api.mustDo("GET", query, api.adminToken, studentHeader(studentID), nil, http.StatusOK, &got)
+	if got.Source != summary.SourceTemplate || got.Summary != again.Summary {
+		t.Errorf("fallback summary = %q from %s", got.Summary, got.Source)
+	}
+
+	// Only the trainee's own supervisor may read it
+	otherSupervisor := api.createSupervisor("Nimal")
+	api.mustDo("GET", query, api.supervisorToken("nimal", otherSupervisor), studentHeader(studentID), nil, http.StatusForbidden, nil)
+	api.mustDo("GET", query, token, nil, nil, http.StatusForbidden, nil)
+}